@@ -6,8 +6,6 @@ import (
 	"log"
 
 	"golang.org/x/sync/errgroup"
-
-	"database-simon/internal/database/storage/replication"
 )
 
 // App ...
@@ -65,28 +63,36 @@ func (a *App) Run(ctx context.Context) error {
 
 	var err error
 
-	if a.serviceProvider.Config(ctx).WAL != nil {
-		if a.serviceProvider.slave != (*replication.Slave)(nil) { // TOOD: ?!
-			a.serviceProvider.Logger(ctx).Info("start slave replication")
-			group.Go(func() error {
-				a.serviceProvider.slave.Start(groupCtx)
-				return nil
-			})
-		} else {
-			a.serviceProvider.Logger(ctx).Info("start WAL")
-			group.Go(func() error {
-				a.serviceProvider.WAL(ctx).Start(groupCtx)
-				return nil
-			})
-		}
+	group.Go(func() error {
+		a.serviceProvider.engine.Start(groupCtx)
+		return nil
+	})
 
-		if a.serviceProvider.master != nil {
-			a.serviceProvider.Logger(ctx).Info("start master replication")
-			group.Go(func() error {
-				a.serviceProvider.master.Start(groupCtx)
-				return nil
-			})
-		}
+	group.Go(func() error {
+		a.serviceProvider.storage.Start(groupCtx)
+		return nil
+	})
+
+	if a.serviceProvider.wal != nil {
+		// WAL of the slave is idle, but it's started to be ready for promotion
+		a.serviceProvider.Logger(ctx).Info("start WAL")
+		group.Go(func() error {
+			a.serviceProvider.WAL(ctx).Start(groupCtx)
+			return nil
+		})
+	}
+
+	if a.serviceProvider.node != nil {
+		a.serviceProvider.Logger(ctx).Info("start replication")
+		group.Go(func() error {
+			a.serviceProvider.node.Start(groupCtx)
+			return nil
+		})
+	}
+
+	if a.serviceProvider.raft != nil {
+		a.serviceProvider.Logger(ctx).Info("start consensus")
+		a.serviceProvider.raft.Start(groupCtx)
 	}
 
 	group.Go(func() error {
@@ -14,21 +14,33 @@ import (
 	"database-simon/internal/database/compute"
 	"database-simon/internal/database/filesystem"
 	"database-simon/internal/database/storage"
+	"database-simon/internal/database/storage/engine/lsm"
 	"database-simon/internal/database/storage/engine/memory"
+	"database-simon/internal/database/storage/raft"
 	"database-simon/internal/database/storage/replication"
 	"database-simon/internal/database/storage/wal"
 	"database-simon/internal/network/client"
 	"database-simon/internal/network/server"
 )
 
+// engine keeps data of the storage, it works in background after the start
+type engine interface {
+	Start(context.Context)
+}
+
 type serviceProvider struct {
 	logger *zap.Logger
 
+	engine   engine
+	lsm      *lsm.LSM
+	storage  *storage.Storage
 	wal      *wal.WAL
-	slave    *replication.Slave
-	master   *replication.Master
+	node     *replication.Node
+	raft     *raft.Raft
 	database *database.Database
 
+	acknowledgements *replication.Acknowledgements
+
 	configFileName string
 	config         *config.Config
 
@@ -48,46 +60,53 @@ func (sp *serviceProvider) Database(ctx context.Context) *database.Database {
 	if sp.database == nil {
 		comp := compute.NewCompute()
 
-		var memoryOptions []memory.EngineOption
-		if sp.Config(ctx).Engine.PartitionsNumber != 0 {
-			memoryOptions = append(memoryOptions, memory.WithPartitions(sp.Config(ctx).Engine.PartitionsNumber))
-		}
-
-		memoryEngine, err := memory.NewMemory(sp.Logger(ctx), memoryOptions...)
-		if err != nil {
-			log.Fatal("init memory engine error")
-		}
-
-		replica, err := sp.Replica(ctx)
+		node, err := sp.Replica(ctx)
 		if err != nil {
 			log.Fatalf("init replica error: %v", err)
 		}
+		sp.node = node
 
-		switch v := replica.(type) {
-		case *replication.Slave:
-			sp.slave = v
-		case *replication.Master:
-			sp.master = v
+		consensus, err := sp.Consensus(ctx)
+		if err != nil {
+			log.Fatalf("init consensus error: %v", err)
 		}
+		sp.raft = consensus
 
 		var storageOptions []storage.Option
-		if sp.WAL(ctx) != nil {
+		if sp.raft != nil {
+			// the log of the cluster replaces WAL, committed writes of other nodes come through the stream
+			storageOptions = append(storageOptions, storage.WithWAL(sp.raft))
+			storageOptions = append(storageOptions, storage.WithReplication(sp.raft))
+			storageOptions = append(storageOptions, storage.WithReplicationStream(sp.raft.ReplicationStream()))
+		} else if sp.WAL(ctx) != nil {
 			storageOptions = append(storageOptions, storage.WithWAL(sp.WAL(ctx)))
+			storageOptions = append(storageOptions, storage.WithCheckpointInterval(sp.Config(ctx).WAL.CheckpointInterval))
+		}
+
+		var databaseOptions []database.Option
+		if sp.node != nil {
+			storageOptions = append(storageOptions, storage.WithReplication(sp.node))
+			storageOptions = append(storageOptions, storage.WithReplicationStream(sp.node.ReplicationStream()))
+			databaseOptions = append(databaseOptions, database.WithReplication(sp.node))
 		}
 
-		if sp.master != nil {
-			storageOptions = append(storageOptions, storage.WithReplication(sp.master))
-		} else if sp.slave != nil {
-			storageOptions = append(storageOptions, storage.WithReplication(sp.slave))
-			storageOptions = append(storageOptions, storage.WithReplicationStream(sp.slave.ReplicationStream()))
+		var stor *storage.Storage
+		if sp.Config(ctx).Engine.Typ == config.LSMEngine {
+			lsmEngine := sp.lsmEngine(ctx)
+			sp.engine = lsmEngine
+			stor, err = storage.NewStorage(lsmEngine, sp.Logger(ctx), storageOptions...)
+		} else {
+			memoryEngine := sp.memoryEngine(ctx)
+			sp.engine = memoryEngine
+			stor, err = storage.NewStorage(memoryEngine, sp.Logger(ctx), storageOptions...)
 		}
 
-		stor, err := storage.NewStorage(memoryEngine, sp.Logger(ctx), storageOptions...)
 		if err != nil {
-			log.Fatal("init storage error")
+			log.Fatalf("init storage error: %v", err)
 		}
+		sp.storage = stor
 
-		db, err := database.NewDatabase(sp.Logger(ctx), comp, stor)
+		db, err := database.NewDatabase(sp.Logger(ctx), comp, stor, databaseOptions...)
 		if err != nil {
 			log.Fatal("init db error")
 		}
@@ -97,6 +116,91 @@ func (sp *serviceProvider) Database(ctx context.Context) *database.Database {
 	return sp.database
 }
 
+// evictionPolicies maps names of the config to policies, noeviction is the default
+var evictionPolicies = map[string]memory.EvictionPolicy{
+	config.NoEvictionPolicy:    memory.NoEviction,
+	config.AllKeysLRUPolicy:    memory.AllKeysLRU,
+	config.AllKeysLFUPolicy:    memory.AllKeysLFU,
+	config.VolatileTTLPolicy:   memory.VolatileTTL,
+	config.AllKeysRandomPolicy: memory.AllKeysRandom,
+}
+
+func (sp *serviceProvider) memoryEngine(ctx context.Context) *memory.Memory {
+	engineType := sp.Config(ctx).Engine.Typ
+	if _, found := config.SupportedEngines[engineType]; !found && engineType != "" {
+		log.Fatalf("init memory engine error: unsupported engine type %q", engineType)
+	}
+
+	var memoryOptions []memory.EngineOption
+	if engineType == config.OrderedEngine {
+		memoryOptions = append(memoryOptions, memory.WithOrdered())
+	}
+
+	if sp.Config(ctx).Engine.PartitionsNumber != 0 {
+		memoryOptions = append(memoryOptions, memory.WithPartitions(sp.Config(ctx).Engine.PartitionsNumber))
+	}
+
+	if sp.Config(ctx).Engine.MVCC {
+		memoryOptions = append(memoryOptions, memory.WithMVCC())
+	}
+
+	if sp.Config(ctx).Engine.LoadFactor != 0 {
+		memoryOptions = append(memoryOptions, memory.WithLoadFactor(sp.Config(ctx).Engine.LoadFactor))
+	}
+
+	if maxMemory := sp.Config(ctx).Engine.GetMaxMemory(); maxMemory != 0 {
+		policyName := sp.Config(ctx).Engine.EvictionPolicy
+		policy, found := evictionPolicies[policyName]
+		if !found && policyName != "" {
+			log.Fatalf("init memory engine error: unsupported eviction policy %q", policyName)
+		}
+		memoryOptions = append(memoryOptions, memory.WithMaxMemory(maxMemory, policy))
+	}
+
+	memoryEngine, err := memory.NewMemory(sp.Logger(ctx), memoryOptions...)
+	if err != nil {
+		log.Fatalf("init memory engine error: %v", err)
+	}
+
+	return memoryEngine
+}
+
+// lsmEngine keeps tables in its own directory, the WAL is its commit log,
+// the master of replication dumps it for replicas
+func (sp *serviceProvider) lsmEngine(ctx context.Context) *lsm.LSM {
+	if sp.lsm != nil {
+		return sp.lsm
+	}
+
+	engineConfig := sp.Config(ctx).Engine
+	if engineConfig.MVCC {
+		log.Fatal("init lsm engine error: mvcc is not supported")
+	}
+
+	var lsmOptions []lsm.EngineOption
+	if size := engineConfig.GetMemtableSize(); size != 0 {
+		lsmOptions = append(lsmOptions, lsm.WithMemtableSize(size))
+	}
+
+	if size := engineConfig.GetBlockCacheSize(); size != 0 {
+		lsmOptions = append(lsmOptions, lsm.WithBlockCacheSize(size))
+	}
+
+	dataDirectory := engineConfig.GetDataDirectory()
+	lsmEngine, err := lsm.NewLSM(
+		filesystem.NewTablesDirectory(dataDirectory),
+		filesystem.NewStateFile(dataDirectory, "MANIFEST"),
+		sp.Logger(ctx),
+		lsmOptions...,
+	)
+	if err != nil {
+		log.Fatalf("init lsm engine error: %v", err)
+	}
+	sp.lsm = lsmEngine
+
+	return sp.lsm
+}
+
 // Logger ...
 func (sp *serviceProvider) Logger(_ context.Context) *zap.Logger {
 	if sp.logger == nil {
@@ -179,17 +283,29 @@ func (sp *serviceProvider) WAL(ctx context.Context) *wal.WAL {
 		log.Fatal(err)
 	}
 
-	segment := filesystem.NewSegment(sp.Config(ctx).WAL.GetDataDirectory(), sp.Config(ctx).WAL.GetMaxSegmentSize())
+	segment := filesystem.NewSegment(
+		sp.Config(ctx).WAL.GetDataDirectory(),
+		sp.Config(ctx).WAL.GetMaxSegmentSize(),
+		filesystem.WithSegmentHeader(wal.SegmentHeader()),
+	)
 	writer, err := wal.NewLogsWriter(segment, sp.Logger(ctx))
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	snapshots := filesystem.NewSnapshotsDirectory(sp.Config(ctx).WAL.GetDataDirectory())
+
+	options := []wal.Option{wal.WithSnapshots(snapshots)}
+	if acknowledgements := sp.Acknowledgements(ctx); acknowledgements != nil {
+		options = append(options, wal.WithReplicas(acknowledgements))
+	}
+
 	w, err := wal.NewWAL(
 		writer,
 		reader,
 		sp.Config(ctx).WAL.GetFlushingBatchTimeout(),
 		sp.Config(ctx).WAL.GetFlushingBatchSize(),
+		options...,
 	)
 	if err != nil {
 		log.Fatal(err)
@@ -200,8 +316,44 @@ func (sp *serviceProvider) WAL(ctx context.Context) *wal.WAL {
 	return sp.wal
 }
 
+// Acknowledgements returns nil if replication is asynchronous
+func (sp *serviceProvider) Acknowledgements(ctx context.Context) *replication.Acknowledgements {
+	if sp.acknowledgements != nil {
+		return sp.acknowledgements
+	}
+
+	// a slave can be promoted, so acknowledgements are created for any role
+	cfg := sp.Config(ctx).Replication
+	if cfg == nil || cfg.SyncReplicas == 0 {
+		return nil
+	}
+
+	if cfg.SyncReplicas > cfg.GetMaxReplicasNumber() {
+		log.Fatal("number of synchronous replicas exceeds max replicas number")
+	}
+
+	policy, err := cfg.GetSyncTimeoutPolicy()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	acknowledgements, err := replication.NewAcknowledgements(
+		cfg.SyncReplicas,
+		cfg.GetSyncTimeout(),
+		policy == config.AsyncPolicy,
+		sp.Logger(ctx),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sp.acknowledgements = acknowledgements
+
+	return sp.acknowledgements
+}
+
 // Replica ...
-func (sp *serviceProvider) Replica(ctx context.Context) (interface{}, error) {
+func (sp *serviceProvider) Replica(ctx context.Context) (*replication.Node, error) {
 	if sp.Config(ctx).Replication == nil {
 		return nil, nil
 	}
@@ -210,39 +362,165 @@ func (sp *serviceProvider) Replica(ctx context.Context) (interface{}, error) {
 		return nil, errors.New("replica type is incorrect")
 	}
 
-	maxMessageSize := sp.Config(ctx).WAL.GetMaxSegmentSize()
+	if sp.Config(ctx).WAL == nil {
+		return nil, errors.New("replication requires WAL")
+	}
+
+	maxMessageSize := sp.Config(ctx).Replication.GetMaxMessageSize()
 	walDirectory := sp.Config(ctx).WAL.GetDataDirectory()
+	reader, err := wal.NewLogsReader(filesystem.NewSegmentsDirectory(walDirectory))
+	if err != nil {
+		return nil, err
+	}
 
-	if sp.Config(ctx).WAL.DataDirectory != "" {
-		walDirectory = sp.Config(ctx).WAL.DataDirectory
+	// replicas which are behind removed segments get the last checkpoint of the master
+	snapshots := filesystem.NewSnapshotsDirectory(walDirectory)
+
+	// segments of the lsm engine are removed after flushes without checkpoints, it's dumped instead
+	masterSnapshots := replication.WithMasterSnapshots(snapshots)
+	if sp.Config(ctx).Engine.Typ == config.LSMEngine {
+		persistedSnapshots, err := storage.NewPersistedSnapshots(sp.lsmEngine(ctx))
+		if err != nil {
+			return nil, err
+		}
+		masterSnapshots = replication.WithMasterSnapshots(persistedSnapshots)
 	}
 
-	if sp.Config(ctx).WAL.MaxSegmentSize != "" {
-		size, _ := common.ParseSize(sp.Config(ctx).WAL.MaxSegmentSize)
-		maxMessageSize = size
+	term, err := replication.NewTerm(filesystem.NewTermFile(walDirectory))
+	if err != nil {
+		return nil, err
 	}
 
 	idleTimeout := sp.Config(ctx).Replication.GetSyncInterval() * 3
-	if sp.config.Replication.ReplicaType == config.MasterType {
+	newMaster := func(address string, masterOptions ...replication.MasterOption) (*replication.Master, error) {
 		var options []server.TCPServerOption
 		options = append(options, server.WithServerIdleTimeout(idleTimeout))
 		options = append(options, server.WithServerBufferSize(uint(maxMessageSize)))                                              // nolint : G115: integer overflow conversion int -> uint (gosec)
 		options = append(options, server.WithServerMaxConnectionsNumber(uint(sp.Config(ctx).Replication.GetMaxReplicasNumber()))) // nolint : G115: integer overflow conversion int -> uint (gosec)
-		s, err := server.NewTCPServer(sp.Config(ctx).Replication.MasterAddress, sp.Logger(ctx), options...)
+		s, err := server.NewTCPServer(address, sp.Logger(ctx), options...)
+		if err != nil {
+			return nil, err
+		}
+
+		if acknowledgements := sp.Acknowledgements(ctx); acknowledgements != nil {
+			masterOptions = append(masterOptions, replication.WithAcknowledgements(acknowledgements))
+		}
+
+		// the master is started after the storage is created
+		masterOptions = append(masterOptions, masterSnapshots)
+		masterOptions = append(masterOptions, replication.WithWatermark(func() int64 {
+			return sp.storage.AppliedLSN()
+		}))
+		masterOptions = append(masterOptions, replication.WithCurrentSnapshot(func() (int64, []byte, error) {
+			return sp.storage.Snapshot(ctx)
+		}))
+		return replication.NewMaster(s, reader, maxMessageSize, sp.Logger(ctx), masterOptions...)
+	}
+
+	newSlave := func(address string, slaveOptions ...replication.SlaveOption) (*replication.Slave, error) {
+		var options []client.TCPClientOption
+		//options = append(options, client.WithClientIdleTimeout(idleTimeout))
+		options = append(options, client.WithClientBufferSize(uint(maxMessageSize))) // nolint : G115: integer overflow conversion int -> uint (gosec)
+		options = append(options, client.WithClientReconnection())
+		c, err := client.NewTCPClient(address, options...)
 		if err != nil {
 			return nil, err
 		}
 
-		return replication.NewMaster(s, walDirectory, sp.Logger(ctx))
+		// records of the master are written to the own WAL of the slave
+		segment := filesystem.NewSegment(
+			walDirectory,
+			sp.Config(ctx).WAL.GetMaxSegmentSize(),
+			filesystem.WithSegmentHeader(wal.SegmentHeader()),
+		)
+
+		slaveOptions = append(slaveOptions, replication.WithSlaveSnapshots(snapshots))
+		return replication.NewSlave(c, segment, reader, sp.Config(ctx).Replication.GetSyncInterval(), sp.Logger(ctx), slaveOptions...)
+	}
+
+	isMaster := sp.Config(ctx).Replication.ReplicaType == config.MasterType
+	listenAddress := sp.Config(ctx).Replication.ListenAddress
+	if isMaster && listenAddress == "" {
+		listenAddress = sp.Config(ctx).Replication.MasterAddress
+	}
+
+	nodeOptions := []replication.NodeOption{
+		replication.WithOnPromote(func(lsn int64) {
+			// the node is started after the storage is created
+			sp.storage.AdvanceLSN(lsn)
+		}),
+	}
+
+	if sp.Config(ctx).Replication.Cascading {
+		nodeOptions = append(nodeOptions, replication.WithCascading())
+	}
+
+	return replication.NewNode(
+		isMaster,
+		listenAddress,
+		sp.Config(ctx).Replication.MasterAddress,
+		term,
+		newMaster,
+		newSlave,
+		sp.Logger(ctx),
+		nodeOptions...,
+	)
+}
+
+// Consensus ...
+func (sp *serviceProvider) Consensus(ctx context.Context) (*raft.Raft, error) {
+	cfg := sp.Config(ctx).Consensus
+	if cfg == nil {
+		return nil, nil
+	}
+
+	if sp.Config(ctx).Replication != nil {
+		return nil, errors.New("consensus and replication are mutually exclusive")
+	}
+
+	if cfg.ListenAddress == "" {
+		return nil, errors.New("consensus listen address is incorrect")
 	}
 
-	var options []client.TCPClientOption
-	//options = append(options, client.WithClientIdleTimeout(idleTimeout))
-	options = append(options, client.WithClientBufferSize(uint(maxMessageSize))) // nolint : G115: integer overflow conversion int -> uint (gosec)
-	c, err := client.NewTCPClient(sp.Config(ctx).Replication.MasterAddress, options...)
+	maxMessageSize := cfg.GetMaxMessageSize()
+	var options []server.TCPServerOption
+	options = append(options, server.WithServerIdleTimeout(cfg.GetElectionTimeout()*10))
+	options = append(options, server.WithServerBufferSize(uint(maxMessageSize)))             // nolint : G115: integer overflow conversion int -> uint (gosec)
+	options = append(options, server.WithServerMaxConnectionsNumber(uint(len(cfg.Peers)*2))) // nolint : G115: integer overflow conversion int -> uint (gosec)
+	s, err := server.NewTCPServer(cfg.ListenAddress, sp.Logger(ctx), options...)
 	if err != nil {
 		return nil, err
 	}
 
-	return replication.NewSlave(c, walDirectory, sp.Config(ctx).Replication.GetSyncInterval(), sp.Logger(ctx))
+	// a request is limited by the election timeout, so a lost peer doesn't delay heartbeats
+	dial := func(address string) (raft.Client, error) {
+		c, err := client.NewTCPClient(
+			address,
+			client.WithClientBufferSize(uint(maxMessageSize)), // nolint : G115: integer overflow conversion int -> uint (gosec)
+			client.WithClientRequestTimeout(cfg.GetElectionTimeout()),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return c, nil
+	}
+
+	return raft.NewRaft(
+		cfg.NodeID,
+		cfg.Peers,
+		s,
+		dial,
+		filesystem.NewLogFile(cfg.GetDataDirectory(), "raft_log"),
+		filesystem.NewStateFile(cfg.GetDataDirectory(), "raft_state"),
+		sp.Logger(ctx),
+		raft.WithElectionTimeout(cfg.GetElectionTimeout()),
+		raft.WithHeartbeatInterval(cfg.GetHeartbeatInterval()),
+		raft.WithCommitTimeout(cfg.GetCommitTimeout()),
+		raft.WithMaxMessageSize(maxMessageSize),
+		raft.WithOnLeader(func(term int64) {
+			// the node is started after the storage is created
+			sp.storage.AdvanceLSN(replication.TermFirstLSN(term))
+		}),
+	)
 }
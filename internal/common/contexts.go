@@ -5,6 +5,9 @@ import "context"
 // TxID ...
 type TxID string
 
+// SessionID ...
+type SessionID string
+
 // ContextWithTxID ...
 func ContextWithTxID(parent context.Context, value int64) context.Context {
 	return context.WithValue(parent, TxID("tx"), value)
@@ -14,3 +17,29 @@ func ContextWithTxID(parent context.Context, value int64) context.Context {
 func GetTxIDFromContext(ctx context.Context) int64 {
 	return ctx.Value(TxID("tx")).(int64)
 }
+
+// ContextWithSessionID ...
+func ContextWithSessionID(parent context.Context, value int64) context.Context {
+	return context.WithValue(parent, SessionID("session"), value)
+}
+
+// GetSessionIDFromContext ...
+func GetSessionIDFromContext(ctx context.Context) (int64, bool) {
+	value, found := ctx.Value(SessionID("session")).(int64)
+	return value, found
+}
+
+// CommitLSN ...
+type CommitLSN string
+
+// ContextWithCommitLSN makes writes store their LSN to the value
+func ContextWithCommitLSN(parent context.Context, value *int64) context.Context {
+	return context.WithValue(parent, CommitLSN("lsn"), value)
+}
+
+// SetCommitLSN stores LSN of the committed write if the context expects it
+func SetCommitLSN(ctx context.Context, lsn int64) {
+	if value, found := ctx.Value(CommitLSN("lsn")).(*int64); found {
+		*value = lsn
+	}
+}
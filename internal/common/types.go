@@ -0,0 +1,56 @@
+package common
+
+import "errors"
+
+var (
+	// ErrorWrongType ...
+	ErrorWrongType = errors.New("operation against a key holding the wrong kind of value")
+	// ErrorNotANumber is returned when an increment of a score results in NaN
+	ErrorNotANumber = errors.New("resulting score is not a number")
+	// ErrorNotInteger is returned when a counter holds a value which is not a 64-bit integer
+	ErrorNotInteger = errors.New("value is not an integer or out of range")
+	// ErrorNotFloat is returned when a counter holds a value which is not a finite float
+	ErrorNotFloat = errors.New("value is not a valid float")
+	// ErrorOverflow is returned when an increment exceeds the range of the counter
+	ErrorOverflow = errors.New("increment or decrement would overflow")
+	// ErrorReplicationTimeout is returned when the write is committed by the master,
+	// but it's not acknowledged by synchronous replicas in time
+	ErrorReplicationTimeout = errors.New("record is not acknowledged by replicas in time")
+)
+
+const (
+	// StringType ...
+	StringType = "string"
+	// HashType ...
+	HashType = "hash"
+	// ListType ...
+	ListType = "list"
+	// SetType ...
+	SetType = "set"
+	// SortedSetType ...
+	SortedSetType = "zset"
+)
+
+// ScoredMember is a member of a sorted set with its score
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+// ScoreRange bounds scores of a sorted set, bounds are inclusive unless they are marked as exclusive
+type ScoreRange struct {
+	Min          float64
+	Max          float64
+	MinExclusive bool
+	MaxExclusive bool
+}
+
+// AboveMin ...
+func (r ScoreRange) AboveMin(score float64) bool {
+	return score > r.Min || !r.MinExclusive && score == r.Min
+}
+
+// BelowMax ...
+func (r ScoreRange) BelowMax(score float64) bool {
+	return score < r.Max || !r.MaxExclusive && score == r.Max
+}
@@ -14,6 +14,7 @@ type Config struct {
 	TCP         *TCP         `yaml:"network"`
 	WAL         *WAL         `yaml:"wal"`
 	Replication *Replication `yaml:"replication"`
+	Consensus   *Consensus   `yaml:"consensus"`
 }
 
 // NewConfig ...
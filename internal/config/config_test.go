@@ -23,8 +23,14 @@ wal:
 replication:
   replica_type: "slave"
   master_address: "127.0.0.1:3232"
+  listen_address: "127.0.0.1:3233"
   sync_interval: "1s"
   max_replicas_number: 1
+  max_message_size: "1MB"
+  sync_replicas: 1
+  sync_timeout: "2s"
+  sync_timeout_policy: "async"
+  cascading: true
 `
 
 func TestNewConfig(t *testing.T) {
@@ -91,9 +97,16 @@ func TestLoadConfig(t *testing.T) {
 				&Replication{
 					ReplicaType:       "slave",
 					MasterAddress:     "127.0.0.1:3232",
+					ListenAddress:     "127.0.0.1:3233",
 					SyncInterval:      time.Second,
 					MaxReplicasNumber: 1,
+					MaxMessageSize:    "1MB",
+					SyncReplicas:      1,
+					SyncTimeout:       2 * time.Second,
+					SyncTimeoutPolicy: "async",
+					Cascading:         true,
 				},
+				nil,
 			},
 		},
 		"load empty config": {
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"database-simon/internal/common"
+)
+
+const (
+	defaultElectionTimeout        = 300 * time.Millisecond
+	defaultHeartbeatInterval      = 50 * time.Millisecond
+	defaultCommitTimeout          = time.Second
+	defaultConsensusMessageSize   = 4 << 20
+	defaultConsensusDataDirectory = "./data/raft"
+)
+
+// Consensus configures the node of the cluster which elects
+// the leader by Raft, it's an alternative to replication
+type Consensus struct {
+	NodeID            string            `yaml:"node_id"`
+	ListenAddress     string            `yaml:"listen_address"`
+	Peers             map[string]string `yaml:"peers"`
+	ElectionTimeout   time.Duration     `yaml:"election_timeout"`
+	HeartbeatInterval time.Duration     `yaml:"heartbeat_interval"`
+	CommitTimeout     time.Duration     `yaml:"commit_timeout"`
+	MaxMessageSize    string            `yaml:"max_message_size"`
+	DataDirectory     string            `yaml:"data_directory"`
+}
+
+// GetElectionTimeout ...
+func (c Consensus) GetElectionTimeout() time.Duration {
+	electionTimeout := defaultElectionTimeout
+	if c.ElectionTimeout != 0 {
+		electionTimeout = c.ElectionTimeout
+	}
+
+	return electionTimeout
+}
+
+// GetHeartbeatInterval ...
+func (c Consensus) GetHeartbeatInterval() time.Duration {
+	heartbeatInterval := defaultHeartbeatInterval
+	if c.HeartbeatInterval != 0 {
+		heartbeatInterval = c.HeartbeatInterval
+	}
+
+	return heartbeatInterval
+}
+
+// GetCommitTimeout ...
+func (c Consensus) GetCommitTimeout() time.Duration {
+	commitTimeout := defaultCommitTimeout
+	if c.CommitTimeout != 0 {
+		commitTimeout = c.CommitTimeout
+	}
+
+	return commitTimeout
+}
+
+// GetMaxMessageSize ...
+func (c Consensus) GetMaxMessageSize() int {
+	maxMessageSize := defaultConsensusMessageSize
+	if c.MaxMessageSize != "" {
+		size, err := common.ParseSize(c.MaxMessageSize)
+		if err != nil {
+			log.Fatal(errors.New("max consensus message size is incorrect"))
+		}
+
+		maxMessageSize = size
+	}
+
+	return maxMessageSize
+}
+
+// GetDataDirectory ...
+func (c Consensus) GetDataDirectory() string {
+	dataDirectory := defaultConsensusDataDirectory
+	if c.DataDirectory != "" {
+		dataDirectory = c.DataDirectory
+	}
+
+	return dataDirectory
+}
@@ -1,7 +1,93 @@
 package config
 
+import (
+	"errors"
+	"log"
+
+	"database-simon/internal/common"
+)
+
+const (
+	// InMemoryEngine ...
+	InMemoryEngine = "in_memory"
+	// OrderedEngine keeps keys in order for range queries
+	OrderedEngine = "ordered"
+	// LSMEngine keeps keys in sorted tables on disk
+	LSMEngine = "lsm"
+
+	defaultEngineDataDirectory = "./data/lsm"
+)
+
+const (
+	// NoEvictionPolicy rejects writes when the memory limit is reached
+	NoEvictionPolicy = "noeviction"
+	// AllKeysLRUPolicy evicts the least recently used keys
+	AllKeysLRUPolicy = "allkeys-lru"
+	// AllKeysLFUPolicy evicts the least frequently used keys
+	AllKeysLFUPolicy = "allkeys-lfu"
+	// VolatileTTLPolicy evicts keys with expiration which expire first
+	VolatileTTLPolicy = "volatile-ttl"
+	// AllKeysRandomPolicy evicts random keys
+	AllKeysRandomPolicy = "allkeys-random"
+)
+
+// SupportedEngines ...
+var SupportedEngines = map[string]struct{}{
+	InMemoryEngine: {},
+	OrderedEngine:  {},
+	LSMEngine:      {},
+}
+
 // Engine ...
 type Engine struct {
 	Typ              string `yaml:"type"`
 	PartitionsNumber int    `yaml:"partitions_number"`
+	MVCC             bool   `yaml:"mvcc"`
+	// average number of keys in a partition which triggers resharding, zero disables it
+	LoadFactor int `yaml:"load_factor"`
+	// memory limit of in-memory engines, zero means no limit
+	MaxMemory      string `yaml:"max_memory"`
+	EvictionPolicy string `yaml:"eviction_policy"`
+	// options of the LSM engine, zero sizes mean defaults of the engine
+	DataDirectory  string `yaml:"data_directory"`
+	MemtableSize   string `yaml:"memtable_size"`
+	BlockCacheSize string `yaml:"block_cache_size"`
+}
+
+// GetDataDirectory ...
+func (e Engine) GetDataDirectory() string {
+	dataDirectory := defaultEngineDataDirectory
+	if e.DataDirectory != "" {
+		dataDirectory = e.DataDirectory
+	}
+
+	return dataDirectory
+}
+
+// GetMaxMemory ...
+func (e Engine) GetMaxMemory() int {
+	return parseEngineSize(e.MaxMemory, "max memory is incorrect")
+}
+
+// GetMemtableSize ...
+func (e Engine) GetMemtableSize() int {
+	return parseEngineSize(e.MemtableSize, "memtable size is incorrect")
+}
+
+// GetBlockCacheSize ...
+func (e Engine) GetBlockCacheSize() int {
+	return parseEngineSize(e.BlockCacheSize, "block cache size is incorrect")
+}
+
+func parseEngineSize(text, message string) int {
+	if text == "" {
+		return 0
+	}
+
+	size, err := common.ParseSize(text)
+	if err != nil {
+		log.Fatal(errors.New(message))
+	}
+
+	return size
 }
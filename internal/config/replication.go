@@ -2,12 +2,17 @@ package config
 
 import (
 	"errors"
+	"log"
 	"time"
+
+	"database-simon/internal/common"
 )
 
 const (
 	defaultReplicationSyncInterval = time.Second
 	defaultMaxReplicasNumber       = 5
+	defaultReplicationMessageSize  = 4 << 20
+	defaultSyncTimeout             = time.Second
 )
 
 const (
@@ -17,6 +22,14 @@ const (
 	SlaveType = "slave"
 )
 
+const (
+	// FailPolicy reports writes which are not acknowledged by synchronous replicas in time
+	// as errors, such writes are already persisted and applied by the master
+	FailPolicy = "fail"
+	// AsyncPolicy acknowledges such writes and degrades to asynchronous replication
+	AsyncPolicy = "async"
+)
+
 // SupportedTypes ...
 var SupportedTypes = map[string]struct{}{
 	MasterType: {},
@@ -27,8 +40,14 @@ var SupportedTypes = map[string]struct{}{
 type Replication struct {
 	ReplicaType       string        `yaml:"replica_type"`
 	MasterAddress     string        `yaml:"master_address"`
+	ListenAddress     string        `yaml:"listen_address"`
 	SyncInterval      time.Duration `yaml:"sync_interval"`
 	MaxReplicasNumber int           `yaml:"max_replicas_number"`
+	MaxMessageSize    string        `yaml:"max_message_size"`
+	SyncReplicas      int           `yaml:"sync_replicas"`
+	SyncTimeout       time.Duration `yaml:"sync_timeout"`
+	SyncTimeoutPolicy string        `yaml:"sync_timeout_policy"`
+	Cascading         bool          `yaml:"cascading"`
 }
 
 // GetSyncInterval ...
@@ -51,6 +70,44 @@ func (r Replication) GetMaxReplicasNumber() int {
 	return maxReplicasNumber
 }
 
+// GetMaxMessageSize returns a limit of the replication message,
+// records are streamed to slaves in chunks of this size
+func (r Replication) GetMaxMessageSize() int {
+	maxMessageSize := defaultReplicationMessageSize
+	if r.MaxMessageSize != "" {
+		size, err := common.ParseSize(r.MaxMessageSize)
+		if err != nil {
+			log.Fatal(errors.New("max replication message size is incorrect"))
+		}
+
+		maxMessageSize = size
+	}
+
+	return maxMessageSize
+}
+
+// GetSyncTimeout ...
+func (r Replication) GetSyncTimeout() time.Duration {
+	syncTimeout := defaultSyncTimeout
+	if r.SyncTimeout != 0 {
+		syncTimeout = r.SyncTimeout
+	}
+
+	return syncTimeout
+}
+
+// GetSyncTimeoutPolicy ...
+func (r Replication) GetSyncTimeoutPolicy() (string, error) {
+	switch r.SyncTimeoutPolicy {
+	case "":
+		return FailPolicy, nil
+	case FailPolicy, AsyncPolicy:
+		return r.SyncTimeoutPolicy, nil
+	default:
+		return "", errors.New("sync timeout policy is incorrect")
+	}
+}
+
 // GetMasterAddress ...
 func (r Replication) GetMasterAddress() (string, error) {
 	if r.MasterAddress == "" {
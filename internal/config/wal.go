@@ -21,6 +21,7 @@ type WAL struct {
 	FlushingBatchTimeout time.Duration `yaml:"flushing_batch_timeout"`
 	MaxSegmentSize       string        `yaml:"max_segment_size"`
 	DataDirectory        string        `yaml:"data_directory"`
+	CheckpointInterval   time.Duration `yaml:"checkpoint_interval"`
 }
 
 // GetFlushingBatchSize ...
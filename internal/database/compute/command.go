@@ -1,5 +1,14 @@
 package compute
 
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
 const (
 	// SetCommand ...
 	SetCommand = "SET"
@@ -7,35 +16,534 @@ const (
 	GetCommand = "GET"
 	// DelCommand ...
 	DelCommand = "DEL"
+	// ExpireCommand ...
+	ExpireCommand = "EXPIRE"
+	// PExpireCommand ...
+	PExpireCommand = "PEXPIRE"
+	// TTLCommand ...
+	TTLCommand = "TTL"
+	// PTTLCommand ...
+	PTTLCommand = "PTTL"
+	// PersistCommand ...
+	PersistCommand = "PERSIST"
+	// BeginCommand ...
+	BeginCommand = "BEGIN"
+	// CommitCommand ...
+	CommitCommand = "COMMIT"
+	// RollbackCommand ...
+	RollbackCommand = "ROLLBACK"
+	// CheckpointCommand ...
+	CheckpointCommand = "CHECKPOINT"
+	// PromoteCommand ...
+	PromoteCommand = "PROMOTE"
+	// DemoteCommand ...
+	DemoteCommand = "DEMOTE"
+	// InfoCommand ...
+	InfoCommand = "INFO"
+	// WaitCommand ...
+	WaitCommand = "WAIT"
+	// LSNCommand ...
+	LSNCommand = "LSN"
+	// ScanCommand ...
+	ScanCommand = "SCAN"
+	// RangeCommand ...
+	RangeCommand = "RANGE"
+	// KeysCommand ...
+	KeysCommand = "KEYS"
+	// ReshardCommand ...
+	ReshardCommand = "RESHARD"
+	// HSetCommand ...
+	HSetCommand = "HSET"
+	// HGetCommand ...
+	HGetCommand = "HGET"
+	// HDelCommand ...
+	HDelCommand = "HDEL"
+	// HGetAllCommand ...
+	HGetAllCommand = "HGETALL"
+	// LPushCommand ...
+	LPushCommand = "LPUSH"
+	// RPushCommand ...
+	RPushCommand = "RPUSH"
+	// LPopCommand ...
+	LPopCommand = "LPOP"
+	// RPopCommand ...
+	RPopCommand = "RPOP"
+	// LRangeCommand ...
+	LRangeCommand = "LRANGE"
+	// SAddCommand ...
+	SAddCommand = "SADD"
+	// SRemCommand ...
+	SRemCommand = "SREM"
+	// SMembersCommand ...
+	SMembersCommand = "SMEMBERS"
+	// SIsMemberCommand ...
+	SIsMemberCommand = "SISMEMBER"
+	// ZAddCommand ...
+	ZAddCommand = "ZADD"
+	// ZRemCommand ...
+	ZRemCommand = "ZREM"
+	// ZScoreCommand ...
+	ZScoreCommand = "ZSCORE"
+	// ZRankCommand ...
+	ZRankCommand = "ZRANK"
+	// ZRangeCommand ...
+	ZRangeCommand = "ZRANGE"
+	// ZRangeByScoreCommand ...
+	ZRangeByScoreCommand = "ZRANGEBYSCORE"
+	// ZIncrByCommand ...
+	ZIncrByCommand = "ZINCRBY"
+	// IncrCommand ...
+	IncrCommand = "INCR"
+	// DecrCommand ...
+	DecrCommand = "DECR"
+	// IncrByCommand ...
+	IncrByCommand = "INCRBY"
+	// IncrByFloatCommand ...
+	IncrByFloatCommand = "INCRBYFLOAT"
+	// CASCommand ...
+	CASCommand = "CAS"
+	// GetSetCommand ...
+	GetSetCommand = "GETSET"
+	// GetDelCommand ...
+	GetDelCommand = "GETDEL"
+	// MSetCommand ...
+	MSetCommand = "MSET"
+	// MGetCommand ...
+	MGetCommand = "MGET"
+	// MDelCommand ...
+	MDelCommand = "MDEL"
 	// UnknownCommand ...
 	UnknownCommand = "UNKNOWN"
 )
 
+// PExpireAtCommand is written to the WAL instead of EXPIRE and PEXPIRE
+// to keep an absolute deadline, it is not accepted from clients
+const PExpireAtCommand = "PEXPIREAT"
+
+// ReplicationSection is the only section of INFO, it's used by default
+const ReplicationSection = "REPLICATION"
+
 const (
-	setCommandArgumentsNumber = 2
-	getCommandArgumentsNumber = 1
-	delCommandArgumentsNumber = 1
+	// ExOption ...
+	ExOption = "EX"
+	// PxOption ...
+	PxOption = "PX"
+	// NxOption ...
+	NxOption = "NX"
+	// XxOption ...
+	XxOption = "XX"
+	// IfEqOption ...
+	IfEqOption = "IFEQ"
+	// OnOption ...
+	OnOption = "ON"
+	// OffOption ...
+	OffOption = "OFF"
+	// MatchOption ...
+	MatchOption = "MATCH"
+	// CountOption ...
+	CountOption = "COUNT"
+	// LimitOption ...
+	LimitOption = "LIMIT"
+	// WithScoresOption ...
+	WithScoresOption = "WITHSCORES"
 )
 
-var argumentsNumber = map[string]int{
-	SetCommand: setCommandArgumentsNumber,
-	GetCommand: getCommandArgumentsNumber,
-	DelCommand: delCommandArgumentsNumber,
+// StartCursor starts and finishes iteration of SCAN, other cursors are hex-encoded keys
+const StartCursor = "0"
+
+// anyArguments is the maximum of commands with any number of arguments
+const anyArguments = math.MaxInt
+
+type arity struct {
+	min int
+	max int
+}
+
+var argumentsNumber = map[string]arity{
+	SetCommand:           {min: 2, max: 5},
+	GetCommand:           {min: 1, max: 1},
+	DelCommand:           {min: 1, max: 3},
+	ExpireCommand:        {min: 2, max: 2},
+	PExpireCommand:       {min: 2, max: 2},
+	TTLCommand:           {min: 1, max: 1},
+	PTTLCommand:          {min: 1, max: 1},
+	PersistCommand:       {min: 1, max: 1},
+	BeginCommand:         {min: 0, max: 0},
+	CommitCommand:        {min: 0, max: 0},
+	RollbackCommand:      {min: 0, max: 0},
+	CheckpointCommand:    {min: 0, max: 0},
+	PromoteCommand:       {min: 0, max: 1},
+	DemoteCommand:        {min: 1, max: 1},
+	InfoCommand:          {min: 0, max: 1},
+	WaitCommand:          {min: 2, max: 3},
+	LSNCommand:           {min: 1, max: 1},
+	ScanCommand:          {min: 1, max: 5},
+	RangeCommand:         {min: 2, max: 4},
+	KeysCommand:          {min: 1, max: 1},
+	ReshardCommand:       {min: 1, max: 1},
+	HSetCommand:          {min: 3, max: anyArguments},
+	HGetCommand:          {min: 2, max: 2},
+	HDelCommand:          {min: 2, max: anyArguments},
+	HGetAllCommand:       {min: 1, max: 1},
+	LPushCommand:         {min: 2, max: anyArguments},
+	RPushCommand:         {min: 2, max: anyArguments},
+	LPopCommand:          {min: 1, max: 2},
+	RPopCommand:          {min: 1, max: 2},
+	LRangeCommand:        {min: 3, max: 3},
+	SAddCommand:          {min: 2, max: anyArguments},
+	SRemCommand:          {min: 2, max: anyArguments},
+	SMembersCommand:      {min: 1, max: 1},
+	SIsMemberCommand:     {min: 2, max: 2},
+	ZAddCommand:          {min: 3, max: anyArguments},
+	ZRemCommand:          {min: 2, max: anyArguments},
+	ZScoreCommand:        {min: 2, max: 2},
+	ZRankCommand:         {min: 2, max: 2},
+	ZRangeCommand:        {min: 3, max: 4},
+	ZRangeByScoreCommand: {min: 3, max: 7},
+	ZIncrByCommand:       {min: 3, max: 3},
+	IncrCommand:          {min: 1, max: 1},
+	DecrCommand:          {min: 1, max: 1},
+	IncrByCommand:        {min: 2, max: 2},
+	IncrByFloatCommand:   {min: 2, max: 2},
+	CASCommand:           {min: 3, max: 3},
+	GetSetCommand:        {min: 2, max: 2},
+	GetDelCommand:        {min: 1, max: 1},
+	MSetCommand:          {min: 2, max: anyArguments},
+	MGetCommand:          {min: 1, max: anyArguments},
+	MDelCommand:          {min: 1, max: anyArguments},
+}
+
+var argumentsValidators = map[string]func([]string) error{
+	SetCommand:           validateSetArguments,
+	DelCommand:           validateDelArguments,
+	MSetCommand:          validateMSetArguments,
+	ExpireCommand:        validateExpireArguments,
+	PExpireCommand:       validatePExpireArguments,
+	InfoCommand:          validateInfoArguments,
+	WaitCommand:          validateWaitArguments,
+	LSNCommand:           validateLSNArguments,
+	ScanCommand:          validateScanArguments,
+	RangeCommand:         validateRangeArguments,
+	ReshardCommand:       validateReshardArguments,
+	HSetCommand:          validateHSetArguments,
+	LPopCommand:          validatePopArguments,
+	RPopCommand:          validatePopArguments,
+	LRangeCommand:        validateLRangeArguments,
+	ZAddCommand:          validateZAddArguments,
+	ZRangeCommand:        validateZRangeArguments,
+	ZRangeByScoreCommand: validateZRangeByScoreArguments,
+	ZIncrByCommand:       validateZIncrByArguments,
+	IncrByCommand:        validateIncrByArguments,
+	IncrByFloatCommand:   validateIncrByFloatArguments,
 }
 
 func getCommand(command string) string {
-	switch command {
-	case SetCommand:
-		return SetCommand
-	case GetCommand:
-		return GetCommand
-	case DelCommand:
-		return DelCommand
-	default:
-		return UnknownCommand
+	if _, found := argumentsNumber[command]; found {
+		return command
+	}
+
+	return UnknownCommand
+}
+
+func checkArgumentsNumber(command string, number int) bool {
+	commandArity := argumentsNumber[command]
+	return number >= commandArity.min && number <= commandArity.max
+}
+
+func validateArguments(command string, arguments []string) error {
+	validator, found := argumentsValidators[command]
+	if !found {
+		return nil
+	}
+
+	return validator(arguments)
+}
+
+// SET key value [NX | XX] [EX seconds | PX milliseconds]
+func validateSetArguments(arguments []string) error {
+	var condition, expiration bool
+	for idx := 2; idx < len(arguments); idx++ {
+		switch option := strings.ToUpper(arguments[idx]); option {
+		case NxOption, XxOption:
+			if condition {
+				return errors.New("syntax error")
+			}
+			condition = true
+		case ExOption, PxOption:
+			if expiration || idx+1 == len(arguments) {
+				return errors.New("syntax error")
+			}
+			expiration = true
+
+			idx++
+			ttl, err := parseTTL(arguments[idx], option == PxOption)
+			if err != nil || ttl <= 0 {
+				return errors.New("invalid expire time")
+			}
+		default:
+			return errors.New("syntax error")
+		}
 	}
+
+	return nil
 }
 
-func commandArgumentsNumber(command string) int {
-	return argumentsNumber[command]
+// DEL key [IFEQ value]
+func validateDelArguments(arguments []string) error {
+	if len(arguments) == 1 {
+		return nil
+	}
+
+	if len(arguments) != 3 || !strings.EqualFold(arguments[1], IfEqOption) {
+		return errors.New("syntax error")
+	}
+
+	return nil
+}
+
+// MSET key value [key value ...]
+func validateMSetArguments(arguments []string) error {
+	if len(arguments)%2 != 0 {
+		return errors.New("syntax error")
+	}
+
+	return nil
+}
+
+// EXPIRE key seconds
+func validateExpireArguments(arguments []string) error {
+	_, err := parseTTL(arguments[1], false)
+	return err
+}
+
+// PEXPIRE key milliseconds
+func validatePExpireArguments(arguments []string) error {
+	_, err := parseTTL(arguments[1], true)
+	return err
+}
+
+// parseTTL rejects TTLs which overflow the duration in seconds or milliseconds
+func parseTTL(argument string, milliseconds bool) (int64, error) {
+	unit := time.Second
+	if milliseconds {
+		unit = time.Millisecond
+	}
+
+	ttl, err := strconv.ParseInt(argument, 10, 64)
+	if err != nil || ttl > math.MaxInt64/int64(unit) || ttl < math.MinInt64/int64(unit) {
+		return 0, errors.New("invalid expire time")
+	}
+
+	return ttl, nil
+}
+
+// INFO [replication]
+func validateInfoArguments(arguments []string) error {
+	if len(arguments) == 0 {
+		return nil
+	}
+
+	if !strings.EqualFold(arguments[0], ReplicationSection) {
+		return errors.New("unknown section")
+	}
+
+	return nil
+}
+
+// WAIT LSN lsn [timeout milliseconds]
+func validateWaitArguments(arguments []string) error {
+	if !strings.EqualFold(arguments[0], LSNCommand) {
+		return errors.New("syntax error")
+	}
+
+	if lsn, err := strconv.ParseInt(arguments[1], 10, 64); err != nil || lsn < 0 {
+		return errors.New("invalid lsn")
+	}
+
+	if len(arguments) == 3 {
+		if timeout, err := strconv.ParseInt(arguments[2], 10, 64); err != nil || timeout < 0 {
+			return errors.New("invalid timeout")
+		}
+	}
+
+	return nil
+}
+
+// LSN ON|OFF
+func validateLSNArguments(arguments []string) error {
+	if !strings.EqualFold(arguments[0], OnOption) && !strings.EqualFold(arguments[0], OffOption) {
+		return errors.New("syntax error")
+	}
+
+	return nil
+}
+
+// SCAN cursor [MATCH pattern] [COUNT count]
+func validateScanArguments(arguments []string) error {
+	if arguments[0] != StartCursor {
+		if cursor, err := hex.DecodeString(arguments[0]); err != nil || len(cursor) == 0 {
+			return errors.New("invalid cursor")
+		}
+	}
+
+	for idx := 1; idx < len(arguments); idx += 2 {
+		if idx+1 == len(arguments) {
+			return errors.New("syntax error")
+		}
+
+		switch strings.ToUpper(arguments[idx]) {
+		case MatchOption:
+			if _, err := CompilePattern(arguments[idx+1]); err != nil {
+				return err
+			}
+		case CountOption:
+			if count, err := strconv.Atoi(arguments[idx+1]); err != nil || count <= 0 {
+				return errors.New("invalid count")
+			}
+		default:
+			return errors.New("syntax error")
+		}
+	}
+
+	return nil
+}
+
+// RANGE from to [LIMIT limit]
+func validateRangeArguments(arguments []string) error {
+	if len(arguments) == 2 {
+		return nil
+	} else if len(arguments) != 4 {
+		return errors.New("syntax error")
+	}
+
+	if !strings.EqualFold(arguments[2], LimitOption) {
+		return errors.New("syntax error")
+	}
+
+	if limit, err := strconv.Atoi(arguments[3]); err != nil || limit <= 0 {
+		return errors.New("invalid limit")
+	}
+
+	return nil
+}
+
+// RESHARD partitions
+func validateReshardArguments(arguments []string) error {
+	if partitions, err := strconv.Atoi(arguments[0]); err != nil || partitions <= 0 {
+		return errors.New("invalid partitions number")
+	}
+
+	return nil
+}
+
+// HSET key field value [field value ...]
+func validateHSetArguments(arguments []string) error {
+	if len(arguments)%2 == 0 {
+		return errors.New("syntax error")
+	}
+
+	return nil
+}
+
+// LPOP key [count], RPOP key [count]
+func validatePopArguments(arguments []string) error {
+	if len(arguments) == 2 {
+		if count, err := strconv.Atoi(arguments[1]); err != nil || count <= 0 {
+			return errors.New("invalid count")
+		}
+	}
+
+	return nil
+}
+
+// LRANGE key start stop
+func validateLRangeArguments(arguments []string) error {
+	for _, index := range arguments[1:] {
+		if _, err := strconv.Atoi(index); err != nil {
+			return errors.New("invalid index")
+		}
+	}
+
+	return nil
+}
+
+// ZADD key score member [score member ...]
+func validateZAddArguments(arguments []string) error {
+	if len(arguments)%2 == 0 {
+		return errors.New("syntax error")
+	}
+
+	for idx := 1; idx < len(arguments); idx += 2 {
+		if _, err := ParseScore(arguments[idx]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ZRANGE key start stop [WITHSCORES]
+func validateZRangeArguments(arguments []string) error {
+	if err := validateLRangeArguments(arguments[:3]); err != nil {
+		return err
+	}
+
+	if len(arguments) == 4 {
+		if !strings.EqualFold(arguments[3], WithScoresOption) {
+			return errors.New("syntax error")
+		}
+	}
+
+	return nil
+}
+
+// ZRANGEBYSCORE key min max [WITHSCORES] [LIMIT offset count]
+func validateZRangeByScoreArguments(arguments []string) error {
+	for _, bound := range arguments[1:3] {
+		if _, _, err := ParseScoreBound(bound); err != nil {
+			return err
+		}
+	}
+
+	for idx := 3; idx < len(arguments); idx++ {
+		switch option := strings.ToUpper(arguments[idx]); {
+		case option == WithScoresOption && idx == 3:
+		case option == LimitOption && idx+2 == len(arguments)-1:
+			if offset, err := strconv.Atoi(arguments[idx+1]); err != nil || offset < 0 {
+				return errors.New("invalid offset")
+			}
+			if limit, err := strconv.Atoi(arguments[idx+2]); err != nil || limit <= 0 {
+				return errors.New("invalid limit")
+			}
+			return nil
+		default:
+			return errors.New("syntax error")
+		}
+	}
+
+	return nil
+}
+
+// ZINCRBY key increment member
+func validateZIncrByArguments(arguments []string) error {
+	_, err := ParseScore(arguments[1])
+	return err
+}
+
+// INCRBY key increment
+func validateIncrByArguments(arguments []string) error {
+	if _, err := strconv.ParseInt(arguments[1], 10, 64); err != nil {
+		return errors.New("value is not an integer or out of range")
+	}
+
+	return nil
+}
+
+// INCRBYFLOAT key increment
+func validateIncrByFloatArguments(arguments []string) error {
+	if increment, err := strconv.ParseFloat(arguments[1], 64); err != nil || math.IsNaN(increment) || math.IsInf(increment, 0) {
+		return errors.New("value is not a valid float")
+	}
+
+	return nil
 }
@@ -3,7 +3,6 @@ package compute
 import (
 	"context"
 	"fmt"
-	"strings"
 )
 
 // Compute ...
@@ -21,7 +20,11 @@ func NewCompute() Compute {
 
 // Parse ...
 func (c *compute) Parse(_ context.Context, queryStr string) (Query, error) {
-	parts := strings.Split(strings.TrimSpace(queryStr), " ")
+	parts, err := tokenize(queryStr)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(parts) == 0 {
 		return nil, fmt.Errorf("invalid command")
 	}
@@ -32,9 +35,13 @@ func (c *compute) Parse(_ context.Context, queryStr string) (Query, error) {
 	}
 
 	q := NewQuery(command, parts[1:])
-	if len(q.Arguments()) != commandArgumentsNumber(command) {
+	if !checkArgumentsNumber(command, len(q.Arguments())) {
 		return nil, fmt.Errorf("invalid command agruments number")
 	}
 
+	if err = validateArguments(command, q.Arguments()); err != nil {
+		return nil, err
+	}
+
 	return q, nil
 }
@@ -0,0 +1,335 @@
+package compute
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		query string
+
+		expectedQuery Query
+		expectedErr   error
+	}{
+		"parse empty query": {
+			query:       "  ",
+			expectedErr: errors.New("invalid command"),
+		},
+		"parse unknown command": {
+			query:       "TRUNCATE",
+			expectedErr: errors.New("unknown command"),
+		},
+		"parse query with invalid arguments number": {
+			query:       "GET key1 key2",
+			expectedErr: errors.New("invalid command agruments number"),
+		},
+		"parse query with tokenizer error": {
+			query:       `SET key "value`,
+			expectedErr: &ParseError{Position: 8, Message: "unterminated double quoted string"},
+		},
+		"parse set query": {
+			query:         "SET key value\r\n",
+			expectedQuery: NewQuery(SetCommand, []string{"key", "value"}),
+		},
+		"parse set query with quoted value": {
+			query:         `SET key "hello  world"`,
+			expectedQuery: NewQuery(SetCommand, []string{"key", "hello  world"}),
+		},
+		"parse set query with expiration in seconds": {
+			query:         "SET key value EX 10",
+			expectedQuery: NewQuery(SetCommand, []string{"key", "value", ExOption, "10"}),
+		},
+		"parse set query with expiration in milliseconds": {
+			query:         "SET key value px 100",
+			expectedQuery: NewQuery(SetCommand, []string{"key", "value", "px", "100"}),
+		},
+		"parse set query with unknown option": {
+			query:       "SET key value KEEPTTL 100",
+			expectedErr: errors.New("syntax error"),
+		},
+		"parse set query without option value": {
+			query:       "SET key value EX",
+			expectedErr: errors.New("syntax error"),
+		},
+		"parse set query with invalid expiration": {
+			query:       "SET key value EX 0",
+			expectedErr: errors.New("invalid expire time"),
+		},
+		"parse set query with overflowed expiration": {
+			query:       "SET key value EX 9300000000",
+			expectedErr: errors.New("invalid expire time"),
+		},
+		"parse set query with long expiration in milliseconds": {
+			query:         "SET key value PX 9300000000",
+			expectedQuery: NewQuery(SetCommand, []string{"key", "value", PxOption, "9300000000"}),
+		},
+		"parse set query with condition and expiration": {
+			query:         "SET key value nx EX 10",
+			expectedQuery: NewQuery(SetCommand, []string{"key", "value", "nx", ExOption, "10"}),
+		},
+		"parse set query with both conditions": {
+			query:       "SET key value NX XX",
+			expectedErr: errors.New("syntax error"),
+		},
+		"parse cas query": {
+			query:         "CAS key old new",
+			expectedQuery: NewQuery(CASCommand, []string{"key", "old", "new"}),
+		},
+		"parse getset query": {
+			query:         "GETSET key value",
+			expectedQuery: NewQuery(GetSetCommand, []string{"key", "value"}),
+		},
+		"parse del query with expected value": {
+			query:         "DEL key ifeq value",
+			expectedQuery: NewQuery(DelCommand, []string{"key", "ifeq", "value"}),
+		},
+		"parse del query with unknown option": {
+			query:       "DEL key IFNE value",
+			expectedErr: errors.New("syntax error"),
+		},
+		"parse mset query": {
+			query:         "MSET a 1 b 2",
+			expectedQuery: NewQuery(MSetCommand, []string{"a", "1", "b", "2"}),
+		},
+		"parse mset query without value": {
+			query:       "MSET a 1 b",
+			expectedErr: errors.New("syntax error"),
+		},
+		"parse mget query": {
+			query:         "MGET a b c",
+			expectedQuery: NewQuery(MGetCommand, []string{"a", "b", "c"}),
+		},
+		"parse mdel query without keys": {
+			query:       "MDEL",
+			expectedErr: errors.New("invalid command agruments number"),
+		},
+		"parse expire query": {
+			query:         "EXPIRE key 10",
+			expectedQuery: NewQuery(ExpireCommand, []string{"key", "10"}),
+		},
+		"parse expire query with overflowed expiration": {
+			query:       "EXPIRE key 9300000000",
+			expectedErr: errors.New("invalid expire time"),
+		},
+		"parse pexpire query with invalid expiration": {
+			query:       "PEXPIRE key ten",
+			expectedErr: errors.New("invalid expire time"),
+		},
+		"parse ttl query": {
+			query:         "TTL key",
+			expectedQuery: NewQuery(TTLCommand, []string{"key"}),
+		},
+		"parse pttl query": {
+			query:         "PTTL key",
+			expectedQuery: NewQuery(PTTLCommand, []string{"key"}),
+		},
+		"parse persist query": {
+			query:         "PERSIST key",
+			expectedQuery: NewQuery(PersistCommand, []string{"key"}),
+		},
+		"parse begin query": {
+			query:         "BEGIN",
+			expectedQuery: NewQuery(BeginCommand, []string{}),
+		},
+		"parse commit query with arguments": {
+			query:       "COMMIT now",
+			expectedErr: errors.New("invalid command agruments number"),
+		},
+		"parse checkpoint query": {
+			query:         "CHECKPOINT",
+			expectedQuery: NewQuery(CheckpointCommand, []string{}),
+		},
+		"parse promote query": {
+			query:         "PROMOTE",
+			expectedQuery: NewQuery(PromoteCommand, []string{}),
+		},
+		"parse promote query with address": {
+			query:         "PROMOTE 127.0.0.1:8082",
+			expectedQuery: NewQuery(PromoteCommand, []string{"127.0.0.1:8082"}),
+		},
+		"parse demote query": {
+			query:         "DEMOTE 127.0.0.1:8082",
+			expectedQuery: NewQuery(DemoteCommand, []string{"127.0.0.1:8082"}),
+		},
+		"parse demote query without address": {
+			query:       "DEMOTE",
+			expectedErr: errors.New("invalid command agruments number"),
+		},
+		"parse info query": {
+			query:         "INFO replication",
+			expectedQuery: NewQuery(InfoCommand, []string{"replication"}),
+		},
+		"parse info query with unknown section": {
+			query:       "INFO memory",
+			expectedErr: errors.New("unknown section"),
+		},
+		"parse wait query": {
+			query:         "WAIT lsn 10 500",
+			expectedQuery: NewQuery(WaitCommand, []string{"lsn", "10", "500"}),
+		},
+		"parse wait query with invalid lsn": {
+			query:       "WAIT LSN -1",
+			expectedErr: errors.New("invalid lsn"),
+		},
+		"parse wait query with invalid timeout": {
+			query:       "WAIT LSN 10 soon",
+			expectedErr: errors.New("invalid timeout"),
+		},
+		"parse lsn query": {
+			query:         "LSN on",
+			expectedQuery: NewQuery(LSNCommand, []string{"on"}),
+		},
+		"parse lsn query with invalid option": {
+			query:       "LSN yes",
+			expectedErr: errors.New("syntax error"),
+		},
+		"parse scan query": {
+			query:         "SCAN 0 match user:* count 5",
+			expectedQuery: NewQuery(ScanCommand, []string{StartCursor, "match", "user:*", "count", "5"}),
+		},
+		"parse scan query with cursor": {
+			query:         "SCAN 6b6579",
+			expectedQuery: NewQuery(ScanCommand, []string{"6b6579"}),
+		},
+		"parse scan query with invalid cursor": {
+			query:       "SCAN key",
+			expectedErr: errors.New("invalid cursor"),
+		},
+		"parse scan query without option value": {
+			query:       "SCAN 0 COUNT",
+			expectedErr: errors.New("syntax error"),
+		},
+		"parse scan query with invalid count": {
+			query:       "SCAN 0 COUNT 0",
+			expectedErr: errors.New("invalid count"),
+		},
+		"parse scan query with invalid pattern": {
+			query:       "SCAN 0 MATCH [a",
+			expectedErr: errors.New("invalid pattern"),
+		},
+		"parse range query": {
+			query:         "RANGE a z limit 10",
+			expectedQuery: NewQuery(RangeCommand, []string{"a", "z", "limit", "10"}),
+		},
+		"parse range query with invalid limit": {
+			query:       "RANGE a z LIMIT -1",
+			expectedErr: errors.New("invalid limit"),
+		},
+		"parse range query with unknown option": {
+			query:       "RANGE a z 10",
+			expectedErr: errors.New("syntax error"),
+		},
+		"parse keys query": {
+			query:         `KEYS ""`,
+			expectedQuery: NewQuery(KeysCommand, []string{""}),
+		},
+		"parse reshard query": {
+			query:         "RESHARD 16",
+			expectedQuery: NewQuery(ReshardCommand, []string{"16"}),
+		},
+		"parse reshard query with invalid partitions": {
+			query:       "RESHARD 0",
+			expectedErr: errors.New("invalid partitions number"),
+		},
+		"parse hset query": {
+			query:         "HSET user name alice age 30",
+			expectedQuery: NewQuery(HSetCommand, []string{"user", "name", "alice", "age", "30"}),
+		},
+		"parse hset query without value": {
+			query:       "HSET user name alice age",
+			expectedErr: errors.New("syntax error"),
+		},
+		"parse lpop query with count": {
+			query:         "LPOP queue 2",
+			expectedQuery: NewQuery(LPopCommand, []string{"queue", "2"}),
+		},
+		"parse rpop query with invalid count": {
+			query:       "RPOP queue 0",
+			expectedErr: errors.New("invalid count"),
+		},
+		"parse lrange query": {
+			query:         "LRANGE queue 0 -1",
+			expectedQuery: NewQuery(LRangeCommand, []string{"queue", "0", "-1"}),
+		},
+		"parse lrange query with invalid index": {
+			query:       "LRANGE queue 0 last",
+			expectedErr: errors.New("invalid index"),
+		},
+		"parse sadd query": {
+			query:         "SADD tags a b",
+			expectedQuery: NewQuery(SAddCommand, []string{"tags", "a", "b"}),
+		},
+		"parse zadd query": {
+			query:         "ZADD board 10 alice -inf bob",
+			expectedQuery: NewQuery(ZAddCommand, []string{"board", "10", "alice", "-inf", "bob"}),
+		},
+		"parse zadd query with invalid score": {
+			query:       "ZADD board nan alice",
+			expectedErr: errors.New("invalid score"),
+		},
+		"parse zrange query with scores": {
+			query:         "ZRANGE board 0 -1 withscores",
+			expectedQuery: NewQuery(ZRangeCommand, []string{"board", "0", "-1", "withscores"}),
+		},
+		"parse zrangebyscore query": {
+			query:         "ZRANGEBYSCORE board (1 +inf WITHSCORES limit 2 10",
+			expectedQuery: NewQuery(ZRangeByScoreCommand, []string{"board", "(1", "+inf", WithScoresOption, "limit", "2", "10"}),
+		},
+		"parse zrangebyscore query with invalid bound": {
+			query:       "ZRANGEBYSCORE board [1 2",
+			expectedErr: errors.New("invalid score"),
+		},
+		"parse zrangebyscore query with incomplete limit": {
+			query:       "ZRANGEBYSCORE board 1 2 LIMIT 2",
+			expectedErr: errors.New("syntax error"),
+		},
+		"parse zincrby query": {
+			query:         "ZINCRBY board 1.5 alice",
+			expectedQuery: NewQuery(ZIncrByCommand, []string{"board", "1.5", "alice"}),
+		},
+		"parse incr query": {
+			query:         "INCR counter",
+			expectedQuery: NewQuery(IncrCommand, []string{"counter"}),
+		},
+		"parse incrby query with invalid increment": {
+			query:       "INCRBY counter 1.5",
+			expectedErr: errors.New("value is not an integer or out of range"),
+		},
+		"parse incrbyfloat query": {
+			query:         "INCRBYFLOAT counter -0.25",
+			expectedQuery: NewQuery(IncrByFloatCommand, []string{"counter", "-0.25"}),
+		},
+		"parse incrbyfloat query with infinite increment": {
+			query:       "INCRBYFLOAT counter inf",
+			expectedErr: errors.New("value is not a valid float"),
+		},
+		"parse internal command": {
+			query:       "PEXPIREAT key 1000",
+			expectedErr: errors.New("unknown command"),
+		},
+		"parse get query": {
+			query:         "GET  key",
+			expectedQuery: NewQuery(GetCommand, []string{"key"}),
+		},
+		"parse del query": {
+			query:         "DEL\tkey",
+			expectedQuery: NewQuery(DelCommand, []string{"key"}),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			query, err := NewCompute().Parse(context.Background(), test.query)
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedQuery, query)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package compute
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// CompilePattern converts a glob-style pattern to a regular expression, the
+// pattern supports * (any sequence), ? (any symbol), [abc], [a-z] and [^abc]
+// classes, special symbols are matched literally after a backslash
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	var builder strings.Builder
+	builder.WriteString("(?s)^")
+
+	for idx := 0; idx < len(pattern); {
+		symbol, size := utf8.DecodeRuneInString(pattern[idx:])
+		idx += size
+
+		switch symbol {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[idx:], ']')
+			if end <= 0 {
+				return nil, errors.New("invalid pattern")
+			}
+
+			class := pattern[idx : idx+end]
+			idx += end + 1
+
+			builder.WriteByte('[')
+			if class[0] == '^' || class[0] == '!' {
+				builder.WriteByte('^')
+				class = class[1:]
+			}
+
+			for _, classSymbol := range class {
+				if classSymbol == '-' {
+					builder.WriteRune(classSymbol)
+				} else {
+					builder.WriteString(regexp.QuoteMeta(string(classSymbol)))
+				}
+			}
+			builder.WriteByte(']')
+		case '\\':
+			if idx < len(pattern) {
+				symbol, size = utf8.DecodeRuneInString(pattern[idx:])
+				idx += size
+			}
+			builder.WriteString(regexp.QuoteMeta(string(symbol)))
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(symbol)))
+		}
+	}
+
+	builder.WriteByte('$')
+	expression, err := regexp.Compile(builder.String())
+	if err != nil {
+		return nil, errors.New("invalid pattern")
+	}
+
+	return expression, nil
+}
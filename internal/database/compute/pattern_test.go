@@ -0,0 +1,66 @@
+package compute
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompilePattern(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		pattern string
+
+		matched     []string
+		notMatched  []string
+		expectedErr error
+	}{
+		"pattern with any sequence": {
+			pattern:    "user:*",
+			matched:    []string{"user:", "user:1", "user:a/b\nc"},
+			notMatched: []string{"users", "admin:user:1"},
+		},
+		"pattern with any symbol": {
+			pattern:    "key_?",
+			matched:    []string{"key_1", "key_a"},
+			notMatched: []string{"key_", "key_10"},
+		},
+		"pattern with classes": {
+			pattern:    "key_[a-c][^0-9]",
+			matched:    []string{"key_ax", "key_c-"},
+			notMatched: []string{"key_dx", "key_a1"},
+		},
+		"pattern with escaped symbols": {
+			pattern:    `key\*.(1)`,
+			matched:    []string{"key*.(1)"},
+			notMatched: []string{"key_1.(1)", "key*x(1)"},
+		},
+		"pattern with unterminated class": {
+			pattern:     "key_[ab",
+			expectedErr: errors.New("invalid pattern"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			expression, err := CompilePattern(test.pattern)
+			if test.expectedErr != nil {
+				assert.Equal(t, test.expectedErr, err)
+				return
+			}
+
+			require.NoError(t, err)
+			for _, key := range test.matched {
+				assert.True(t, expression.MatchString(key), key)
+			}
+			for _, key := range test.notMatched {
+				assert.False(t, expression.MatchString(key), key)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package compute
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// exclusiveBoundPrefix marks a bound of ZRANGEBYSCORE as exclusive
+const exclusiveBoundPrefix = "("
+
+// ParseScore parses a score of a sorted set, infinities like "-inf" are allowed and NaN is not
+func ParseScore(value string) (float64, error) {
+	score, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, errors.New("invalid score")
+	}
+
+	return score, nil
+}
+
+// ParseScoreBound parses a bound of ZRANGEBYSCORE, it reports whether the bound is exclusive
+func ParseScoreBound(value string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(value, exclusiveBoundPrefix)
+	score, err := ParseScore(strings.TrimPrefix(value, exclusiveBoundPrefix))
+	return score, exclusive, err
+}
+
+// FormatScore formats the score, so it's parsed by ParseScore to the same value
+func FormatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	default:
+		return strconv.FormatFloat(score, 'g', -1, 64)
+	}
+}
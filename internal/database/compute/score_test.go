@@ -0,0 +1,51 @@
+package compute
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScoreBound(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		bound string
+
+		expectedScore     float64
+		expectedExclusive bool
+		expectedErr       error
+	}{
+		"inclusive bound":    {bound: "1.5", expectedScore: 1.5},
+		"exclusive bound":    {bound: "(-2", expectedScore: -2, expectedExclusive: true},
+		"infinite bound":     {bound: "-inf", expectedScore: math.Inf(-1)},
+		"not a number bound": {bound: "(NaN", expectedExclusive: true, expectedErr: errors.New("invalid score")},
+		"invalid bound":      {bound: "[1", expectedErr: errors.New("invalid score")},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			score, exclusive, err := ParseScoreBound(test.bound)
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedScore, score)
+			assert.Equal(t, test.expectedExclusive, exclusive)
+		})
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	t.Parallel()
+
+	for _, score := range []float64{0, -1.25, 1e21, 0.1 + 0.2, math.Inf(1), math.Inf(-1)} {
+		parsed, err := ParseScore(FormatScore(score))
+		assert.NoError(t, err)
+		assert.Equal(t, score, parsed)
+	}
+
+	assert.Equal(t, "inf", FormatScore(math.Inf(1)))
+	assert.Equal(t, "3.5", FormatScore(3.5))
+}
@@ -0,0 +1,230 @@
+package compute
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError ...
+type ParseError struct {
+	Position int
+	Message  string
+}
+
+// Error ...
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Position)
+}
+
+func newParseError(position int, format string, args ...any) *ParseError {
+	return &ParseError{
+		Position: position,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// tokenize splits a query into arguments. Arguments are separated by any
+// whitespace, can be wrapped into single quotes (only \' is escaped inside)
+// or double quotes (with \n, \r, \t, \b, \a, \\, \" and binary-safe \xHH
+// escapes). Positions in errors are byte offsets in the query.
+func tokenize(query string) ([]string, error) {
+	var tokens []string
+
+	idx := 0
+	for {
+		for idx < len(query) && isSpace(query[idx]) {
+			idx++
+		}
+
+		if idx == len(query) {
+			return tokens, nil
+		}
+
+		var token string
+		var err error
+
+		switch query[idx] {
+		case '"':
+			token, idx, err = readDoubleQuoted(query, idx)
+		case '\'':
+			token, idx, err = readSingleQuoted(query, idx)
+		default:
+			token, idx = readBare(query, idx)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+}
+
+// Quote returns the token as is if the tokenizer reads it back as is, otherwise
+// it's double quoted with escapes, so responses are parsed by the same rules
+func Quote(token string) string {
+	if !needsQuotes(token) {
+		return token
+	}
+
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for idx := 0; idx < len(token); idx++ {
+		switch symbol := token[idx]; symbol {
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		case '\b':
+			builder.WriteString(`\b`)
+		case '\a':
+			builder.WriteString(`\a`)
+		case '\\':
+			builder.WriteString(`\\`)
+		case '"':
+			builder.WriteString(`\"`)
+		default:
+			if symbol < ' ' || symbol > '~' {
+				fmt.Fprintf(&builder, `\x%02x`, symbol)
+			} else {
+				builder.WriteByte(symbol)
+			}
+		}
+	}
+	builder.WriteByte('"')
+
+	return builder.String()
+}
+
+func needsQuotes(token string) bool {
+	if token == "" || token[0] == '"' || token[0] == '\'' {
+		return true
+	}
+
+	for idx := 0; idx < len(token); idx++ {
+		if token[idx] <= ' ' || token[idx] > '~' {
+			return true
+		}
+	}
+
+	return false
+}
+
+func readBare(query string, start int) (string, int) {
+	idx := start
+	for idx < len(query) && !isSpace(query[idx]) {
+		idx++
+	}
+
+	return query[start:idx], idx
+}
+
+func readDoubleQuoted(query string, start int) (string, int, error) {
+	var builder strings.Builder
+
+	idx := start + 1
+	for idx < len(query) {
+		symbol := query[idx]
+		switch {
+		case symbol == '"':
+			return builder.String(), idx + 1, checkTokenEnd(query, idx+1)
+		case symbol == '\\' && idx+1 < len(query):
+			escaped, size, err := readEscape(query, idx)
+			if err != nil {
+				return "", 0, err
+			}
+
+			builder.WriteByte(escaped)
+			idx += size
+		default:
+			builder.WriteByte(symbol)
+			idx++
+		}
+	}
+
+	return "", 0, newParseError(start, "unterminated double quoted string")
+}
+
+func readSingleQuoted(query string, start int) (string, int, error) {
+	var builder strings.Builder
+
+	idx := start + 1
+	for idx < len(query) {
+		symbol := query[idx]
+		switch {
+		case symbol == '\'':
+			return builder.String(), idx + 1, checkTokenEnd(query, idx+1)
+		case symbol == '\\' && idx+1 < len(query) && query[idx+1] == '\'':
+			builder.WriteByte('\'')
+			idx += 2
+		default:
+			builder.WriteByte(symbol)
+			idx++
+		}
+	}
+
+	return "", 0, newParseError(start, "unterminated single quoted string")
+}
+
+func readEscape(query string, start int) (byte, int, error) {
+	switch query[start+1] {
+	case 'n':
+		return '\n', 2, nil
+	case 'r':
+		return '\r', 2, nil
+	case 't':
+		return '\t', 2, nil
+	case 'b':
+		return '\b', 2, nil
+	case 'a':
+		return '\a', 2, nil
+	case '\\':
+		return '\\', 2, nil
+	case '"':
+		return '"', 2, nil
+	case 'x':
+		if start+3 < len(query) {
+			high, okHigh := hexDigit(query[start+2])
+			low, okLow := hexDigit(query[start+3])
+			if okHigh && okLow {
+				return high<<4 | low, 4, nil
+			}
+		}
+
+		return 0, 0, newParseError(start, "invalid hex escape sequence")
+	default:
+		return 0, 0, newParseError(start, "unknown escape sequence \\%c", query[start+1])
+	}
+}
+
+func checkTokenEnd(query string, idx int) error {
+	if idx < len(query) && !isSpace(query[idx]) {
+		return newParseError(idx, "closing quote must be followed by a space")
+	}
+
+	return nil
+}
+
+func hexDigit(symbol byte) (byte, bool) {
+	switch {
+	case symbol >= '0' && symbol <= '9':
+		return symbol - '0', true
+	case symbol >= 'a' && symbol <= 'f':
+		return symbol - 'a' + 10, true
+	case symbol >= 'A' && symbol <= 'F':
+		return symbol - 'A' + 10, true
+	default:
+		return 0, false
+	}
+}
+
+func isSpace(symbol byte) bool {
+	switch symbol {
+	case ' ', '\t', '\r', '\n', '\v', '\f':
+		return true
+	default:
+		return false
+	}
+}
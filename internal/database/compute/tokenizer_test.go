@@ -0,0 +1,134 @@
+package compute
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		query string
+
+		expectedTokens []string
+		expectedErr    error
+	}{
+		"empty query": {
+			query: "",
+		},
+		"query with spaces only": {
+			query: " \t\r\n",
+		},
+		"query with bare words": {
+			query:          "SET key value",
+			expectedTokens: []string{"SET", "key", "value"},
+		},
+		"query with arbitrary whitespace": {
+			query:          "  SET\tkey   value\r\n",
+			expectedTokens: []string{"SET", "key", "value"},
+		},
+		"query with double quoted string": {
+			query:          `SET key "hello world"`,
+			expectedTokens: []string{"SET", "key", "hello world"},
+		},
+		"query with empty quoted string": {
+			query:          `SET key ""`,
+			expectedTokens: []string{"SET", "key", ""},
+		},
+		"query with escapes": {
+			query:          `SET key "a\"b\\c\n\t"`,
+			expectedTokens: []string{"SET", "key", "a\"b\\c\n\t"},
+		},
+		"query with hex escapes": {
+			query:          `SET key "\x00\xfF\x41"`,
+			expectedTokens: []string{"SET", "key", "\x00\xff\x41"},
+		},
+		"query with single quoted string": {
+			query:          `SET key 'it\'s "raw" \n'`,
+			expectedTokens: []string{"SET", "key", `it's "raw" \n`},
+		},
+		"query with quotes inside bare word": {
+			query:          `SET key va"lue`,
+			expectedTokens: []string{"SET", "key", `va"lue`},
+		},
+		"query with unterminated double quoted string": {
+			query:       `SET key "value`,
+			expectedErr: &ParseError{Position: 8, Message: "unterminated double quoted string"},
+		},
+		"query with unterminated single quoted string": {
+			query:       `SET key 'value`,
+			expectedErr: &ParseError{Position: 8, Message: "unterminated single quoted string"},
+		},
+		"query with text after closing quote": {
+			query:       `SET "key"value value`,
+			expectedErr: &ParseError{Position: 9, Message: "closing quote must be followed by a space"},
+		},
+		"query with invalid hex escape": {
+			query:       `SET key "\xZZ"`,
+			expectedErr: &ParseError{Position: 9, Message: "invalid hex escape sequence"},
+		},
+		"query with unknown escape": {
+			query:       `SET key "\q"`,
+			expectedErr: &ParseError{Position: 9, Message: `unknown escape sequence \q`},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tokens, err := tokenize(test.query)
+			if test.expectedErr != nil {
+				assert.Equal(t, test.expectedErr, err)
+				assert.Nil(t, tokens)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedTokens, tokens)
+		})
+	}
+}
+
+func TestParseErrorMessage(t *testing.T) {
+	t.Parallel()
+
+	err := &ParseError{Position: 8, Message: "unterminated double quoted string"}
+	assert.Equal(t, "unterminated double quoted string at position 8", err.Error())
+}
+
+func TestQuote(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		token string
+
+		expected string
+	}{
+		"bare token":                  {token: `key\1"`, expected: `key\1"`},
+		"empty token":                 {token: "", expected: `""`},
+		"token with spaces":           {token: "hello world", expected: `"hello world"`},
+		"token with leading quote":    {token: `'key`, expected: `"'key"`},
+		"token with escaped symbols":  {token: "a\"b\\c\n", expected: `"a\"b\\c\n"`},
+		"token with binary symbols":   {token: "\x00\xff", expected: `"\x00\xff"`},
+		"token with unicode symbols":  {token: "ключ", expected: `"\xd0\xba\xd0\xbb\xd1\x8e\xd1\x87"`},
+		"token with vertical tab":     {token: "a\vb", expected: `"a\x0bb"`},
+		"token with a trailing space": {token: "key ", expected: `"key "`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			quoted := Quote(test.token)
+			assert.Equal(t, test.expected, quoted)
+
+			// the tokenizer reads the quoted token back
+			tokens, err := tokenize(quoted)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{test.token}, tokens)
+		})
+	}
+}
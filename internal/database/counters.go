@@ -0,0 +1,31 @@
+package database
+
+import (
+	"context"
+	"strconv"
+
+	"database-simon/internal/database/compute"
+)
+
+// handlerCounterQuery returns the new value of the counter
+func (db *Database) handlerCounterQuery(ctx context.Context, query compute.Query) (string, error) {
+	arguments := query.Arguments()
+	key := arguments[0]
+
+	switch query.Command() {
+	case compute.IncrCommand:
+		value, err := db.stor.IncrBy(ctx, key, 1)
+		return strconv.FormatInt(value, 10), err
+	case compute.DecrCommand:
+		value, err := db.stor.IncrBy(ctx, key, -1)
+		return strconv.FormatInt(value, 10), err
+	case compute.IncrByCommand:
+		delta, _ := strconv.ParseInt(arguments[1], 10, 64) // validated by compute layer
+		value, err := db.stor.IncrBy(ctx, key, delta)
+		return strconv.FormatInt(value, 10), err
+	default:
+		delta, _ := strconv.ParseFloat(arguments[1], 64) // validated by compute layer
+		value, err := db.stor.IncrByFloat(ctx, key, delta)
+		return strconv.FormatFloat(value, 'f', -1, 64), err
+	}
+}
@@ -2,17 +2,44 @@ package database
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
+	"database-simon/internal/common"
 	"database-simon/internal/database/compute"
+	"database-simon/internal/database/storage"
+	"database-simon/internal/database/storage/replication"
 )
 
 const (
 	errorResult    = "[error]"
 	okResult       = "[ok]"
 	notFoundResult = "[not found]"
+	// outOfMemoryResult is a result of writes rejected by the memory limit
+	outOfMemoryResult = "[error] out of memory"
+	// wrongTypeResult is a result of operations against a key of another type
+	wrongTypeResult = "[error] WRONGTYPE operation against a key holding the wrong kind of value"
+	// notAppliedResult is a result of conditional writes which conditions failed
+	notAppliedResult = "[not applied]"
+	// notReplicatedResult is a result of writes which are applied by the master,
+	// but aren't acknowledged by synchronous replicas in time
+	notReplicatedResult = "[error] NOTREPLICATED write is applied, but isn't acknowledged by replicas in time"
+	// notANumberResult is a result of ZINCRBY which makes the score NaN
+	notANumberResult = "[error] resulting score is not a number"
+
+	// noExpirationResult is a result of TTL and PTTL for keys without expiration
+	noExpirationResult = "-1"
+
+	// defaultScanCount is a number of keys returned by SCAN without COUNT
+	defaultScanCount = 10
 )
 
 type computeLayer interface {
@@ -21,19 +48,74 @@ type computeLayer interface {
 
 type storageLayer interface {
 	Set(context.Context, string, string) error
+	SetWithExpiration(context.Context, string, string, time.Time) error
 	Get(context.Context, string) (string, error)
 	Del(context.Context, string) error
+	Expire(context.Context, string, time.Time) error
+	Expiration(context.Context, string) (time.Time, error)
+	Persist(context.Context, string) error
+	Begin(context.Context) error
+	Commit(context.Context) error
+	Rollback(context.Context) error
+	Checkpoint(context.Context) error
+	WaitLSN(context.Context, int64, time.Duration) error
+	Range(context.Context, string, string, int) ([]storage.KeyValue, error)
+	Reshard(context.Context, int) error
+	HSet(context.Context, string, []string) (int, error)
+	HGet(context.Context, string, string) (string, error)
+	HDel(context.Context, string, []string) (int, error)
+	HGetAll(context.Context, string) ([]string, error)
+	LPush(context.Context, string, []string) (int, error)
+	RPush(context.Context, string, []string) (int, error)
+	LPop(context.Context, string, int) ([]string, error)
+	RPop(context.Context, string, int) ([]string, error)
+	LRange(context.Context, string, int, int) ([]string, error)
+	SAdd(context.Context, string, []string) (int, error)
+	SRem(context.Context, string, []string) (int, error)
+	SMembers(context.Context, string) ([]string, error)
+	SIsMember(context.Context, string, string) (bool, error)
+	ZAdd(context.Context, string, []common.ScoredMember) (int, error)
+	ZRem(context.Context, string, []string) (int, error)
+	ZIncrBy(context.Context, string, float64, string) (float64, error)
+	ZScore(context.Context, string, string) (float64, error)
+	ZRank(context.Context, string, string) (int, error)
+	ZRange(context.Context, string, int, int) ([]common.ScoredMember, error)
+	ZRangeByScore(context.Context, string, common.ScoreRange, int, int) ([]common.ScoredMember, error)
+	MSet(context.Context, []string) error
+	MGet(context.Context, []string) ([]string, []bool, error)
+	MDel(context.Context, []string) error
+	SetIf(context.Context, string, string, time.Time, bool) (bool, error)
+	CompareAndSwap(context.Context, string, string, string) (bool, error)
+	GetSet(context.Context, string, string) (string, bool, error)
+	GetDel(context.Context, string) (string, error)
+	DelIfEqual(context.Context, string, string) (bool, error)
+	IncrBy(context.Context, string, int64) (int64, error)
+	IncrByFloat(context.Context, string, float64) (float64, error)
+}
+
+type replicationLayer interface {
+	Promote(string) error
+	Demote(string) error
+	Status() replication.Status
 }
 
+// ErrorNoReplication ...
+var ErrorNoReplication = errors.New("replication is not configured")
+
 // Database ...
 type Database struct {
 	comp   computeLayer
 	stor   storageLayer
+	repl   replicationLayer
 	logger *zap.Logger
+
+	// sessions which get LSN of their writes in responses
+	lsnSessionsMutex sync.Mutex
+	lsnSessions      map[int64]struct{}
 }
 
 // NewDatabase ...
-func NewDatabase(logger *zap.Logger, comp compute.Compute, stor storageLayer) (*Database, error) {
+func NewDatabase(logger *zap.Logger, comp compute.Compute, stor storageLayer, options ...Option) (*Database, error) {
 	if logger == nil {
 		return nil, fmt.Errorf("logger is invalid")
 	}
@@ -46,52 +128,213 @@ func NewDatabase(logger *zap.Logger, comp compute.Compute, stor storageLayer) (*
 		return nil, fmt.Errorf("storage is invalid")
 	}
 
-	return &Database{
-		logger: logger,
-		comp:   comp,
-		stor:   stor,
-	}, nil
+	db := &Database{
+		logger:      logger,
+		comp:        comp,
+		stor:        stor,
+		lsnSessions: make(map[int64]struct{}),
+	}
+
+	for _, option := range options {
+		option(db)
+	}
+
+	return db, nil
 }
 
 // HandleQuery ...
 // TODO: Не возвращать ошибку
 func (db *Database) HandleQuery(ctx context.Context, queryStr string) (string, error) {
+	result, err := db.handleQuery(ctx, queryStr)
+	if errors.Is(err, common.ErrorReplicationTimeout) {
+		return notReplicatedResult, err
+	}
+
+	return result, err
+}
+
+func (db *Database) handleQuery(ctx context.Context, queryStr string) (string, error) {
 	query, err := db.comp.Parse(ctx, queryStr)
 	if err != nil {
 		return errorResult, fmt.Errorf("error parsing: %w", err)
 	}
 
+	var lsn int64
+	if db.isLSNSession(ctx) {
+		ctx = common.ContextWithCommitLSN(ctx, &lsn)
+	}
+
 	switch query.Command() {
 	case compute.SetCommand:
-		_, errSet := db.handlerSetQuery(ctx, query)
+		applied, errSet := db.handlerSetQuery(ctx, query)
 		if errSet != nil {
 			db.logger.Error("error handling query", zap.String("query", queryStr), zap.Error(errSet))
-			return errorResult, errSet
+			return errorOrOutOfMemoryResult(errSet), errSet
+		} else if !applied {
+			return notAppliedResult, nil
 		}
-		return okResult, nil
+		return writeResult(lsn), nil
 	case compute.GetCommand:
 		res, errGet := db.handlerGetQuery(ctx, query)
 		if errGet != nil {
-			return notFoundResult, errGet
+			return valueErrorResult(errGet, notFoundResult), errGet
 		}
 		return res, nil
 	case compute.DelCommand:
-		_, errDel := db.handlerDelQuery(ctx, query)
+		applied, errDel := db.handlerDelQuery(ctx, query)
 		if errDel != nil {
-			return errorResult, errDel
+			return valueErrorResult(errDel, errorResult), errDel
+		} else if !applied {
+			return notAppliedResult, nil
+		}
+		return writeResult(lsn), nil
+	case compute.ExpireCommand, compute.PExpireCommand:
+		_, errExpire := db.handlerExpireQuery(ctx, query)
+		if errExpire != nil {
+			return errorOrNotFoundResult(errExpire), errExpire
+		}
+		return writeResult(lsn), nil
+	case compute.TTLCommand, compute.PTTLCommand:
+		res, errTTL := db.handlerTTLQuery(ctx, query)
+		if errTTL != nil {
+			return errorOrNotFoundResult(errTTL), errTTL
+		}
+		return res, nil
+	case compute.PersistCommand:
+		_, errPersist := db.handlerPersistQuery(ctx, query)
+		if errPersist != nil {
+			return errorOrNotFoundResult(errPersist), errPersist
+		}
+		return writeResult(lsn), nil
+	case compute.BeginCommand, compute.CommitCommand, compute.RollbackCommand:
+		errTX := db.handlerTransactionQuery(ctx, query)
+		if errTX != nil {
+			db.logger.Error("error handling query", zap.String("query", queryStr), zap.Error(errTX))
+			return errorOrOutOfMemoryResult(errTX), errTX
+		}
+		return writeResult(lsn), nil
+	case compute.CheckpointCommand:
+		errCheckpoint := db.stor.Checkpoint(ctx)
+		if errCheckpoint != nil {
+			db.logger.Error("error handling query", zap.String("query", queryStr), zap.Error(errCheckpoint))
+			return errorResult, errCheckpoint
 		}
 		return okResult, nil
+	case compute.PromoteCommand, compute.DemoteCommand:
+		errReplication := db.handlerReplicationQuery(query)
+		if errReplication != nil {
+			db.logger.Error("error handling query", zap.String("query", queryStr), zap.Error(errReplication))
+			return errorResult, errReplication
+		}
+		return okResult, nil
+	case compute.WaitCommand:
+		errWait := db.handlerWaitQuery(ctx, query)
+		if errWait != nil {
+			return errorResult, errWait
+		}
+		return okResult, nil
+	case compute.LSNCommand:
+		errLSN := db.handlerLSNQuery(ctx, query)
+		if errLSN != nil {
+			return errorResult, errLSN
+		}
+		return okResult, nil
+	case compute.ScanCommand, compute.RangeCommand, compute.KeysCommand:
+		res, errRange := db.handlerRangeQuery(ctx, query)
+		if errRange != nil {
+			return errorResult, errRange
+		}
+		return res, nil
+	case compute.ReshardCommand:
+		partitions, _ := strconv.Atoi(query.Arguments()[0]) // validated by compute layer
+		errReshard := db.stor.Reshard(ctx, partitions)
+		if errReshard != nil {
+			db.logger.Error("error handling query", zap.String("query", queryStr), zap.Error(errReshard))
+			return errorResult, errReshard
+		}
+		return okResult, nil
+	case compute.HSetCommand, compute.HGetCommand, compute.HDelCommand, compute.HGetAllCommand,
+		compute.LPushCommand, compute.RPushCommand, compute.LPopCommand, compute.RPopCommand, compute.LRangeCommand,
+		compute.SAddCommand, compute.SRemCommand, compute.SMembersCommand, compute.SIsMemberCommand,
+		compute.ZAddCommand, compute.ZRemCommand, compute.ZIncrByCommand, compute.ZScoreCommand,
+		compute.ZRankCommand, compute.ZRangeCommand, compute.ZRangeByScoreCommand:
+		res, errStructure := db.handlerStructureQuery(ctx, query)
+		if errStructure != nil {
+			return valueErrorResult(errStructure, errorResult), errStructure
+		}
+		return valueResult(res, lsn), nil
+	case compute.IncrCommand, compute.DecrCommand, compute.IncrByCommand, compute.IncrByFloatCommand:
+		res, errCounter := db.handlerCounterQuery(ctx, query)
+		if errCounter != nil {
+			return valueErrorResult(errCounter, errorResult), errCounter
+		}
+		return valueResult(res, lsn), nil
+	case compute.CASCommand:
+		swapped, errCAS := db.stor.CompareAndSwap(ctx, query.Arguments()[0], query.Arguments()[1], query.Arguments()[2])
+		if errCAS != nil {
+			return valueErrorResult(errCAS, errorResult), errCAS
+		} else if !swapped {
+			return notAppliedResult, nil
+		}
+		return writeResult(lsn), nil
+	case compute.GetSetCommand, compute.GetDelCommand:
+		res, errGetSet := db.handlerGetSetQuery(ctx, query)
+		if errGetSet != nil {
+			return valueErrorResult(errGetSet, errorResult), errGetSet
+		}
+		return valueResult(res, lsn), nil
+	case compute.MSetCommand, compute.MDelCommand:
+		errBatch := db.handlerBatchQuery(ctx, query)
+		if errBatch != nil {
+			db.logger.Error("error handling query", zap.String("query", queryStr), zap.Error(errBatch))
+			return errorOrOutOfMemoryResult(errBatch), errBatch
+		}
+		return writeResult(lsn), nil
+	case compute.MGetCommand:
+		res, errMGet := db.handlerMGetQuery(ctx, query)
+		if errMGet != nil {
+			return errorResult, errMGet
+		}
+		return res, nil
+	case compute.InfoCommand:
+		if db.repl == nil {
+			return errorResult, ErrorNoReplication
+		}
+		return formatReplicationStatus(db.repl.Status(), time.Now()), nil
 	}
 
 	return errorResult, fmt.Errorf("error handle query")
 }
 
-func (db *Database) handlerSetQuery(ctx context.Context, query compute.Query) (string, error) {
-	err := db.stor.Set(ctx, query.Arguments()[0], query.Arguments()[1])
-	if err != nil {
-		return "", err
+// handlerSetQuery reports whether the value is set, it's always set without NX and XX
+func (db *Database) handlerSetQuery(ctx context.Context, query compute.Query) (bool, error) {
+	arguments := query.Arguments()
+
+	var deadline time.Time
+	condition := ""
+	for idx := 2; idx < len(arguments); idx++ {
+		switch option := strings.ToUpper(arguments[idx]); option {
+		case compute.NxOption, compute.XxOption:
+			condition = option
+		default:
+			ttl, _ := strconv.ParseInt(arguments[idx+1], 10, 64) // validated by compute layer
+			deadline = deadlineAfter(ttl, option == compute.PxOption)
+			idx++
+		}
 	}
-	return "", nil
+
+	if condition != "" {
+		return db.stor.SetIf(ctx, arguments[0], arguments[1], deadline, condition == compute.XxOption)
+	}
+
+	var err error
+	if !deadline.IsZero() {
+		err = db.stor.SetWithExpiration(ctx, arguments[0], arguments[1], deadline)
+	} else {
+		err = db.stor.Set(ctx, arguments[0], arguments[1])
+	}
+
+	return err == nil, err
 }
 
 func (db *Database) handlerGetQuery(ctx context.Context, query compute.Query) (string, error) {
@@ -102,10 +345,333 @@ func (db *Database) handlerGetQuery(ctx context.Context, query compute.Query) (s
 	return value, nil
 }
 
-func (db *Database) handlerDelQuery(ctx context.Context, query compute.Query) (string, error) {
-	err := db.stor.Del(ctx, query.Arguments()[0])
+// handlerDelQuery reports whether the key is deleted, it's always deleted without IFEQ
+func (db *Database) handlerDelQuery(ctx context.Context, query compute.Query) (bool, error) {
+	arguments := query.Arguments()
+	if len(arguments) == 3 {
+		return db.stor.DelIfEqual(ctx, arguments[0], arguments[2])
+	}
+
+	err := db.stor.Del(ctx, arguments[0])
+	return err == nil, err
+}
+
+// handlerBatchQuery writes all keys of MSET or MDEL as a single batch
+func (db *Database) handlerBatchQuery(ctx context.Context, query compute.Query) error {
+	if query.Command() == compute.MSetCommand {
+		return db.stor.MSet(ctx, query.Arguments())
+	}
+
+	return db.stor.MDel(ctx, query.Arguments())
+}
+
+// handlerMGetQuery returns values in order of keys one per line, values are quoted
+// if it's required to parse them and missing keys are marked as not found
+func (db *Database) handlerMGetQuery(ctx context.Context, query compute.Query) (string, error) {
+	values, found, err := db.stor.MGet(ctx, query.Arguments())
+	if err != nil {
+		return "", err
+	}
+
+	lines := make([]string, 0, len(values))
+	for idx, value := range values {
+		if !found[idx] {
+			lines = append(lines, notFoundResult)
+			continue
+		}
+		lines = append(lines, compute.Quote(value))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// handlerGetSetQuery returns the previous value of the key,
+// GETSET sets the value even if the key is not found
+func (db *Database) handlerGetSetQuery(ctx context.Context, query compute.Query) (string, error) {
+	arguments := query.Arguments()
+	if query.Command() == compute.GetDelCommand {
+		return db.stor.GetDel(ctx, arguments[0])
+	}
+
+	previous, existed, err := db.stor.GetSet(ctx, arguments[0], arguments[1])
+	if err == nil && !existed {
+		return notFoundResult, nil
+	}
+
+	return previous, err
+}
+
+func (db *Database) handlerExpireQuery(ctx context.Context, query compute.Query) (string, error) {
+	ttl, _ := strconv.ParseInt(query.Arguments()[1], 10, 64) // validated by compute layer
+	deadline := deadlineAfter(ttl, query.Command() == compute.PExpireCommand)
+
+	err := db.stor.Expire(ctx, query.Arguments()[0], deadline)
+	if err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+func (db *Database) handlerTTLQuery(ctx context.Context, query compute.Query) (string, error) {
+	deadline, err := db.stor.Expiration(ctx, query.Arguments()[0])
+	if err != nil {
+		return "", err
+	}
+
+	if deadline.IsZero() {
+		return noExpirationResult, nil
+	}
+
+	ttl := max(time.Until(deadline), 0)
+	if query.Command() == compute.PTTLCommand {
+		return strconv.FormatInt(ttl.Milliseconds(), 10), nil
+	}
+
+	return strconv.FormatInt(int64(ttl.Round(time.Second)/time.Second), 10), nil
+}
+
+func (db *Database) handlerPersistQuery(ctx context.Context, query compute.Query) (string, error) {
+	err := db.stor.Persist(ctx, query.Arguments()[0])
 	if err != nil {
 		return "", err
 	}
 	return "", nil
 }
+
+func (db *Database) handlerTransactionQuery(ctx context.Context, query compute.Query) error {
+	switch query.Command() {
+	case compute.BeginCommand:
+		return db.stor.Begin(ctx)
+	case compute.CommitCommand:
+		return db.stor.Commit(ctx)
+	default:
+		return db.stor.Rollback(ctx)
+	}
+}
+
+func (db *Database) handlerReplicationQuery(query compute.Query) error {
+	if db.repl == nil {
+		return ErrorNoReplication
+	}
+
+	var address string
+	if arguments := query.Arguments(); len(arguments) != 0 {
+		address = arguments[0]
+	}
+
+	if query.Command() == compute.PromoteCommand {
+		return db.repl.Promote(address)
+	}
+
+	return db.repl.Demote(address)
+}
+
+// handlerRangeQuery returns keys in order one per line, RANGE returns keys with values
+// and SCAN returns the cursor for the next call in the first line. Keys are quoted
+// if it's required to parse them, the ordered engine is required
+func (db *Database) handlerRangeQuery(ctx context.Context, query compute.Query) (string, error) {
+	arguments := query.Arguments()
+
+	var lines []string
+	switch query.Command() {
+	case compute.KeysCommand:
+		entries, err := db.stor.Range(ctx, arguments[0], prefixEnd(arguments[0]), 0)
+		if err != nil {
+			return "", err
+		}
+
+		for _, entry := range entries {
+			lines = append(lines, compute.Quote(entry.Key))
+		}
+	case compute.RangeCommand:
+		var limit int
+		if len(arguments) == 4 {
+			limit, _ = strconv.Atoi(arguments[3]) // validated by compute layer
+		}
+
+		// both bounds are inclusive, the key with zero byte follows the upper bound
+		entries, err := db.stor.Range(ctx, arguments[0], arguments[1]+"\x00", limit)
+		if err != nil {
+			return "", err
+		}
+
+		for _, entry := range entries {
+			lines = append(lines, compute.Quote(entry.Key)+" "+compute.Quote(entry.Value))
+		}
+	default:
+		return db.handlerScanQuery(ctx, arguments)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// handlerScanQuery uses the key which follows the last returned one as the cursor,
+// so keys written between calls are returned if they are after the cursor
+func (db *Database) handlerScanQuery(ctx context.Context, arguments []string) (string, error) {
+	var from string
+	if arguments[0] != compute.StartCursor {
+		cursor, _ := hex.DecodeString(arguments[0]) // validated by compute layer
+		from = string(cursor)
+	}
+
+	count := defaultScanCount
+	var pattern *regexp.Regexp
+	for idx := 1; idx < len(arguments); idx += 2 {
+		if strings.EqualFold(arguments[idx], compute.CountOption) {
+			count, _ = strconv.Atoi(arguments[idx+1]) // validated by compute layer
+		} else {
+			pattern, _ = compute.CompilePattern(arguments[idx+1]) // validated by compute layer
+		}
+	}
+
+	// COUNT limits scanned keys, so less keys can be returned if they don't match
+	entries, err := db.stor.Range(ctx, from, "", count)
+	if err != nil {
+		return "", err
+	}
+
+	cursor := compute.StartCursor
+	if len(entries) == count {
+		cursor = hex.EncodeToString([]byte(entries[len(entries)-1].Key + "\x00"))
+	}
+
+	lines := []string{cursor}
+	for _, entry := range entries {
+		if pattern == nil || pattern.MatchString(entry.Key) {
+			lines = append(lines, compute.Quote(entry.Key))
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (db *Database) handlerWaitQuery(ctx context.Context, query compute.Query) error {
+	arguments := query.Arguments()
+	lsn, _ := strconv.ParseInt(arguments[1], 10, 64) // validated by compute layer
+
+	var timeout int64
+	if len(arguments) == 3 {
+		timeout, _ = strconv.ParseInt(arguments[2], 10, 64) // validated by compute layer
+	}
+
+	return db.stor.WaitLSN(ctx, lsn, time.Duration(timeout)*time.Millisecond)
+}
+
+// handlerLSNQuery switches LSN in responses to writes of the session,
+// the session is forgotten when it's closed
+func (db *Database) handlerLSNQuery(ctx context.Context, query compute.Query) error {
+	sessionID, found := common.GetSessionIDFromContext(ctx)
+	if !found {
+		return storage.ErrorNoSession
+	}
+
+	db.lsnSessionsMutex.Lock()
+	defer db.lsnSessionsMutex.Unlock()
+
+	if strings.EqualFold(query.Arguments()[0], compute.OffOption) {
+		delete(db.lsnSessions, sessionID)
+		return nil
+	}
+
+	if _, enabled := db.lsnSessions[sessionID]; !enabled {
+		db.lsnSessions[sessionID] = struct{}{}
+		context.AfterFunc(ctx, func() {
+			db.lsnSessionsMutex.Lock()
+			defer db.lsnSessionsMutex.Unlock()
+
+			delete(db.lsnSessions, sessionID)
+		})
+	}
+
+	return nil
+}
+
+func (db *Database) isLSNSession(ctx context.Context) bool {
+	sessionID, found := common.GetSessionIDFromContext(ctx)
+	if !found {
+		return false
+	}
+
+	db.lsnSessionsMutex.Lock()
+	defer db.lsnSessionsMutex.Unlock()
+
+	_, enabled := db.lsnSessions[sessionID]
+	return enabled
+}
+
+// writeResult contains LSN of the write if the session asks for it,
+// writes buffered by a transaction have no LSN until COMMIT
+func writeResult(lsn int64) string {
+	if lsn == 0 {
+		return okResult
+	}
+
+	return okResult + " " + strconv.FormatInt(lsn, 10)
+}
+
+// prefixEnd returns the first key after all keys with the prefix, empty
+// result means that there is no such key
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for len(end) != 0 && end[len(end)-1] == 0xff {
+		end = end[:len(end)-1]
+	}
+
+	if len(end) == 0 {
+		return ""
+	}
+
+	end[len(end)-1]++
+	return string(end)
+}
+
+func deadlineAfter(ttl int64, milliseconds bool) time.Time {
+	unit := time.Second
+	if milliseconds {
+		unit = time.Millisecond
+	}
+
+	return time.Now().Add(time.Duration(ttl) * unit)
+}
+
+func errorOrNotFoundResult(err error) string {
+	if errors.Is(err, storage.ErrorNotFound) {
+		return notFoundResult
+	}
+
+	return errorResult
+}
+
+func errorOrOutOfMemoryResult(err error) string {
+	if errors.Is(err, storage.ErrorOutOfMemory) {
+		return outOfMemoryResult
+	}
+
+	return errorResult
+}
+
+// valueResult puts LSN of the write before the result if the session asks for it
+func valueResult(result string, lsn int64) string {
+	if lsn == 0 {
+		return result
+	}
+
+	return writeResult(lsn) + "\n" + result
+}
+
+func valueErrorResult(err error, defaultResult string) string {
+	switch {
+	case errors.Is(err, common.ErrorWrongType):
+		return wrongTypeResult
+	case errors.Is(err, storage.ErrorNotFound):
+		return notFoundResult
+	case errors.Is(err, storage.ErrorOutOfMemory):
+		return outOfMemoryResult
+	case errors.Is(err, common.ErrorNotANumber):
+		return notANumberResult
+	case errors.Is(err, common.ErrorNotInteger), errors.Is(err, common.ErrorNotFloat), errors.Is(err, common.ErrorOverflow):
+		return errorResult + " " + err.Error()
+	default:
+		return defaultResult
+	}
+}
@@ -11,8 +11,12 @@ package database
 
 import (
 	context "context"
+	common "database-simon/internal/common"
 	compute "database-simon/internal/database/compute"
+	storage "database-simon/internal/database/storage"
+	replication "database-simon/internal/database/storage/replication"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -80,6 +84,63 @@ func (m *MockstorageLayer) EXPECT() *MockstorageLayerMockRecorder {
 	return m.recorder
 }
 
+// Begin mocks base method.
+func (m *MockstorageLayer) Begin(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Begin", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Begin indicates an expected call of Begin.
+func (mr *MockstorageLayerMockRecorder) Begin(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Begin", reflect.TypeOf((*MockstorageLayer)(nil).Begin), arg0)
+}
+
+// Checkpoint mocks base method.
+func (m *MockstorageLayer) Checkpoint(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Checkpoint", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Checkpoint indicates an expected call of Checkpoint.
+func (mr *MockstorageLayerMockRecorder) Checkpoint(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Checkpoint", reflect.TypeOf((*MockstorageLayer)(nil).Checkpoint), arg0)
+}
+
+// Commit mocks base method.
+func (m *MockstorageLayer) Commit(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Commit", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Commit indicates an expected call of Commit.
+func (mr *MockstorageLayerMockRecorder) Commit(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Commit", reflect.TypeOf((*MockstorageLayer)(nil).Commit), arg0)
+}
+
+// CompareAndSwap mocks base method.
+func (m *MockstorageLayer) CompareAndSwap(arg0 context.Context, arg1, arg2, arg3 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompareAndSwap", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompareAndSwap indicates an expected call of CompareAndSwap.
+func (mr *MockstorageLayerMockRecorder) CompareAndSwap(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompareAndSwap", reflect.TypeOf((*MockstorageLayer)(nil).CompareAndSwap), arg0, arg1, arg2, arg3)
+}
+
 // Del mocks base method.
 func (m *MockstorageLayer) Del(arg0 context.Context, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -94,6 +155,50 @@ func (mr *MockstorageLayerMockRecorder) Del(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Del", reflect.TypeOf((*MockstorageLayer)(nil).Del), arg0, arg1)
 }
 
+// DelIfEqual mocks base method.
+func (m *MockstorageLayer) DelIfEqual(arg0 context.Context, arg1, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DelIfEqual", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DelIfEqual indicates an expected call of DelIfEqual.
+func (mr *MockstorageLayerMockRecorder) DelIfEqual(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DelIfEqual", reflect.TypeOf((*MockstorageLayer)(nil).DelIfEqual), arg0, arg1, arg2)
+}
+
+// Expiration mocks base method.
+func (m *MockstorageLayer) Expiration(arg0 context.Context, arg1 string) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Expiration", arg0, arg1)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Expiration indicates an expected call of Expiration.
+func (mr *MockstorageLayerMockRecorder) Expiration(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expiration", reflect.TypeOf((*MockstorageLayer)(nil).Expiration), arg0, arg1)
+}
+
+// Expire mocks base method.
+func (m *MockstorageLayer) Expire(arg0 context.Context, arg1 string, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Expire", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Expire indicates an expected call of Expire.
+func (mr *MockstorageLayerMockRecorder) Expire(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expire", reflect.TypeOf((*MockstorageLayer)(nil).Expire), arg0, arg1, arg2)
+}
+
 // Get mocks base method.
 func (m *MockstorageLayer) Get(arg0 context.Context, arg1 string) (string, error) {
 	m.ctrl.T.Helper()
@@ -109,6 +214,363 @@ func (mr *MockstorageLayerMockRecorder) Get(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockstorageLayer)(nil).Get), arg0, arg1)
 }
 
+// GetDel mocks base method.
+func (m *MockstorageLayer) GetDel(arg0 context.Context, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDel", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDel indicates an expected call of GetDel.
+func (mr *MockstorageLayerMockRecorder) GetDel(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDel", reflect.TypeOf((*MockstorageLayer)(nil).GetDel), arg0, arg1)
+}
+
+// GetSet mocks base method.
+func (m *MockstorageLayer) GetSet(arg0 context.Context, arg1, arg2 string) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSet", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetSet indicates an expected call of GetSet.
+func (mr *MockstorageLayerMockRecorder) GetSet(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSet", reflect.TypeOf((*MockstorageLayer)(nil).GetSet), arg0, arg1, arg2)
+}
+
+// HDel mocks base method.
+func (m *MockstorageLayer) HDel(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HDel", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HDel indicates an expected call of HDel.
+func (mr *MockstorageLayerMockRecorder) HDel(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HDel", reflect.TypeOf((*MockstorageLayer)(nil).HDel), arg0, arg1, arg2)
+}
+
+// HGet mocks base method.
+func (m *MockstorageLayer) HGet(arg0 context.Context, arg1, arg2 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HGet", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HGet indicates an expected call of HGet.
+func (mr *MockstorageLayerMockRecorder) HGet(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HGet", reflect.TypeOf((*MockstorageLayer)(nil).HGet), arg0, arg1, arg2)
+}
+
+// HGetAll mocks base method.
+func (m *MockstorageLayer) HGetAll(arg0 context.Context, arg1 string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HGetAll", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HGetAll indicates an expected call of HGetAll.
+func (mr *MockstorageLayerMockRecorder) HGetAll(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HGetAll", reflect.TypeOf((*MockstorageLayer)(nil).HGetAll), arg0, arg1)
+}
+
+// HSet mocks base method.
+func (m *MockstorageLayer) HSet(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HSet", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HSet indicates an expected call of HSet.
+func (mr *MockstorageLayerMockRecorder) HSet(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HSet", reflect.TypeOf((*MockstorageLayer)(nil).HSet), arg0, arg1, arg2)
+}
+
+// IncrBy mocks base method.
+func (m *MockstorageLayer) IncrBy(arg0 context.Context, arg1 string, arg2 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrBy", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrBy indicates an expected call of IncrBy.
+func (mr *MockstorageLayerMockRecorder) IncrBy(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrBy", reflect.TypeOf((*MockstorageLayer)(nil).IncrBy), arg0, arg1, arg2)
+}
+
+// IncrByFloat mocks base method.
+func (m *MockstorageLayer) IncrByFloat(arg0 context.Context, arg1 string, arg2 float64) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrByFloat", arg0, arg1, arg2)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrByFloat indicates an expected call of IncrByFloat.
+func (mr *MockstorageLayerMockRecorder) IncrByFloat(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrByFloat", reflect.TypeOf((*MockstorageLayer)(nil).IncrByFloat), arg0, arg1, arg2)
+}
+
+// LPop mocks base method.
+func (m *MockstorageLayer) LPop(arg0 context.Context, arg1 string, arg2 int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LPop", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LPop indicates an expected call of LPop.
+func (mr *MockstorageLayerMockRecorder) LPop(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LPop", reflect.TypeOf((*MockstorageLayer)(nil).LPop), arg0, arg1, arg2)
+}
+
+// LPush mocks base method.
+func (m *MockstorageLayer) LPush(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LPush", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LPush indicates an expected call of LPush.
+func (mr *MockstorageLayerMockRecorder) LPush(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LPush", reflect.TypeOf((*MockstorageLayer)(nil).LPush), arg0, arg1, arg2)
+}
+
+// LRange mocks base method.
+func (m *MockstorageLayer) LRange(arg0 context.Context, arg1 string, arg2, arg3 int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LRange", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LRange indicates an expected call of LRange.
+func (mr *MockstorageLayerMockRecorder) LRange(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LRange", reflect.TypeOf((*MockstorageLayer)(nil).LRange), arg0, arg1, arg2, arg3)
+}
+
+// MDel mocks base method.
+func (m *MockstorageLayer) MDel(arg0 context.Context, arg1 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MDel", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MDel indicates an expected call of MDel.
+func (mr *MockstorageLayerMockRecorder) MDel(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MDel", reflect.TypeOf((*MockstorageLayer)(nil).MDel), arg0, arg1)
+}
+
+// MGet mocks base method.
+func (m *MockstorageLayer) MGet(arg0 context.Context, arg1 []string) ([]string, []bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MGet", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].([]bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// MGet indicates an expected call of MGet.
+func (mr *MockstorageLayerMockRecorder) MGet(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MGet", reflect.TypeOf((*MockstorageLayer)(nil).MGet), arg0, arg1)
+}
+
+// MSet mocks base method.
+func (m *MockstorageLayer) MSet(arg0 context.Context, arg1 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MSet", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MSet indicates an expected call of MSet.
+func (mr *MockstorageLayerMockRecorder) MSet(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MSet", reflect.TypeOf((*MockstorageLayer)(nil).MSet), arg0, arg1)
+}
+
+// Persist mocks base method.
+func (m *MockstorageLayer) Persist(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Persist", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Persist indicates an expected call of Persist.
+func (mr *MockstorageLayerMockRecorder) Persist(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Persist", reflect.TypeOf((*MockstorageLayer)(nil).Persist), arg0, arg1)
+}
+
+// RPop mocks base method.
+func (m *MockstorageLayer) RPop(arg0 context.Context, arg1 string, arg2 int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RPop", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RPop indicates an expected call of RPop.
+func (mr *MockstorageLayerMockRecorder) RPop(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RPop", reflect.TypeOf((*MockstorageLayer)(nil).RPop), arg0, arg1, arg2)
+}
+
+// RPush mocks base method.
+func (m *MockstorageLayer) RPush(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RPush", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RPush indicates an expected call of RPush.
+func (mr *MockstorageLayerMockRecorder) RPush(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RPush", reflect.TypeOf((*MockstorageLayer)(nil).RPush), arg0, arg1, arg2)
+}
+
+// Range mocks base method.
+func (m *MockstorageLayer) Range(arg0 context.Context, arg1, arg2 string, arg3 int) ([]storage.KeyValue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Range", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]storage.KeyValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Range indicates an expected call of Range.
+func (mr *MockstorageLayerMockRecorder) Range(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Range", reflect.TypeOf((*MockstorageLayer)(nil).Range), arg0, arg1, arg2, arg3)
+}
+
+// Reshard mocks base method.
+func (m *MockstorageLayer) Reshard(arg0 context.Context, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reshard", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reshard indicates an expected call of Reshard.
+func (mr *MockstorageLayerMockRecorder) Reshard(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reshard", reflect.TypeOf((*MockstorageLayer)(nil).Reshard), arg0, arg1)
+}
+
+// Rollback mocks base method.
+func (m *MockstorageLayer) Rollback(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rollback", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rollback indicates an expected call of Rollback.
+func (mr *MockstorageLayerMockRecorder) Rollback(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rollback", reflect.TypeOf((*MockstorageLayer)(nil).Rollback), arg0)
+}
+
+// SAdd mocks base method.
+func (m *MockstorageLayer) SAdd(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SAdd", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SAdd indicates an expected call of SAdd.
+func (mr *MockstorageLayerMockRecorder) SAdd(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SAdd", reflect.TypeOf((*MockstorageLayer)(nil).SAdd), arg0, arg1, arg2)
+}
+
+// SIsMember mocks base method.
+func (m *MockstorageLayer) SIsMember(arg0 context.Context, arg1, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SIsMember", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SIsMember indicates an expected call of SIsMember.
+func (mr *MockstorageLayerMockRecorder) SIsMember(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SIsMember", reflect.TypeOf((*MockstorageLayer)(nil).SIsMember), arg0, arg1, arg2)
+}
+
+// SMembers mocks base method.
+func (m *MockstorageLayer) SMembers(arg0 context.Context, arg1 string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SMembers", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SMembers indicates an expected call of SMembers.
+func (mr *MockstorageLayerMockRecorder) SMembers(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SMembers", reflect.TypeOf((*MockstorageLayer)(nil).SMembers), arg0, arg1)
+}
+
+// SRem mocks base method.
+func (m *MockstorageLayer) SRem(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SRem", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SRem indicates an expected call of SRem.
+func (mr *MockstorageLayerMockRecorder) SRem(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SRem", reflect.TypeOf((*MockstorageLayer)(nil).SRem), arg0, arg1, arg2)
+}
+
 // Set mocks base method.
 func (m *MockstorageLayer) Set(arg0 context.Context, arg1, arg2 string) error {
 	m.ctrl.T.Helper()
@@ -122,3 +584,217 @@ func (mr *MockstorageLayerMockRecorder) Set(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockstorageLayer)(nil).Set), arg0, arg1, arg2)
 }
+
+// SetIf mocks base method.
+func (m *MockstorageLayer) SetIf(arg0 context.Context, arg1, arg2 string, arg3 time.Time, arg4 bool) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetIf", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetIf indicates an expected call of SetIf.
+func (mr *MockstorageLayerMockRecorder) SetIf(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIf", reflect.TypeOf((*MockstorageLayer)(nil).SetIf), arg0, arg1, arg2, arg3, arg4)
+}
+
+// SetWithExpiration mocks base method.
+func (m *MockstorageLayer) SetWithExpiration(arg0 context.Context, arg1, arg2 string, arg3 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWithExpiration", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetWithExpiration indicates an expected call of SetWithExpiration.
+func (mr *MockstorageLayerMockRecorder) SetWithExpiration(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithExpiration", reflect.TypeOf((*MockstorageLayer)(nil).SetWithExpiration), arg0, arg1, arg2, arg3)
+}
+
+// WaitLSN mocks base method.
+func (m *MockstorageLayer) WaitLSN(arg0 context.Context, arg1 int64, arg2 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitLSN", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitLSN indicates an expected call of WaitLSN.
+func (mr *MockstorageLayerMockRecorder) WaitLSN(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitLSN", reflect.TypeOf((*MockstorageLayer)(nil).WaitLSN), arg0, arg1, arg2)
+}
+
+// ZAdd mocks base method.
+func (m *MockstorageLayer) ZAdd(arg0 context.Context, arg1 string, arg2 []common.ScoredMember) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZAdd", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ZAdd indicates an expected call of ZAdd.
+func (mr *MockstorageLayerMockRecorder) ZAdd(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZAdd", reflect.TypeOf((*MockstorageLayer)(nil).ZAdd), arg0, arg1, arg2)
+}
+
+// ZIncrBy mocks base method.
+func (m *MockstorageLayer) ZIncrBy(arg0 context.Context, arg1 string, arg2 float64, arg3 string) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZIncrBy", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ZIncrBy indicates an expected call of ZIncrBy.
+func (mr *MockstorageLayerMockRecorder) ZIncrBy(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZIncrBy", reflect.TypeOf((*MockstorageLayer)(nil).ZIncrBy), arg0, arg1, arg2, arg3)
+}
+
+// ZRange mocks base method.
+func (m *MockstorageLayer) ZRange(arg0 context.Context, arg1 string, arg2, arg3 int) ([]common.ScoredMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZRange", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]common.ScoredMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ZRange indicates an expected call of ZRange.
+func (mr *MockstorageLayerMockRecorder) ZRange(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRange", reflect.TypeOf((*MockstorageLayer)(nil).ZRange), arg0, arg1, arg2, arg3)
+}
+
+// ZRangeByScore mocks base method.
+func (m *MockstorageLayer) ZRangeByScore(arg0 context.Context, arg1 string, arg2 common.ScoreRange, arg3, arg4 int) ([]common.ScoredMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZRangeByScore", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].([]common.ScoredMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ZRangeByScore indicates an expected call of ZRangeByScore.
+func (mr *MockstorageLayerMockRecorder) ZRangeByScore(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRangeByScore", reflect.TypeOf((*MockstorageLayer)(nil).ZRangeByScore), arg0, arg1, arg2, arg3, arg4)
+}
+
+// ZRank mocks base method.
+func (m *MockstorageLayer) ZRank(arg0 context.Context, arg1, arg2 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZRank", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ZRank indicates an expected call of ZRank.
+func (mr *MockstorageLayerMockRecorder) ZRank(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRank", reflect.TypeOf((*MockstorageLayer)(nil).ZRank), arg0, arg1, arg2)
+}
+
+// ZRem mocks base method.
+func (m *MockstorageLayer) ZRem(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZRem", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ZRem indicates an expected call of ZRem.
+func (mr *MockstorageLayerMockRecorder) ZRem(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRem", reflect.TypeOf((*MockstorageLayer)(nil).ZRem), arg0, arg1, arg2)
+}
+
+// ZScore mocks base method.
+func (m *MockstorageLayer) ZScore(arg0 context.Context, arg1, arg2 string) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZScore", arg0, arg1, arg2)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ZScore indicates an expected call of ZScore.
+func (mr *MockstorageLayerMockRecorder) ZScore(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZScore", reflect.TypeOf((*MockstorageLayer)(nil).ZScore), arg0, arg1, arg2)
+}
+
+// MockreplicationLayer is a mock of replicationLayer interface.
+type MockreplicationLayer struct {
+	ctrl     *gomock.Controller
+	recorder *MockreplicationLayerMockRecorder
+	isgomock struct{}
+}
+
+// MockreplicationLayerMockRecorder is the mock recorder for MockreplicationLayer.
+type MockreplicationLayerMockRecorder struct {
+	mock *MockreplicationLayer
+}
+
+// NewMockreplicationLayer creates a new mock instance.
+func NewMockreplicationLayer(ctrl *gomock.Controller) *MockreplicationLayer {
+	mock := &MockreplicationLayer{ctrl: ctrl}
+	mock.recorder = &MockreplicationLayerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockreplicationLayer) EXPECT() *MockreplicationLayerMockRecorder {
+	return m.recorder
+}
+
+// Demote mocks base method.
+func (m *MockreplicationLayer) Demote(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Demote", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Demote indicates an expected call of Demote.
+func (mr *MockreplicationLayerMockRecorder) Demote(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Demote", reflect.TypeOf((*MockreplicationLayer)(nil).Demote), arg0)
+}
+
+// Promote mocks base method.
+func (m *MockreplicationLayer) Promote(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Promote", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Promote indicates an expected call of Promote.
+func (mr *MockreplicationLayerMockRecorder) Promote(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Promote", reflect.TypeOf((*MockreplicationLayer)(nil).Promote), arg0)
+}
+
+// Status mocks base method.
+func (m *MockreplicationLayer) Status() replication.Status {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status")
+	ret0, _ := ret[0].(replication.Status)
+	return ret0
+}
+
+// Status indicates an expected call of Status.
+func (mr *MockreplicationLayerMockRecorder) Status() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockreplicationLayer)(nil).Status))
+}
@@ -0,0 +1,11 @@
+package database
+
+// Option ...
+type Option func(*Database)
+
+// WithReplication enables commands which switch the replication role of the node
+func WithReplication(replication replicationLayer) Option {
+	return func(db *Database) {
+		db.repl = replication
+	}
+}
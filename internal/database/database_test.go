@@ -3,14 +3,21 @@ package database
 import (
 	"context"
 	"errors"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
 
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
 	"database-simon/internal/database/compute"
+	"database-simon/internal/database/storage"
+	"database-simon/internal/database/storage/engine/memory"
+	"database-simon/internal/database/storage/replication"
 )
 
 func TestNewDatabase(t *testing.T) {
@@ -110,6 +117,48 @@ func TestHandleQuery(t *testing.T) {
 			},
 			expectedResponse: "[error]",
 		},
+		"handle set query out of memory": {
+			query: "SET key value",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "SET key value").
+					Return(compute.NewQuery(
+						compute.SetCommand,
+						[]string{"key", "value"},
+					), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stop := NewMockstorageLayer(controller)
+				stop.EXPECT().
+					Set(gomock.Any(), "key", "value").
+					Return(storage.ErrorOutOfMemory)
+				return stop
+			},
+			expectedResponse: "[error] out of memory",
+		},
+		"handle set query not replicated": {
+			query: "SET key value",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "SET key value").
+					Return(compute.NewQuery(
+						compute.SetCommand,
+						[]string{"key", "value"},
+					), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Set(gomock.Any(), "key", "value").
+					Return(common.ErrorReplicationTimeout)
+				return stor
+			},
+			expectedResponse: "[error] NOTREPLICATED write is applied, but isn't acknowledged by replicas in time",
+		},
 		"handle set query": {
 			query: "SET key value",
 			comp: func() computeLayer {
@@ -215,14 +264,77 @@ func TestHandleQuery(t *testing.T) {
 			},
 			expectedResponse: "[not found]",
 		},
-		"handle get query": {
-			query: "GET key",
+		"handle set query with expiration": {
+			query: "SET key value EX 10",
 			comp: func() computeLayer {
 				comp := NewMockcomputeLayer(controller)
 				comp.EXPECT().
-					Parse(gomock.Any(), "GET key").
+					Parse(gomock.Any(), "SET key value EX 10").
 					Return(compute.NewQuery(
-						compute.GetCommand,
+						compute.SetCommand,
+						[]string{"key", "value", compute.ExOption, "10"},
+					), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					SetWithExpiration(gomock.Any(), "key", "value", gomock.Any()).
+					Return(nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle expire query with not found error from storage": {
+			query: "EXPIRE key 10",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "EXPIRE key 10").
+					Return(compute.NewQuery(
+						compute.ExpireCommand,
+						[]string{"key", "10"},
+					), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Expire(gomock.Any(), "key", gomock.Any()).
+					Return(storage.ErrorNotFound)
+				return stor
+			},
+			expectedResponse: "[not found]",
+		},
+		"handle pexpire query": {
+			query: "PEXPIRE key 100",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "PEXPIRE key 100").
+					Return(compute.NewQuery(
+						compute.PExpireCommand,
+						[]string{"key", "100"},
+					), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Expire(gomock.Any(), "key", gomock.Any()).
+					Return(nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle ttl query with not found error from storage": {
+			query: "TTL key",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "TTL key").
+					Return(compute.NewQuery(
+						compute.TTLCommand,
 						[]string{"key"},
 					), nil)
 				return comp
@@ -230,23 +342,1073 @@ func TestHandleQuery(t *testing.T) {
 			stor: func() storageLayer {
 				stor := NewMockstorageLayer(controller)
 				stor.EXPECT().
-					Get(gomock.Any(), "key").
-					Return("value", nil)
+					Expiration(gomock.Any(), "key").
+					Return(time.Time{}, storage.ErrorNotFound)
 				return stor
 			},
-			expectedResponse: "value",
+			expectedResponse: "[not found]",
 		},
-	}
-
-	for name, test := range tests {
-		t.Run(name, func(t *testing.T) {
-			t.Parallel()
-
-			stor, err := NewDatabase(zap.NewNop(), test.comp(), test.stor())
-			require.NoError(t, err)
-
-			response, _ := stor.HandleQuery(context.Background(), test.query)
-			assert.Equal(t, test.expectedResponse, response)
-		})
-	}
+		"handle ttl query for key without expiration": {
+			query: "TTL key",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "TTL key").
+					Return(compute.NewQuery(
+						compute.TTLCommand,
+						[]string{"key"},
+					), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Expiration(gomock.Any(), "key").
+					Return(time.Time{}, nil)
+				return stor
+			},
+			expectedResponse: "-1",
+		},
+		"handle ttl query": {
+			query: "TTL key",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "TTL key").
+					Return(compute.NewQuery(
+						compute.TTLCommand,
+						[]string{"key"},
+					), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Expiration(gomock.Any(), "key").
+					Return(time.Now().Add(10*time.Second), nil)
+				return stor
+			},
+			expectedResponse: "10",
+		},
+		"handle pttl query for expired key": {
+			query: "PTTL key",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "PTTL key").
+					Return(compute.NewQuery(
+						compute.PTTLCommand,
+						[]string{"key"},
+					), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Expiration(gomock.Any(), "key").
+					Return(time.Now().Add(-time.Second), nil)
+				return stor
+			},
+			expectedResponse: "0",
+		},
+		"handle persist query with error from storage": {
+			query: "PERSIST key",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "PERSIST key").
+					Return(compute.NewQuery(
+						compute.PersistCommand,
+						[]string{"key"},
+					), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Persist(gomock.Any(), "key").
+					Return(storage.ErrorMutableTX)
+				return stor
+			},
+			expectedResponse: "[error]",
+		},
+		"handle persist query": {
+			query: "PERSIST key",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "PERSIST key").
+					Return(compute.NewQuery(
+						compute.PersistCommand,
+						[]string{"key"},
+					), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Persist(gomock.Any(), "key").
+					Return(nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle begin query": {
+			query: "BEGIN",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "BEGIN").
+					Return(compute.NewQuery(compute.BeginCommand, nil), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Begin(gomock.Any()).
+					Return(nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle commit query with error from storage": {
+			query: "COMMIT",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "COMMIT").
+					Return(compute.NewQuery(compute.CommitCommand, nil), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Commit(gomock.Any()).
+					Return(storage.ErrorTXNotStarted)
+				return stor
+			},
+			expectedResponse: "[error]",
+		},
+		"handle commit query": {
+			query: "COMMIT",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "COMMIT").
+					Return(compute.NewQuery(compute.CommitCommand, nil), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Commit(gomock.Any()).
+					Return(nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle rollback query": {
+			query: "ROLLBACK",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "ROLLBACK").
+					Return(compute.NewQuery(compute.RollbackCommand, nil), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Rollback(gomock.Any()).
+					Return(nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle checkpoint query": {
+			query: "CHECKPOINT",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "CHECKPOINT").
+					Return(compute.NewQuery(compute.CheckpointCommand, nil), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Checkpoint(gomock.Any()).
+					Return(nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle reshard query": {
+			query: "RESHARD 16",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "RESHARD 16").
+					Return(compute.NewQuery(compute.ReshardCommand, []string{"16"}), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Reshard(gomock.Any(), 16).
+					Return(nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle reshard query with unsupported engine": {
+			query: "RESHARD 16",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "RESHARD 16").
+					Return(compute.NewQuery(compute.ReshardCommand, []string{"16"}), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Reshard(gomock.Any(), 16).
+					Return(storage.ErrorNoResharding)
+				return stor
+			},
+			expectedResponse: "[error]",
+		},
+		"handle checkpoint query without WAL": {
+			query: "CHECKPOINT",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "CHECKPOINT").
+					Return(compute.NewQuery(compute.CheckpointCommand, nil), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Checkpoint(gomock.Any()).
+					Return(storage.ErrorNoWAL)
+				return stor
+			},
+			expectedResponse: "[error]",
+		},
+		"handle wait query": {
+			query: "WAIT LSN 10 500",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "WAIT LSN 10 500").
+					Return(compute.NewQuery(compute.WaitCommand, []string{compute.LSNCommand, "10", "500"}), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					WaitLSN(gomock.Any(), int64(10), 500*time.Millisecond).
+					Return(nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle wait query with timeout": {
+			query: "WAIT LSN 10",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "WAIT LSN 10").
+					Return(compute.NewQuery(compute.WaitCommand, []string{compute.LSNCommand, "10"}), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					WaitLSN(gomock.Any(), int64(10), time.Duration(0)).
+					Return(storage.ErrorLSNTimeout)
+				return stor
+			},
+			expectedResponse: "[error]",
+		},
+		"handle get query": {
+			query: "GET key",
+			comp: func() computeLayer {
+				comp := NewMockcomputeLayer(controller)
+				comp.EXPECT().
+					Parse(gomock.Any(), "GET key").
+					Return(compute.NewQuery(
+						compute.GetCommand,
+						[]string{"key"},
+					), nil)
+				return comp
+			},
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Get(gomock.Any(), "key").
+					Return("value", nil)
+				return stor
+			},
+			expectedResponse: "value",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stor, err := NewDatabase(zap.NewNop(), test.comp(), test.stor())
+			require.NoError(t, err)
+
+			response, _ := stor.HandleQuery(context.Background(), test.query)
+			assert.Equal(t, test.expectedResponse, response)
+		})
+	}
+}
+
+func TestDatabase_HandleReplicationQuery(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	tests := map[string]struct {
+		query       compute.Query
+		repl        func() replicationLayer
+		withoutRepl bool
+
+		expectedResponse string
+	}{
+		"handle promote query": {
+			query: compute.NewQuery(compute.PromoteCommand, nil),
+			repl: func() replicationLayer {
+				repl := NewMockreplicationLayer(controller)
+				repl.EXPECT().
+					Promote("").
+					Return(nil)
+				return repl
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle demote query": {
+			query: compute.NewQuery(compute.DemoteCommand, []string{"127.0.0.1:8082"}),
+			repl: func() replicationLayer {
+				repl := NewMockreplicationLayer(controller)
+				repl.EXPECT().
+					Demote("127.0.0.1:8082").
+					Return(errors.New("failed to create slave"))
+				return repl
+			},
+			expectedResponse: "[error]",
+		},
+		"handle info query": {
+			query: compute.NewQuery(compute.InfoCommand, nil),
+			repl: func() replicationLayer {
+				repl := NewMockreplicationLayer(controller)
+				repl.EXPECT().
+					Status().
+					Return(replication.Status{IsMaster: true, Term: 2})
+				return repl
+			},
+			expectedResponse: "role:master\nterm:2\nfenced:false\nconnected_replicas:0",
+		},
+		"handle info query without replication": {
+			query:            compute.NewQuery(compute.InfoCommand, []string{compute.ReplicationSection}),
+			withoutRepl:      true,
+			expectedResponse: "[error]",
+		},
+		"handle promote query without replication": {
+			query:            compute.NewQuery(compute.PromoteCommand, []string{"127.0.0.1:8082"}),
+			withoutRepl:      true,
+			expectedResponse: "[error]",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			comp := NewMockcomputeLayer(controller)
+			comp.EXPECT().
+				Parse(gomock.Any(), name).
+				Return(test.query, nil)
+
+			var options []Option
+			if !test.withoutRepl {
+				options = append(options, WithReplication(test.repl()))
+			}
+
+			db, err := NewDatabase(zap.NewNop(), comp, NewMockstorageLayer(controller), options...)
+			require.NoError(t, err)
+
+			response, _ := db.HandleQuery(context.Background(), name)
+			assert.Equal(t, test.expectedResponse, response)
+		})
+	}
+}
+
+func TestDatabase_HandleQueryWithLSN(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	ctx, cancel := context.WithCancel(common.ContextWithSessionID(context.Background(), 1))
+	defer cancel()
+
+	comp := NewMockcomputeLayer(controller)
+	comp.EXPECT().
+		Parse(gomock.Any(), "LSN ON").
+		Return(compute.NewQuery(compute.LSNCommand, []string{compute.OnOption}), nil)
+	comp.EXPECT().
+		Parse(gomock.Any(), "LSN OFF").
+		Return(compute.NewQuery(compute.LSNCommand, []string{compute.OffOption}), nil)
+	comp.EXPECT().
+		Parse(gomock.Any(), "SET key value").
+		Return(compute.NewQuery(compute.SetCommand, []string{"key", "value"}), nil).
+		Times(3)
+
+	stor := NewMockstorageLayer(controller)
+	stor.EXPECT().
+		Set(gomock.Any(), "key", "value").
+		DoAndReturn(func(ctx context.Context, _, _ string) error {
+			common.SetCommitLSN(ctx, 42)
+			return nil
+		}).
+		Times(3)
+
+	db, err := NewDatabase(zap.NewNop(), comp, stor)
+	require.NoError(t, err)
+
+	response, _ := db.HandleQuery(ctx, "LSN ON")
+	assert.Equal(t, "[ok]", response)
+
+	response, _ = db.HandleQuery(ctx, "SET key value")
+	assert.Equal(t, "[ok] 42", response)
+
+	// other sessions get responses without LSN
+	response, _ = db.HandleQuery(common.ContextWithSessionID(context.Background(), 2), "SET key value")
+	assert.Equal(t, "[ok]", response)
+
+	response, _ = db.HandleQuery(ctx, "LSN OFF")
+	assert.Equal(t, "[ok]", response)
+
+	response, _ = db.HandleQuery(ctx, "SET key value")
+	assert.Equal(t, "[ok]", response)
+}
+
+func TestDatabase_HandleRangeQuery(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	entries := []storage.KeyValue{
+		{Key: "user:1", Value: "alice"},
+		{Key: "user:2", Value: "bob smith"},
+		{Key: "users", Value: "2"},
+	}
+
+	tests := map[string]struct {
+		query compute.Query
+		stor  func() storageLayer
+
+		expectedResponse string
+	}{
+		"handle keys query": {
+			query: compute.NewQuery(compute.KeysCommand, []string{"user"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Range(gomock.Any(), "user", "uses", 0).
+					Return(entries, nil)
+				return stor
+			},
+			expectedResponse: "user:1\nuser:2\nusers",
+		},
+		"handle range query": {
+			query: compute.NewQuery(compute.RangeCommand, []string{"user:1", "user:2", compute.LimitOption, "5"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Range(gomock.Any(), "user:1", "user:2\x00", 5).
+					Return(entries[:2], nil)
+				return stor
+			},
+			expectedResponse: "user:1 alice\nuser:2 \"bob smith\"",
+		},
+		"handle range query with unordered engine": {
+			query: compute.NewQuery(compute.RangeCommand, []string{"a", "b"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Range(gomock.Any(), "a", "b\x00", 0).
+					Return(nil, storage.ErrorUnordered)
+				return stor
+			},
+			expectedResponse: "[error]",
+		},
+		"handle scan query": {
+			query: compute.NewQuery(compute.ScanCommand, []string{compute.StartCursor, compute.CountOption, "3", compute.MatchOption, "user:*"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Range(gomock.Any(), "", "", 3).
+					Return(entries, nil)
+				return stor
+			},
+			// the cursor is the key which follows "users"
+			expectedResponse: "757365727300\nuser:1\nuser:2",
+		},
+		"handle last scan query": {
+			query: compute.NewQuery(compute.ScanCommand, []string{"7573657273"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Range(gomock.Any(), "users", "", 10).
+					Return(entries[2:], nil)
+				return stor
+			},
+			expectedResponse: "0\nusers",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			comp := NewMockcomputeLayer(controller)
+			comp.EXPECT().
+				Parse(gomock.Any(), name).
+				Return(test.query, nil)
+
+			db, err := NewDatabase(zap.NewNop(), comp, test.stor())
+			require.NoError(t, err)
+
+			response, _ := db.HandleQuery(context.Background(), name)
+			assert.Equal(t, test.expectedResponse, response)
+		})
+	}
+}
+
+func TestDatabase_HandleStructureQuery(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	tests := map[string]struct {
+		query compute.Query
+		stor  func() storageLayer
+
+		expectedResponse string
+	}{
+		"handle hset query": {
+			query: compute.NewQuery(compute.HSetCommand, []string{"user", "name", "alice"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					HSet(gomock.Any(), "user", []string{"name", "alice"}).
+					Return(1, nil)
+				return stor
+			},
+			expectedResponse: "1",
+		},
+		"handle hset query wrong type": {
+			query: compute.NewQuery(compute.HSetCommand, []string{"user", "name", "alice"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					HSet(gomock.Any(), "user", []string{"name", "alice"}).
+					Return(0, common.ErrorWrongType)
+				return stor
+			},
+			expectedResponse: "[error] WRONGTYPE operation against a key holding the wrong kind of value",
+		},
+		"handle hget query not found": {
+			query: compute.NewQuery(compute.HGetCommand, []string{"user", "age"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					HGet(gomock.Any(), "user", "age").
+					Return("", storage.ErrorNotFound)
+				return stor
+			},
+			expectedResponse: "[not found]",
+		},
+		"handle hgetall query": {
+			query: compute.NewQuery(compute.HGetAllCommand, []string{"user"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					HGetAll(gomock.Any(), "user").
+					Return([]string{"city", "new york", "name", "alice"}, nil)
+				return stor
+			},
+			expectedResponse: "city \"new york\"\nname alice",
+		},
+		"handle lpop query": {
+			query: compute.NewQuery(compute.LPopCommand, []string{"queue"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					LPop(gomock.Any(), "queue", 1).
+					Return([]string{"first value"}, nil)
+				return stor
+			},
+			expectedResponse: "first value",
+		},
+		"handle rpop query with count": {
+			query: compute.NewQuery(compute.RPopCommand, []string{"queue", "2"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					RPop(gomock.Any(), "queue", 2).
+					Return([]string{"c", "b"}, nil)
+				return stor
+			},
+			expectedResponse: "c\nb",
+		},
+		"handle lrange query": {
+			query: compute.NewQuery(compute.LRangeCommand, []string{"queue", "0", "-1"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					LRange(gomock.Any(), "queue", 0, -1).
+					Return([]string{"a", "b"}, nil)
+				return stor
+			},
+			expectedResponse: "a\nb",
+		},
+		"handle sismember query": {
+			query: compute.NewQuery(compute.SIsMemberCommand, []string{"tags", "go"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					SIsMember(gomock.Any(), "tags", "go").
+					Return(true, nil)
+				return stor
+			},
+			expectedResponse: "1",
+		},
+		"handle zadd query": {
+			query: compute.NewQuery(compute.ZAddCommand, []string{"board", "10", "alice", "-inf", "bob"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					ZAdd(gomock.Any(), "board", []common.ScoredMember{{Member: "alice", Score: 10}, {Member: "bob", Score: math.Inf(-1)}}).
+					Return(2, nil)
+				return stor
+			},
+			expectedResponse: "2",
+		},
+		"handle zincrby query not a number": {
+			query: compute.NewQuery(compute.ZIncrByCommand, []string{"board", "-inf", "alice"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					ZIncrBy(gomock.Any(), "board", math.Inf(-1), "alice").
+					Return(float64(0), common.ErrorNotANumber)
+				return stor
+			},
+			expectedResponse: "[error] resulting score is not a number",
+		},
+		"handle zscore query": {
+			query: compute.NewQuery(compute.ZScoreCommand, []string{"board", "alice"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					ZScore(gomock.Any(), "board", "alice").
+					Return(1.5, nil)
+				return stor
+			},
+			expectedResponse: "1.5",
+		},
+		"handle zrank query not found": {
+			query: compute.NewQuery(compute.ZRankCommand, []string{"board", "dave"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					ZRank(gomock.Any(), "board", "dave").
+					Return(0, storage.ErrorNotFound)
+				return stor
+			},
+			expectedResponse: "[not found]",
+		},
+		"handle zrange query with scores": {
+			query: compute.NewQuery(compute.ZRangeCommand, []string{"board", "0", "-1", compute.WithScoresOption}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					ZRange(gomock.Any(), "board", 0, -1).
+					Return([]common.ScoredMember{{Member: "bob smith", Score: math.Inf(-1)}, {Member: "alice", Score: 10}}, nil)
+				return stor
+			},
+			expectedResponse: "\"bob smith\" -inf\nalice 10",
+		},
+		"handle zrangebyscore query with limit": {
+			query: compute.NewQuery(compute.ZRangeByScoreCommand, []string{"board", "(1", "+inf", compute.LimitOption, "1", "2"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					ZRangeByScore(gomock.Any(), "board", common.ScoreRange{Min: 1, Max: math.Inf(1), MinExclusive: true}, 1, 2).
+					Return([]common.ScoredMember{{Member: "alice", Score: 10}}, nil)
+				return stor
+			},
+			expectedResponse: "alice",
+		},
+		"handle get query wrong type": {
+			query: compute.NewQuery(compute.GetCommand, []string{"tags"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					Get(gomock.Any(), "tags").
+					Return("", common.ErrorWrongType)
+				return stor
+			},
+			expectedResponse: "[error] WRONGTYPE operation against a key holding the wrong kind of value",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			comp := NewMockcomputeLayer(controller)
+			comp.EXPECT().
+				Parse(gomock.Any(), name).
+				Return(test.query, nil)
+
+			db, err := NewDatabase(zap.NewNop(), comp, test.stor())
+			require.NoError(t, err)
+
+			response, _ := db.HandleQuery(context.Background(), name)
+			assert.Equal(t, test.expectedResponse, response)
+		})
+	}
+}
+
+func TestDatabase_HandleCounterQuery(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	tests := map[string]struct {
+		query compute.Query
+		stor  func() storageLayer
+
+		expectedResponse string
+	}{
+		"handle incr query": {
+			query: compute.NewQuery(compute.IncrCommand, []string{"counter"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					IncrBy(gomock.Any(), "counter", int64(1)).
+					Return(int64(11), nil)
+				return stor
+			},
+			expectedResponse: "11",
+		},
+		"handle decr query not integer": {
+			query: compute.NewQuery(compute.DecrCommand, []string{"counter"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					IncrBy(gomock.Any(), "counter", int64(-1)).
+					Return(int64(0), common.ErrorNotInteger)
+				return stor
+			},
+			expectedResponse: "[error] value is not an integer or out of range",
+		},
+		"handle incrby query overflow": {
+			query: compute.NewQuery(compute.IncrByCommand, []string{"counter", "9223372036854775807"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					IncrBy(gomock.Any(), "counter", int64(math.MaxInt64)).
+					Return(int64(0), common.ErrorOverflow)
+				return stor
+			},
+			expectedResponse: "[error] increment or decrement would overflow",
+		},
+		"handle incrbyfloat query": {
+			query: compute.NewQuery(compute.IncrByFloatCommand, []string{"counter", "0.2"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					IncrByFloat(gomock.Any(), "counter", 0.2).
+					Return(0.30000000000000004, nil)
+				return stor
+			},
+			expectedResponse: "0.30000000000000004",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			comp := NewMockcomputeLayer(controller)
+			comp.EXPECT().
+				Parse(gomock.Any(), name).
+				Return(test.query, nil)
+
+			db, err := NewDatabase(zap.NewNop(), comp, test.stor())
+			require.NoError(t, err)
+
+			response, _ := db.HandleQuery(context.Background(), name)
+			assert.Equal(t, test.expectedResponse, response)
+		})
+	}
+}
+
+func TestDatabase_HandleConditionalQuery(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	tests := map[string]struct {
+		query compute.Query
+		stor  func() storageLayer
+
+		expectedResponse string
+	}{
+		"handle set nx query": {
+			query: compute.NewQuery(compute.SetCommand, []string{"key", "value", compute.NxOption}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					SetIf(gomock.Any(), "key", "value", time.Time{}, false).
+					Return(true, nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle set xx query not applied": {
+			query: compute.NewQuery(compute.SetCommand, []string{"key", "value", compute.PxOption, "100", compute.XxOption}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					SetIf(gomock.Any(), "key", "value", gomock.Not(time.Time{}), true).
+					Return(false, nil)
+				return stor
+			},
+			expectedResponse: "[not applied]",
+		},
+		"handle set query with lowercase options": {
+			query: compute.NewQuery(compute.SetCommand, []string{"key", "value", "px", "100", "xx"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					SetIf(gomock.Any(), "key", "value", gomock.Not(time.Time{}), true).
+					Return(true, nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle cas query": {
+			query: compute.NewQuery(compute.CASCommand, []string{"key", "old", "new"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					CompareAndSwap(gomock.Any(), "key", "old", "new").
+					Return(false, nil)
+				return stor
+			},
+			expectedResponse: "[not applied]",
+		},
+		"handle cas query not found": {
+			query: compute.NewQuery(compute.CASCommand, []string{"key", "old", "new"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					CompareAndSwap(gomock.Any(), "key", "old", "new").
+					Return(false, storage.ErrorNotFound)
+				return stor
+			},
+			expectedResponse: "[not found]",
+		},
+		"handle getset query": {
+			query: compute.NewQuery(compute.GetSetCommand, []string{"key", "new"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					GetSet(gomock.Any(), "key", "new").
+					Return("old", true, nil)
+				return stor
+			},
+			expectedResponse: "old",
+		},
+		"handle getset query for missing key": {
+			query: compute.NewQuery(compute.GetSetCommand, []string{"key", "new"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					GetSet(gomock.Any(), "key", "new").
+					Return("", false, nil)
+				return stor
+			},
+			expectedResponse: "[not found]",
+		},
+		"handle getdel query wrong type": {
+			query: compute.NewQuery(compute.GetDelCommand, []string{"key"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					GetDel(gomock.Any(), "key").
+					Return("", common.ErrorWrongType)
+				return stor
+			},
+			expectedResponse: "[error] WRONGTYPE operation against a key holding the wrong kind of value",
+		},
+		"handle del ifeq query": {
+			query: compute.NewQuery(compute.DelCommand, []string{"key", compute.IfEqOption, "value"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					DelIfEqual(gomock.Any(), "key", "value").
+					Return(false, nil)
+				return stor
+			},
+			expectedResponse: "[not applied]",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			comp := NewMockcomputeLayer(controller)
+			comp.EXPECT().
+				Parse(gomock.Any(), name).
+				Return(test.query, nil)
+
+			db, err := NewDatabase(zap.NewNop(), comp, test.stor())
+			require.NoError(t, err)
+
+			response, _ := db.HandleQuery(context.Background(), name)
+			assert.Equal(t, test.expectedResponse, response)
+		})
+	}
+}
+
+func TestDatabase_HandleBatchQuery(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	tests := map[string]struct {
+		query compute.Query
+		stor  func() storageLayer
+
+		expectedResponse string
+	}{
+		"handle mset query": {
+			query: compute.NewQuery(compute.MSetCommand, []string{"a", "1", "b", "2"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					MSet(gomock.Any(), []string{"a", "1", "b", "2"}).
+					Return(nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle mset query out of memory": {
+			query: compute.NewQuery(compute.MSetCommand, []string{"a", "1"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					MSet(gomock.Any(), []string{"a", "1"}).
+					Return(storage.ErrorOutOfMemory)
+				return stor
+			},
+			expectedResponse: outOfMemoryResult,
+		},
+		"handle mdel query": {
+			query: compute.NewQuery(compute.MDelCommand, []string{"a", "b"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					MDel(gomock.Any(), []string{"a", "b"}).
+					Return(nil)
+				return stor
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle mget query": {
+			query: compute.NewQuery(compute.MGetCommand, []string{"a", "b", "c"}),
+			stor: func() storageLayer {
+				stor := NewMockstorageLayer(controller)
+				stor.EXPECT().
+					MGet(gomock.Any(), []string{"a", "b", "c"}).
+					Return([]string{"1", "", "hello world"}, []bool{true, false, true}, nil)
+				return stor
+			},
+			expectedResponse: "1\n[not found]\n\"hello world\"",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			comp := NewMockcomputeLayer(controller)
+			comp.EXPECT().
+				Parse(gomock.Any(), name).
+				Return(test.query, nil)
+
+			db, err := NewDatabase(zap.NewNop(), comp, test.stor())
+			require.NoError(t, err)
+
+			response, _ := db.HandleQuery(context.Background(), name)
+			assert.Equal(t, test.expectedResponse, response)
+		})
+	}
+}
+
+func TestDatabase_NotReplicatedWriteIsVisible(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	writeAheadLog := storage.NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, nil)
+	// the record is persisted by the master, but replicas don't acknowledge it in time
+	writeAheadLog.EXPECT().
+		Set(gomock.Any(), "key", "value").
+		DoAndReturn(func(context.Context, string, string) concurrency.FutureError {
+			promise := concurrency.NewPromise[error]()
+			promise.Set(common.ErrorReplicationTimeout)
+			return promise.GetFuture()
+		})
+
+	eng, err := memory.NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	stor, err := storage.NewStorage(eng, zap.NewNop(), storage.WithWAL(writeAheadLog))
+	require.NoError(t, err)
+	db, err := NewDatabase(zap.NewNop(), compute.NewCompute(), stor)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	response, err := db.HandleQuery(ctx, "SET key value")
+	assert.ErrorIs(t, err, common.ErrorReplicationTimeout)
+	assert.Equal(t, notReplicatedResult, response)
+
+	// the fail policy reports the write, but doesn't roll it back
+	response, err = db.HandleQuery(ctx, "GET key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", response)
+}
+
+func TestPrefixEnd(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "abd", prefixEnd("abc"))
+	assert.Equal(t, "b", prefixEnd("a\xff\xff"))
+	assert.Equal(t, "", prefixEnd("\xff"))
+	assert.Equal(t, "", prefixEnd(""))
 }
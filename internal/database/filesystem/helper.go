@@ -3,51 +3,16 @@ package filesystem
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-// SegmentNext ...
-func SegmentNext(directory string, segmentName string) (string, error) {
-	files, err := os.ReadDir(directory)
-	if err != nil {
-		return "", fmt.Errorf("failed to scan WAL directory: %w", err)
-	}
-
-	filenames := make([]string, 0, len(files))
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		filenames = append(filenames, file.Name())
-	}
-
-	idx := upperBound(filenames, segmentName)
-	if idx <= len(filenames)-1 {
-		return filenames[idx], nil
-	}
-
-	return "", nil
-}
-
-// SegmentLast ...
-func SegmentLast(directory string) (string, error) {
-	files, err := os.ReadDir(directory)
-	if err != nil {
-		return "", fmt.Errorf("failed to scan WAL directory: %w", err)
-	}
-
-	filename := ""
-	for i := len(files) - 1; i >= 0; i-- {
-		file := files[i]
-		if file.IsDir() {
-			continue
-		}
-
-		filename = file.Name()
-		break
-	}
+const segmentPrefix = "wal_"
 
-	return filename, nil
+// IsSegment checks that the file in the WAL directory is a segment,
+// the directory also contains snapshots and temporary files
+func IsSegment(filename string) bool {
+	return strings.HasPrefix(filename, segmentPrefix)
 }
 
 // CreateFile ...
@@ -75,17 +40,30 @@ func WriteFile(file *os.File, data []byte) (int, error) {
 	return writtenBytes, nil
 }
 
-func upperBound(array []string, target string) int {
-	low, high := 0, len(array)-1
+// writeAtomically replaces the file through a temporary file,
+// so a reader sees either the old data or the new one
+func writeAtomically(directory, name string, data []byte) error {
+	filename := filepath.Join(directory, name)
+	temporaryFilename := filename + temporaryExtension
+
+	file, err := os.OpenFile(temporaryFilename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600) // nolint : G304: Potential file inclusion via variable
+	if err != nil {
+		return fmt.Errorf("failed to create %s file: %w", name, err)
+	}
+
+	if _, err = WriteFile(file, data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(temporaryFilename)
+		return fmt.Errorf("failed to write %s file: %w", name, err)
+	}
+
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s file: %w", name, err)
+	}
 
-	for low <= high {
-		mid := (low + high) / 2
-		if array[mid] > target {
-			high = mid - 1
-		} else {
-			low = mid + 1
-		}
+	if err = os.Rename(temporaryFilename, filename); err != nil {
+		return fmt.Errorf("failed to rename %s file: %w", name, err)
 	}
 
-	return low
+	return syncDirectory(directory)
 }
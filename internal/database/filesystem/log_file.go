@@ -0,0 +1,95 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LogFile is a file which is written only at the end, unlike segments
+// its tail can be cut, so it's used for logs with rewritable suffix
+type LogFile struct {
+	directory string
+	name      string
+	file      *os.File
+}
+
+// NewLogFile ...
+func NewLogFile(directory, name string) *LogFile {
+	return &LogFile{
+		directory: directory,
+		name:      name,
+	}
+}
+
+// Read returns nil data if the file doesn't exist
+func (f *LogFile) Read() ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(f.directory, f.name)) // nolint : G304: Potential file inclusion via variable
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s file: %w", f.name, err)
+	}
+
+	return data, nil
+}
+
+// Append ...
+func (f *LogFile) Append(data []byte) error {
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	if _, err := WriteFile(f.file, data); err != nil {
+		return fmt.Errorf("failed to write %s file: %w", f.name, err)
+	}
+
+	return nil
+}
+
+// Truncate cuts the file to the size
+func (f *LogFile) Truncate(size int64) error {
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	if err := f.file.Truncate(size); err != nil {
+		return fmt.Errorf("failed to truncate %s file: %w", f.name, err)
+	}
+
+	if err := f.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s file: %w", f.name, err)
+	}
+
+	return nil
+}
+
+// Close ...
+func (f *LogFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *LogFile) open() error {
+	if f.file != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(f.directory, 0750); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(f.directory, f.name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600) // nolint : G304: Potential file inclusion via variable
+	if err != nil {
+		return fmt.Errorf("failed to open %s file: %w", f.name, err)
+	}
+
+	f.file = file
+	return nil
+}
@@ -0,0 +1,47 @@
+package filesystem
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogFile(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir() + "/raft"
+	logFile := NewLogFile(directory, "log")
+	defer func() { _ = logFile.Close() }()
+
+	data, err := logFile.Read()
+	require.NoError(t, err)
+	assert.Nil(t, data)
+
+	require.NoError(t, logFile.Append([]byte("first")))
+	require.NoError(t, logFile.Append([]byte("second")))
+	require.NoError(t, logFile.Truncate(5))
+	require.NoError(t, logFile.Append([]byte("third")))
+
+	data, err = NewLogFile(directory, "log").Read()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("firstthird"), data)
+}
+
+func TestStateFile(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir() + "/raft"
+	stateFile := NewStateFile(directory, "state")
+
+	data, err := stateFile.Load()
+	require.NoError(t, err)
+	assert.Nil(t, data)
+
+	require.NoError(t, stateFile.Store([]byte("first")))
+	require.NoError(t, stateFile.Store([]byte("second")))
+
+	data, err = NewStateFile(directory, "state").Load()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), data)
+}
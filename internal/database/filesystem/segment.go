@@ -12,17 +12,24 @@ var now = time.Now
 type Segment struct {
 	file      *os.File
 	directory string
+	header    []byte
 
 	segmentSize    int
 	maxSegmentSize int
 }
 
 // NewSegment ...
-func NewSegment(directory string, maxSegmentSize int) *Segment {
-	return &Segment{
+func NewSegment(directory string, maxSegmentSize int, options ...SegmentOption) *Segment {
+	segment := &Segment{
 		directory:      directory,
 		maxSegmentSize: maxSegmentSize,
 	}
+
+	for _, option := range options {
+		option(segment)
+	}
+
+	return segment
 }
 
 func (s *Segment) Write(data []byte) error {
@@ -42,7 +49,7 @@ func (s *Segment) Write(data []byte) error {
 }
 
 func (s *Segment) rotateSegment() error {
-	segmentName := fmt.Sprintf("%s/wal_%d.log", s.directory, now().UnixMilli())
+	segmentName := fmt.Sprintf("%s/%s%d.log", s.directory, segmentPrefix, now().UnixMilli())
 	file, err := CreateFile(segmentName)
 	if err != nil {
 		return err
@@ -50,5 +57,15 @@ func (s *Segment) rotateSegment() error {
 
 	s.file = file
 	s.segmentSize = 0
+
+	if len(s.header) != 0 {
+		writtenBytes, err := WriteFile(s.file, s.header)
+		if err != nil {
+			return err
+		}
+
+		s.segmentSize = writtenBytes
+	}
+
 	return nil
 }
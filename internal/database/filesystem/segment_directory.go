@@ -1,6 +1,7 @@
 package filesystem
 
 import (
+	"errors"
 	"fmt"
 	"os"
 )
@@ -17,16 +18,20 @@ func NewSegmentsDirectory(directory string) *SegmentsDirectory {
 	}
 }
 
-// ForEach ...
-func (d *SegmentsDirectory) ForEach(action func([]byte) error) error {
+// ForEach calls action for segments in order of their names
+func (d *SegmentsDirectory) ForEach(action func(string, []byte) error) error {
 	files, err := os.ReadDir(d.directory)
-	if err != nil {
-		// TODO: need to create a directory if it is missing
+	if errors.Is(err, os.ErrNotExist) {
+		if err = os.MkdirAll(d.directory, 0750); err != nil {
+			return fmt.Errorf("failed to create directory with segments: %w", err)
+		}
+		return nil
+	} else if err != nil {
 		return fmt.Errorf("failed to scan directory with segments: %w", err)
 	}
 
 	for _, file := range files {
-		if file.IsDir() {
+		if file.IsDir() || !IsSegment(file.Name()) {
 			continue
 		}
 
@@ -37,10 +42,62 @@ func (d *SegmentsDirectory) ForEach(action func([]byte) error) error {
 			return errReadFile
 		}
 
-		if err = action(data); err != nil {
+		if err = action(file.Name(), data); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// Names returns names of segments in order, missing directory has no segments
+func (d *SegmentsDirectory) Names() ([]string, error) {
+	files, err := os.ReadDir(d.directory)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to scan directory with segments: %w", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if file.IsDir() || !IsSegment(file.Name()) {
+			continue
+		}
+
+		names = append(names, file.Name())
+	}
+
+	return names, nil
+}
+
+// Read ...
+func (d *SegmentsDirectory) Read(segmentName string) ([]byte, error) {
+	filename := fmt.Sprintf("%s/%s", d.directory, segmentName)
+	data, err := os.ReadFile(filename) // nolint : TODO: G304: Potential file inclusion via variable
+	if err != nil {
+		return nil, fmt.Errorf("failed to read segment: %w", err)
+	}
+
+	return data, nil
+}
+
+// Remove ...
+func (d *SegmentsDirectory) Remove(segmentName string) error {
+	filename := fmt.Sprintf("%s/%s", d.directory, segmentName)
+	if err := os.Remove(filename); err != nil {
+		return fmt.Errorf("failed to remove segment: %w", err)
+	}
+
+	return nil
+}
+
+// Truncate cuts the segment to the size, it's used to drop a torn tail of the segment
+func (d *SegmentsDirectory) Truncate(segmentName string, size int64) error {
+	filename := fmt.Sprintf("%s/%s", d.directory, segmentName)
+	if err := os.Truncate(filename, size); err != nil {
+		return fmt.Errorf("failed to truncate segment: %w", err)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package filesystem
 
 import (
 	"errors"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,7 +16,8 @@ func TestSegmentsDirectoryForEach(t *testing.T) {
 	expectedSegmentsCount := 3
 
 	directory := NewSegmentsDirectory("test_data")
-	err := directory.ForEach(func(data []byte) error {
+	err := directory.ForEach(func(name string, data []byte) error {
+		assert.True(t, IsSegment(name))
 		assert.True(t, len(data) != 0)
 		segmentsCount++
 		return nil
@@ -29,9 +31,79 @@ func TestSegmentsDirectoryForEachWithBreak(t *testing.T) {
 	t.Parallel()
 
 	directory := NewSegmentsDirectory("test_data")
-	err := directory.ForEach(func([]byte) error {
+	err := directory.ForEach(func(string, []byte) error {
 		return errors.New("error")
 	})
 
 	assert.Error(t, err, "error")
 }
+
+func TestSegmentsDirectoryRemove(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	require.NoError(t, os.WriteFile(directory+"/wal_1000.log", []byte("data"), 0600))
+	require.NoError(t, os.WriteFile(directory+"/snapshot_1.dat", []byte("data"), 0600))
+
+	segments := NewSegmentsDirectory(directory)
+	require.NoError(t, segments.Remove("wal_1000.log"))
+	assert.Error(t, segments.Remove("wal_1000.log"))
+
+	err := segments.ForEach(func(string, []byte) error {
+		return errors.New("unexpected segment")
+	})
+	assert.NoError(t, err)
+}
+
+func TestSegmentsDirectoryCreateMissing(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir() + "/wal"
+	segments := NewSegmentsDirectory(directory)
+	require.NoError(t, segments.ForEach(func(string, []byte) error {
+		return errors.New("unexpected segment")
+	}))
+
+	stat, err := os.Stat(directory)
+	require.NoError(t, err)
+	assert.True(t, stat.IsDir())
+}
+
+func TestSegmentsDirectoryTruncate(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	require.NoError(t, os.WriteFile(directory+"/wal_1000.log", []byte("data"), 0600))
+
+	segments := NewSegmentsDirectory(directory)
+	require.NoError(t, segments.Truncate("wal_1000.log", 2))
+
+	data, err := os.ReadFile(directory + "/wal_1000.log")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("da"), data)
+}
+
+func TestSegmentsDirectoryNamesAndRead(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	require.NoError(t, os.WriteFile(directory+"/wal_2000.log", []byte("second"), 0600))
+	require.NoError(t, os.WriteFile(directory+"/wal_1000.log", []byte("first"), 0600))
+	require.NoError(t, os.WriteFile(directory+"/snapshot_1.dat", []byte("data"), 0600))
+
+	segments := NewSegmentsDirectory(directory)
+	names, err := segments.Names()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wal_1000.log", "wal_2000.log"}, names)
+
+	data, err := segments.Read("wal_2000.log")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), data)
+
+	_, err = segments.Read("wal_3000.log")
+	assert.Error(t, err)
+
+	names, err = NewSegmentsDirectory(directory + "/missing").Names()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
@@ -0,0 +1,11 @@
+package filesystem
+
+// SegmentOption ...
+type SegmentOption func(*Segment)
+
+// WithSegmentHeader sets the header which is written at the beginning of each segment
+func WithSegmentHeader(header []byte) SegmentOption {
+	return func(segment *Segment) {
+		segment.header = header
+	}
+}
@@ -48,3 +48,19 @@ func TestSegmentWrite(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, int64(5), stat.Size())
 }
+
+// TestSegmentWriteWithHeader is not parallel, because TestSegmentWrite replaces now
+func TestSegmentWriteWithHeader(t *testing.T) {
+	directory := t.TempDir()
+	segment := NewSegment(directory, 10, WithSegmentHeader([]byte("header")))
+
+	require.NoError(t, segment.Write([]byte("aaaaa")))
+
+	files, err := os.ReadDir(directory)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	data, err := os.ReadFile(directory + "/" + files[0].Name())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("headeraaaaa"), data)
+}
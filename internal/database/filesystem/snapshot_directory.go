@@ -0,0 +1,208 @@
+package filesystem
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	snapshotPrefix     = "snapshot_"
+	snapshotExtension  = ".dat"
+	temporaryExtension = ".tmp"
+)
+
+var (
+	snapshotMagic   = []byte("SNP1")
+	castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// ErrorInvalidSnapshot ...
+var ErrorInvalidSnapshot = errors.New("invalid snapshot")
+
+// SnapshotsDirectory keeps snapshots of the engine, each snapshot
+// is named by LSN of the last WAL record which it contains
+type SnapshotsDirectory struct {
+	directory string
+}
+
+// NewSnapshotsDirectory ...
+func NewSnapshotsDirectory(directory string) *SnapshotsDirectory {
+	return &SnapshotsDirectory{
+		directory: directory,
+	}
+}
+
+// Save writes the snapshot to a temporary file and renames it, so partially
+// written snapshot is never visible, older snapshots are removed after that
+func (d *SnapshotsDirectory) Save(lsn int64, data []byte) error {
+	if err := os.MkdirAll(d.directory, 0750); err != nil {
+		return fmt.Errorf("failed to create snapshots directory: %w", err)
+	}
+
+	filename := d.filename(lsn)
+	temporaryFilename := filename + temporaryExtension
+	if err := writeSnapshot(temporaryFilename, data); err != nil {
+		_ = os.Remove(temporaryFilename)
+		return err
+	}
+
+	if err := os.Rename(temporaryFilename, filename); err != nil {
+		return fmt.Errorf("failed to rename snapshot: %w", err)
+	}
+
+	if err := syncDirectory(d.directory); err != nil {
+		return err
+	}
+
+	names, err := d.snapshots()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if name != filepath.Base(filename) {
+			_ = os.Remove(filepath.Join(d.directory, name))
+		}
+	}
+
+	return nil
+}
+
+// Last returns the newest valid snapshot and its LSN, damaged snapshots
+// are skipped, nil data means that there are no snapshots
+func (d *SnapshotsDirectory) Last() (int64, []byte, error) {
+	names, err := d.snapshots()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var errs []error
+	for i := len(names) - 1; i >= 0; i-- {
+		lsn, data, err := d.read(names[i])
+		if err == nil {
+			return lsn, data, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", names[i], err))
+	}
+
+	return 0, nil, errors.Join(errs...)
+}
+
+// LastLSN returns LSN of the newest snapshot without reading
+// of it, zero means that there are no snapshots
+func (d *SnapshotsDirectory) LastLSN() (int64, error) {
+	names, err := d.snapshots()
+	if err != nil || len(names) == 0 {
+		return 0, err
+	}
+
+	return parseSnapshotLSN(names[len(names)-1])
+}
+
+func (d *SnapshotsDirectory) read(name string) (int64, []byte, error) {
+	lsn, err := parseSnapshotLSN(name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	content, err := os.ReadFile(filepath.Join(d.directory, name)) // nolint : G304: Potential file inclusion via variable
+	if err != nil {
+		return 0, nil, err
+	}
+
+	headerSize := len(snapshotMagic) + crc32.Size
+	if len(content) < headerSize || !bytes.Equal(content[:len(snapshotMagic)], snapshotMagic) {
+		return 0, nil, ErrorInvalidSnapshot
+	}
+
+	checksum := binary.BigEndian.Uint32(content[len(snapshotMagic):headerSize])
+	data := content[headerSize:]
+	if crc32.Checksum(data, castagnoliTable) != checksum {
+		return 0, nil, ErrorInvalidSnapshot
+	}
+
+	return lsn, data, nil
+}
+
+// snapshots returns names of snapshots sorted by LSN
+func (d *SnapshotsDirectory) snapshots() ([]string, error) {
+	files, err := os.ReadDir(d.directory)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to scan snapshots directory: %w", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		name := file.Name()
+		if !file.IsDir() && strings.HasPrefix(name, snapshotPrefix) && strings.HasSuffix(name, snapshotExtension) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func parseSnapshotLSN(name string) (int64, error) {
+	lsn, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotExtension), 10, 64)
+	if err != nil {
+		return 0, ErrorInvalidSnapshot
+	}
+
+	return lsn, nil
+}
+
+func (d *SnapshotsDirectory) filename(lsn int64) string {
+	// LSN is padded, so names are sorted in order of LSN
+	return filepath.Join(d.directory, fmt.Sprintf("%s%020d%s", snapshotPrefix, lsn, snapshotExtension))
+}
+
+func writeSnapshot(filename string, data []byte) error {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600) // nolint : G304: Potential file inclusion via variable
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	header := make([]byte, len(snapshotMagic)+crc32.Size)
+	copy(header, snapshotMagic)
+	binary.BigEndian.PutUint32(header[len(snapshotMagic):], crc32.Checksum(data, castagnoliTable))
+
+	if _, err = file.Write(append(header, data...)); err == nil {
+		err = file.Sync()
+	}
+
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return nil
+}
+
+func syncDirectory(directory string) error {
+	dir, err := os.Open(directory) // nolint : G304: Potential file inclusion via variable
+	if err != nil {
+		return fmt.Errorf("failed to open directory: %w", err)
+	}
+	defer func() { _ = dir.Close() }()
+
+	if err = dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotsDirectorySave(t *testing.T) {
+	t.Parallel()
+
+	directory := NewSnapshotsDirectory(filepath.Join(t.TempDir(), "wal"))
+
+	lsn, data, err := directory.Last()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), lsn)
+	assert.Nil(t, data)
+
+	lsn, err = directory.LastLSN()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), lsn)
+
+	require.NoError(t, directory.Save(10, []byte("first")))
+	require.NoError(t, directory.Save(20, []byte("second")))
+
+	lsn, err = directory.LastLSN()
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), lsn)
+
+	lsn, data, err = directory.Last()
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), lsn)
+	assert.Equal(t, []byte("second"), data)
+
+	// older snapshots are removed
+	files, err := os.ReadDir(directory.directory)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.False(t, IsSegment(files[0].Name()))
+}
+
+func TestSnapshotsDirectorySkipDamaged(t *testing.T) {
+	t.Parallel()
+
+	directory := NewSnapshotsDirectory(t.TempDir())
+	require.NoError(t, directory.Save(10, []byte("first")))
+
+	// damaged snapshot can appear only on failure of storage
+	filename := directory.filename(20)
+	require.NoError(t, writeSnapshot(filename, []byte("second")))
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	content[len(content)-1] ^= 0xFF
+	require.NoError(t, os.WriteFile(filename, content, 0600))
+
+	lsn, data, err := directory.Last()
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), lsn)
+	assert.Equal(t, []byte("first"), data)
+
+	require.NoError(t, os.Remove(directory.filename(10)))
+	_, data, err = directory.Last()
+	assert.ErrorIs(t, err, ErrorInvalidSnapshot)
+	assert.Nil(t, data)
+}
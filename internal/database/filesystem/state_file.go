@@ -0,0 +1,43 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// StateFile keeps small state which is replaced as a whole
+type StateFile struct {
+	directory string
+	name      string
+}
+
+// NewStateFile ...
+func NewStateFile(directory, name string) *StateFile {
+	return &StateFile{
+		directory: directory,
+		name:      name,
+	}
+}
+
+// Load returns nil data if the state has never been stored
+func (f *StateFile) Load() ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(f.directory, f.name)) // nolint : G304: Potential file inclusion via variable
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s file: %w", f.name, err)
+	}
+
+	return data, nil
+}
+
+// Store ...
+func (f *StateFile) Store(data []byte) error {
+	if err := os.MkdirAll(f.directory, 0750); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	return writeAtomically(f.directory, f.name, data)
+}
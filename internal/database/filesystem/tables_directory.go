@@ -0,0 +1,72 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const tableExtension = ".sst"
+
+// TablesDirectory keeps sorted tables of the LSM engine, tables are
+// immutable, so they are written once and then read by offsets
+type TablesDirectory struct {
+	directory string
+}
+
+// NewTablesDirectory ...
+func NewTablesDirectory(directory string) *TablesDirectory {
+	return &TablesDirectory{
+		directory: directory,
+	}
+}
+
+// Write replaces the table atomically, so a partially written table is never visible
+func (d *TablesDirectory) Write(name string, data []byte) error {
+	if err := os.MkdirAll(d.directory, 0750); err != nil {
+		return fmt.Errorf("failed to create tables directory: %w", err)
+	}
+
+	return writeAtomically(d.directory, name+tableExtension, data)
+}
+
+// Open opens the table for reading
+func (d *TablesDirectory) Open(name string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(d.directory, name+tableExtension)) // nolint : G304: Potential file inclusion via variable
+	if err != nil {
+		return nil, fmt.Errorf("failed to open table: %w", err)
+	}
+
+	return file, nil
+}
+
+// Remove removes the table, missing table is not an error
+func (d *TablesDirectory) Remove(name string) error {
+	err := os.Remove(filepath.Join(d.directory, name+tableExtension))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove table: %w", err)
+	}
+
+	return nil
+}
+
+// Names returns names of all tables in the directory, missing directory has no tables
+func (d *TablesDirectory) Names() ([]string, error) {
+	files, err := os.ReadDir(d.directory)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to scan tables directory: %w", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), tableExtension) {
+			names = append(names, strings.TrimSuffix(file.Name(), tableExtension))
+		}
+	}
+
+	return names, nil
+}
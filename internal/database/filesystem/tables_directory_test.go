@@ -0,0 +1,40 @@
+package filesystem
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTablesDirectory(t *testing.T) {
+	t.Parallel()
+
+	directory := NewTablesDirectory(filepath.Join(t.TempDir(), "lsm"))
+
+	names, err := directory.Names()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	require.NoError(t, directory.Write("table_1", []byte("first")))
+	require.NoError(t, directory.Write("table_2", []byte("second")))
+
+	file, err := directory.Open("table_2")
+	require.NoError(t, err)
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	assert.Equal(t, []byte("second"), data)
+
+	require.NoError(t, directory.Remove("table_1"))
+	require.NoError(t, directory.Remove("table_1"))
+
+	names, err = directory.Names()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"table_2"}, names)
+
+	_, err = directory.Open("table_1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,50 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const termFilename = "term"
+
+// TermFile keeps the replication term in the WAL directory
+type TermFile struct {
+	directory string
+}
+
+// NewTermFile ...
+func NewTermFile(directory string) *TermFile {
+	return &TermFile{
+		directory: directory,
+	}
+}
+
+// Load returns zero term if it has never been stored
+func (f *TermFile) Load() (int64, error) {
+	data, err := os.ReadFile(filepath.Join(f.directory, termFilename)) // nolint : G304: Potential file inclusion via variable
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to read term: %w", err)
+	}
+
+	term, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse term: %w", err)
+	}
+
+	return term, nil
+}
+
+// Store replaces the term atomically through a temporary file
+func (f *TermFile) Store(term int64) error {
+	if err := os.MkdirAll(f.directory, 0750); err != nil {
+		return fmt.Errorf("failed to create term directory: %w", err)
+	}
+
+	return writeAtomically(f.directory, termFilename, []byte(strconv.FormatInt(term, 10)))
+}
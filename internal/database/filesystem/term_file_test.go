@@ -0,0 +1,36 @@
+package filesystem
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTermFile(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir() + "/wal"
+	termFile := NewTermFile(directory)
+
+	term, err := termFile.Load()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), term)
+
+	require.NoError(t, termFile.Store(3))
+	require.NoError(t, termFile.Store(4))
+
+	term, err = NewTermFile(directory).Load()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), term)
+
+	// the term file is not a segment of the WAL
+	names, err := NewSegmentsDirectory(directory).Names()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	require.NoError(t, os.WriteFile(directory+"/term", []byte("incorrect"), 0600))
+	_, err = termFile.Load()
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"database-simon/internal/database/storage/replication"
+)
+
+// unknownSeconds is reported for moments which have not happened yet
+const unknownSeconds = -1
+
+// formatReplicationStatus formats the status as lines of "field:value",
+// moments are reported as the number of seconds before now
+func formatReplicationStatus(status replication.Status, now time.Time) string {
+	var lines []string
+	field := func(name string, value any) {
+		lines = append(lines, fmt.Sprintf("%s:%v", name, value))
+	}
+
+	if status.Slave == nil {
+		field("role", "master")
+		field("term", status.Term)
+		field("fenced", !status.IsMaster)
+	} else {
+		slave := status.Slave
+		field("role", "slave")
+		field("term", status.Term)
+		field("master_address", slave.MasterAddress)
+		field("last_lsn", slave.LastLSN)
+		field("lag_records", slave.LagRecords)
+		field("lag_seconds", secondsBefore(now, slave.SyncedAt))
+		field("last_sync_seconds", secondsBefore(now, slave.LastSync))
+		field("last_error", slave.LastError)
+		field("last_error_seconds", secondsBefore(now, slave.LastErrorTime))
+	}
+
+	field("connected_replicas", len(status.Replicas))
+	for idx, replica := range status.Replicas {
+		field(fmt.Sprintf("replica_%d", idx), fmt.Sprintf(
+			"id=%s,last_lsn=%d,last_seen_seconds=%d",
+			replica.ID, replica.LastLSN, secondsBefore(now, replica.LastSeen),
+		))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func secondsBefore(now time.Time, moment time.Time) int64 {
+	if moment.IsZero() {
+		return unknownSeconds
+	}
+
+	return int64(max(now.Sub(moment), 0) / time.Second)
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"database-simon/internal/database/storage/replication"
+)
+
+func TestFormatReplicationStatus(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	tests := map[string]struct {
+		status replication.Status
+
+		expected string
+	}{
+		"format master status": {
+			status: replication.Status{
+				IsMaster: true,
+				Term:     3,
+				Replicas: []replication.ReplicaStatus{
+					{ID: "a1", LastLSN: 10, LastSeen: now.Add(-2 * time.Second)},
+				},
+			},
+			expected: "role:master\nterm:3\nfenced:false\nconnected_replicas:1\n" +
+				"replica_0:id=a1,last_lsn=10,last_seen_seconds=2",
+		},
+		"format slave status": {
+			status: replication.Status{
+				Term: 1,
+				Slave: &replication.SlaveStatus{
+					MasterAddress: "127.0.0.1:8082",
+					LastLSN:       7,
+					LagRecords:    5,
+					LastSync:      now,
+					SyncedAt:      now.Add(-10 * time.Second),
+					LastError:     "master is unavailable",
+					LastErrorTime: now.Add(-time.Minute),
+				},
+			},
+			expected: "role:slave\nterm:1\nmaster_address:127.0.0.1:8082\nlast_lsn:7\nlag_records:5\n" +
+				"lag_seconds:10\nlast_sync_seconds:0\nlast_error:master is unavailable\nlast_error_seconds:60\nconnected_replicas:0",
+		},
+		"format status of slave which has never synchronized": {
+			status: replication.Status{
+				Slave: &replication.SlaveStatus{MasterAddress: "127.0.0.1:8082"},
+			},
+			expected: "role:slave\nterm:0\nmaster_address:127.0.0.1:8082\nlast_lsn:0\nlag_records:0\n" +
+				"lag_seconds:-1\nlast_sync_seconds:-1\nlast_error:\nlast_error_seconds:-1\nconnected_replicas:0",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, formatReplicationStatus(test.status, now))
+		})
+	}
+}
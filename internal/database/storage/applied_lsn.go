@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrorLSNTimeout ...
+var ErrorLSNTimeout = errors.New("record is not applied in time")
+
+// appliedLSN is LSN of the last applied record, reads of a replica
+// wait for it to see writes which have been committed on the master
+type appliedLSN struct {
+	mutex   sync.Mutex
+	lsn     int64
+	changed chan struct{}
+}
+
+func newAppliedLSN(lsn int64) *appliedLSN {
+	return &appliedLSN{
+		lsn:     lsn,
+		changed: make(chan struct{}),
+	}
+}
+
+func (a *appliedLSN) get() int64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.lsn
+}
+
+func (a *appliedLSN) advance(lsn int64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if lsn <= a.lsn {
+		return
+	}
+
+	a.lsn = lsn
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+func (a *appliedLSN) wait(ctx context.Context, lsn int64) error {
+	for {
+		a.mutex.Lock()
+		applied, changed := a.lsn, a.changed
+		a.mutex.Unlock()
+
+		if applied >= lsn {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ErrorLSNTimeout
+			}
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
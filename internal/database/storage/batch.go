@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/compute"
+	"database-simon/internal/database/storage/wal"
+)
+
+// MSet sets values of keys from pairs of keys and values, all values
+// are written to the WAL as a single batch and become visible at once
+func (s *Storage) MSet(ctx context.Context, pairs []string) error {
+	batch := newTransaction(0)
+	for idx := 0; idx+1 < len(pairs); idx += 2 {
+		key, value := pairs[idx], pairs[idx+1]
+		batch.set(key, value, wal.NewOperation(compute.SetCommand, []string{key, value}))
+	}
+
+	return s.writeBatch(ctx, batch)
+}
+
+// MDel deletes keys, all keys are deleted by a single batch of the WAL
+func (s *Storage) MDel(ctx context.Context, keys []string) error {
+	batch := newTransaction(0)
+	for _, key := range keys {
+		batch.del(key, wal.NewOperation(compute.DelCommand, []string{key}))
+	}
+
+	return s.writeBatch(ctx, batch)
+}
+
+// MGet returns values of keys from the same snapshot, found reports whether
+// each key exists. Keys which keep data structures aren't found
+func (s *Storage) MGet(ctx context.Context, keys []string) ([]string, []bool, error) {
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	values := make([]string, len(keys))
+	found := make([]bool, len(keys))
+
+	tx := s.transaction(ctx)
+	if tx != nil {
+		ctx = common.ContextWithTxID(ctx, tx.snapshot)
+	} else {
+		snapshot := s.snapshots.acquire()
+		defer s.snapshots.release(snapshot)
+		ctx = common.ContextWithTxID(ctx, snapshot)
+	}
+
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		for idx, key := range keys {
+			if tx != nil {
+				if write, pending := tx.get(key); pending {
+					values[idx], found[idx] = write.value, !write.deleted
+					continue
+				}
+			}
+
+			values[idx], found[idx], _ = s.lookup(ctx, key)
+		}
+	})
+
+	return values, found, nil
+}
+
+// writeBatch commits the batch or adds it to the transaction of the session
+func (s *Storage) writeBatch(ctx context.Context, batch *transaction) error {
+	if s.replica != nil && !s.replica.IsMaster() {
+		return ErrorMutableTX
+	} else if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if tx := s.transaction(ctx); tx != nil {
+		tx.merge(batch)
+		return nil
+	}
+
+	return s.commit(ctx, batch)
+}
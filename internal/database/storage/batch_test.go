@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/compute"
+	"database-simon/internal/database/storage/engine/memory"
+	"database-simon/internal/database/storage/wal"
+)
+
+func TestStorage_Batch(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	committed := func(context.Context, []wal.Operation) concurrency.FutureError {
+		promise := concurrency.NewPromise[error]()
+		promise.Set(nil)
+		return promise.GetFuture()
+	}
+
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, nil)
+	// each batch is written as a single record
+	gomock.InOrder(
+		writeAheadLog.EXPECT().Commit(gomock.Any(), []wal.Operation{
+			wal.NewOperation(compute.SetCommand, []string{"a", "1"}),
+			wal.NewOperation(compute.SetCommand, []string{"b", "2"}),
+		}).DoAndReturn(committed),
+		writeAheadLog.EXPECT().Commit(gomock.Any(), []wal.Operation{
+			wal.NewOperation(compute.DelCommand, []string{"a"}),
+			wal.NewOperation(compute.DelCommand, []string{"c"}),
+		}).DoAndReturn(committed),
+	)
+
+	eng, err := memory.NewMemory(zap.NewNop(), memory.WithPartitions(4))
+	require.NoError(t, err)
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, stor.MSet(ctx, []string{"a", "1", "b", "2"}))
+
+	_, err = eng.SAdd(common.ContextWithTxID(ctx, 1), "set", []string{"member"})
+	require.NoError(t, err)
+	values, found, err := stor.MGet(ctx, []string{"a", "set", "b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "", "2", ""}, values)
+	assert.Equal(t, []bool{true, false, true, false}, found)
+
+	require.NoError(t, stor.MDel(ctx, []string{"a", "c"}))
+
+	// batches of transactions are written by COMMIT
+	sessionCtx := common.ContextWithSessionID(ctx, 1)
+	require.NoError(t, stor.Begin(sessionCtx))
+	require.NoError(t, stor.MSet(sessionCtx, []string{"c", "3"}))
+	require.NoError(t, stor.MDel(sessionCtx, []string{"b"}))
+
+	values, found, err = stor.MGet(sessionCtx, []string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "", "3"}, values)
+	assert.Equal(t, []bool{false, false, true}, found)
+
+	values, found, err = stor.MGet(ctx, []string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "2", ""}, values)
+	assert.Equal(t, []bool{false, true, false}, found)
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+)
+
+var (
+	// ErrorNoWAL ...
+	ErrorNoWAL = errors.New("WAL is not enabled")
+	// ErrorNoTruncation ...
+	ErrorNoTruncation = errors.New("WAL doesn't remove persisted records")
+)
+
+// Checkpoint saves a consistent snapshot of the engine with LSN of the last
+// record which it contains, WAL segments covered by the snapshot are removed.
+// Engines which persist records themselves aren't dumped, only segments with
+// persisted records are removed
+func (s *Storage) Checkpoint(ctx context.Context) error {
+	if s.wal == nil {
+		return ErrorNoWAL
+	} else if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if engine, ok := s.engine.(persistentEngine); ok {
+		return s.truncate(engine.PersistedLSN())
+	}
+
+	s.checkpointMutex.Lock()
+	defer s.checkpointMutex.Unlock()
+
+	lsn, data, err := s.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = s.wal.Checkpoint(lsn, data); err != nil {
+		return err
+	}
+
+	s.logger.Info("checkpoint is saved", zap.Int64("lsn", lsn), zap.Int("size", len(data)))
+	return nil
+}
+
+// Snapshot dumps the engine with LSN up to which all writes are contained in it
+func (s *Storage) Snapshot(ctx context.Context) (int64, []byte, error) {
+	// all writes up to the snapshot are applied, later writes can be in the
+	// snapshot of single-version engine too, but replaying of them is idempotent
+	snapshot := s.snapshots.acquire()
+	defer s.snapshots.release(snapshot)
+	ctx = common.ContextWithTxID(ctx, snapshot)
+
+	var data []byte
+	var err error
+	if s.engine.Versioned() {
+		data, err = s.engine.Snapshot(ctx)
+	} else {
+		concurrency.WithLock(&s.mutex, func() {
+			data, err = s.engine.Snapshot(ctx)
+		})
+	}
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return snapshot, data, nil
+}
+
+func (s *Storage) truncatable() bool {
+	_, ok := s.wal.(truncatingWAL)
+	return ok
+}
+
+// truncate removes WAL segments with records which are persisted by the engine
+func (s *Storage) truncate(lsn int64) error {
+	w, ok := s.wal.(truncatingWAL)
+	if !ok {
+		return ErrorNoTruncation
+	}
+
+	s.checkpointMutex.Lock()
+	defer s.checkpointMutex.Unlock()
+
+	if err := w.Truncate(lsn); err != nil {
+		s.logger.Error("failed to truncate WAL", zap.Int64("lsn", lsn), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) runCheckpoints(ctx context.Context) {
+	ticker := time.NewTicker(s.checkpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Checkpoint(ctx); err != nil {
+				s.logger.Error("failed to save checkpoint", zap.Error(err))
+			}
+		}
+	}
+}
+
+// recover loads the newest checkpoint and replays WAL records written after it,
+// the checkpoint isn't needed if the engine has persisted newer records itself
+func (s *Storage) recover() (int64, error) {
+	checkpointLSN, data, err := s.wal.LastCheckpoint()
+	if err != nil {
+		s.logger.Error("failed to load checkpoint", zap.Error(err))
+	}
+
+	var persistedLSN int64
+	if engine, ok := s.engine.(persistentEngine); ok {
+		persistedLSN = engine.PersistedLSN()
+	}
+
+	if data != nil && checkpointLSN > persistedLSN {
+		ctx := common.ContextWithTxID(context.Background(), checkpointLSN)
+		if err = s.engine.Restore(ctx, data); err != nil {
+			s.logger.Error("failed to restore checkpoint", zap.Int64("lsn", checkpointLSN), zap.Error(err))
+		}
+	}
+
+	logs, err := s.wal.Recover()
+	if err != nil {
+		return 0, fmt.Errorf("failed to recover data from WAL: %w", err)
+	}
+
+	// records up to the checkpoint are contained in the snapshot
+	recoveredLSN := max(checkpointLSN, persistedLSN)
+	idx := sort.Search(len(logs), func(i int) bool {
+		return logs[i].LSN > recoveredLSN
+	})
+
+	return max(recoveredLSN, s.applyData(logs[idx:])), nil
+}
@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/compute"
+	"database-simon/internal/database/storage/wal"
+)
+
+func TestStorage_RecoverFromCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(2), []byte("snapshot"), nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return([]wal.Log{
+			{LSN: 1, CommandID: compute.SetCommand, Arguments: []string{"key_1", "value_1"}},
+			{LSN: 2, CommandID: compute.SetCommand, Arguments: []string{"key_2", "value_2"}},
+			{LSN: 3, CommandID: compute.SetCommand, Arguments: []string{"key_3", "value_3"}},
+			{LSN: 4, CommandID: compute.DelCommand, Arguments: []string{"key_1"}},
+		}, nil)
+
+	eng := NewMockengine(controller)
+	gomock.InOrder(
+		eng.EXPECT().
+			Restore(gomock.Any(), []byte("snapshot")).
+			DoAndReturn(func(ctx context.Context, _ []byte) error {
+				assert.Equal(t, int64(2), common.GetTxIDFromContext(ctx))
+				return nil
+			}),
+		eng.EXPECT().Set(gomock.Any(), "key_3", "value_3"),
+		eng.EXPECT().Del(gomock.Any(), "key_1"),
+	)
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), stor.generator.Generate())
+}
+
+func TestStorage_RecoverOnlyFromCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(10), []byte("snapshot"), nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, nil)
+
+	eng := NewMockengine(controller)
+	eng.EXPECT().
+		Restore(gomock.Any(), []byte("snapshot")).
+		Return(nil)
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), stor.generator.Generate())
+}
+
+// persistentMockEngine is an engine which keeps data on disk
+type persistentMockEngine struct {
+	*Mockengine
+	*MockpersistentEngine
+}
+
+// truncatingMockWAL is a WAL which removes persisted records
+type truncatingMockWAL struct {
+	*MockwalI
+	*MocktruncatingWAL
+}
+
+func TestStorage_RecoverPersistedEngine(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	writeAheadLog := truncatingMockWAL{NewMockwalI(controller), NewMocktruncatingWAL(controller)}
+	writeAheadLog.MockwalI.EXPECT().
+		LastCheckpoint().
+		Return(int64(2), []byte("snapshot"), nil)
+	writeAheadLog.MockwalI.EXPECT().
+		Recover().
+		Return([]wal.Log{
+			{LSN: 3, CommandID: compute.SetCommand, Arguments: []string{"key_3", "value_3"}},
+			{LSN: 4, CommandID: compute.DelCommand, Arguments: []string{"key_1"}},
+		}, nil)
+
+	// the engine has persisted records after the checkpoint, so it isn't restored
+	var persisted func(int64)
+	eng := persistentMockEngine{NewMockengine(controller), NewMockpersistentEngine(controller)}
+	eng.MockpersistentEngine.EXPECT().PersistedLSN().Return(int64(3))
+	eng.MockpersistentEngine.EXPECT().OnPersisted(gomock.Any()).Do(func(callback func(int64)) {
+		persisted = callback
+	})
+	eng.Mockengine.EXPECT().Del(gomock.Any(), "key_1")
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), stor.generator.Generate())
+
+	// segments with records persisted by the engine are removed after flushes
+	writeAheadLog.MocktruncatingWAL.EXPECT().Truncate(int64(4)).Return(nil)
+	persisted(4)
+}
+
+func TestStorage_CheckpointPersistedEngine(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	writeAheadLog := truncatingMockWAL{NewMockwalI(controller), NewMocktruncatingWAL(controller)}
+	writeAheadLog.MockwalI.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.MockwalI.EXPECT().
+		Recover().
+		Return(nil, nil)
+	writeAheadLog.MocktruncatingWAL.EXPECT().
+		Truncate(int64(7)).
+		Return(nil)
+
+	// the engine isn't dumped to the snapshot
+	eng := persistentMockEngine{NewMockengine(controller), NewMockpersistentEngine(controller)}
+	eng.MockpersistentEngine.EXPECT().OnPersisted(gomock.Any())
+	gomock.InOrder(
+		eng.MockpersistentEngine.EXPECT().PersistedLSN().Return(int64(0)),
+		eng.MockpersistentEngine.EXPECT().PersistedLSN().Return(int64(7)),
+	)
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+	assert.NoError(t, stor.Checkpoint(context.Background()))
+}
+
+func TestStorage_CheckpointPersistedEngineWithoutTruncation(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	// the log of the consensus cluster keeps all records
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, nil)
+
+	// flushes aren't reported, so records aren't removed by them
+	eng := persistentMockEngine{NewMockengine(controller), NewMockpersistentEngine(controller)}
+	eng.MockpersistentEngine.EXPECT().PersistedLSN().Return(int64(0)).Times(2)
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+	assert.ErrorIs(t, stor.Checkpoint(context.Background()), ErrorNoTruncation)
+}
+
+func TestStorage_Checkpoint(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		versioned bool
+	}{
+		"checkpoint of single-version engine": {},
+		"checkpoint of versioned engine": {
+			versioned: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			controller := gomock.NewController(t)
+
+			writeAheadLog := NewMockwalI(controller)
+			writeAheadLog.EXPECT().
+				LastCheckpoint().
+				Return(int64(0), nil, nil)
+			writeAheadLog.EXPECT().
+				Recover().
+				Return(nil, nil)
+			writeAheadLog.EXPECT().
+				Set(gomock.Any(), "key", "value").
+				DoAndReturn(func(context.Context, string, string) concurrency.FutureError {
+					promise := concurrency.NewPromise[error]()
+					promise.Set(nil)
+					return promise.GetFuture()
+				})
+			writeAheadLog.EXPECT().
+				Checkpoint(int64(1), []byte("snapshot")).
+				Return(nil)
+			writeAheadLog.EXPECT().
+				Checkpoint(int64(1), []byte("snapshot")).
+				Return(errors.New("checkpoint error"))
+
+			eng := NewMockengine(controller)
+			eng.EXPECT().Set(gomock.Any(), "key", "value")
+			eng.EXPECT().Versioned().Return(test.versioned).Times(2)
+			eng.EXPECT().
+				Snapshot(gomock.Any()).
+				DoAndReturn(func(ctx context.Context) ([]byte, error) {
+					assert.Equal(t, int64(1), common.GetTxIDFromContext(ctx))
+					return []byte("snapshot"), nil
+				}).
+				Times(2)
+
+			stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+			require.NoError(t, err)
+
+			require.NoError(t, stor.Set(context.Background(), "key", "value"))
+			assert.NoError(t, stor.Checkpoint(context.Background()))
+			assert.Error(t, stor.Checkpoint(context.Background()))
+		})
+	}
+}
+
+func TestStorage_CheckpointWithoutWAL(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	stor, err := NewStorage(NewMockengine(controller), zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, ErrorNoWAL, stor.Checkpoint(context.Background()))
+}
+
+func TestStorage_RecoverWithCorruptedWAL(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	corruptionErr := &wal.CorruptionError{Segment: "wal_1000.log", Offset: 100, Reason: "checksum mismatch"}
+
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, corruptionErr)
+
+	stor, err := NewStorage(NewMockengine(controller), zap.NewNop(), WithWAL(writeAheadLog))
+	assert.Nil(t, stor)
+	assert.ErrorIs(t, err, corruptionErr)
+	assert.ErrorContains(t, err, "segment wal_1000.log is corrupted at offset 100")
+}
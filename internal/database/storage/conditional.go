@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/compute"
+	"database-simon/internal/database/storage/wal"
+)
+
+// SetIf sets the value if the key exists or if it's missing depending on exists, the zero
+// deadline means no expiration. Keys of any type exist. It reports whether the value is set
+func (s *Storage) SetIf(ctx context.Context, key, value string, deadline time.Time, exists bool) (bool, error) {
+	return s.conditional(ctx, key, setOperation(key, value, deadline), func(_ string, found bool, err error) (bool, error) {
+		found = found || errors.Is(err, common.ErrorWrongType)
+		return found == exists, nil
+	})
+}
+
+// CompareAndSwap sets the value if the key holds the expected value, it reports whether the value is set
+func (s *Storage) CompareAndSwap(ctx context.Context, key, expected, value string) (bool, error) {
+	return s.conditional(ctx, key, setOperation(key, value, time.Time{}), func(current string, found bool, err error) (bool, error) {
+		if err != nil {
+			return false, err
+		} else if !found {
+			return false, ErrorNotFound
+		}
+
+		return current == expected, nil
+	})
+}
+
+// GetSet sets the value and returns the previous one, it reports whether the key existed
+func (s *Storage) GetSet(ctx context.Context, key, value string) (string, bool, error) {
+	var previous string
+	var existed bool
+	_, err := s.conditional(ctx, key, setOperation(key, value, time.Time{}), func(current string, found bool, err error) (bool, error) {
+		previous, existed = current, found
+		return err == nil, err
+	})
+
+	return previous, existed, err
+}
+
+// GetDel deletes the key and returns its value
+func (s *Storage) GetDel(ctx context.Context, key string) (string, error) {
+	var value string
+	operation := wal.NewOperation(compute.DelCommand, []string{key})
+	_, err := s.conditional(ctx, key, operation, func(current string, found bool, err error) (bool, error) {
+		if err != nil {
+			return false, err
+		} else if !found {
+			return false, ErrorNotFound
+		}
+
+		value = current
+		return true, nil
+	})
+
+	return value, err
+}
+
+// DelIfEqual deletes the key if it holds the expected value, it reports whether the key is deleted
+func (s *Storage) DelIfEqual(ctx context.Context, key, expected string) (bool, error) {
+	operation := wal.NewOperation(compute.DelCommand, []string{key})
+	return s.conditional(ctx, key, operation, func(current string, found bool, err error) (bool, error) {
+		if err != nil {
+			return false, err
+		} else if !found {
+			return false, ErrorNotFound
+		}
+
+		return current == expected, nil
+	})
+}
+
+// conditional writes the operation if check passes for the current value of the key. The key
+// is locked from the check until the operation is applied, so no other write of the key gets
+// between them. Nothing is written to the WAL if the check fails
+func (s *Storage) conditional(
+	ctx context.Context,
+	key string,
+	operation wal.Operation,
+	check func(value string, found bool, err error) (bool, error),
+) (bool, error) {
+	if s.replica != nil && !s.replica.IsMaster() {
+		return false, ErrorMutableTX
+	} else if ctx.Err() != nil {
+		return false, ctx.Err()
+	} else if s.transaction(ctx) != nil {
+		return false, ErrorTXCommand
+	}
+
+	unlock := s.keyLocks.lock(key)
+	defer unlock()
+
+	txID := s.snapshots.beginWrite()
+	defer s.endWrite(txID)
+	ctx = common.ContextWithTxID(ctx, txID)
+
+	var value string
+	var found bool
+	var err error
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		value, found, err = s.lookup(ctx, key)
+	})
+
+	passed, err := check(value, found, err)
+	if err != nil || !passed {
+		return false, err
+	} else if operation.CommandID == compute.SetCommand && s.outOfMemory(key) {
+		return false, ErrorOutOfMemory
+	}
+
+	var errLog error
+	if s.wal != nil {
+		futureResponse := s.wal.Write(ctx, operation)
+		if errLog = futureResponse.Get(); !persisted(errLog) {
+			return false, errLog
+		}
+	}
+
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		s.applyOperation(ctx, txID, operation)
+	})
+
+	s.committed(ctx, txID)
+
+	return true, errLog
+}
+
+// lookup returns the string value of the key, it returns common.ErrorWrongType
+// if the key keeps a data structure, strings of such keys are not found
+func (s *Storage) lookup(ctx context.Context, key string) (string, bool, error) {
+	value, found := s.engine.Get(ctx, key)
+	if engine, ok := s.engine.(structuredEngine); ok && !found {
+		if typ, exists := engine.Type(ctx, key); exists && typ != common.StringType {
+			return "", false, common.ErrorWrongType
+		}
+	}
+
+	return value, found, nil
+}
+
+func setOperation(key, value string, deadline time.Time) wal.Operation {
+	if deadline.IsZero() {
+		return wal.NewOperation(compute.SetCommand, []string{key, value})
+	}
+
+	return wal.NewOperation(compute.SetCommand, []string{key, value, wal.EncodeDeadline(deadline)})
+}
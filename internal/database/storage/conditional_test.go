@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/compute"
+	"database-simon/internal/database/storage/engine/memory"
+	"database-simon/internal/database/storage/wal"
+)
+
+func TestStorage_Conditional(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	written := func(context.Context, wal.Operation) concurrency.FutureError {
+		promise := concurrency.NewPromise[error]()
+		promise.Set(nil)
+		return promise.GetFuture()
+	}
+
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, nil)
+	// only operations which conditions pass are logged
+	gomock.InOrder(
+		writeAheadLog.EXPECT().Write(gomock.Any(), wal.NewOperation(compute.SetCommand, []string{"key", "a"})).DoAndReturn(written),
+		writeAheadLog.EXPECT().Write(gomock.Any(), wal.NewOperation(compute.SetCommand, []string{"key", "b"})).DoAndReturn(written),
+		writeAheadLog.EXPECT().Write(gomock.Any(), wal.NewOperation(compute.SetCommand, []string{"key", "c"})).DoAndReturn(written),
+		writeAheadLog.EXPECT().Write(gomock.Any(), wal.NewOperation(compute.DelCommand, []string{"key"})).DoAndReturn(written),
+		writeAheadLog.EXPECT().Write(gomock.Any(), wal.NewOperation(compute.SetCommand, []string{"key", "d"})).DoAndReturn(written),
+		writeAheadLog.EXPECT().Write(gomock.Any(), wal.NewOperation(compute.DelCommand, []string{"key"})).DoAndReturn(written),
+	)
+
+	eng, err := memory.NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	applied, err := stor.SetIf(ctx, "key", "x", time.Time{}, true)
+	require.NoError(t, err)
+	assert.False(t, applied)
+	applied, err = stor.SetIf(ctx, "key", "a", time.Time{}, false)
+	require.NoError(t, err)
+	assert.True(t, applied)
+	applied, err = stor.SetIf(ctx, "key", "x", time.Time{}, false)
+	require.NoError(t, err)
+	assert.False(t, applied)
+
+	applied, err = stor.CompareAndSwap(ctx, "key", "x", "y")
+	require.NoError(t, err)
+	assert.False(t, applied)
+	applied, err = stor.CompareAndSwap(ctx, "key", "a", "b")
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	previous, existed, err := stor.GetSet(ctx, "key", "c")
+	require.NoError(t, err)
+	assert.True(t, existed)
+	assert.Equal(t, "b", previous)
+
+	applied, err = stor.DelIfEqual(ctx, "key", "x")
+	require.NoError(t, err)
+	assert.False(t, applied)
+	applied, err = stor.DelIfEqual(ctx, "key", "c")
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	_, err = stor.CompareAndSwap(ctx, "key", "c", "x")
+	assert.Equal(t, ErrorNotFound, err)
+	_, err = stor.GetDel(ctx, "key")
+	assert.Equal(t, ErrorNotFound, err)
+
+	previous, existed, err = stor.GetSet(ctx, "key", "d")
+	require.NoError(t, err)
+	assert.False(t, existed)
+	assert.Empty(t, previous)
+
+	value, err := stor.GetDel(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "d", value)
+
+	_, err = eng.SAdd(common.ContextWithTxID(ctx, 1), "set", []string{"member"})
+	require.NoError(t, err)
+	_, _, err = stor.GetSet(ctx, "set", "value")
+	assert.Equal(t, common.ErrorWrongType, err)
+	// data structures exist for NX and XX
+	applied, err = stor.SetIf(ctx, "set", "value", time.Time{}, false)
+	require.NoError(t, err)
+	assert.False(t, applied)
+
+	sessionCtx := common.ContextWithSessionID(ctx, 1)
+	require.NoError(t, stor.Begin(sessionCtx))
+	_, err = stor.GetDel(sessionCtx, "key")
+	assert.Equal(t, ErrorTXCommand, err)
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+)
+
+// IncrBy increases the integer value of the key by delta, a missing key is zero
+func (s *Storage) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	value, err := s.increment(ctx, key, func(ctx context.Context, engine counterEngine) (string, time.Time, error) {
+		return engine.Increment(ctx, key, delta)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// IncrByFloat increases the float value of the key by delta, a missing key is zero
+func (s *Storage) IncrByFloat(ctx context.Context, key string, delta float64) (float64, error) {
+	value, err := s.increment(ctx, key, func(ctx context.Context, engine counterEngine) (string, time.Time, error) {
+		return engine.IncrementFloat(ctx, key, delta)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(value, 64)
+}
+
+// increment evaluates the new value of the key under the lock of the key, so the key isn't
+// changed until the value is written. The value is logged like SET which keeps the deadline
+// of the key, so replaying it twice gives the same value.
+//
+// Active expiry, eviction and resharding don't take locks of keys, it's safe because
+// they only remove or move the key: the key expires only after its deadline, which is
+// kept by the written value, so the value expires too; DEL of the evicted key isn't
+// logged if the key is written again; the key is moved with all its versions and writes
+// move it before writing, so the value is written on top of the evaluated one
+func (s *Storage) increment(
+	ctx context.Context,
+	key string,
+	evaluate func(context.Context, counterEngine) (string, time.Time, error),
+) (string, error) {
+	if s.replica != nil && !s.replica.IsMaster() {
+		return "", ErrorMutableTX
+	} else if ctx.Err() != nil {
+		return "", ctx.Err()
+	} else if s.transaction(ctx) != nil {
+		return "", ErrorTXCommand
+	}
+
+	engine, ok := s.engine.(counterEngine)
+	if !ok {
+		return "", ErrorNoCounters
+	}
+
+	unlock := s.keyLocks.lock(key)
+	defer unlock()
+
+	if s.outOfMemory(key) {
+		return "", ErrorOutOfMemory
+	}
+
+	txID := s.snapshots.beginWrite()
+	defer s.endWrite(txID)
+	ctx = common.ContextWithTxID(ctx, txID)
+
+	var value string
+	var deadline time.Time
+	var err error
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		value, deadline, err = evaluate(ctx, engine)
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	var errLog error
+	if s.wal != nil {
+		var futureResponse concurrency.FutureError
+		if deadline.IsZero() {
+			futureResponse = s.wal.Set(ctx, key, value)
+		} else {
+			futureResponse = s.wal.SetWithExpiration(ctx, key, value, deadline)
+		}
+
+		if errLog = futureResponse.Get(); !persisted(errLog) {
+			return "", errLog
+		}
+	}
+
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		if deadline.IsZero() {
+			s.engine.Set(ctx, key, value)
+		} else {
+			s.engine.SetWithExpiration(ctx, key, value, deadline)
+		}
+	})
+
+	s.committed(ctx, txID)
+
+	return value, errLog
+}
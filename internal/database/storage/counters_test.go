@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/storage/engine/memory"
+)
+
+// counterMockEngine is an engine with counters
+type counterMockEngine struct {
+	*Mockengine
+	*MockcounterEngine
+}
+
+func TestStorage_IncrBy(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	deadline := time.UnixMilli(time.Now().Add(time.Minute).UnixMilli())
+
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, nil)
+	// the value is logged instead of the increment
+	writeAheadLog.EXPECT().
+		SetWithExpiration(gomock.Any(), "counter", "15", deadline).
+		DoAndReturn(func(context.Context, string, string, time.Time) concurrency.FutureError {
+			promise := concurrency.NewPromise[error]()
+			promise.Set(nil)
+			return promise.GetFuture()
+		})
+
+	eng := counterMockEngine{NewMockengine(controller), NewMockcounterEngine(controller)}
+	gomock.InOrder(
+		eng.MockcounterEngine.EXPECT().Increment(gomock.Any(), "counter", int64(5)).Return("15", deadline, nil),
+		eng.Mockengine.EXPECT().SetWithExpiration(gomock.Any(), "counter", "15", deadline),
+		eng.MockcounterEngine.EXPECT().Increment(gomock.Any(), "counter", int64(1)).Return("", time.Time{}, common.ErrorOverflow),
+	)
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+
+	value, err := stor.IncrBy(context.Background(), "counter", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(15), value)
+
+	// failed increments are not logged
+	_, err = stor.IncrBy(context.Background(), "counter", 1)
+	assert.Equal(t, common.ErrorOverflow, err)
+
+	ctx := common.ContextWithSessionID(context.Background(), 1)
+	require.NoError(t, stor.Begin(ctx))
+	_, err = stor.IncrBy(ctx, "counter", 1)
+	assert.Equal(t, ErrorTXCommand, err)
+
+	stor, err = NewStorage(NewMockengine(controller), zap.NewNop())
+	require.NoError(t, err)
+	_, err = stor.IncrByFloat(context.Background(), "counter", 1.5)
+	assert.Equal(t, ErrorNoCounters, err)
+}
+
+func TestStorage_ConcurrentIncrBy(t *testing.T) {
+	t.Parallel()
+
+	eng, err := memory.NewMemory(zap.NewNop())
+	require.NoError(t, err)
+
+	stor, err := NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+
+	const workers = 8
+	const increments = 100
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for range increments {
+				_, err := stor.IncrBy(context.Background(), "counter", 1)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := stor.Get(context.Background(), "counter")
+	require.NoError(t, err)
+	assert.Equal(t, "800", value)
+}
+
+func TestStorage_ConcurrentIncrByWithExpiration(t *testing.T) {
+	t.Parallel()
+
+	eng, err := memory.NewMemory(zap.NewNop())
+	require.NoError(t, err)
+
+	stor, err := NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	deadline := time.Now().Add(20 * time.Millisecond)
+	require.NoError(t, stor.SetWithExpiration(ctx, "counter", "0", deadline))
+
+	const workers = 8
+	var mutex sync.Mutex
+	var results []int64
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline.Add(20 * time.Millisecond)) {
+				value, err := stor.IncrBy(ctx, "counter", 1)
+				assert.NoError(t, err)
+				concurrency.WithLock(&mutex, func() {
+					results = append(results, value)
+				})
+			}
+		}()
+	}
+
+	// reads delete the expired key without the lock of the key
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for time.Now().Before(deadline.Add(20 * time.Millisecond)) {
+			_, _ = stor.Get(ctx, "counter")
+		}
+	}()
+
+	wg.Wait()
+	<-done
+
+	// increments of the expiring key are followed by increments of the new key,
+	// so each value is returned once by each of them and no increment is lost
+	value, err := stor.Get(ctx, "counter")
+	require.NoError(t, err)
+	last, err := strconv.ParseInt(value, 10, 64)
+	require.NoError(t, err)
+
+	counts := make(map[int64]int)
+	for _, result := range results {
+		counts[result]++
+	}
+
+	expiring := int64(len(results)) - last
+	for result := int64(1); result <= max(expiring, last); result++ {
+		expected := 0
+		if result <= expiring {
+			expected++
+		}
+		if result <= last {
+			expected++
+		}
+		assert.Equal(t, expected, counts[result], "value %d", result)
+	}
+}
@@ -0,0 +1,73 @@
+package lsm
+
+import (
+	"container/list"
+	"sync"
+)
+
+// blockID identifies a block by the table and its offset, tables
+// are never rewritten, so cached blocks are never stale
+type blockID struct {
+	table  uint64
+	offset int64
+}
+
+type cachedBlock struct {
+	id      blockID
+	records []record
+	size    int
+}
+
+// blockCache keeps decoded blocks of tables, the least recently used
+// blocks are evicted when the size of blocks exceeds the capacity
+type blockCache struct {
+	mutex    sync.Mutex
+	capacity int
+	size     int
+	blocks   map[blockID]*list.Element
+	order    *list.List
+}
+
+func newBlockCache(capacity int) *blockCache {
+	return &blockCache{
+		capacity: capacity,
+		blocks:   make(map[blockID]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *blockCache) get(id blockID) ([]record, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, found := c.blocks[id]
+	if !found {
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return element.Value.(*cachedBlock).records, true
+}
+
+// put adds the block of size bytes, the block which is larger than the capacity isn't cached
+func (c *blockCache) put(id blockID, records []record, size int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if size > c.capacity {
+		return
+	} else if _, found := c.blocks[id]; found {
+		return
+	}
+
+	c.blocks[id] = c.order.PushFront(&cachedBlock{id: id, records: records, size: size})
+	c.size += size
+
+	for c.size > c.capacity {
+		oldest := c.order.Back()
+		block := oldest.Value.(*cachedBlock)
+		c.order.Remove(oldest)
+		delete(c.blocks, block.id)
+		c.size -= block.size
+	}
+}
@@ -0,0 +1,56 @@
+package lsm
+
+import "hash/fnv"
+
+const (
+	bloomBitsPerKey = 10
+	// the optimal number of hashes is bloomBitsPerKey * ln(2)
+	bloomHashes = 7
+)
+
+// bloomFilter answers that the key is definitely missing in the table,
+// so most lookups of missing keys don't read blocks of the table
+type bloomFilter []byte
+
+func newBloomFilter(keys []string) bloomFilter {
+	bits := max(64, len(keys)*bloomBitsPerKey)
+	filter := make(bloomFilter, (bits+7)/8)
+	for _, key := range keys {
+		filter.forEachBit(key, func(bit uint64) bool {
+			filter[bit/8] |= 1 << (bit % 8)
+			return true
+		})
+	}
+
+	return filter
+}
+
+func (f bloomFilter) mayContain(key string) bool {
+	if len(f) == 0 {
+		return true
+	}
+
+	contains := true
+	f.forEachBit(key, func(bit uint64) bool {
+		contains = f[bit/8]&(1<<(bit%8)) != 0
+		return contains
+	})
+
+	return contains
+}
+
+// forEachBit uses double hashing, so bits are calculated by one hash of the key
+func (f bloomFilter) forEachBit(key string, action func(uint64) bool) {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+	sum := hash.Sum64()
+	delta := sum>>33 | sum<<31
+
+	bits := uint64(len(f)) * 8
+	for range bloomHashes {
+		if !action(sum % bits) {
+			return
+		}
+		sum += delta
+	}
+}
@@ -0,0 +1,239 @@
+package lsm
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maintain flushes immutable memtables from the oldest one and then
+// compacts levels while they exceed their limits, failed work is
+// repeated later, data isn't lost, because it's kept until success
+func (l *LSM) maintain() {
+	l.maintenanceMutex.Lock()
+	defer l.maintenanceMutex.Unlock()
+
+	for {
+		var mem *memtable
+		l.mutex.RLock()
+		if len(l.immutables) != 0 {
+			mem = l.immutables[len(l.immutables)-1]
+		}
+		l.mutex.RUnlock()
+
+		if mem == nil {
+			break
+		}
+
+		if err := l.flush(mem); err != nil {
+			l.logger.Error("failed to flush memtable", zap.Error(err))
+			return
+		}
+	}
+
+	for {
+		level, found := l.pickCompaction()
+		if !found {
+			return
+		}
+
+		if err := l.compact(level); err != nil {
+			l.logger.Error("failed to compact level", zap.Int("level", level), zap.Error(err))
+			return
+		}
+	}
+}
+
+// flush writes the memtable to a table of level 0, tombstones are kept,
+// because older tables can contain deleted keys
+func (l *LSM) flush(mem *memtable) error {
+	tables, err := l.writeTables(&memtableIterator{node: mem.seek("")}, false, 0)
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	l.levels[0] = append(tables, l.levels[0]...)
+	l.immutables = l.immutables[:len(l.immutables)-1]
+	l.persistedLSN = max(l.persistedLSN, mem.stableLSN)
+	err = l.saveManifest()
+	persistedLSN, persisted := l.persistedLSN, l.persisted
+	l.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	l.logger.Debug("memtable is flushed", zap.Int("size", mem.size), zap.Int64("persisted_lsn", persistedLSN))
+	if persisted != nil {
+		persisted(persistedLSN)
+	}
+
+	return nil
+}
+
+// pickCompaction returns the level which exceeds its limit, the last level has no limit
+func (l *LSM) pickCompaction() (int, bool) {
+	if len(l.levels[0]) >= level0Tables {
+		return 0, true
+	}
+
+	maxSize := int64(l.levelSize)
+	for level := 1; level < levelsNumber-1; level++ {
+		var size int64
+		for _, t := range l.levels[level] {
+			size += t.Size
+		}
+
+		if size > maxSize {
+			return level, true
+		}
+		maxSize *= levelSizeMultiplier
+	}
+
+	return 0, false
+}
+
+// compact merges tables of the level with overlapping tables of the next level, all
+// tables of level 0 are merged at once, other levels are compacted by one table in
+// a round robin, so all keys of the level are compacted in turn
+func (l *LSM) compact(level int) error {
+	var inputs []*table
+	if level == 0 {
+		inputs = slices.Clone(l.levels[0])
+	} else {
+		tables := l.levels[level]
+		idx := sort.Search(len(tables), func(i int) bool {
+			return tables[i].Smallest > l.compactionKeys[level]
+		})
+		if idx == len(tables) {
+			idx = 0
+		}
+
+		inputs = []*table{tables[idx]}
+		l.compactionKeys[level] = tables[idx].Largest
+	}
+
+	smallest, largest := inputs[0].Smallest, inputs[0].Largest
+	for _, t := range inputs {
+		smallest, largest = min(smallest, t.Smallest), max(largest, t.Largest)
+	}
+
+	target := level + 1
+	var overlapping []*table
+	for _, t := range l.levels[target] {
+		if t.overlaps(smallest, largest) {
+			overlapping = append(overlapping, t)
+		}
+	}
+
+	// tombstones and expired keys are needed only while deeper levels can contain the keys
+	bottom := true
+	for _, tables := range l.levels[target+1:] {
+		bottom = bottom && len(tables) == 0
+	}
+
+	var sources []iterator
+	for _, t := range inputs {
+		sources = append(sources, t.iterator(""))
+	}
+	sources = append(sources, newLevelIterator(overlapping, ""))
+
+	tables, err := l.writeTables(newMergeIterator(sources), bottom, l.tableSize)
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	l.levels[level] = withoutTables(l.levels[level], inputs)
+	l.levels[target] = append(withoutTables(l.levels[target], overlapping), tables...)
+	sort.Slice(l.levels[target], func(i, j int) bool {
+		return l.levels[target][i].Smallest < l.levels[target][j].Smallest
+	})
+	err = l.saveManifest()
+	l.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	// readers hold the lock while they use tables, so replaced tables aren't used anymore
+	l.removeTables(append(inputs, overlapping...))
+
+	l.logger.Debug(
+		"level is compacted",
+		zap.Int("level", level),
+		zap.Int("inputs", len(inputs)+len(overlapping)),
+		zap.Int("outputs", len(tables)),
+	)
+	return nil
+}
+
+// writeTables writes records of the iterator to tables of tableSize, zero
+// size means a single table, dropDead removes tombstones and expired keys
+func (l *LSM) writeTables(it iterator, dropDead bool, tableSize int) ([]*table, error) {
+	var tables []*table
+	builder := &tableBuilder{}
+	write := func() error {
+		l.nextTableID++
+		data, meta := builder.finish(l.nextTableID)
+		builder = &tableBuilder{}
+
+		if err := l.directory.Write(meta.name(), data); err != nil {
+			return err
+		}
+
+		t, err := openTable(l.directory, meta, l.cache)
+		if err != nil {
+			return err
+		}
+
+		tables = append(tables, t)
+		return nil
+	}
+
+	var err error
+	now := time.Now().UnixNano()
+	for ; it.valid() && err == nil; it.next() {
+		if rec := it.current(); !dropDead || rec.alive(now) {
+			builder.add(rec)
+		}
+
+		if tableSize > 0 && builder.size() >= tableSize {
+			err = write()
+		}
+	}
+
+	if err == nil {
+		err = it.err()
+	}
+
+	if err == nil && !builder.empty() {
+		err = write()
+	}
+
+	if err != nil {
+		// written tables aren't in the manifest, so they are not needed
+		l.removeTables(tables)
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+func (l *LSM) removeTables(tables []*table) {
+	for _, t := range tables {
+		if err := errors.Join(t.close(), l.directory.Remove(t.name())); err != nil {
+			l.logger.Warn("failed to remove table", zap.String("table", t.name()), zap.Error(err))
+		}
+	}
+}
+
+func withoutTables(tables []*table, removed []*table) []*table {
+	return slices.DeleteFunc(slices.Clone(tables), func(t *table) bool {
+		return slices.Contains(removed, t)
+	})
+}
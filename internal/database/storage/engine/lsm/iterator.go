@@ -0,0 +1,116 @@
+package lsm
+
+import (
+	"errors"
+	"sort"
+)
+
+// iterator returns records in order of keys
+type iterator interface {
+	valid() bool
+	current() record
+	next()
+	err() error
+}
+
+// mergeIterator merges sorted sources, they are ordered from the newest
+// to the oldest one, so the newest record of a key hides older records
+type mergeIterator struct {
+	sources []iterator
+	idx     int
+}
+
+func newMergeIterator(sources []iterator) *mergeIterator {
+	it := &mergeIterator{sources: sources}
+	it.choose()
+	return it
+}
+
+func (it *mergeIterator) valid() bool {
+	return it.idx >= 0
+}
+
+func (it *mergeIterator) current() record {
+	return it.sources[it.idx].current()
+}
+
+// next skips older records of the current key in all sources
+func (it *mergeIterator) next() {
+	key := it.current().key
+	for _, source := range it.sources {
+		if source.valid() && source.current().key == key {
+			source.next()
+		}
+	}
+
+	it.choose()
+}
+
+func (it *mergeIterator) err() error {
+	var errs []error
+	for _, source := range it.sources {
+		errs = append(errs, source.err())
+	}
+
+	return errors.Join(errs...)
+}
+
+func (it *mergeIterator) choose() {
+	it.idx = -1
+	for idx, source := range it.sources {
+		if source.valid() && (it.idx < 0 || source.current().key < it.sources[it.idx].current().key) {
+			it.idx = idx
+		}
+	}
+}
+
+// levelIterator reads tables of the level one by one, they don't overlap
+type levelIterator struct {
+	tables []*table
+	idx    int
+	table  *tableIterator
+	error  error
+}
+
+func newLevelIterator(tables []*table, from string) *levelIterator {
+	idx := sort.Search(len(tables), func(i int) bool {
+		return tables[i].Largest >= from
+	})
+
+	it := &levelIterator{tables: tables, idx: idx}
+	if idx < len(tables) {
+		it.table = tables[idx].iterator(from)
+		it.skipExhausted()
+	}
+
+	return it
+}
+
+func (it *levelIterator) valid() bool {
+	return it.table != nil && it.table.valid()
+}
+
+func (it *levelIterator) current() record {
+	return it.table.current()
+}
+
+func (it *levelIterator) next() {
+	it.table.next()
+	it.skipExhausted()
+}
+
+func (it *levelIterator) err() error {
+	return it.error
+}
+
+func (it *levelIterator) skipExhausted() {
+	for !it.table.valid() {
+		if it.error = it.table.err(); it.error != nil || it.idx+1 == len(it.tables) {
+			it.table = nil
+			return
+		}
+
+		it.idx++
+		it.table = it.tables[it.idx].iterator("")
+	}
+}
@@ -0,0 +1,428 @@
+package lsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+)
+
+const (
+	levelsNumber = 7
+	// tables of level 0 overlap, so they are compacted as soon as there are several of them
+	level0Tables        = 4
+	levelSizeMultiplier = 10
+	maintenanceInterval = time.Second
+
+	defaultMemtableSize   = 4 << 20
+	defaultTableSize      = 2 << 20
+	defaultLevelSize      = 10 << 20
+	defaultBlockCacheSize = 8 << 20
+)
+
+// LSM keeps the newest writes in the memtable, the full memtable is flushed to a sorted
+// table of level 0 and tables are merged to deeper levels by leveled compaction. Writes
+// become durable only through the WAL, records up to the persisted LSN aren't needed
+// for recovery, because they are contained in tables
+type LSM struct {
+	directory    tablesDirectory
+	manifestFile manifestFile
+	cache        *blockCache
+	logger       *zap.Logger
+
+	memtableSize   int
+	tableSize      int
+	levelSize      int
+	blockCacheSize int
+
+	mutex    sync.RWMutex
+	memtable *memtable
+	// immutable memtables wait for flush, the newest one is the first
+	immutables   []*memtable
+	levels       [levelsNumber][]*table
+	persistedLSN int64
+	// persisted is called with the new persisted LSN after flushes
+	persisted func(lsn int64)
+	// stableLSN is LSN up to which all records are applied to the engine
+	stableLSN int64
+
+	// flushes, compactions and restores are serialized, so tables
+	// of levels are changed only by one of them at the moment
+	maintenanceMutex sync.Mutex
+	nextTableID      uint64
+	// compaction of the level continues from the table after the key
+	compactionKeys [levelsNumber]string
+	flushes        chan struct{}
+}
+
+// NewLSM opens tables of the manifest, tables which are missing in it are removed
+func NewLSM(directory tablesDirectory, manifestFile manifestFile, logger *zap.Logger, options ...EngineOption) (*LSM, error) {
+	if directory == nil {
+		return nil, errors.New("tables directory is invalid")
+	}
+
+	if manifestFile == nil {
+		return nil, errors.New("manifest file is invalid")
+	}
+
+	if logger == nil {
+		return nil, errors.New("logger is invalid")
+	}
+
+	engine := &LSM{
+		directory:      directory,
+		manifestFile:   manifestFile,
+		logger:         logger,
+		memtableSize:   defaultMemtableSize,
+		tableSize:      defaultTableSize,
+		levelSize:      defaultLevelSize,
+		blockCacheSize: defaultBlockCacheSize,
+		memtable:       newMemtable(),
+		flushes:        make(chan struct{}, 1),
+	}
+
+	for _, option := range options {
+		option(engine)
+	}
+
+	engine.cache = newBlockCache(engine.blockCacheSize)
+	if err := engine.load(); err != nil {
+		engine.close()
+		return nil, err
+	}
+
+	return engine, nil
+}
+
+// Start flushes memtables and compacts tables in background
+func (l *LSM) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(maintenanceInterval)
+		defer ticker.Stop()
+
+		for {
+			l.maintain()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-l.flushes:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Set ...
+func (l *LSM) Set(ctx context.Context, key, value string) {
+	l.put(record{key: key, value: value})
+
+	l.logger.Debug("successful set query", zap.Int64("tx", common.GetTxIDFromContext(ctx)))
+}
+
+// SetWithExpiration ...
+func (l *LSM) SetWithExpiration(ctx context.Context, key, value string, deadline time.Time) {
+	l.put(record{key: key, value: value, deadline: encodeDeadline(deadline)})
+
+	l.logger.Debug("successful set query", zap.Int64("tx", common.GetTxIDFromContext(ctx)))
+}
+
+// Get ...
+func (l *LSM) Get(ctx context.Context, key string) (string, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	rec, found := l.find(key)
+
+	l.logger.Debug("successful get query", zap.Int64("tx", common.GetTxIDFromContext(ctx)))
+
+	if !found || !rec.alive(time.Now().UnixNano()) {
+		return "", false
+	}
+
+	return rec.value, true
+}
+
+// Del writes a tombstone, it hides the key in tables until compaction into the last level
+func (l *LSM) Del(ctx context.Context, key string) {
+	l.put(record{key: key, deleted: true})
+
+	l.logger.Debug("successful del query", zap.Int64("tx", common.GetTxIDFromContext(ctx)))
+}
+
+// Expire ...
+func (l *LSM) Expire(ctx context.Context, key string, deadline time.Time) bool {
+	found := l.update(key, func(rec *record) {
+		rec.deadline = encodeDeadline(deadline)
+	})
+
+	l.logger.Debug("successful expire query", zap.Int64("tx", common.GetTxIDFromContext(ctx)))
+
+	return found
+}
+
+// Expiration ...
+func (l *LSM) Expiration(ctx context.Context, key string) (time.Time, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	rec, found := l.find(key)
+
+	l.logger.Debug("successful expiration query", zap.Int64("tx", common.GetTxIDFromContext(ctx)))
+
+	if !found || !rec.alive(time.Now().UnixNano()) {
+		return time.Time{}, false
+	} else if rec.deadline == 0 {
+		return time.Time{}, true
+	}
+
+	return time.Unix(0, rec.deadline), true
+}
+
+// Persist ...
+func (l *LSM) Persist(ctx context.Context, key string) bool {
+	found := l.update(key, func(rec *record) {
+		rec.deadline = 0
+	})
+
+	l.logger.Debug("successful persist query", zap.Int64("tx", common.GetTxIDFromContext(ctx)))
+
+	return found
+}
+
+// Ordered reports that the engine supports range queries
+func (l *LSM) Ordered() bool {
+	return true
+}
+
+// Range calls action in order of keys for keys from from up to to (exclusive,
+// empty to means no bound), limit restricts the number of keys if it's positive
+func (l *LSM) Range(ctx context.Context, from, to string, limit int, action func(key, value string)) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	count := 0
+	now := time.Now().UnixNano()
+	it := l.iterator(from)
+	for ; it.valid() && (to == "" || it.current().key < to); it.next() {
+		if rec := it.current(); rec.alive(now) {
+			action(rec.key, rec.value)
+			if count++; count == limit {
+				break
+			}
+		}
+	}
+
+	if err := it.err(); err != nil {
+		l.logger.Error("failed to read tables", zap.Error(err))
+	}
+
+	l.logger.Debug("successful range query", zap.Int64("tx", common.GetTxIDFromContext(ctx)), zap.Int("keys", count))
+}
+
+// CollectGarbage learns that all records up to oldestSnapshot are applied, so they
+// are persisted after flush of the current memtable, there are no versions to collect
+func (l *LSM) CollectGarbage(_ context.Context, oldestSnapshot int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.stableLSN = max(l.stableLSN, oldestSnapshot)
+}
+
+// PersistedLSN returns LSN up to which all records are contained in tables
+func (l *LSM) PersistedLSN() int64 {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.persistedLSN
+}
+
+// OnPersisted sets the callback which is called after the persisted LSN advances
+// by a flush, so WAL records which are contained in tables can be removed
+func (l *LSM) OnPersisted(callback func(lsn int64)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.persisted = callback
+}
+
+func (l *LSM) put(rec record) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.putLocked(rec)
+}
+
+// update changes the record of the existing key
+func (l *LSM) update(key string, action func(*record)) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	rec, found := l.find(key)
+	if !found || !rec.alive(time.Now().UnixNano()) {
+		return false
+	}
+
+	action(&rec)
+	l.putLocked(rec)
+	return true
+}
+
+// putLocked makes the full memtable immutable, so it's flushed in background
+func (l *LSM) putLocked(rec record) {
+	l.memtable.put(rec)
+	if l.memtable.size < l.memtableSize {
+		return
+	}
+
+	l.memtable.stableLSN = l.stableLSN
+	l.immutables = append([]*memtable{l.memtable}, l.immutables...)
+	l.memtable = newMemtable()
+
+	select {
+	case l.flushes <- struct{}{}:
+	default:
+	}
+}
+
+// find returns the newest record of the key, so it can be a tombstone
+func (l *LSM) find(key string) (record, bool) {
+	for _, mem := range append([]*memtable{l.memtable}, l.immutables...) {
+		if rec, found := mem.get(key); found {
+			return rec, true
+		}
+	}
+
+	for level, tables := range l.levels {
+		if level != 0 {
+			// tables of deeper levels don't overlap, so only one of them can contain the key
+			idx := sort.Search(len(tables), func(i int) bool {
+				return tables[i].Largest >= key
+			})
+			tables = tables[idx:min(idx+1, len(tables))]
+		}
+
+		for _, t := range tables {
+			rec, found, err := t.get(key)
+			if err != nil {
+				l.logger.Error("failed to read table", zap.String("table", t.name()), zap.Error(err))
+				return record{}, false
+			} else if found {
+				return rec, true
+			}
+		}
+	}
+
+	return record{}, false
+}
+
+// iterator merges all memtables and tables from the newest to the oldest ones
+func (l *LSM) iterator(from string) iterator {
+	var sources []iterator
+	for _, mem := range append([]*memtable{l.memtable}, l.immutables...) {
+		sources = append(sources, &memtableIterator{node: mem.seek(from)})
+	}
+
+	for _, t := range l.levels[0] {
+		sources = append(sources, t.iterator(from))
+	}
+
+	for _, tables := range l.levels[1:] {
+		if len(tables) != 0 {
+			sources = append(sources, newLevelIterator(tables, from))
+		}
+	}
+
+	return newMergeIterator(sources)
+}
+
+func (l *LSM) load() error {
+	data, err := l.manifestFile.Load()
+	if err != nil {
+		return err
+	}
+
+	used := make(map[string]struct{})
+	if data != nil {
+		m, err := decodeManifest(data)
+		if err != nil {
+			return err
+		}
+
+		l.persistedLSN, l.stableLSN, l.nextTableID = m.PersistedLSN, m.PersistedLSN, m.NextTableID
+		for level, metas := range m.Levels {
+			for _, meta := range metas {
+				t, err := openTable(l.directory, meta, l.cache)
+				if err != nil {
+					return err
+				}
+
+				l.levels[level] = append(l.levels[level], t)
+				used[meta.name()] = struct{}{}
+			}
+		}
+	}
+
+	names, err := l.directory.Names()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, found := used[name]; !found {
+			if err = l.directory.Remove(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *LSM) close() {
+	for _, tables := range l.levels {
+		for _, t := range tables {
+			_ = t.close()
+		}
+	}
+}
+
+// saveManifest must be called under both locks
+func (l *LSM) saveManifest() error {
+	m := manifest{
+		PersistedLSN: l.persistedLSN,
+		NextTableID:  l.nextTableID,
+		Levels:       make([][]tableMeta, levelsNumber),
+	}
+
+	for level, tables := range l.levels {
+		for _, t := range tables {
+			m.Levels[level] = append(m.Levels[level], t.tableMeta)
+		}
+	}
+
+	data, err := encodeManifest(m)
+	if err != nil {
+		return err
+	}
+
+	if err = l.manifestFile.Store(data); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	return nil
+}
+
+func encodeDeadline(deadline time.Time) int64 {
+	if deadline.IsZero() {
+		return 0
+	}
+
+	return deadline.UnixNano()
+}
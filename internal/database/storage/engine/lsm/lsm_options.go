@@ -0,0 +1,33 @@
+package lsm
+
+// EngineOption ...
+type EngineOption func(engine *LSM)
+
+// WithMemtableSize sets the size of the memtable in bytes after which it's flushed to a table
+func WithMemtableSize(size int) EngineOption {
+	return func(engine *LSM) {
+		engine.memtableSize = size
+	}
+}
+
+// WithTableSize sets the size of tables which are written by compaction
+func WithTableSize(size int) EngineOption {
+	return func(engine *LSM) {
+		engine.tableSize = size
+	}
+}
+
+// WithLevelSize sets the size of the first level, each next level is
+// levelSizeMultiplier times larger, larger levels are compacted to the next one
+func WithLevelSize(size int) EngineOption {
+	return func(engine *LSM) {
+		engine.levelSize = size
+	}
+}
+
+// WithBlockCacheSize sets the size of decoded blocks which are kept in memory
+func WithBlockCacheSize(size int) EngineOption {
+	return func(engine *LSM) {
+		engine.blockCacheSize = size
+	}
+}
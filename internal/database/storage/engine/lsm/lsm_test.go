@@ -0,0 +1,280 @@
+package lsm
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/database/filesystem"
+)
+
+func newTestLSM(t *testing.T, directory string) *LSM {
+	t.Helper()
+
+	engine, err := NewLSM(
+		filesystem.NewTablesDirectory(directory),
+		filesystem.NewStateFile(directory, "MANIFEST"),
+		zap.NewNop(),
+		WithMemtableSize(4<<10),
+		WithTableSize(2<<10),
+		WithLevelSize(8<<10),
+	)
+	require.NoError(t, err)
+	return engine
+}
+
+func TestNewLSM(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	tests := map[string]struct {
+		directory    tablesDirectory
+		manifestFile manifestFile
+		logger       *zap.Logger
+		expectedErr  bool
+	}{
+		"without directory": {
+			manifestFile: filesystem.NewStateFile(directory, "MANIFEST"),
+			logger:       zap.NewNop(),
+			expectedErr:  true,
+		},
+		"without manifest": {
+			directory:   filesystem.NewTablesDirectory(directory),
+			logger:      zap.NewNop(),
+			expectedErr: true,
+		},
+		"without logger": {
+			directory:    filesystem.NewTablesDirectory(directory),
+			manifestFile: filesystem.NewStateFile(directory, "MANIFEST"),
+			expectedErr:  true,
+		},
+		"with all dependencies": {
+			directory:    filesystem.NewTablesDirectory(directory),
+			manifestFile: filesystem.NewStateFile(directory, "MANIFEST"),
+			logger:       zap.NewNop(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			engine, err := NewLSM(test.directory, test.manifestFile, test.logger)
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Nil(t, engine)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, engine)
+			}
+		})
+	}
+}
+
+func TestLSMOperations(t *testing.T) {
+	t.Parallel()
+
+	ctx := common.ContextWithTxID(context.Background(), 1)
+	engine := newTestLSM(t, t.TempDir())
+
+	engine.Set(ctx, "key", "value")
+	value, found := engine.Get(ctx, "key")
+	assert.True(t, found)
+	assert.Equal(t, "value", value)
+
+	deadline := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	assert.True(t, engine.Expire(ctx, "key", deadline))
+	expiration, found := engine.Expiration(ctx, "key")
+	assert.True(t, found)
+	assert.True(t, deadline.Equal(expiration))
+
+	assert.True(t, engine.Persist(ctx, "key"))
+	expiration, found = engine.Expiration(ctx, "key")
+	assert.True(t, found)
+	assert.True(t, expiration.IsZero())
+
+	engine.SetWithExpiration(ctx, "expired", "value", time.Now().Add(-time.Second))
+	_, found = engine.Get(ctx, "expired")
+	assert.False(t, found)
+	assert.False(t, engine.Expire(ctx, "expired", deadline))
+	assert.False(t, engine.Persist(ctx, "missing"))
+
+	engine.Del(ctx, "key")
+	_, found = engine.Get(ctx, "key")
+	assert.False(t, found)
+	_, found = engine.Expiration(ctx, "key")
+	assert.False(t, found)
+}
+
+// TestLSMCompaction compares the engine with a map, small memtables and
+// levels make writes to be flushed and compacted through all levels
+func TestLSMCompaction(t *testing.T) {
+	t.Parallel()
+
+	ctx := common.ContextWithTxID(context.Background(), 1)
+	directory := t.TempDir()
+	engine := newTestLSM(t, directory)
+
+	expected := make(map[string]string)
+	for idx := range 20000 {
+		key := fmt.Sprintf("key_%04d", rand.IntN(2000)) // nolint : G404: Use of weak random number generator
+		if idx%5 == 0 {
+			engine.Del(ctx, key)
+			delete(expected, key)
+		} else {
+			value := fmt.Sprintf("value_%d", idx)
+			engine.Set(ctx, key, value)
+			expected[key] = value
+		}
+
+		if idx%1000 == 0 {
+			engine.maintain()
+		}
+	}
+
+	engine.maintain()
+	assert.Empty(t, engine.immutables)
+	assert.Less(t, len(engine.levels[0]), level0Tables)
+	assert.NotEmpty(t, engine.levels[2])
+
+	check := func(engine *LSM) {
+		for idx := range 2000 {
+			key := fmt.Sprintf("key_%04d", idx)
+			value, found := engine.Get(ctx, key)
+			expectedValue, expectedFound := expected[key]
+			require.Equal(t, expectedFound, found, key)
+			require.Equal(t, expectedValue, value, key)
+		}
+
+		var keys []string
+		engine.Range(ctx, "", "", 0, func(key, value string) {
+			assert.Equal(t, expected[key], value)
+			keys = append(keys, key)
+		})
+
+		expectedKeys := make([]string, 0, len(expected))
+		for key := range expected {
+			expectedKeys = append(expectedKeys, key)
+		}
+		sort.Strings(expectedKeys)
+		assert.Equal(t, expectedKeys, keys)
+	}
+
+	check(engine)
+
+	// tables are kept after restart, the memtable is recovered from the WAL
+	engine.CollectGarbage(ctx, 100)
+	engine.Set(ctx, "key_9999", "value")
+	expected["key_9999"] = "value"
+	engine.mutex.Lock()
+	engine.memtable.size = engine.memtableSize
+	engine.putLocked(record{key: "key_9999", value: "value"})
+	engine.mutex.Unlock()
+	engine.maintain()
+	engine.close()
+
+	reopened := newTestLSM(t, directory)
+	assert.Equal(t, int64(100), reopened.PersistedLSN())
+	check(reopened)
+}
+
+func TestLSMOnPersisted(t *testing.T) {
+	t.Parallel()
+
+	ctx := common.ContextWithTxID(context.Background(), 1)
+	engine := newTestLSM(t, t.TempDir())
+
+	var persistedLSN int64
+	engine.OnPersisted(func(lsn int64) {
+		persistedLSN = lsn
+	})
+
+	engine.Set(ctx, "key", "value")
+	engine.CollectGarbage(ctx, 10)
+	engine.mutex.Lock()
+	engine.memtable.size = engine.memtableSize
+	engine.putLocked(record{key: "key", value: "new_value"})
+	engine.mutex.Unlock()
+	engine.maintain()
+
+	assert.Equal(t, int64(10), persistedLSN)
+	assert.Equal(t, engine.PersistedLSN(), persistedLSN)
+}
+
+func TestLSMRange(t *testing.T) {
+	t.Parallel()
+
+	ctx := common.ContextWithTxID(context.Background(), 1)
+	engine := newTestLSM(t, t.TempDir())
+
+	for idx := range 10 {
+		engine.Set(ctx, fmt.Sprintf("key_%d", idx), fmt.Sprintf("value_%d", idx))
+	}
+
+	engine.mutex.Lock()
+	engine.memtable.size = engine.memtableSize
+	engine.putLocked(record{key: "key_3", deleted: true})
+	engine.mutex.Unlock()
+	engine.maintain()
+
+	// the memtable hides records of tables
+	engine.Set(ctx, "key_5", "new_value")
+	engine.SetWithExpiration(ctx, "key_6", "value_6", time.Now().Add(-time.Second))
+
+	var entries []string
+	engine.Range(ctx, "key_2", "key_8", 4, func(key, value string) {
+		entries = append(entries, key+"="+value)
+	})
+
+	assert.Equal(t, []string{"key_2=value_2", "key_4=value_4", "key_5=new_value", "key_7=value_7"}, entries)
+}
+
+func TestLSMSnapshot(t *testing.T) {
+	t.Parallel()
+
+	ctx := common.ContextWithTxID(context.Background(), 1)
+	source := newTestLSM(t, t.TempDir())
+	deadline := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	source.Set(ctx, "first", "1")
+	source.SetWithExpiration(ctx, "second", "2", deadline)
+	source.Set(ctx, "deleted", "3")
+	source.Del(ctx, "deleted")
+
+	data, err := source.Snapshot(ctx)
+	require.NoError(t, err)
+
+	// keys which are missing in the snapshot are removed
+	directory := t.TempDir()
+	target := newTestLSM(t, directory)
+	target.Set(ctx, "stale", "value")
+	require.NoError(t, target.Restore(common.ContextWithTxID(ctx, 42), data))
+
+	check := func(engine *LSM) {
+		value, found := engine.Get(ctx, "first")
+		assert.True(t, found)
+		assert.Equal(t, "1", value)
+
+		expiration, found := engine.Expiration(ctx, "second")
+		assert.True(t, found)
+		assert.True(t, deadline.Equal(expiration))
+
+		_, found = engine.Get(ctx, "deleted")
+		assert.False(t, found)
+		_, found = engine.Get(ctx, "stale")
+		assert.False(t, found)
+	}
+
+	check(target)
+	assert.Equal(t, int64(42), target.PersistedLSN())
+
+	target.close()
+	check(newTestLSM(t, directory))
+}
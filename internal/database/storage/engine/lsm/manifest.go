@@ -0,0 +1,43 @@
+package lsm
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+type manifestFile interface {
+	Load() ([]byte, error)
+	Store([]byte) error
+}
+
+// manifest describes tables of levels, a table which is missing in the
+// manifest is a result of interrupted flush or compaction, so it's removed
+type manifest struct {
+	// PersistedLSN is LSN up to which all records are contained in tables
+	PersistedLSN int64
+	NextTableID  uint64
+	Levels       [][]tableMeta
+}
+
+func encodeManifest(m manifest) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(m); err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func decodeManifest(data []byte) (manifest, error) {
+	var m manifest
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&m); err != nil {
+		return manifest{}, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	if len(m.Levels) > levelsNumber {
+		return manifest{}, fmt.Errorf("manifest has %d levels, but only %d are supported", len(m.Levels), levelsNumber)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,132 @@
+package lsm
+
+import "math/rand/v2"
+
+const (
+	memtableMaxLevel = 32
+	// a node is promoted to the next level with probability 1/memtableBranching
+	memtableBranching = 4
+	// approximate size of a node without the key and the value
+	memtableNodeOverhead = 64
+)
+
+// record is a state of the key, deleted keys are kept as tombstones until
+// compaction into the last level, so they hide older versions in deeper levels
+type record struct {
+	key   string
+	value string
+	// deadline is unix time in nanoseconds, zero means no expiration
+	deadline int64
+	deleted  bool
+}
+
+// alive checks that the key exists at the moment
+func (r record) alive(now int64) bool {
+	return !r.deleted && (r.deadline == 0 || now < r.deadline)
+}
+
+type memtableNode struct {
+	record record
+	next   []*memtableNode
+}
+
+// memtable keeps the newest records in a skip list, so they are flushed to
+// a table in order, it has no own lock, so it's protected by the lock of the engine
+type memtable struct {
+	head  *memtableNode
+	level int
+	size  int
+	// stableLSN is LSN up to which all records are contained in
+	// this memtable, older memtables or tables
+	stableLSN int64
+}
+
+func newMemtable() *memtable {
+	return &memtable{
+		head:  &memtableNode{next: make([]*memtableNode, memtableMaxLevel)},
+		level: 1,
+	}
+}
+
+// put replaces the record of the key
+func (m *memtable) put(rec record) {
+	var update [memtableMaxLevel]*memtableNode
+	node := m.findPrevious(rec.key, &update).next[0]
+	if node != nil && node.record.key == rec.key {
+		m.size += len(rec.value) - len(node.record.value)
+		node.record = rec
+		return
+	}
+
+	level := 1
+	for level < memtableMaxLevel && rand.IntN(memtableBranching) == 0 { // nolint : G404: Use of weak random number generator
+		level++
+	}
+
+	if level > m.level {
+		for idx := m.level; idx < level; idx++ {
+			update[idx] = m.head
+		}
+		m.level = level
+	}
+
+	node = &memtableNode{record: rec, next: make([]*memtableNode, level)}
+	for idx := range level {
+		node.next[idx] = update[idx].next[idx]
+		update[idx].next[idx] = node
+	}
+
+	m.size += len(rec.key) + len(rec.value) + memtableNodeOverhead
+}
+
+func (m *memtable) get(key string) (record, bool) {
+	node := m.seek(key)
+	if node == nil || node.record.key != key {
+		return record{}, false
+	}
+
+	return node.record, true
+}
+
+// seek returns the first node with a key which is not less than key
+func (m *memtable) seek(key string) *memtableNode {
+	var update [memtableMaxLevel]*memtableNode
+	return m.findPrevious(key, &update).next[0]
+}
+
+func (m *memtable) empty() bool {
+	return m.head.next[0] == nil
+}
+
+func (m *memtable) findPrevious(key string, update *[memtableMaxLevel]*memtableNode) *memtableNode {
+	current := m.head
+	for idx := m.level - 1; idx >= 0; idx-- {
+		for current.next[idx] != nil && current.next[idx].record.key < key {
+			current = current.next[idx]
+		}
+		update[idx] = current
+	}
+
+	return current
+}
+
+// memtableIterator ...
+type memtableIterator struct {
+	node *memtableNode
+}
+
+func (it *memtableIterator) valid() bool {
+	return it.node != nil
+}
+
+func (it *memtableIterator) current() record {
+	return it.node.record
+}
+
+func (it *memtableIterator) next() {
+	it.node = it.node.next[0]
+}
+
+func (it *memtableIterator) err() error {
+	return nil
+}
@@ -0,0 +1,142 @@
+package lsm
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+)
+
+// snapshotEntry has the same fields as an entry of the memory engine,
+// so replicas can restore the snapshot by another engine
+type snapshotEntry struct {
+	Key   string
+	Value string
+	// Deadline is unix time in milliseconds, zero means no expiration
+	Deadline int64
+	// Type is set for data structures of the memory engine, they aren't supported
+	Type string
+}
+
+// sliceIterator ...
+type sliceIterator struct {
+	records []record
+}
+
+func (it *sliceIterator) valid() bool {
+	return len(it.records) != 0
+}
+
+func (it *sliceIterator) current() record {
+	return it.records[0]
+}
+
+func (it *sliceIterator) next() {
+	it.records = it.records[1:]
+}
+
+func (it *sliceIterator) err() error {
+	return nil
+}
+
+// Versioned ...
+func (l *LSM) Versioned() bool {
+	return false
+}
+
+// Snapshot dumps all keys, the snapshot is consistent only if there are no concurrent writes
+func (l *LSM) Snapshot(ctx context.Context) ([]byte, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	var entries []snapshotEntry
+	now := time.Now().UnixNano()
+	it := l.iterator("")
+	for ; it.valid(); it.next() {
+		if rec := it.current(); rec.alive(now) {
+			entry := snapshotEntry{Key: rec.key, Value: rec.value}
+			if rec.deadline != 0 {
+				entry.Deadline = time.Unix(0, rec.deadline).UnixMilli()
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := it.err(); err != nil {
+		return nil, fmt.Errorf("failed to read tables: %w", err)
+	}
+
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(entries); err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	l.logger.Debug("successful snapshot", zap.Int64("tx", common.GetTxIDFromContext(ctx)), zap.Int("keys", len(entries)))
+	return buffer.Bytes(), nil
+}
+
+// Restore replaces all tables by tables of the last level with keys of the snapshot,
+// records up to LSN of the transaction from the context become persisted
+func (l *LSM) Restore(ctx context.Context, data []byte) error {
+	var entries []snapshotEntry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	records := make([]record, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type != "" {
+			return fmt.Errorf("failed to restore key %q: data structures are not supported", entry.Key)
+		}
+
+		rec := record{key: entry.Key, value: entry.Value}
+		if entry.Deadline != 0 {
+			rec.deadline = time.UnixMilli(entry.Deadline).UnixNano()
+		}
+		records = append(records, rec)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].key < records[j].key
+	})
+
+	l.maintenanceMutex.Lock()
+	defer l.maintenanceMutex.Unlock()
+
+	tables, err := l.writeTables(&sliceIterator{records: records}, false, l.tableSize)
+	if err != nil {
+		return err
+	}
+
+	txID := common.GetTxIDFromContext(ctx)
+	l.mutex.Lock()
+	var replaced []*table
+	for level := range l.levels {
+		replaced = append(replaced, l.levels[level]...)
+		l.levels[level] = nil
+	}
+
+	l.levels[levelsNumber-1] = tables
+	l.memtable = newMemtable()
+	l.immutables = nil
+	l.persistedLSN = txID
+	l.stableLSN = max(l.stableLSN, txID)
+	err = l.saveManifest()
+	l.mutex.Unlock()
+
+	if err != nil {
+		// replaced tables are kept, because the saved manifest refers to them
+		return err
+	}
+
+	l.removeTables(replaced)
+
+	l.logger.Debug("successful restore", zap.Int64("tx", txID), zap.Int("keys", len(records)))
+	return nil
+}
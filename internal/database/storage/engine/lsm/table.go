@@ -0,0 +1,415 @@
+package lsm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"sort"
+)
+
+const (
+	blockSize = 4 << 10
+	// footer keeps offset and size of the index, size of the filter,
+	// checksum of the index with the filter and the magic number
+	footerSize = 3*8 + 2*4
+
+	recordDeleted = 1
+)
+
+var (
+	tableMagic      = []byte("SST1")
+	castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// ErrorCorruptedTable ...
+var ErrorCorruptedTable = errors.New("table is corrupted")
+
+type tablesDirectory interface {
+	Write(string, []byte) error
+	Open(string) (*os.File, error)
+	Remove(string) error
+	Names() ([]string, error)
+}
+
+// tableMeta describes the table in the manifest
+type tableMeta struct {
+	ID       uint64
+	Smallest string
+	Largest  string
+	Size     int64
+}
+
+func (m tableMeta) name() string {
+	return fmt.Sprintf("table_%020d", m.ID)
+}
+
+func (m tableMeta) overlaps(smallest, largest string) bool {
+	return m.Smallest <= largest && smallest <= m.Largest
+}
+
+// blockHandle locates a block of records, each block is followed by its checksum
+type blockHandle struct {
+	firstKey string
+	offset   int64
+	size     int64
+}
+
+// tableBuilder encodes sorted records to a table: blocks of records,
+// the index of blocks, the bloom filter of keys and the footer
+type tableBuilder struct {
+	data          bytes.Buffer
+	block         bytes.Buffer
+	blockFirstKey string
+	index         []blockHandle
+	keys          []string
+}
+
+// add appends the record, records must be added in order of keys
+func (b *tableBuilder) add(rec record) {
+	if b.block.Len() == 0 {
+		b.blockFirstKey = rec.key
+	}
+
+	encodeRecord(&b.block, rec)
+	b.keys = append(b.keys, rec.key)
+
+	if b.block.Len() >= blockSize {
+		b.finishBlock()
+	}
+}
+
+func (b *tableBuilder) size() int {
+	return b.data.Len() + b.block.Len()
+}
+
+func (b *tableBuilder) empty() bool {
+	return len(b.keys) == 0
+}
+
+// finish returns the encoded table with its meta, the builder can't be used after that
+func (b *tableBuilder) finish(id uint64) ([]byte, tableMeta) {
+	b.finishBlock()
+
+	indexOffset := b.data.Len()
+	var buffer []byte
+	buffer = binary.AppendUvarint(buffer, uint64(len(b.index)))
+	for _, handle := range b.index {
+		buffer = binary.AppendUvarint(buffer, uint64(len(handle.firstKey)))
+		buffer = append(buffer, handle.firstKey...)
+		buffer = binary.AppendUvarint(buffer, uint64(handle.offset))
+		buffer = binary.AppendUvarint(buffer, uint64(handle.size))
+	}
+
+	indexSize := len(buffer)
+	filter := newBloomFilter(b.keys)
+	buffer = append(buffer, filter...)
+	checksum := crc32.Checksum(buffer, castagnoliTable)
+
+	b.data.Write(buffer)
+	buffer = binary.BigEndian.AppendUint64(buffer[:0], uint64(indexOffset))
+	buffer = binary.BigEndian.AppendUint64(buffer, uint64(indexSize))
+	buffer = binary.BigEndian.AppendUint64(buffer, uint64(len(filter)))
+	buffer = binary.BigEndian.AppendUint32(buffer, checksum)
+	buffer = append(buffer, tableMagic...)
+	b.data.Write(buffer)
+
+	meta := tableMeta{
+		ID:       id,
+		Smallest: b.keys[0],
+		Largest:  b.keys[len(b.keys)-1],
+		Size:     int64(b.data.Len()),
+	}
+
+	return b.data.Bytes(), meta
+}
+
+func (b *tableBuilder) finishBlock() {
+	if b.block.Len() == 0 {
+		return
+	}
+
+	b.index = append(b.index, blockHandle{
+		firstKey: b.blockFirstKey,
+		offset:   int64(b.data.Len()),
+		size:     int64(b.block.Len()),
+	})
+
+	b.data.Write(b.block.Bytes())
+	b.data.Write(binary.BigEndian.AppendUint32(nil, crc32.Checksum(b.block.Bytes(), castagnoliTable)))
+	b.block.Reset()
+}
+
+// table reads records of the immutable table file, the index and
+// the filter are kept in memory, blocks are read through the cache
+type table struct {
+	tableMeta
+	file   *os.File
+	index  []blockHandle
+	filter bloomFilter
+	cache  *blockCache
+}
+
+func openTable(directory tablesDirectory, meta tableMeta, cache *blockCache) (*table, error) {
+	file, err := directory.Open(meta.name())
+	if err != nil {
+		return nil, err
+	}
+
+	t := &table{tableMeta: meta, file: file, cache: cache}
+	if err = t.readMeta(); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", meta.name(), err)
+	}
+
+	return t, nil
+}
+
+func (t *table) readMeta() error {
+	if t.Size < footerSize {
+		return ErrorCorruptedTable
+	}
+
+	footer := make([]byte, footerSize)
+	if _, err := t.file.ReadAt(footer, t.Size-footerSize); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(footer[footerSize-len(tableMagic):], tableMagic) {
+		return ErrorCorruptedTable
+	}
+
+	indexOffset := int64(binary.BigEndian.Uint64(footer))
+	indexSize := int64(binary.BigEndian.Uint64(footer[8:]))
+	filterSize := int64(binary.BigEndian.Uint64(footer[16:]))
+	checksum := binary.BigEndian.Uint32(footer[24:])
+	if indexOffset < 0 || indexSize < 0 || filterSize < 0 || indexOffset+indexSize+filterSize != t.Size-footerSize {
+		return ErrorCorruptedTable
+	}
+
+	meta := make([]byte, indexSize+filterSize)
+	if _, err := t.file.ReadAt(meta, indexOffset); err != nil {
+		return err
+	}
+
+	if crc32.Checksum(meta, castagnoliTable) != checksum {
+		return ErrorCorruptedTable
+	}
+
+	index, err := decodeIndex(meta[:indexSize])
+	if err != nil {
+		return err
+	}
+
+	t.index = index
+	t.filter = meta[indexSize:]
+	return nil
+}
+
+// get returns the record of the key, the filter skips most of missing keys
+func (t *table) get(key string) (record, bool, error) {
+	if key < t.Smallest || key > t.Largest || !t.filter.mayContain(key) {
+		return record{}, false, nil
+	}
+
+	records, err := t.block(t.blockFor(key))
+	if err != nil {
+		return record{}, false, err
+	}
+
+	idx := sort.Search(len(records), func(i int) bool {
+		return records[i].key >= key
+	})
+
+	if idx == len(records) || records[idx].key != key {
+		return record{}, false, nil
+	}
+
+	return records[idx], true, nil
+}
+
+// iterator returns records of the table starting from the first key which is not less than from
+func (t *table) iterator(from string) *tableIterator {
+	it := &tableIterator{table: t}
+	it.load(t.blockFor(from))
+	for it.valid() && it.current().key < from {
+		it.next()
+	}
+
+	return it
+}
+
+func (t *table) close() error {
+	return t.file.Close()
+}
+
+// blockFor returns index of the last block which starts with a key not greater than key
+func (t *table) blockFor(key string) int {
+	idx := sort.Search(len(t.index), func(i int) bool {
+		return t.index[i].firstKey > key
+	})
+
+	return max(0, idx-1)
+}
+
+func (t *table) block(idx int) ([]record, error) {
+	handle := t.index[idx]
+	id := blockID{table: t.ID, offset: handle.offset}
+	if records, found := t.cache.get(id); found {
+		return records, nil
+	}
+
+	data := make([]byte, handle.size+crc32.Size)
+	if _, err := t.file.ReadAt(data, handle.offset); err != nil {
+		return nil, fmt.Errorf("failed to read block of %s: %w", t.name(), err)
+	}
+
+	checksum := binary.BigEndian.Uint32(data[handle.size:])
+	data = data[:handle.size]
+	if crc32.Checksum(data, castagnoliTable) != checksum {
+		return nil, fmt.Errorf("%s: %w", t.name(), ErrorCorruptedTable)
+	}
+
+	records, err := decodeRecords(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.name(), err)
+	}
+
+	t.cache.put(id, records, int(handle.size))
+	return records, nil
+}
+
+// tableIterator reads blocks of the table one by one
+type tableIterator struct {
+	table    *table
+	blockIdx int
+	records  []record
+	position int
+	error    error
+}
+
+func (it *tableIterator) valid() bool {
+	return it.position < len(it.records)
+}
+
+func (it *tableIterator) current() record {
+	return it.records[it.position]
+}
+
+func (it *tableIterator) next() {
+	it.position++
+	if it.position == len(it.records) {
+		it.load(it.blockIdx + 1)
+	}
+}
+
+func (it *tableIterator) err() error {
+	return it.error
+}
+
+func (it *tableIterator) load(idx int) {
+	it.blockIdx, it.records, it.position = idx, nil, 0
+	if idx >= len(it.table.index) {
+		return
+	}
+
+	if it.records, it.error = it.table.block(idx); it.error != nil {
+		it.records = nil
+	}
+}
+
+func encodeRecord(buffer *bytes.Buffer, rec record) {
+	var flags byte
+	if rec.deleted {
+		flags |= recordDeleted
+	}
+
+	data := binary.AppendUvarint(nil, uint64(len(rec.key)))
+	data = append(data, rec.key...)
+	data = append(data, flags)
+	data = binary.AppendVarint(data, rec.deadline)
+	data = binary.AppendUvarint(data, uint64(len(rec.value)))
+	data = append(data, rec.value...)
+	buffer.Write(data)
+}
+
+func decodeRecords(data []byte) ([]record, error) {
+	var records []record
+	for len(data) != 0 {
+		var rec record
+		var flags []byte
+		var err error
+		if rec.key, data, err = decodeString(data); err != nil {
+			return nil, err
+		}
+
+		if flags, data, err = decodeBytes(data, 1); err != nil {
+			return nil, err
+		}
+		rec.deleted = flags[0]&recordDeleted != 0
+
+		var size int
+		if rec.deadline, size = binary.Varint(data); size <= 0 {
+			return nil, ErrorCorruptedTable
+		}
+		data = data[size:]
+
+		if rec.value, data, err = decodeString(data); err != nil {
+			return nil, err
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+func decodeIndex(data []byte) ([]blockHandle, error) {
+	count, size := binary.Uvarint(data)
+	if size <= 0 || count > uint64(len(data)) {
+		return nil, ErrorCorruptedTable
+	}
+	data = data[size:]
+
+	index := make([]blockHandle, 0, count)
+	for range count {
+		var handle blockHandle
+		var err error
+		if handle.firstKey, data, err = decodeString(data); err != nil {
+			return nil, err
+		}
+
+		for _, value := range []*int64{&handle.offset, &handle.size} {
+			number, size := binary.Uvarint(data)
+			if size <= 0 {
+				return nil, ErrorCorruptedTable
+			}
+			*value = int64(number) // nolint : G115: integer overflow conversion
+			data = data[size:]
+		}
+
+		index = append(index, handle)
+	}
+
+	return index, nil
+}
+
+func decodeString(data []byte) (string, []byte, error) {
+	length, size := binary.Uvarint(data)
+	if size <= 0 || length > uint64(len(data)-size) {
+		return "", nil, ErrorCorruptedTable
+	}
+
+	value, data, err := decodeBytes(data[size:], int(length))
+	return string(value), data, err
+}
+
+func decodeBytes(data []byte, length int) ([]byte, []byte, error) {
+	if length > len(data) {
+		return nil, nil, ErrorCorruptedTable
+	}
+
+	return data[:length], data[length:], nil
+}
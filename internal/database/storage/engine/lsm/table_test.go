@@ -0,0 +1,149 @@
+package lsm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"database-simon/internal/database/filesystem"
+)
+
+func writeTestTable(t *testing.T, directory tablesDirectory, records []record) *table {
+	t.Helper()
+
+	builder := &tableBuilder{}
+	for _, rec := range records {
+		builder.add(rec)
+	}
+
+	data, meta := builder.finish(1)
+	require.NoError(t, directory.Write(meta.name(), data))
+
+	tbl, err := openTable(directory, meta, newBlockCache(defaultBlockCacheSize))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = tbl.close() })
+	return tbl
+}
+
+func TestTable(t *testing.T) {
+	t.Parallel()
+
+	var records []record
+	for idx := range 1000 {
+		rec := record{key: fmt.Sprintf("key_%04d", idx*2), value: fmt.Sprintf("value_%d", idx)}
+		if idx%10 == 0 {
+			rec = record{key: rec.key, deleted: true}
+		} else if idx%7 == 0 {
+			rec.deadline = int64(idx)
+		}
+		records = append(records, rec)
+	}
+
+	tbl := writeTestTable(t, filesystem.NewTablesDirectory(t.TempDir()), records)
+	assert.Greater(t, len(tbl.index), 1)
+	assert.Equal(t, "key_0000", tbl.Smallest)
+	assert.Equal(t, "key_1998", tbl.Largest)
+
+	for _, rec := range records {
+		found, ok, err := tbl.get(rec.key)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, rec, found)
+	}
+
+	for _, key := range []string{"key_0001", "key_1999", "a", "z"} {
+		_, ok, err := tbl.get(key)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+
+	var keys []string
+	for it := tbl.iterator("key_1991"); it.valid(); it.next() {
+		keys = append(keys, it.current().key)
+	}
+	assert.Equal(t, []string{"key_1992", "key_1994", "key_1996", "key_1998"}, keys)
+}
+
+func TestTableCorruption(t *testing.T) {
+	t.Parallel()
+
+	directoryPath := t.TempDir()
+	directory := filesystem.NewTablesDirectory(directoryPath)
+	tbl := writeTestTable(t, directory, []record{{key: "key", value: "value"}})
+
+	// the block is damaged, but the index and the filter are valid
+	filename := filepath.Join(directoryPath, tbl.name()+".sst")
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	data[0] ^= 0xff
+	require.NoError(t, os.WriteFile(filename, data, 0600))
+
+	damaged, err := openTable(directory, tbl.tableMeta, newBlockCache(defaultBlockCacheSize))
+	require.NoError(t, err)
+	defer func() { _ = damaged.close() }()
+
+	_, _, err = damaged.get("key")
+	assert.ErrorIs(t, err, ErrorCorruptedTable)
+
+	// the footer is damaged
+	data[len(data)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(filename, data, 0600))
+
+	_, err = openTable(directory, tbl.tableMeta, newBlockCache(defaultBlockCacheSize))
+	assert.ErrorIs(t, err, ErrorCorruptedTable)
+}
+
+func TestBloomFilter(t *testing.T) {
+	t.Parallel()
+
+	var keys []string
+	for idx := range 10000 {
+		keys = append(keys, fmt.Sprintf("key_%d", idx))
+	}
+
+	filter := newBloomFilter(keys)
+	for _, key := range keys {
+		require.True(t, filter.mayContain(key))
+	}
+
+	falsePositives := 0
+	for idx := range 10000 {
+		if filter.mayContain(fmt.Sprintf("missing_%d", idx)) {
+			falsePositives++
+		}
+	}
+
+	// the expected rate is about 1% for 10 bits per key
+	assert.Less(t, falsePositives, 300)
+}
+
+func TestBlockCache(t *testing.T) {
+	t.Parallel()
+
+	cache := newBlockCache(100)
+	cache.put(blockID{table: 1}, []record{{key: "first"}}, 40)
+	cache.put(blockID{table: 2}, []record{{key: "second"}}, 40)
+
+	_, found := cache.get(blockID{table: 1})
+	assert.True(t, found)
+
+	// the least recently used block is evicted
+	cache.put(blockID{table: 3}, []record{{key: "third"}}, 40)
+	_, found = cache.get(blockID{table: 2})
+	assert.False(t, found)
+
+	records, found := cache.get(blockID{table: 1})
+	assert.True(t, found)
+	assert.Equal(t, []record{{key: "first"}}, records)
+
+	// the block which is larger than the cache isn't cached
+	cache.put(blockID{table: 4}, nil, 200)
+	_, found = cache.get(blockID{table: 4})
+	assert.False(t, found)
+	_, found = cache.get(blockID{table: 3})
+	assert.True(t, found)
+}
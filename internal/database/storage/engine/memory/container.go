@@ -0,0 +1,258 @@
+package memory
+
+import (
+	"slices"
+	"strconv"
+
+	"database-simon/internal/common"
+)
+
+// approximate size of an item of a container without strings
+const itemOverhead = 16
+
+// container is a value of a data structure, containers of the single-version
+// table are changed in place and containers of versions are copied on write
+type container interface {
+	typ() string
+	len() int
+	size() int
+	clone() container
+	// items returns the content in the order which is restored by newContainer
+	items() []string
+}
+
+// newContainer creates the container of the type from its items,
+// it returns nil for unknown types
+func newContainer(typ string, items []string) container {
+	switch typ {
+	case common.HashType:
+		hash := newHashValue().(*hashValue)
+		for idx := 0; idx+1 < len(items); idx += 2 {
+			hash.set(items[idx], items[idx+1])
+		}
+		return hash
+	case common.ListType:
+		list := newListValue().(*listValue)
+		list.push(false, items)
+		return list
+	case common.SetType:
+		set := newSetValue().(*setValue)
+		for _, member := range items {
+			set.add(member)
+		}
+		return set
+	case common.SortedSetType:
+		sortedSet := newSortedSetValue().(*sortedSetValue)
+		for idx := 0; idx+1 < len(items); idx += 2 {
+			// scores are written by items, so they are always valid
+			score, _ := strconv.ParseFloat(items[idx+1], 64)
+			sortedSet.add(items[idx], score)
+		}
+		return sortedSet
+	default:
+		return nil
+	}
+}
+
+type hashValue struct {
+	fields map[string]string
+	bytes  int
+}
+
+func newHashValue() container {
+	return &hashValue{fields: make(map[string]string)}
+}
+
+func (h *hashValue) typ() string { return common.HashType }
+func (h *hashValue) len() int    { return len(h.fields) }
+func (h *hashValue) size() int   { return h.bytes }
+
+func (h *hashValue) clone() container {
+	cloned := &hashValue{fields: make(map[string]string, len(h.fields)), bytes: h.bytes}
+	for field, value := range h.fields {
+		cloned.fields[field] = value
+	}
+
+	return cloned
+}
+
+// items returns fields with values in order of fields
+func (h *hashValue) items() []string {
+	fields := make([]string, 0, len(h.fields))
+	for field := range h.fields {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	items := make([]string, 0, 2*len(fields))
+	for _, field := range fields {
+		items = append(items, field, h.fields[field])
+	}
+
+	return items
+}
+
+// set returns true if the field is added
+func (h *hashValue) set(field, value string) bool {
+	previous, found := h.fields[field]
+	if found {
+		h.bytes += len(value) - len(previous)
+	} else {
+		h.bytes += len(field) + len(value) + itemOverhead
+	}
+
+	h.fields[field] = value
+	return !found
+}
+
+func (h *hashValue) get(field string) (string, bool) {
+	value, found := h.fields[field]
+	return value, found
+}
+
+func (h *hashValue) del(field string) bool {
+	value, found := h.fields[field]
+	if found {
+		h.bytes -= len(field) + len(value) + itemOverhead
+		delete(h.fields, field)
+	}
+
+	return found
+}
+
+type listValue struct {
+	values []string
+	bytes  int
+}
+
+func newListValue() container {
+	return &listValue{}
+}
+
+func (l *listValue) typ() string { return common.ListType }
+func (l *listValue) len() int    { return len(l.values) }
+func (l *listValue) size() int   { return l.bytes }
+
+func (l *listValue) clone() container {
+	return &listValue{values: slices.Clone(l.values), bytes: l.bytes}
+}
+
+func (l *listValue) items() []string {
+	return slices.Clone(l.values)
+}
+
+// push inserts values one by one to the head or to the tail of the list,
+// so values pushed to the head are in reversed order
+func (l *listValue) push(head bool, values []string) {
+	for _, value := range values {
+		l.bytes += len(value) + itemOverhead
+	}
+
+	if !head {
+		l.values = append(l.values, values...)
+		return
+	}
+
+	pushed := slices.Clone(values)
+	slices.Reverse(pushed)
+	l.values = append(pushed, l.values...)
+}
+
+// pop removes up to count values from the head or from the tail of the list
+// and returns them in order of removing
+func (l *listValue) pop(head bool, count int) []string {
+	count = min(count, len(l.values))
+
+	var popped []string
+	if head {
+		popped = slices.Clone(l.values[:count])
+		l.values = l.values[count:]
+	} else {
+		popped = slices.Clone(l.values[len(l.values)-count:])
+		slices.Reverse(popped)
+		l.values = l.values[:len(l.values)-count]
+	}
+
+	for _, value := range popped {
+		l.bytes -= len(value) + itemOverhead
+	}
+
+	return popped
+}
+
+// slice returns values from start to stop inclusive, negative
+// indexes are counted from the tail of the list
+func (l *listValue) slice(start, stop int) []string {
+	if start < 0 {
+		start = max(len(l.values)+start, 0)
+	}
+	if stop < 0 {
+		stop = len(l.values) + stop
+	}
+	stop = min(stop, len(l.values)-1)
+
+	if start > stop {
+		return nil
+	}
+
+	return slices.Clone(l.values[start : stop+1])
+}
+
+type setValue struct {
+	members map[string]struct{}
+	bytes   int
+}
+
+func newSetValue() container {
+	return &setValue{members: make(map[string]struct{})}
+}
+
+func (s *setValue) typ() string { return common.SetType }
+func (s *setValue) len() int    { return len(s.members) }
+func (s *setValue) size() int   { return s.bytes }
+
+func (s *setValue) clone() container {
+	cloned := &setValue{members: make(map[string]struct{}, len(s.members)), bytes: s.bytes}
+	for member := range s.members {
+		cloned.members[member] = struct{}{}
+	}
+
+	return cloned
+}
+
+// items returns members in order
+func (s *setValue) items() []string {
+	members := make([]string, 0, len(s.members))
+	for member := range s.members {
+		members = append(members, member)
+	}
+	slices.Sort(members)
+
+	return members
+}
+
+// add returns true if the member is added
+func (s *setValue) add(member string) bool {
+	if _, found := s.members[member]; found {
+		return false
+	}
+
+	s.members[member] = struct{}{}
+	s.bytes += len(member) + itemOverhead
+	return true
+}
+
+func (s *setValue) rem(member string) bool {
+	if _, found := s.members[member]; !found {
+		return false
+	}
+
+	delete(s.members, member)
+	s.bytes -= len(member) + itemOverhead
+	return true
+}
+
+func (s *setValue) has(member string) bool {
+	_, found := s.members[member]
+	return found
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+)
+
+// Increment returns the value of the key increased by delta and the deadline of the key,
+// a missing key is zero. The value isn't written, so it's written after logging it, the
+// caller must hold the lock of the key until then, see Storage.increment
+func (m *Memory) Increment(ctx context.Context, key string, delta int64) (string, time.Time, error) {
+	return m.increment(ctx, "incrby", key, func(value string, found bool) (string, error) {
+		current := int64(0)
+		if found {
+			var err error
+			if current, err = parseInteger(value); err != nil {
+				return "", err
+			}
+		}
+
+		if delta > 0 && current > math.MaxInt64-delta || delta < 0 && current < math.MinInt64-delta {
+			return "", common.ErrorOverflow
+		}
+
+		return strconv.FormatInt(current+delta, 10), nil
+	})
+}
+
+// IncrementFloat returns the value of the key increased by delta like Increment
+func (m *Memory) IncrementFloat(ctx context.Context, key string, delta float64) (string, time.Time, error) {
+	return m.increment(ctx, "incrbyfloat", key, func(value string, found bool) (string, error) {
+		current := float64(0)
+		if found {
+			var err error
+			if current, err = parseFloat(value); err != nil {
+				return "", err
+			}
+		}
+
+		result := current + delta
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			return "", common.ErrorOverflow
+		}
+
+		return strconv.FormatFloat(result, 'f', -1, 64), nil
+	})
+}
+
+func (m *Memory) increment(
+	ctx context.Context,
+	command, key string,
+	evaluate func(value string, found bool) (string, error),
+) (string, time.Time, error) {
+	txID := common.GetTxIDFromContext(ctx)
+
+	var value string
+	var deadline time.Time
+	var err error
+	m.read(key, func(partition *HashTable) bool {
+		var found bool
+		value, deadline, found, err = partition.increment(txID, key, evaluate)
+		return found
+	})
+
+	m.logger.Debug("successful "+command+" query", zap.Int64("tx", txID))
+	return value, deadline, err
+}
+
+// increment evaluates the new value of the key visible for the transaction txID under
+// the lock of the table, it reports whether the key exists in the table
+func (ht *HashTable) increment(
+	txID int64,
+	key string,
+	evaluate func(value string, found bool) (string, error),
+) (string, time.Time, bool, error) {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+
+	current := ht.alive(txID, key)
+	if current == nil {
+		value, err := evaluate("", false)
+		return value, time.Time{}, false, err
+	} else if current.container != nil {
+		return "", time.Time{}, true, common.ErrorWrongType
+	}
+
+	value, err := evaluate(current.value, true)
+	return value, current.deadline, true, err
+}
+
+// parseInteger accepts only values formatted like strconv.FormatInt,
+// so values like "+1" or "01" aren't counters
+func parseInteger(value string) (int64, error) {
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || strconv.FormatInt(number, 10) != value {
+		return 0, common.ErrorNotInteger
+	}
+
+	return number, nil
+}
+
+func parseFloat(value string) (float64, error) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, common.ErrorNotFloat
+	}
+
+	return number, nil
+}
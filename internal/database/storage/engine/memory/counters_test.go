@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+)
+
+func TestEngineIncrement(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		value string
+		delta int64
+
+		expectedValue string
+		expectedErr   error
+	}{
+		"increment missing key":       {delta: 5, expectedValue: "5"},
+		"increment integer":           {value: "10", delta: -11, expectedValue: "-1"},
+		"increment float":             {value: "1.5", delta: 1, expectedErr: common.ErrorNotInteger},
+		"increment integer with sign": {value: "+1", delta: 1, expectedErr: common.ErrorNotInteger},
+		"increment too big integer":   {value: "9223372036854775808", delta: 1, expectedErr: common.ErrorNotInteger},
+		"increment with overflow":     {value: strconv.FormatInt(math.MaxInt64, 10), delta: 1, expectedErr: common.ErrorOverflow},
+		"decrement with overflow":     {value: strconv.FormatInt(math.MinInt64+1, 10), delta: -2, expectedErr: common.ErrorOverflow},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			engine, err := NewMemory(zap.NewNop())
+			require.NoError(t, err)
+			ctx := common.ContextWithTxID(context.Background(), 1)
+			if test.value != "" {
+				engine.Set(ctx, "counter", test.value)
+			}
+
+			value, deadline, err := engine.Increment(ctx, "counter", test.delta)
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedValue, value)
+			assert.True(t, deadline.IsZero())
+
+			// the value isn't written by the engine
+			current, _ := engine.Get(ctx, "counter")
+			assert.Equal(t, test.value, current)
+		})
+	}
+}
+
+func TestEngineIncrementFloat(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	ctx := common.ContextWithTxID(context.Background(), 1)
+
+	deadline := time.Now().Add(time.Hour)
+	engine.SetWithExpiration(ctx, "counter", "10", deadline)
+	value, expiration, err := engine.IncrementFloat(ctx, "counter", 0.1)
+	require.NoError(t, err)
+	assert.Equal(t, "10.1", value)
+	assert.Equal(t, deadline, expiration)
+
+	engine.Set(ctx, "counter", "1e308")
+	_, _, err = engine.IncrementFloat(ctx, "counter", 1e308)
+	assert.Equal(t, common.ErrorOverflow, err)
+
+	engine.Set(ctx, "counter", "inf")
+	_, _, err = engine.IncrementFloat(ctx, "counter", 1)
+	assert.Equal(t, common.ErrorNotFloat, err)
+
+	_, err = engine.SAdd(ctx, "set", []string{"member"})
+	require.NoError(t, err)
+	_, _, err = engine.IncrementFloat(ctx, "set", 1)
+	assert.Equal(t, common.ErrorWrongType, err)
+}
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// EvictionPolicy chooses keys which are evicted when a partition exceeds its memory limit
+type EvictionPolicy int
+
+const (
+	// NoEviction keeps all keys, writes fail while the limit is exceeded
+	NoEviction EvictionPolicy = iota
+	// AllKeysLRU evicts the least recently used keys
+	AllKeysLRU
+	// AllKeysLFU evicts the least frequently used keys
+	AllKeysLFU
+	// VolatileTTL evicts keys with expiration which expire first,
+	// keys without expiration are kept, as in NoEviction
+	VolatileTTL
+	// AllKeysRandom evicts random keys
+	AllKeysRandom
+)
+
+const (
+	// the best key of the sample is evicted, a larger sample is more precise but slower
+	evictionSampleSize = 5
+
+	// the frequency is a logarithmic counter, so it counts millions of accesses in a byte,
+	// it's decremented each lfuDecayPeriod without access, so old popular keys are evicted
+	lfuInitialFrequency = 5
+	lfuMaxFrequency     = 255
+	lfuLogFactor        = 10
+	lfuDecayPeriod      = time.Minute
+)
+
+// evictionHooks let the storage forbid eviction on replicas, which delete
+// keys evicted by the master, and propagate evicted keys to the WAL
+type evictionHooks struct {
+	allowed func() bool
+	evicted func(key string)
+}
+
+// OutOfMemory reports that the table exceeds its limit and keys can't be evicted
+func (ht *HashTable) OutOfMemory() bool {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+
+	return ht.maxSize > 0 && ht.size > ht.maxSize
+}
+
+// evict removes keys chosen by the policy while the table exceeds its limit,
+// the key which is written at the moment is never evicted
+func (ht *HashTable) evict(written string) {
+	if ht.maxSize == 0 || ht.policy == NoEviction || ht.size <= ht.maxSize {
+		return
+	}
+
+	var hooks *evictionHooks
+	if ht.hooks != nil {
+		hooks = ht.hooks.Load()
+	}
+
+	if hooks != nil && !hooks.allowed() {
+		return
+	}
+
+	for ht.size > ht.maxSize {
+		key, found := ht.evictionCandidate(written)
+		if !found {
+			return
+		}
+
+		ht.remove(key)
+		if hooks != nil {
+			hooks.evicted(key)
+		}
+	}
+}
+
+// evictionCandidate samples keys in random order of the map, volatile-ttl skips keys
+// without expiration, so it can check all keys if most of them don't expire
+func (ht *HashTable) evictionCandidate(written string) (string, bool) {
+	var candidate string
+	var best *version
+	sampled := 0
+	currentTime := now()
+	for key, head := range ht.data {
+		if key == written || (ht.policy == VolatileTTL && head.deadline.IsZero()) {
+			continue
+		}
+
+		if best == nil || ht.isBetterCandidate(head, best, currentTime) {
+			candidate, best = key, head
+		}
+
+		if sampled++; sampled == evictionSampleSize || ht.policy == AllKeysRandom {
+			break
+		}
+	}
+
+	return candidate, best != nil
+}
+
+func (ht *HashTable) isBetterCandidate(v, best *version, currentTime time.Time) bool {
+	switch ht.policy {
+	case AllKeysLRU:
+		return v.accessed.Load() < best.accessed.Load()
+	case AllKeysLFU:
+		frequency, bestFrequency := v.decayedFrequency(currentTime), best.decayedFrequency(currentTime)
+		return frequency < bestFrequency || (frequency == bestFrequency && v.accessed.Load() < best.accessed.Load())
+	case VolatileTTL:
+		return v.deadline.Before(best.deadline)
+	default:
+		return false
+	}
+}
+
+// inherit keeps statistics of the key when its version is replaced
+func (v *version) inherit(previous *version) {
+	if previous == nil {
+		v.frequency.Store(lfuInitialFrequency)
+	} else {
+		v.frequency.Store(previous.frequency.Load())
+	}
+
+	v.touch()
+}
+
+// touch updates statistics of access, it's called under the read lock,
+// so concurrent accesses can lose an increment of the approximate counter
+func (v *version) touch() {
+	currentTime := now()
+	frequency := v.decayedFrequency(currentTime)
+	if frequency < lfuMaxFrequency {
+		// the probability of increment is lower for frequently used keys
+		base := max(0, float64(frequency)-lfuInitialFrequency)
+		if rand.Float64() < 1/(base*lfuLogFactor+1) { // nolint : G404: Use of weak random number generator
+			frequency++
+		}
+	}
+
+	v.frequency.Store(frequency)
+	v.accessed.Store(currentTime.UnixNano())
+}
+
+func (v *version) decayedFrequency(currentTime time.Time) uint32 {
+	frequency := v.frequency.Load()
+	accessed := v.accessed.Load()
+	if accessed == 0 {
+		return frequency
+	}
+
+	periods := uint32(min(currentTime.Sub(time.Unix(0, accessed))/lfuDecayPeriod, lfuMaxFrequency)) // nolint : G115: integer overflow conversion
+	if periods >= frequency {
+		return 0
+	}
+
+	return frequency - periods
+}
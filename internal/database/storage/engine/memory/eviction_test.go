@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// entrySize is the size of a key of one byte with a value of ten bytes
+const entrySize = 1 + keyOverhead + 10 + versionOverhead
+
+func TestHashTableSize(t *testing.T) {
+	t.Parallel()
+
+	table := NewHashTable()
+	table.Set(1, "a", "0123456789")
+	table.Set(2, "b", "0123456789")
+	assert.Equal(t, 2*entrySize, table.size)
+
+	table.Set(3, "a", "01234")
+	assert.Equal(t, 2*entrySize-5, table.size)
+
+	table.Del(4, "a")
+	table.Del(5, "b")
+	assert.Zero(t, table.size)
+
+	// versions are counted until garbage collection
+	versioned := NewVersionedHashTable()
+	versioned.Set(1, "a", "0123456789")
+	versioned.Set(2, "a", "0123456789")
+	versioned.Del(3, "a")
+	assert.Equal(t, entrySize+2*versionOverhead+10, versioned.size)
+
+	versioned.CollectGarbage(3)
+	assert.Zero(t, versioned.size)
+}
+
+func TestHashTableEviction(t *testing.T) {
+	t.Parallel()
+
+	deadline := time.Now().Add(time.Hour)
+	tests := map[string]struct {
+		policy              EvictionPolicy
+		prepare             func(*HashTable)
+		expectedEvicted     []string
+		expectedOutOfMemory bool
+	}{
+		"no eviction": {
+			policy:              NoEviction,
+			expectedOutOfMemory: true,
+		},
+		"least recently used": {
+			policy: AllKeysLRU,
+			prepare: func(table *HashTable) {
+				table.data["a"].accessed.Store(3)
+				table.data["b"].accessed.Store(1)
+				table.data["c"].accessed.Store(2)
+			},
+			expectedEvicted: []string{"b"},
+		},
+		"least frequently used": {
+			policy: AllKeysLFU,
+			prepare: func(table *HashTable) {
+				table.data["a"].frequency.Store(10)
+				table.data["b"].frequency.Store(20)
+				table.data["c"].frequency.Store(3)
+			},
+			expectedEvicted: []string{"c"},
+		},
+		"frequency decays without access": {
+			policy: AllKeysLFU,
+			prepare: func(table *HashTable) {
+				table.data["a"].frequency.Store(10)
+				table.data["b"].frequency.Store(20)
+				table.data["b"].accessed.Store(time.Now().Add(-time.Hour).UnixNano())
+				table.data["c"].frequency.Store(15)
+			},
+			expectedEvicted: []string{"b"},
+		},
+		"nearest expiration": {
+			policy: VolatileTTL,
+			prepare: func(table *HashTable) {
+				table.SetWithExpiration(1, "b", "0123456789", deadline.Add(time.Minute))
+				table.SetWithExpiration(1, "c", "0123456789", deadline)
+			},
+			expectedEvicted: []string{"c"},
+		},
+		"no keys with expiration": {
+			policy:              VolatileTTL,
+			expectedOutOfMemory: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			table := NewHashTable()
+			table.maxSize = 3 * entrySize
+			table.policy = test.policy
+			for _, key := range []string{"a", "b", "c"} {
+				table.Set(1, key, "0123456789")
+			}
+
+			if test.prepare != nil {
+				test.prepare(table)
+			}
+
+			assert.False(t, table.OutOfMemory())
+			table.Set(2, "d", "0123456789")
+			assert.Equal(t, test.expectedOutOfMemory, table.OutOfMemory())
+
+			for _, key := range []string{"a", "b", "c", "d"} {
+				_, found := table.data[key]
+				assert.Equal(t, slices.Contains(test.expectedEvicted, key), !found, key)
+			}
+		})
+	}
+}
+
+func TestHashTableRandomEviction(t *testing.T) {
+	t.Parallel()
+
+	table := NewHashTable()
+	table.maxSize = 3 * entrySize
+	table.policy = AllKeysRandom
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		table.Set(1, key, "0123456789")
+		_, found := table.data[key]
+		assert.True(t, found, "written key is never evicted")
+	}
+
+	assert.Len(t, table.data, 3)
+	assert.False(t, table.OutOfMemory())
+}
+
+func TestMemoryMaxMemory(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewMemory(zap.NewNop(), WithMVCC(), WithMaxMemory(1<<20, AllKeysLRU))
+	assert.Error(t, err)
+
+	// the limit is divided between partitions
+	engine, err := NewMemory(zap.NewNop(), WithPartitions(4), WithMaxMemory(4*entrySize, NoEviction))
+	assert.NoError(t, err)
+	for _, partition := range engine.partitions {
+		assert.Equal(t, entrySize, partition.maxSize)
+	}
+
+	assert.False(t, engine.OutOfMemory("a"))
+	engine.partition("a").Set(1, "a", "0123456789")
+	assert.False(t, engine.OutOfMemory("a"))
+	engine.partition("a").Set(1, "a", "01234567890")
+	assert.True(t, engine.OutOfMemory("a"))
+}
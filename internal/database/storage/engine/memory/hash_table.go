@@ -2,42 +2,367 @@ package memory
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+var now = time.Now
+
+const (
+	// approximate sizes of a map entry and a version without strings
+	keyOverhead     = 48
+	versionOverhead = 64
+)
+
+// version is a value of the key written by the transaction txID, in the
+// multi-version mode versions are chained from the newest to the oldest
+type version struct {
+	value string
+	// container keeps a value of a data structure, it's nil for strings
+	container container
+	deadline  time.Time
+	txID      int64
+	deleted   bool
+	previous  *version
+
+	// statistics of access for eviction, they are updated under the read lock
+	accessed  atomic.Int64
+	frequency atomic.Uint32
+}
+
+func (v *version) isExpired() bool {
+	return !v.deadline.IsZero() && !now().Before(v.deadline)
+}
+
+func (v *version) size() int {
+	if v.container != nil {
+		return v.container.size() + versionOverhead
+	}
+
+	return len(v.value) + versionOverhead
+}
+
 // HashTable ...
 type HashTable struct {
 	mu   sync.RWMutex
-	data map[string]string
+	data map[string]*version
+	mvcc bool
+	// index keeps keys in order for range queries, it's set for ordered engine
+	index *skipList
+
+	// size is an approximate size of keys with their versions, keys are
+	// evicted by the policy when the size exceeds maxSize, zero means no limit
+	size    int
+	maxSize int
+	policy  EvictionPolicy
+	// hooks are shared by partitions of the engine, they're set by the storage
+	hooks *atomic.Pointer[evictionHooks]
 }
 
 // NewHashTable ...
 func NewHashTable() *HashTable {
 	return &HashTable{
-		data: make(map[string]string),
+		data: make(map[string]*version),
+	}
+}
+
+// NewVersionedHashTable creates a table keeping versions of keys for snapshot reads
+func NewVersionedHashTable() *HashTable {
+	return &HashTable{
+		data: make(map[string]*version),
+		mvcc: true,
 	}
 }
 
 // Set ...
-func (ht *HashTable) Set(key, value string) {
+func (ht *HashTable) Set(txID int64, key, value string) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	ht.write(key, &version{value: value, txID: txID})
+	ht.evict(key)
+}
+
+// SetWithExpiration ...
+func (ht *HashTable) SetWithExpiration(txID int64, key, value string, deadline time.Time) {
 	ht.mu.Lock()
 	defer ht.mu.Unlock()
 
-	ht.data[key] = value
+	ht.write(key, &version{value: value, deadline: deadline, txID: txID})
+	ht.evict(key)
 }
 
-// Get ...
-func (ht *HashTable) Get(key string) (string, bool) {
+// Get returns a value visible for the transaction txID
+func (ht *HashTable) Get(txID int64, key string) (string, bool) {
 	ht.mu.RLock()
-	defer ht.mu.RUnlock()
+	current := ht.visible(txID, key)
+	expired := current != nil && current.isExpired()
+	ht.mu.RUnlock()
 
-	value, found := ht.data[key]
-	return value, found
+	if expired {
+		ht.deleteIfExpired(key)
+		return "", false
+	} else if current == nil || current.container != nil {
+		return "", false
+	}
+
+	if ht.maxSize > 0 {
+		current.touch()
+	}
+
+	return current.value, true
 }
 
 // Del ...
-func (ht *HashTable) Del(key string) {
+func (ht *HashTable) Del(txID int64, key string) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	if !ht.mvcc {
+		ht.remove(key)
+		return
+	}
+
+	if _, found := ht.data[key]; found {
+		ht.write(key, &version{txID: txID, deleted: true})
+	}
+}
+
+// Expire ...
+func (ht *HashTable) Expire(txID int64, key string, deadline time.Time) bool {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	current := ht.alive(txID, key)
+	if current == nil {
+		return false
+	}
+
+	ht.write(key, &version{value: current.value, container: current.container, deadline: deadline, txID: txID})
+	return true
+}
+
+// Expiration returns a deadline of the key, zero deadline means that
+// the key has no associated expiration
+func (ht *HashTable) Expiration(txID int64, key string) (time.Time, bool) {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+
+	current := ht.alive(txID, key)
+	if current == nil {
+		return time.Time{}, false
+	}
+
+	return current.deadline, true
+}
+
+// Persist ...
+func (ht *HashTable) Persist(txID int64, key string) bool {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	current := ht.alive(txID, key)
+	if current == nil {
+		return false
+	}
+
+	ht.write(key, &version{value: current.value, container: current.container, txID: txID})
+	return true
+}
+
+// forEach calls action for keys visible for the transaction txID
+func (ht *HashTable) forEach(txID int64, action func(key string, current *version)) {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+
+	for key := range ht.data {
+		if current := ht.alive(txID, key); current != nil {
+			action(key, current)
+		}
+	}
+}
+
+// Range calls action in order of keys for string keys visible for the transaction txID
+// from from up to to (exclusive, empty to means no bound), limit restricts the number
+// of keys if it's positive. Keys of data structures are skipped like by Get, because
+// they have no string values. Only ordered table supports ranges
+func (ht *HashTable) Range(txID int64, from, to string, limit int, action func(key, value string)) {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+
+	if ht.index == nil {
+		return
+	}
+
+	found := 0
+	for node := ht.index.seek(from); node != nil && (to == "" || node.key < to); node = node.next[0] {
+		if limit > 0 && found == limit {
+			return
+		}
+
+		if current := ht.alive(txID, node.key); current != nil && current.container == nil {
+			action(node.key, current.value)
+			found++
+		}
+	}
+}
+
+// DeleteExpired checks up to sampleSize keys with expiration and deletes
+// expired ones, it returns numbers of deleted and checked keys
+func (ht *HashTable) DeleteExpired(sampleSize int) (int, int) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	expired, sampled := 0, 0
+	for key, head := range ht.data {
+		if sampled == sampleSize {
+			break
+		} else if head.deadline.IsZero() {
+			continue
+		}
+
+		sampled++
+		if ht.isRemovable(head) {
+			ht.remove(key)
+			expired++
+		}
+	}
+
+	return expired, sampled
+}
+
+// CollectGarbage removes versions which are invisible for all snapshots
+// starting from oldestSnapshot
+func (ht *HashTable) CollectGarbage(oldestSnapshot int64) int {
+	if !ht.mvcc {
+		return 0
+	}
+
 	ht.mu.Lock()
 	defer ht.mu.Unlock()
 
+	collected := 0
+	for key, head := range ht.data {
+		current := head
+		for current != nil && current.txID > oldestSnapshot {
+			current = current.previous
+		}
+
+		if current == nil {
+			continue
+		}
+
+		// current is the newest version visible for the oldest snapshot,
+		// older versions are not visible for anybody
+		for older := current.previous; older != nil; older = older.previous {
+			ht.size -= older.size()
+			collected++
+		}
+		current.previous = nil
+
+		if current == head && current.deleted {
+			ht.remove(key)
+			collected++
+		}
+	}
+
+	return collected
+}
+
+// write installs a new version of the key, versions are ordered by transactions
+// because transactions can be applied not in order of their identifiers
+func (ht *HashTable) write(key string, newVersion *version) {
+	head := ht.data[key]
+	ht.size += newVersion.size()
+	if ht.maxSize > 0 {
+		newVersion.inherit(head)
+	}
+
+	if !ht.mvcc || head == nil || head.txID < newVersion.txID {
+		if ht.mvcc {
+			newVersion.previous = head
+		} else if head != nil {
+			ht.size -= head.size()
+		}
+		if head == nil {
+			ht.size += len(key) + keyOverhead
+			if ht.index != nil {
+				ht.index.insert(key)
+			}
+		}
+		ht.data[key] = newVersion
+		return
+	}
+
+	if head.txID == newVersion.txID {
+		ht.size -= head.size()
+		newVersion.previous = head.previous
+		ht.data[key] = newVersion
+		return
+	}
+
+	current := head
+	for current.previous != nil && current.previous.txID > newVersion.txID {
+		current = current.previous
+	}
+
+	if current.previous != nil && current.previous.txID == newVersion.txID {
+		ht.size -= current.previous.size()
+		newVersion.previous = current.previous.previous
+	} else {
+		newVersion.previous = current.previous
+	}
+	current.previous = newVersion
+}
+
+// visible returns the newest version written before the snapshot txID
+func (ht *HashTable) visible(txID int64, key string) *version {
+	current := ht.data[key]
+	if ht.mvcc {
+		for current != nil && current.txID > txID {
+			current = current.previous
+		}
+	}
+
+	if current == nil || current.deleted {
+		return nil
+	}
+
+	return current
+}
+
+func (ht *HashTable) alive(txID int64, key string) *version {
+	current := ht.visible(txID, key)
+	if current == nil || current.isExpired() {
+		return nil
+	}
+
+	return current
+}
+
+func (ht *HashTable) remove(key string) {
+	for current := ht.data[key]; current != nil; current = current.previous {
+		ht.size -= current.size()
+	}
+	ht.size -= len(key) + keyOverhead
+
 	delete(ht.data, key)
+	if ht.index != nil {
+		ht.index.remove(key)
+	}
+}
+
+func (ht *HashTable) deleteIfExpired(key string) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	if head, found := ht.data[key]; found && ht.isRemovable(head) {
+		ht.remove(key)
+	}
+}
+
+// isRemovable checks that the expired key can be deleted, in the multi-version mode
+// older versions can be still visible for snapshots, so they are collected by GC
+func (ht *HashTable) isRemovable(head *version) bool {
+	return head.isExpired() && head.previous == nil
 }
@@ -2,6 +2,7 @@ package memory
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -18,12 +19,9 @@ func TestNewHashTable(t *testing.T) {
 func TestHashTableSet(t *testing.T) {
 	t.Parallel()
 
-	table := &HashTable{
-		data: map[string]string{
-			"key_1": "value_1",
-			"key_2": "value_2",
-		},
-	}
+	table := NewHashTable()
+	table.Set(1, "key_1", "value_1")
+	table.Set(1, "key_2", "value_2")
 
 	tests := map[string]struct {
 		key           string
@@ -46,8 +44,8 @@ func TestHashTableSet(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			table.Set(test.key, test.value)
-			value, found := table.Get(test.key)
+			table.Set(2, test.key, test.value)
+			value, found := table.Get(2, test.key)
 			assert.Equal(t, test.expectedValue, value)
 			assert.True(t, found)
 		})
@@ -57,12 +55,9 @@ func TestHashTableSet(t *testing.T) {
 func TestHashTableGet(t *testing.T) {
 	t.Parallel()
 
-	table := &HashTable{
-		data: map[string]string{
-			"key_1": "value_1",
-			"key_2": "value_2",
-		},
-	}
+	table := NewHashTable()
+	table.Set(1, "key_1", "value_1")
+	table.Set(1, "key_2", "value_2")
 
 	tests := map[string]struct {
 		key           string
@@ -85,7 +80,7 @@ func TestHashTableGet(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			value, found := table.Get(test.key)
+			value, found := table.Get(2, test.key)
 			assert.Equal(t, test.expectedValue, value)
 			assert.Equal(t, test.found, found)
 		})
@@ -95,12 +90,9 @@ func TestHashTableGet(t *testing.T) {
 func TestHashTableDel(t *testing.T) {
 	t.Parallel()
 
-	table := &HashTable{
-		data: map[string]string{
-			"key_1": "value_1",
-			"key_2": "value_2",
-		},
-	}
+	table := NewHashTable()
+	table.Set(1, "key_1", "value_1")
+	table.Set(1, "key_2", "value_2")
 
 	tests := map[string]struct {
 		key string
@@ -117,10 +109,218 @@ func TestHashTableDel(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			table.Del(test.key)
-			value, found := table.Get(test.key)
+			table.Del(2, test.key)
+			value, found := table.Get(2, test.key)
 			assert.Equal(t, "", value)
 			assert.False(t, found)
 		})
 	}
 }
+
+func TestHashTableSetWithExpiration(t *testing.T) {
+	t.Parallel()
+
+	table := NewHashTable()
+	table.SetWithExpiration(2, "key_1", "value_1", time.Now().Add(time.Hour))
+	table.SetWithExpiration(2, "key_2", "value_2", time.Now().Add(-time.Second))
+
+	value, found := table.Get(2, "key_1")
+	assert.True(t, found)
+	assert.Equal(t, "value_1", value)
+
+	value, found = table.Get(2, "key_2")
+	assert.False(t, found)
+	assert.Empty(t, value)
+
+	// expired key is deleted lazily on access
+	assert.NotContains(t, table.data, "key_2")
+
+	// set without expiration removes previous deadline
+	table.Set(2, "key_1", "new_value")
+	deadline, found := table.Expiration(2, "key_1")
+	assert.True(t, found)
+	assert.True(t, deadline.IsZero())
+}
+
+func TestHashTableExpire(t *testing.T) {
+	t.Parallel()
+
+	deadline := time.Now().Add(time.Hour)
+
+	tests := map[string]struct {
+		key      string
+		deadline time.Time
+
+		expectedFound    bool
+		expectedDeadline time.Time
+	}{
+		"expire not-existing key": {
+			key:      "key_3",
+			deadline: deadline,
+		},
+		"expire existing key": {
+			key:              "key_1",
+			deadline:         deadline,
+			expectedFound:    true,
+			expectedDeadline: deadline,
+		},
+		"expire existing key in the past": {
+			key:           "key_2",
+			deadline:      time.Now().Add(-time.Second),
+			expectedFound: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			table := NewHashTable()
+			table.Set(2, "key_1", "value_1")
+			table.Set(2, "key_2", "value_2")
+
+			found := table.Expire(2, test.key, test.deadline)
+			assert.Equal(t, test.expectedFound, found)
+
+			deadline, found := table.Expiration(2, test.key)
+			assert.Equal(t, test.expectedDeadline, deadline)
+			assert.Equal(t, !test.expectedDeadline.IsZero(), found)
+		})
+	}
+}
+
+func TestHashTablePersist(t *testing.T) {
+	t.Parallel()
+
+	table := NewHashTable()
+	table.SetWithExpiration(2, "key_1", "value_1", time.Now().Add(time.Hour))
+
+	assert.False(t, table.Persist(2, "key_2"))
+	assert.True(t, table.Persist(2, "key_1"))
+
+	deadline, found := table.Expiration(2, "key_1")
+	assert.True(t, found)
+	assert.True(t, deadline.IsZero())
+}
+
+func TestHashTableDeleteExpired(t *testing.T) {
+	t.Parallel()
+
+	table := NewHashTable()
+	table.Set(2, "key_1", "value_1")
+	table.SetWithExpiration(2, "key_2", "value_2", time.Now().Add(time.Hour))
+	table.SetWithExpiration(2, "key_3", "value_3", time.Now().Add(-time.Second))
+	table.SetWithExpiration(2, "key_4", "value_4", time.Now().Add(-time.Second))
+
+	expired, sampled := table.DeleteExpired(10)
+	assert.Equal(t, 2, expired)
+	assert.Equal(t, 3, sampled)
+	assert.Len(t, table.data, 2)
+
+	expired, sampled = table.DeleteExpired(10)
+	assert.Equal(t, 0, expired)
+	assert.Equal(t, 1, sampled)
+}
+
+func TestVersionedHashTableSnapshots(t *testing.T) {
+	t.Parallel()
+
+	table := NewVersionedHashTable()
+	table.Set(1, "key_1", "value_1")
+	table.Set(3, "key_1", "value_3")
+	table.Del(5, "key_1")
+	// transaction with smaller identifier can be applied later
+	table.Set(2, "key_1", "value_2")
+
+	tests := map[string]struct {
+		txID          int64
+		expectedValue string
+		expectedFound bool
+	}{
+		"read before first version": {
+			txID: 0,
+		},
+		"read first version": {
+			txID:          1,
+			expectedValue: "value_1",
+			expectedFound: true,
+		},
+		"read version applied out of order": {
+			txID:          2,
+			expectedValue: "value_2",
+			expectedFound: true,
+		},
+		"read between versions": {
+			txID:          4,
+			expectedValue: "value_3",
+			expectedFound: true,
+		},
+		"read deleted version": {
+			txID: 6,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			value, found := table.Get(test.txID, "key_1")
+			assert.Equal(t, test.expectedValue, value)
+			assert.Equal(t, test.expectedFound, found)
+		})
+	}
+}
+
+func TestVersionedHashTableExpiration(t *testing.T) {
+	t.Parallel()
+
+	table := NewVersionedHashTable()
+	table.Set(1, "key_1", "value_1")
+	assert.True(t, table.Expire(2, "key_1", time.Now().Add(-time.Second)))
+
+	value, found := table.Get(1, "key_1")
+	assert.True(t, found)
+	assert.Equal(t, "value_1", value)
+
+	_, found = table.Get(2, "key_1")
+	assert.False(t, found)
+
+	// expired key is kept while older versions exist
+	expired, sampled := table.DeleteExpired(10)
+	assert.Equal(t, 0, expired)
+	assert.Equal(t, 1, sampled)
+
+	assert.Equal(t, 1, table.CollectGarbage(2))
+	expired, _ = table.DeleteExpired(10)
+	assert.Equal(t, 1, expired)
+	assert.Empty(t, table.data)
+}
+
+func TestVersionedHashTableCollectGarbage(t *testing.T) {
+	t.Parallel()
+
+	table := NewVersionedHashTable()
+	table.Set(1, "key_1", "value_1")
+	table.Set(2, "key_1", "value_2")
+	table.Set(4, "key_1", "value_4")
+	table.Set(1, "key_2", "value_1")
+	table.Del(2, "key_2")
+
+	assert.Equal(t, 0, table.CollectGarbage(1))
+
+	// versions visible for the snapshot 3 are kept
+	assert.Equal(t, 3, table.CollectGarbage(3))
+	value, found := table.Get(3, "key_1")
+	assert.True(t, found)
+	assert.Equal(t, "value_2", value)
+	assert.NotContains(t, table.data, "key_2")
+
+	assert.Equal(t, 1, table.CollectGarbage(4))
+	value, found = table.Get(4, "key_1")
+	assert.True(t, found)
+	assert.Equal(t, "value_4", value)
+	assert.Nil(t, table.data["key_1"].previous)
+
+	// single-version table has no garbage
+	assert.Equal(t, 0, NewHashTable().CollectGarbage(10))
+}
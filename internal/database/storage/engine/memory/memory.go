@@ -2,18 +2,44 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
 
 	"database-simon/internal/common"
 )
 
+const (
+	activeExpirationInterval   = 100 * time.Millisecond
+	activeExpirationSampleSize = 20
+	// a partition is checked again while more than 1/4 of sampled keys are expired
+	activeExpirationThreshold = 4
+)
+
 // Memory ...
 type Memory struct {
-	partitions []*HashTable
-	logger     *zap.Logger
+	// partitions are the current layout, while resharding is in progress keys are
+	// migrated from the previous layout, a key is in one of layouts at any moment
+	layoutMutex sync.RWMutex
+	partitions  []*HashTable
+	previous    []*HashTable
+	// scans hold the read lock, so resharding doesn't start during a scan
+	reshardingMutex sync.RWMutex
+
+	partitionsNumber int
+	loadFactor       int
+	mvcc             bool
+	ordered          bool
+	maxMemory        int
+	evictionPolicy   EvictionPolicy
+	evictionHooks    atomic.Pointer[evictionHooks]
+	logger           *zap.Logger
 }
 
 // NewMemory ...
@@ -23,47 +49,78 @@ func NewMemory(logger *zap.Logger, options ...EngineOption) (*Memory, error) {
 	}
 
 	memoryEngine := &Memory{
-		logger: logger,
+		partitionsNumber: 1,
+		logger:           logger,
 	}
 
 	for _, option := range options {
 		option(memoryEngine)
 	}
 
-	if len(memoryEngine.partitions) == 0 {
-		memoryEngine.partitions = make([]*HashTable, 1)
-		memoryEngine.partitions[0] = NewHashTable()
+	if memoryEngine.partitionsNumber <= 0 {
+		memoryEngine.partitionsNumber = 1
 	}
 
+	// evicted keys are removed with all versions, so snapshots would see partial data
+	if memoryEngine.mvcc && memoryEngine.maxMemory > 0 {
+		return nil, errors.New("memory limit is not supported in multi-version mode")
+	}
+
+	memoryEngine.partitions = memoryEngine.newPartitions(memoryEngine.partitionsNumber)
+
 	return memoryEngine, nil
 }
 
+// Start runs active expiration of keys and resharding in background
+func (m *Memory) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(activeExpirationInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.deleteExpired(ctx)
+			}
+		}
+	}()
+
+	go m.runResharding(ctx)
+}
+
 // Set ...
 func (m *Memory) Set(ctx context.Context, key, value string) {
-	partitionIdx := 0
-	if len(m.partitions) > 1 {
-		partitionIdx = m.partitionIdx(key)
-	}
+	txID := common.GetTxIDFromContext(ctx)
+	m.write(key, func(partition *HashTable) {
+		partition.Set(txID, key, value)
+	})
 
-	partition := m.partitions[partitionIdx]
-	partition.Set(key, value)
+	m.logger.Debug("successful set query", zap.Int64("tx", txID))
+}
 
-	//m.hashTable.Set(key, value)
+// SetWithExpiration ...
+func (m *Memory) SetWithExpiration(ctx context.Context, key, value string, deadline time.Time) {
 	txID := common.GetTxIDFromContext(ctx)
-	m.logger.Debug("successful get query", zap.Int64("tx", txID))
+	m.write(key, func(partition *HashTable) {
+		partition.SetWithExpiration(txID, key, value, deadline)
+	})
+
+	m.logger.Debug("successful set query", zap.Int64("tx", txID))
 }
 
 // Get ...
 func (m *Memory) Get(ctx context.Context, key string) (string, bool) {
-	partitionIdx := 0
-	if len(m.partitions) > 1 {
-		partitionIdx = m.partitionIdx(key)
-	}
+	txID := common.GetTxIDFromContext(ctx)
 
-	partition := m.partitions[partitionIdx]
-	value, found := partition.Get(key)
+	var value string
+	var found bool
+	m.read(key, func(partition *HashTable) bool {
+		value, found = partition.Get(txID, key)
+		return found
+	})
 
-	txID := common.GetTxIDFromContext(ctx)
 	m.logger.Debug("successful get query", zap.Int64("tx", txID))
 
 	return value, found
@@ -71,20 +128,203 @@ func (m *Memory) Get(ctx context.Context, key string) (string, bool) {
 
 // Del ...
 func (m *Memory) Del(ctx context.Context, key string) {
-	partitionIdx := 0
-	if len(m.partitions) > 1 {
-		partitionIdx = m.partitionIdx(key)
-	}
+	txID := common.GetTxIDFromContext(ctx)
+	m.write(key, func(partition *HashTable) {
+		partition.Del(txID, key)
+	})
 
-	partition := m.partitions[partitionIdx]
-	partition.Del(key)
+	m.logger.Debug("successful del query", zap.Int64("tx", txID))
+}
 
+// Expire ...
+func (m *Memory) Expire(ctx context.Context, key string, deadline time.Time) bool {
 	txID := common.GetTxIDFromContext(ctx)
-	m.logger.Debug("successful del query", zap.Int64("tx", txID))
+
+	var found bool
+	m.write(key, func(partition *HashTable) {
+		found = partition.Expire(txID, key, deadline)
+	})
+
+	m.logger.Debug("successful expire query", zap.Int64("tx", txID))
+
+	return found
+}
+
+// Expiration ...
+func (m *Memory) Expiration(ctx context.Context, key string) (time.Time, bool) {
+	txID := common.GetTxIDFromContext(ctx)
+
+	var deadline time.Time
+	var found bool
+	m.read(key, func(partition *HashTable) bool {
+		deadline, found = partition.Expiration(txID, key)
+		return found
+	})
+
+	m.logger.Debug("successful expiration query", zap.Int64("tx", txID))
+
+	return deadline, found
+}
+
+// Persist ...
+func (m *Memory) Persist(ctx context.Context, key string) bool {
+	txID := common.GetTxIDFromContext(ctx)
+
+	var found bool
+	m.write(key, func(partition *HashTable) {
+		found = partition.Persist(txID, key)
+	})
+
+	m.logger.Debug("successful persist query", zap.Int64("tx", txID))
+
+	return found
+}
+
+// OutOfMemory reports that the partition of the key exceeds its memory limit
+// and keys can't be evicted, so writes which add data must be rejected
+func (m *Memory) OutOfMemory(key string) bool {
+	m.layoutMutex.RLock()
+	defer m.layoutMutex.RUnlock()
+
+	return m.partition(key).OutOfMemory()
+}
+
+// OnEviction sets hooks of eviction, keys are evicted only while allowed reports true
+// and evicted is called for each evicted key under the lock of its partition
+func (m *Memory) OnEviction(allowed func() bool, evicted func(key string)) {
+	m.evictionHooks.Store(&evictionHooks{allowed: allowed, evicted: evicted})
 }
 
-func (m *Memory) partitionIdx(key string) int {
+// Ordered reports that the engine supports range queries
+func (m *Memory) Ordered() bool {
+	return m.ordered
+}
+
+// Range calls action in order of keys for keys from from up to to (exclusive,
+// empty to means no bound), limit restricts the number of keys if it's positive
+func (m *Memory) Range(ctx context.Context, from, to string, limit int, action func(key, value string)) {
+	txID := common.GetTxIDFromContext(ctx)
+
+	m.reshardingMutex.RLock()
+	defer m.reshardingMutex.RUnlock()
+
+	// each partition is ordered, so the first keys of all partitions are merged
+	type entry struct {
+		key   string
+		value string
+	}
+
+	var entries []entry
+	partitions := m.tables()
+	for _, partition := range partitions {
+		partition.Range(txID, from, to, limit, func(key, value string) {
+			entries = append(entries, entry{key: key, value: value})
+		})
+	}
+
+	if len(partitions) > 1 {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].key < entries[j].key
+		})
+
+		// a key migrated during the scan can be found in both layouts,
+		// the current layout is scanned later, so its value is newer
+		unique := entries[:0]
+		for idx := range entries {
+			if idx+1 < len(entries) && entries[idx+1].key == entries[idx].key {
+				continue
+			}
+			unique = append(unique, entries[idx])
+		}
+		entries = unique
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	for _, entry := range entries {
+		action(entry.key, entry.value)
+	}
+
+	m.logger.Debug("successful range query", zap.Int64("tx", txID), zap.Int("keys", len(entries)))
+}
+
+// CollectGarbage removes versions which are not visible for any snapshot
+// starting from oldestSnapshot, it does nothing for single-version engine
+func (m *Memory) CollectGarbage(ctx context.Context, oldestSnapshot int64) {
+	collected := 0
+	for _, partition := range m.tables() {
+		if ctx.Err() != nil {
+			break
+		}
+		collected += partition.CollectGarbage(oldestSnapshot)
+	}
+
+	if collected != 0 {
+		m.logger.Debug("collected garbage versions", zap.Int("versions", collected), zap.Int64("snapshot", oldestSnapshot))
+	}
+}
+
+func (m *Memory) deleteExpired(ctx context.Context) {
+	for _, partition := range m.tables() {
+		for ctx.Err() == nil {
+			expired, sampled := partition.DeleteExpired(activeExpirationSampleSize)
+			if expired*activeExpirationThreshold <= sampled {
+				break
+			}
+		}
+	}
+}
+
+// read calls action for the partition of the key in the previous layout and then
+// in the current one until action finds the key, keys are moved only from the
+// previous layout to the current one, so the key isn't missed during migration
+func (m *Memory) read(key string, action func(*HashTable) bool) {
+	m.layoutMutex.RLock()
+	defer m.layoutMutex.RUnlock()
+
+	if m.previous != nil && action(partitionOf(m.previous, key)) {
+		return
+	}
+
+	action(m.partition(key))
+}
+
+// write calls action for the partition of the key in the current layout,
+// the key is migrated from the previous layout before
+func (m *Memory) write(key string, action func(*HashTable)) {
+	m.layoutMutex.RLock()
+	defer m.layoutMutex.RUnlock()
+
+	partition := m.partition(key)
+	if m.previous != nil {
+		partitionOf(m.previous, key).migrate(key, partition)
+	}
+
+	action(partition)
+}
+
+// tables returns partitions of both layouts, the previous layout goes first
+func (m *Memory) tables() []*HashTable {
+	m.layoutMutex.RLock()
+	defer m.layoutMutex.RUnlock()
+
+	tables := make([]*HashTable, 0, len(m.previous)+len(m.partitions))
+	tables = append(tables, m.previous...)
+	return append(tables, m.partitions...)
+}
+
+func (m *Memory) partition(key string) *HashTable {
+	return partitionOf(m.partitions, key)
+}
+
+func partitionOf(partitions []*HashTable, key string) *HashTable {
+	if len(partitions) == 1 {
+		return partitions[0]
+	}
+
 	hash := fnv.New32a()
 	_, _ = hash.Write([]byte(key))
-	return int(hash.Sum32()) % len(m.partitions)
+	return partitions[int(hash.Sum32())%len(partitions)]
 }
@@ -6,9 +6,39 @@ type EngineOption func(memory *Memory)
 // WithPartitions ...
 func WithPartitions(partitionsNumber int) EngineOption {
 	return func(engine *Memory) {
-		engine.partitions = make([]*HashTable, partitionsNumber)
-		for i := 0; i < partitionsNumber; i++ {
-			engine.partitions[i] = NewHashTable()
-		}
+		engine.partitionsNumber = partitionsNumber
+	}
+}
+
+// WithLoadFactor enables automatic resharding, partitions are doubled when
+// the average number of keys in a partition exceeds loadFactor
+func WithLoadFactor(loadFactor int) EngineOption {
+	return func(engine *Memory) {
+		engine.loadFactor = loadFactor
+	}
+}
+
+// WithMVCC enables multi-version mode, where each key keeps versions
+// written by different transactions for snapshot reads
+func WithMVCC() EngineOption {
+	return func(engine *Memory) {
+		engine.mvcc = true
+	}
+}
+
+// WithOrdered keeps keys of partitions in order, so the
+// engine supports range queries
+func WithOrdered() EngineOption {
+	return func(engine *Memory) {
+		engine.ordered = true
+	}
+}
+
+// WithMaxMemory limits the size of keys with values, the limit is divided
+// between partitions, keys are evicted from the partition by the policy
+func WithMaxMemory(size int, policy EvictionPolicy) EngineOption {
+	return func(engine *Memory) {
+		engine.maxMemory = size
+		engine.evictionPolicy = policy
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -145,3 +146,129 @@ func TestEngineDel(t *testing.T) {
 		})
 	}
 }
+
+func TestEngineExpiration(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop(), WithPartitions(4))
+	require.NoError(t, err)
+
+	const txID int64 = 1
+	ctx := common.ContextWithTxID(context.Background(), txID)
+	deadline := time.Now().Add(time.Hour)
+
+	engine.SetWithExpiration(ctx, "key_1", "value_1", deadline)
+	engine.Set(ctx, "key_2", "value_2")
+
+	expiration, found := engine.Expiration(ctx, "key_1")
+	assert.True(t, found)
+	assert.Equal(t, deadline, expiration)
+
+	assert.True(t, engine.Expire(ctx, "key_2", deadline))
+	assert.False(t, engine.Expire(ctx, "key_3", deadline))
+
+	assert.True(t, engine.Persist(ctx, "key_1"))
+	assert.False(t, engine.Persist(ctx, "key_3"))
+
+	expiration, found = engine.Expiration(ctx, "key_1")
+	assert.True(t, found)
+	assert.True(t, expiration.IsZero())
+
+	_, found = engine.Expiration(ctx, "key_3")
+	assert.False(t, found)
+}
+
+func TestEngineActiveExpiration(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop(), WithPartitions(4))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(common.ContextWithTxID(context.Background(), 1))
+	defer cancel()
+
+	for _, key := range []string{"key_1", "key_2", "key_3", "key_4"} {
+		engine.SetWithExpiration(ctx, key, "value", time.Now().Add(50*time.Millisecond))
+	}
+	engine.Set(ctx, "key_5", "value")
+
+	engine.Start(ctx)
+	time.Sleep(300 * time.Millisecond)
+
+	total := 0
+	for _, partition := range engine.partitions {
+		partition.mu.RLock()
+		total += len(partition.data)
+		partition.mu.RUnlock()
+	}
+
+	assert.Equal(t, 1, total)
+}
+
+func TestEngineMVCC(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop(), WithPartitions(4), WithMVCC())
+	require.NoError(t, err)
+
+	engine.Set(common.ContextWithTxID(context.Background(), 1), "key", "value_1")
+	engine.Set(common.ContextWithTxID(context.Background(), 2), "key", "value_2")
+
+	value, found := engine.Get(common.ContextWithTxID(context.Background(), 1), "key")
+	assert.True(t, found)
+	assert.Equal(t, "value_1", value)
+
+	engine.CollectGarbage(context.Background(), 2)
+
+	_, found = engine.Get(common.ContextWithTxID(context.Background(), 1), "key")
+	assert.False(t, found)
+
+	value, found = engine.Get(common.ContextWithTxID(context.Background(), 2), "key")
+	assert.True(t, found)
+	assert.Equal(t, "value_2", value)
+}
+
+func TestEngineRange(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop(), WithPartitions(4), WithMVCC(), WithOrdered())
+	require.NoError(t, err)
+	assert.True(t, engine.Ordered())
+
+	for idx, key := range []string{"b", "a", "d", "c", "e"} {
+		engine.Set(common.ContextWithTxID(context.Background(), int64(idx+1)), key, "value_"+key)
+	}
+	engine.Del(common.ContextWithTxID(context.Background(), 6), "c")
+	engine.SetWithExpiration(common.ContextWithTxID(context.Background(), 7), "f", "value_f", time.Now().Add(-time.Second))
+
+	keys := func(txID int64, from, to string, limit int) []string {
+		var keys []string
+		engine.Range(common.ContextWithTxID(context.Background(), txID), from, to, limit, func(key, value string) {
+			assert.Equal(t, "value_"+key, value)
+			keys = append(keys, key)
+		})
+		return keys
+	}
+
+	assert.Equal(t, []string{"a", "b", "d", "e"}, keys(7, "", "", 0))
+	assert.Equal(t, []string{"b", "d"}, keys(7, "b", "e", 0))
+	assert.Equal(t, []string{"b", "d"}, keys(7, "ab", "", 2))
+
+	// the snapshot before deletion sees the key
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, keys(5, "", "", 0))
+	assert.Equal(t, []string{"a", "b"}, keys(2, "", "", 0))
+}
+
+func TestEngineRangeWithoutOrder(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	assert.False(t, engine.Ordered())
+
+	ctx := common.ContextWithTxID(context.Background(), 1)
+	engine.Set(ctx, "key", "value")
+	engine.Range(ctx, "", "", 0, func(string, string) {
+		assert.Fail(t, "unordered engine has no ranges")
+	})
+}
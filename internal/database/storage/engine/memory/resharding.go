@@ -0,0 +1,211 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// keys are migrated by batches, so the locks of partitions are held for a short time
+	reshardingInterval  = 10 * time.Millisecond
+	reshardingBatchSize = 1000
+	loadCheckInterval   = time.Second
+
+	// MaxPartitions ...
+	MaxPartitions = 1024
+)
+
+var (
+	// ErrorInvalidPartitions ...
+	ErrorInvalidPartitions = errors.New("number of partitions is invalid")
+	// ErrorReshardingInProgress ...
+	ErrorReshardingInProgress = errors.New("resharding is already in progress")
+)
+
+// Reshard changes the number of partitions online, keys are migrated to the new layout
+// in background and by writes, the previous layout is consulted until it's empty
+func (m *Memory) Reshard(partitionsNumber int) error {
+	if partitionsNumber <= 0 || partitionsNumber > MaxPartitions {
+		return ErrorInvalidPartitions
+	}
+
+	m.reshardingMutex.Lock()
+	defer m.reshardingMutex.Unlock()
+
+	m.layoutMutex.Lock()
+	defer m.layoutMutex.Unlock()
+
+	if m.previous != nil {
+		return ErrorReshardingInProgress
+	} else if len(m.partitions) == partitionsNumber {
+		return nil
+	}
+
+	m.logger.Info("resharding is started", zap.Int("from", len(m.partitions)), zap.Int("to", partitionsNumber))
+	m.previous = m.partitions
+	m.partitions = m.newPartitions(partitionsNumber)
+	return nil
+}
+
+// Resharding reports that keys are migrated to the new layout
+func (m *Memory) Resharding() bool {
+	m.layoutMutex.RLock()
+	defer m.layoutMutex.RUnlock()
+
+	return m.previous != nil
+}
+
+func (m *Memory) newPartitions(partitionsNumber int) []*HashTable {
+	partitions := make([]*HashTable, partitionsNumber)
+	for i := range partitions {
+		if m.mvcc {
+			partitions[i] = NewVersionedHashTable()
+		} else {
+			partitions[i] = NewHashTable()
+		}
+
+		if m.ordered {
+			partitions[i].index = newSkipList()
+		}
+
+		// tables of the previous layout keep their limits until they are empty,
+		// so the memory can exceed the limit during resharding
+		if m.maxMemory > 0 {
+			partitions[i].maxSize = max(1, m.maxMemory/partitionsNumber)
+			partitions[i].policy = m.evictionPolicy
+			partitions[i].hooks = &m.evictionHooks
+		}
+	}
+
+	return partitions
+}
+
+func (m *Memory) runResharding(ctx context.Context) {
+	ticker := time.NewTicker(reshardingInterval)
+	defer ticker.Stop()
+
+	lastCheck := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if m.Resharding() {
+				m.migrate(reshardingBatchSize)
+			} else if m.loadFactor > 0 && time.Since(lastCheck) >= loadCheckInterval {
+				lastCheck = time.Now()
+				m.balance()
+			}
+		}
+	}
+}
+
+// migrate moves up to batchSize keys from the previous layout and finishes
+// resharding when the previous layout is empty, it returns the number of moved keys
+func (m *Memory) migrate(batchSize int) int {
+	m.layoutMutex.RLock()
+	previous, partitions := m.previous, m.partitions
+	m.layoutMutex.RUnlock()
+
+	moved := 0
+	for _, partition := range previous {
+		moved += partition.migrateBatch(batchSize-moved, partitions)
+		if moved == batchSize {
+			return moved
+		}
+	}
+
+	// writes don't add keys to the previous layout, so it's empty after a full pass
+	m.layoutMutex.Lock()
+	defer m.layoutMutex.Unlock()
+
+	if previous != nil {
+		m.previous = nil
+		m.logger.Info("resharding is finished", zap.Int("partitions", len(m.partitions)))
+	}
+
+	return moved
+}
+
+// balance doubles partitions when the average number of keys exceeds the load factor
+// and halves them down to the initial number when it's less than a quarter of it
+func (m *Memory) balance() {
+	partitions := m.tables()
+	keys := 0
+	for _, partition := range partitions {
+		keys += partition.Len()
+	}
+
+	partitionsNumber := len(partitions)
+	var err error
+	if keys > m.loadFactor*partitionsNumber && partitionsNumber < MaxPartitions {
+		err = m.Reshard(min(2*partitionsNumber, MaxPartitions))
+	} else if keys*4 < m.loadFactor*partitionsNumber && partitionsNumber > m.partitionsNumber {
+		err = m.Reshard(max(partitionsNumber/2, m.partitionsNumber))
+	}
+
+	if err != nil {
+		m.logger.Error("failed to start resharding", zap.Error(err))
+	}
+}
+
+// Len returns the number of keys including deleted ones which are not collected yet
+func (ht *HashTable) Len() int {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+
+	return len(ht.data)
+}
+
+// migrate moves the key with all versions to the target table
+func (ht *HashTable) migrate(key string, target *HashTable) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	ht.moveTo(key, target)
+}
+
+// migrateBatch moves up to batchSize keys to their partitions of the layout
+func (ht *HashTable) migrateBatch(batchSize int, partitions []*HashTable) int {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	moved := 0
+	for key := range ht.data {
+		if moved == batchSize {
+			break
+		}
+
+		ht.moveTo(key, partitionOf(partitions, key))
+		moved++
+	}
+
+	return moved
+}
+
+// moveTo is called under the lock of the table, tables of the previous
+// layout are always locked before tables of the current one
+func (ht *HashTable) moveTo(key string, target *HashTable) {
+	head, found := ht.data[key]
+	if !found {
+		return
+	}
+
+	ht.remove(key)
+
+	target.mu.Lock()
+	defer target.mu.Unlock()
+
+	for current := head; current != nil; current = current.previous {
+		target.size += current.size()
+	}
+	target.size += len(key) + keyOverhead
+
+	target.data[key] = head
+	if target.index != nil {
+		target.index.insert(key)
+	}
+}
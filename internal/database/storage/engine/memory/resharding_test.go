@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+)
+
+func TestEngineReshard(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		from int
+		to   int
+		mvcc bool
+	}{
+		"grow partitions":           {from: 2, to: 8},
+		"shrink partitions":         {from: 8, to: 3},
+		"grow versioned partitions": {from: 1, to: 4, mvcc: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			options := []EngineOption{WithPartitions(test.from), WithOrdered()}
+			if test.mvcc {
+				options = append(options, WithMVCC())
+			}
+
+			engine, err := NewMemory(zap.NewNop(), options...)
+			require.NoError(t, err)
+
+			ctx := common.ContextWithTxID(context.Background(), 1)
+			for i := 0; i < 100; i++ {
+				engine.Set(ctx, fmt.Sprintf("key_%03d", i), "value")
+			}
+
+			require.NoError(t, engine.Reshard(test.to))
+			assert.True(t, engine.Resharding())
+			assert.Equal(t, ErrorReshardingInProgress, engine.Reshard(test.to))
+
+			// keys of both layouts are visible during migration
+			assert.Equal(t, 10, engine.migrate(10))
+			ctx = common.ContextWithTxID(context.Background(), 2)
+			engine.Set(ctx, "key_000", "new_value")
+			engine.Del(ctx, "key_001")
+			engine.Set(ctx, "key_100", "value")
+
+			value, found := engine.Get(ctx, "key_000")
+			assert.True(t, found)
+			assert.Equal(t, "new_value", value)
+			_, found = engine.Get(ctx, "key_001")
+			assert.False(t, found)
+			_, found = engine.Get(ctx, "key_099")
+			assert.True(t, found)
+
+			var keys []string
+			engine.Range(ctx, "key_", "", 0, func(key, _ string) {
+				keys = append(keys, key)
+			})
+			assert.Len(t, keys, 100)
+			assert.Equal(t, "key_000", keys[0])
+			assert.Equal(t, "key_002", keys[1])
+
+			for engine.Resharding() {
+				engine.migrate(10)
+			}
+
+			require.Len(t, engine.partitions, test.to)
+			total := 0
+			for _, partition := range engine.partitions {
+				total += partition.Len()
+				for key := range partition.data {
+					assert.Same(t, partition, engine.partition(key))
+				}
+			}
+			if test.mvcc {
+				// the deleted key is kept until garbage collection
+				assert.Equal(t, 101, total)
+			} else {
+				assert.Equal(t, 100, total)
+			}
+
+			value, found = engine.Get(common.ContextWithTxID(context.Background(), 1), "key_000")
+			assert.True(t, found)
+			if test.mvcc {
+				assert.Equal(t, "value", value)
+			} else {
+				assert.Equal(t, "new_value", value)
+			}
+		})
+	}
+}
+
+func TestEngineReshardInvalidPartitions(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop(), WithPartitions(2))
+	require.NoError(t, err)
+
+	assert.Equal(t, ErrorInvalidPartitions, engine.Reshard(0))
+	assert.Equal(t, ErrorInvalidPartitions, engine.Reshard(MaxPartitions+1))
+	assert.NoError(t, engine.Reshard(2))
+	assert.False(t, engine.Resharding())
+}
+
+func TestEngineSnapshotDuringResharding(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop(), WithPartitions(2))
+	require.NoError(t, err)
+
+	ctx := common.ContextWithTxID(context.Background(), 1)
+	for i := 0; i < 10; i++ {
+		engine.Set(ctx, fmt.Sprintf("key_%d", i), "value")
+	}
+
+	require.NoError(t, engine.Reshard(4))
+	engine.migrate(5)
+
+	data, err := engine.Snapshot(ctx)
+	require.NoError(t, err)
+
+	restored, err := NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, restored.Restore(ctx, data))
+
+	for i := 0; i < 10; i++ {
+		_, found := restored.Get(ctx, fmt.Sprintf("key_%d", i))
+		assert.True(t, found)
+	}
+}
+
+func TestEngineBalance(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop(), WithPartitions(2), WithLoadFactor(10))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(common.ContextWithTxID(context.Background(), 1))
+	defer cancel()
+
+	for i := 0; i < 100; i++ {
+		engine.Set(ctx, fmt.Sprintf("key_%d", i), "value")
+	}
+
+	// partitions are doubled while there are more than 10 keys per partition
+	engine.balance()
+	for engine.Resharding() {
+		engine.migrate(reshardingBatchSize)
+	}
+	assert.Len(t, engine.partitions, 4)
+
+	for i := 0; i < 95; i++ {
+		engine.Del(ctx, fmt.Sprintf("key_%d", i))
+	}
+
+	// partitions are halved down to the initial number
+	engine.Start(ctx)
+	assert.Eventually(t, func() bool {
+		engine.layoutMutex.RLock()
+		defer engine.layoutMutex.RUnlock()
+
+		return len(engine.partitions) == 2 && engine.previous == nil
+	}, 3*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,93 @@
+package memory
+
+import "math/rand/v2"
+
+const (
+	skipListMaxLevel = 32
+	// a node is promoted to the next level with probability 1/skipListBranching
+	skipListBranching = 4
+)
+
+type skipListNode struct {
+	key  string
+	next []*skipListNode
+}
+
+// skipList keeps keys of the table in order, it has no own lock,
+// so it's protected by the lock of the table
+type skipList struct {
+	head  *skipListNode
+	level int
+}
+
+func newSkipList() *skipList {
+	return &skipList{
+		head:  &skipListNode{next: make([]*skipListNode, skipListMaxLevel)},
+		level: 1,
+	}
+}
+
+// insert adds the key, it must not be in the list
+func (l *skipList) insert(key string) {
+	var update [skipListMaxLevel]*skipListNode
+	l.findPrevious(key, &update)
+
+	level := randomLevel()
+	if level > l.level {
+		for idx := l.level; idx < level; idx++ {
+			update[idx] = l.head
+		}
+		l.level = level
+	}
+
+	node := &skipListNode{key: key, next: make([]*skipListNode, level)}
+	for idx := range level {
+		node.next[idx] = update[idx].next[idx]
+		update[idx].next[idx] = node
+	}
+}
+
+func (l *skipList) remove(key string) {
+	var update [skipListMaxLevel]*skipListNode
+	node := l.findPrevious(key, &update).next[0]
+	if node == nil || node.key != key {
+		return
+	}
+
+	for idx := range node.next {
+		update[idx].next[idx] = node.next[idx]
+	}
+
+	for l.level > 1 && l.head.next[l.level-1] == nil {
+		l.level--
+	}
+}
+
+// seek returns the first node with a key which is not less than key
+func (l *skipList) seek(key string) *skipListNode {
+	var update [skipListMaxLevel]*skipListNode
+	return l.findPrevious(key, &update).next[0]
+}
+
+// findPrevious fills update by the last nodes with keys less than key on
+// each level and returns such node of the lowest level
+func (l *skipList) findPrevious(key string, update *[skipListMaxLevel]*skipListNode) *skipListNode {
+	current := l.head
+	for idx := l.level - 1; idx >= 0; idx-- {
+		for current.next[idx] != nil && current.next[idx].key < key {
+			current = current.next[idx]
+		}
+		update[idx] = current
+	}
+
+	return current
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skipListMaxLevel && rand.IntN(skipListBranching) == 0 { // nolint : G404: Use of weak random number generator
+		level++
+	}
+
+	return level
+}
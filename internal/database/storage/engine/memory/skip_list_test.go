@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipList(t *testing.T) {
+	t.Parallel()
+
+	list := newSkipList()
+	existing := make(map[string]struct{})
+	for range 1000 {
+		key := fmt.Sprintf("key_%03d", rand.IntN(500)) // nolint : G404: Use of weak random number generator
+		if _, found := existing[key]; found {
+			list.remove(key)
+			delete(existing, key)
+		} else {
+			list.insert(key)
+			existing[key] = struct{}{}
+		}
+	}
+
+	expected := make([]string, 0, len(existing))
+	for key := range existing {
+		expected = append(expected, key)
+	}
+	sort.Strings(expected)
+
+	var keys []string
+	for node := list.seek(""); node != nil; node = node.next[0] {
+		keys = append(keys, node.key)
+	}
+	assert.Equal(t, expected, keys)
+
+	// seek finds the first key which is not less than the given one
+	idx := sort.SearchStrings(expected, "key_250")
+	node := list.seek("key_250")
+	if idx == len(expected) {
+		assert.Nil(t, node)
+	} else {
+		assert.Equal(t, expected[idx], node.key)
+	}
+
+	list.remove("missing")
+	assert.Nil(t, list.seek("z"))
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+)
+
+type snapshotEntry struct {
+	Key   string
+	Value string
+	// Deadline is unix time in milliseconds, zero means no expiration
+	Deadline int64
+	// Type and Items keep data structures, empty type means a string
+	Type  string
+	Items []string
+}
+
+// Versioned ...
+func (m *Memory) Versioned() bool {
+	return m.mvcc
+}
+
+// Snapshot dumps keys visible for the transaction from the context, the
+// snapshot is consistent only if there are no concurrent writes or the
+// engine is versioned
+func (m *Memory) Snapshot(ctx context.Context) ([]byte, error) {
+	txID := common.GetTxIDFromContext(ctx)
+
+	m.reshardingMutex.RLock()
+	defer m.reshardingMutex.RUnlock()
+
+	// a key migrated during the snapshot can be found in both layouts,
+	// the current layout is dumped later, so its value is newer
+	var positions map[string]int
+	if m.Resharding() {
+		positions = make(map[string]int)
+	}
+
+	var entries []snapshotEntry
+	for _, partition := range m.tables() {
+		partition.forEach(txID, func(key string, current *version) {
+			entry := snapshotEntry{Key: key, Value: current.value}
+			if !current.deadline.IsZero() {
+				entry.Deadline = current.deadline.UnixMilli()
+			}
+			if current.container != nil {
+				entry.Type = current.container.typ()
+				entry.Items = current.container.items()
+			}
+
+			if positions == nil {
+				entries = append(entries, entry)
+			} else if idx, found := positions[key]; found {
+				entries[idx] = entry
+			} else {
+				positions[key] = len(entries)
+				entries = append(entries, entry)
+			}
+		})
+	}
+
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(entries); err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	m.logger.Debug("successful snapshot", zap.Int64("tx", txID), zap.Int("keys", len(entries)))
+	return buffer.Bytes(), nil
+}
+
+// Restore loads keys from the snapshot as written by the transaction from the context,
+// keys which are missing in the snapshot are deleted, so the engine has the state of
+// the snapshot even if it's not empty, e.g. on a replica which is far behind
+func (m *Memory) Restore(ctx context.Context, data []byte) error {
+	var entries []snapshotEntry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	txID := common.GetTxIDFromContext(ctx)
+	restored := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		if entry.Type != "" && newContainer(entry.Type, nil) == nil {
+			return fmt.Errorf("failed to restore key %q: unknown type %q", entry.Key, entry.Type)
+		}
+		restored[entry.Key] = struct{}{}
+	}
+
+	m.reshardingMutex.RLock()
+	defer m.reshardingMutex.RUnlock()
+
+	var deleted []string
+	for _, partition := range m.tables() {
+		partition.forEach(txID, func(key string, _ *version) {
+			if _, found := restored[key]; !found {
+				deleted = append(deleted, key)
+			}
+		})
+	}
+
+	for _, key := range deleted {
+		m.write(key, func(partition *HashTable) {
+			partition.Del(txID, key)
+		})
+	}
+
+	for _, entry := range entries {
+		var deadline time.Time
+		if entry.Deadline != 0 {
+			deadline = time.UnixMilli(entry.Deadline)
+		}
+
+		m.write(entry.Key, func(partition *HashTable) {
+			if entry.Type != "" {
+				partition.setContainer(txID, entry.Key, newContainer(entry.Type, entry.Items), deadline)
+			} else {
+				partition.SetWithExpiration(txID, entry.Key, entry.Value, deadline)
+			}
+		})
+	}
+
+	m.logger.Debug("successful restore", zap.Int64("tx", txID), zap.Int("keys", len(entries)))
+	return nil
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+)
+
+func TestEngineSnapshotRestore(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		options []EngineOption
+	}{
+		"single-version engine": {
+			options: []EngineOption{WithPartitions(4)},
+		},
+		"versioned engine": {
+			options: []EngineOption{WithPartitions(4), WithMVCC()},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			deadline := time.UnixMilli(time.Now().Add(time.Hour).UnixMilli())
+
+			source, err := NewMemory(zap.NewNop(), test.options...)
+			require.NoError(t, err)
+
+			source.Set(common.ContextWithTxID(context.Background(), 1), "key_1", "value_1")
+			source.SetWithExpiration(common.ContextWithTxID(context.Background(), 2), "key_2", "value_2", deadline)
+			source.SetWithExpiration(common.ContextWithTxID(context.Background(), 3), "key_3", "value_3", time.Now().Add(-time.Second))
+
+			ctx := common.ContextWithTxID(context.Background(), 3)
+			data, err := source.Snapshot(ctx)
+			require.NoError(t, err)
+
+			destination, err := NewMemory(zap.NewNop(), test.options...)
+			require.NoError(t, err)
+
+			// keys which are not in the snapshot are deleted
+			destination.Set(common.ContextWithTxID(context.Background(), 1), "key_4", "value_4")
+			require.NoError(t, destination.Restore(ctx, data))
+
+			_, found := destination.Get(ctx, "key_4")
+			assert.False(t, found)
+
+			value, found := destination.Get(ctx, "key_1")
+			assert.True(t, found)
+			assert.Equal(t, "value_1", value)
+
+			expiration, found := destination.Expiration(ctx, "key_2")
+			assert.True(t, found)
+			assert.Equal(t, deadline, expiration)
+
+			_, found = destination.Get(ctx, "key_3")
+			assert.False(t, found)
+		})
+	}
+
+	engine, err := NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	assert.Error(t, engine.Restore(context.Background(), []byte("invalid")))
+}
+
+func TestVersionedEngineSnapshotAtTransaction(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop(), WithMVCC())
+	require.NoError(t, err)
+
+	engine.Set(common.ContextWithTxID(context.Background(), 1), "key_1", "value_1")
+	engine.Set(common.ContextWithTxID(context.Background(), 2), "key_2", "value_2")
+
+	data, err := engine.Snapshot(common.ContextWithTxID(context.Background(), 1))
+	require.NoError(t, err)
+
+	restored, err := NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	ctx := common.ContextWithTxID(context.Background(), 1)
+	require.NoError(t, restored.Restore(ctx, data))
+
+	_, found := restored.Get(ctx, "key_1")
+	assert.True(t, found)
+	_, found = restored.Get(ctx, "key_2")
+	assert.False(t, found)
+}
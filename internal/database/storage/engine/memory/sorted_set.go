@@ -0,0 +1,288 @@
+package memory
+
+import (
+	"math"
+	"strconv"
+
+	"database-simon/internal/common"
+)
+
+type scoreLevel struct {
+	next *scoreNode
+	// span is the number of nodes between the node and the next one on the level
+	span int
+}
+
+type scoreNode struct {
+	member string
+	score  float64
+	levels []scoreLevel
+}
+
+// before reports whether the node precedes the member with the score
+func (n *scoreNode) before(score float64, member string) bool {
+	return n.score < score || n.score == score && n.member < member
+}
+
+// scoreList keeps members ordered by scores and then by members, spans of
+// levels allow to find ranks of members and members by ranks in O(log n)
+type scoreList struct {
+	head   *scoreNode
+	level  int
+	length int
+}
+
+func newScoreList() *scoreList {
+	return &scoreList{
+		head:  &scoreNode{levels: make([]scoreLevel, skipListMaxLevel)},
+		level: 1,
+	}
+}
+
+// insert adds the member, it must not be in the list
+func (l *scoreList) insert(score float64, member string) {
+	var update [skipListMaxLevel]*scoreNode
+	var rank [skipListMaxLevel]int
+
+	current := l.head
+	for idx := l.level - 1; idx >= 0; idx-- {
+		if idx < l.level-1 {
+			rank[idx] = rank[idx+1]
+		}
+		for current.levels[idx].next != nil && current.levels[idx].next.before(score, member) {
+			rank[idx] += current.levels[idx].span
+			current = current.levels[idx].next
+		}
+		update[idx] = current
+	}
+
+	level := randomLevel()
+	if level > l.level {
+		for idx := l.level; idx < level; idx++ {
+			update[idx] = l.head
+			l.head.levels[idx].span = l.length
+		}
+		l.level = level
+	}
+
+	node := &scoreNode{member: member, score: score, levels: make([]scoreLevel, level)}
+	for idx := range level {
+		node.levels[idx].next = update[idx].levels[idx].next
+		update[idx].levels[idx].next = node
+		node.levels[idx].span = update[idx].levels[idx].span - (rank[0] - rank[idx])
+		update[idx].levels[idx].span = rank[0] - rank[idx] + 1
+	}
+
+	for idx := level; idx < l.level; idx++ {
+		update[idx].levels[idx].span++
+	}
+
+	l.length++
+}
+
+func (l *scoreList) remove(score float64, member string) {
+	var update [skipListMaxLevel]*scoreNode
+
+	current := l.head
+	for idx := l.level - 1; idx >= 0; idx-- {
+		for current.levels[idx].next != nil && current.levels[idx].next.before(score, member) {
+			current = current.levels[idx].next
+		}
+		update[idx] = current
+	}
+
+	node := current.levels[0].next
+	if node == nil || node.score != score || node.member != member {
+		return
+	}
+
+	for idx := range l.level {
+		if update[idx].levels[idx].next == node {
+			update[idx].levels[idx].span += node.levels[idx].span - 1
+			update[idx].levels[idx].next = node.levels[idx].next
+		} else {
+			update[idx].levels[idx].span--
+		}
+	}
+
+	for l.level > 1 && l.head.levels[l.level-1].next == nil {
+		l.level--
+	}
+
+	l.length--
+}
+
+// rank returns the zero-based position of the member, it must be in the list
+func (l *scoreList) rank(score float64, member string) int {
+	rank := 0
+	current := l.head
+	for idx := l.level - 1; idx >= 0; idx-- {
+		for current.levels[idx].next != nil && current.levels[idx].next.before(score, member) {
+			rank += current.levels[idx].span
+			current = current.levels[idx].next
+		}
+	}
+
+	return rank
+}
+
+// byRank returns the node at the zero-based position or nil
+func (l *scoreList) byRank(rank int) *scoreNode {
+	if rank < 0 || rank >= l.length {
+		return nil
+	}
+
+	traversed := 0
+	current := l.head
+	for idx := l.level - 1; idx >= 0; idx-- {
+		for current.levels[idx].next != nil && traversed+current.levels[idx].span <= rank+1 {
+			traversed += current.levels[idx].span
+			current = current.levels[idx].next
+		}
+		if traversed == rank+1 {
+			return current
+		}
+	}
+
+	return nil
+}
+
+// firstInRange returns the first node with a score which is above the minimum of the range
+func (l *scoreList) firstInRange(scores common.ScoreRange) *scoreNode {
+	current := l.head
+	for idx := l.level - 1; idx >= 0; idx-- {
+		for current.levels[idx].next != nil && !scores.AboveMin(current.levels[idx].next.score) {
+			current = current.levels[idx].next
+		}
+	}
+
+	return current.levels[0].next
+}
+
+// sortedSetValue indexes scores by members and orders members by scores
+type sortedSetValue struct {
+	scores map[string]float64
+	list   *scoreList
+	bytes  int
+}
+
+func newSortedSetValue() container {
+	return &sortedSetValue{scores: make(map[string]float64), list: newScoreList()}
+}
+
+func (z *sortedSetValue) typ() string { return common.SortedSetType }
+func (z *sortedSetValue) len() int    { return len(z.scores) }
+func (z *sortedSetValue) size() int   { return z.bytes }
+
+func (z *sortedSetValue) clone() container {
+	cloned := newSortedSetValue().(*sortedSetValue)
+	for node := z.list.head.levels[0].next; node != nil; node = node.levels[0].next {
+		cloned.add(node.member, node.score)
+	}
+
+	return cloned
+}
+
+// items returns members with scores in order of scores
+func (z *sortedSetValue) items() []string {
+	items := make([]string, 0, 2*len(z.scores))
+	for node := z.list.head.levels[0].next; node != nil; node = node.levels[0].next {
+		items = append(items, node.member, strconv.FormatFloat(node.score, 'g', -1, 64))
+	}
+
+	return items
+}
+
+// add sets the score of the member, it returns true if the member is added
+func (z *sortedSetValue) add(member string, score float64) bool {
+	previous, found := z.scores[member]
+	if found && previous == score {
+		return false
+	} else if found {
+		z.list.remove(previous, member)
+	} else {
+		z.bytes += len(member) + 2*itemOverhead
+	}
+
+	z.scores[member] = score
+	z.list.insert(score, member)
+	return !found
+}
+
+func (z *sortedSetValue) rem(member string) bool {
+	score, found := z.scores[member]
+	if !found {
+		return false
+	}
+
+	delete(z.scores, member)
+	z.list.remove(score, member)
+	z.bytes -= len(member) + 2*itemOverhead
+	return true
+}
+
+// incr adds the increment to the score of the member, a missing member has zero score
+func (z *sortedSetValue) incr(member string, increment float64) (float64, error) {
+	score := z.scores[member] + increment
+	if math.IsNaN(score) {
+		return 0, common.ErrorNotANumber
+	}
+
+	z.add(member, score)
+	return score, nil
+}
+
+func (z *sortedSetValue) score(member string) (float64, bool) {
+	score, found := z.scores[member]
+	return score, found
+}
+
+func (z *sortedSetValue) rank(member string) (int, bool) {
+	score, found := z.scores[member]
+	if !found {
+		return 0, false
+	}
+
+	return z.list.rank(score, member), true
+}
+
+// slice returns members from start to stop rank inclusive, negative
+// ranks are counted from the member with the highest score
+func (z *sortedSetValue) slice(start, stop int) []common.ScoredMember {
+	if start < 0 {
+		start = max(z.list.length+start, 0)
+	}
+	if stop < 0 {
+		stop = z.list.length + stop
+	}
+	stop = min(stop, z.list.length-1)
+
+	if start > stop {
+		return nil
+	}
+
+	members := make([]common.ScoredMember, 0, stop-start+1)
+	for node := z.list.byRank(start); node != nil && len(members) < stop-start+1; node = node.levels[0].next {
+		members = append(members, common.ScoredMember{Member: node.member, Score: node.score})
+	}
+
+	return members
+}
+
+// rangeByScore returns members with scores in the range skipping offset members,
+// the number of members is not limited if limit isn't positive
+func (z *sortedSetValue) rangeByScore(scores common.ScoreRange, offset, limit int) []common.ScoredMember {
+	var members []common.ScoredMember
+	for node := z.list.firstInRange(scores); node != nil && scores.BelowMax(node.score); node = node.levels[0].next {
+		if offset > 0 {
+			offset--
+			continue
+		} else if limit > 0 && len(members) == limit {
+			break
+		}
+
+		members = append(members, common.ScoredMember{Member: node.member, Score: node.score})
+	}
+
+	return members
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"database-simon/internal/common"
+)
+
+func TestSortedSetValue(t *testing.T) {
+	t.Parallel()
+
+	sortedSet := newSortedSetValue().(*sortedSetValue)
+	scores := make(map[string]float64)
+	for range 2000 {
+		member := fmt.Sprintf("member_%03d", rand.IntN(300)) // nolint : G404: Use of weak random number generator
+		if rand.IntN(4) == 0 {                               // nolint : G404: Use of weak random number generator
+			_, found := scores[member]
+			assert.Equal(t, found, sortedSet.rem(member))
+			delete(scores, member)
+			continue
+		}
+
+		score := float64(rand.IntN(50)) // nolint : G404: Use of weak random number generator
+		_, found := scores[member]
+		assert.Equal(t, !found, sortedSet.add(member, score))
+		scores[member] = score
+	}
+
+	expected := make([]common.ScoredMember, 0, len(scores))
+	for member, score := range scores {
+		expected = append(expected, common.ScoredMember{Member: member, Score: score})
+	}
+	slices.SortFunc(expected, func(a, b common.ScoredMember) int {
+		return cmp.Or(cmp.Compare(a.Score, b.Score), strings.Compare(a.Member, b.Member))
+	})
+
+	require.Equal(t, len(expected), sortedSet.len())
+	assert.Equal(t, expected, sortedSet.slice(0, -1))
+	for rank, member := range expected {
+		actual, found := sortedSet.rank(member.Member)
+		assert.True(t, found)
+		assert.Equal(t, rank, actual)
+	}
+	assert.Equal(t, expected[len(expected)-3:], sortedSet.slice(-3, 1000))
+
+	scoreRange := common.ScoreRange{Min: 10, Max: 20, MinExclusive: true}
+	var inRange []common.ScoredMember
+	for _, member := range expected {
+		if member.Score > 10 && member.Score <= 20 {
+			inRange = append(inRange, member)
+		}
+	}
+	assert.Equal(t, inRange, sortedSet.rangeByScore(scoreRange, 0, 0))
+	assert.Equal(t, inRange[2:5], sortedSet.rangeByScore(scoreRange, 2, 3))
+
+	cloned := sortedSet.clone()
+	assert.Equal(t, sortedSet.items(), cloned.items())
+	assert.Equal(t, sortedSet.size(), cloned.size())
+}
+
+func TestSortedSetValueIncr(t *testing.T) {
+	t.Parallel()
+
+	sortedSet := newSortedSetValue().(*sortedSetValue)
+	score, err := sortedSet.incr("alice", 2.5)
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, score)
+
+	score, err = sortedSet.incr("alice", math.Inf(1))
+	require.NoError(t, err)
+	assert.Equal(t, math.Inf(1), score)
+
+	_, err = sortedSet.incr("alice", math.Inf(-1))
+	assert.Equal(t, common.ErrorNotANumber, err)
+	score, _ = sortedSet.score("alice")
+	assert.Equal(t, math.Inf(1), score)
+}
@@ -0,0 +1,386 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+)
+
+// Type returns the type of the key visible for the transaction from the context
+func (m *Memory) Type(ctx context.Context, key string) (string, bool) {
+	txID := common.GetTxIDFromContext(ctx)
+
+	var typ string
+	var found bool
+	m.read(key, func(partition *HashTable) bool {
+		typ, found = partition.Type(txID, key)
+		return found
+	})
+
+	return typ, found
+}
+
+// HSet sets fields of the hash from pairs of fields and values,
+// it returns the number of added fields
+func (m *Memory) HSet(ctx context.Context, key string, pairs []string) (int, error) {
+	added := 0
+	err := m.update(ctx, "hset", key, common.HashType, newHashValue, func(value container) {
+		hash := value.(*hashValue)
+		for idx := 0; idx+1 < len(pairs); idx += 2 {
+			if hash.set(pairs[idx], pairs[idx+1]) {
+				added++
+			}
+		}
+	})
+
+	return added, err
+}
+
+// HGet ...
+func (m *Memory) HGet(ctx context.Context, key, field string) (string, bool, error) {
+	var value string
+	var found bool
+	err := m.inspect(ctx, "hget", key, common.HashType, func(hash container) {
+		value, found = hash.(*hashValue).get(field)
+	})
+
+	return value, found, err
+}
+
+// HDel deletes fields of the hash, it returns the number of deleted fields
+func (m *Memory) HDel(ctx context.Context, key string, fields []string) (int, error) {
+	deleted := 0
+	err := m.update(ctx, "hdel", key, common.HashType, nil, func(value container) {
+		hash := value.(*hashValue)
+		for _, field := range fields {
+			if hash.del(field) {
+				deleted++
+			}
+		}
+	})
+
+	return deleted, err
+}
+
+// HGetAll returns fields with values of the hash in order of fields
+func (m *Memory) HGetAll(ctx context.Context, key string) ([]string, error) {
+	var items []string
+	err := m.inspect(ctx, "hgetall", key, common.HashType, func(hash container) {
+		items = hash.items()
+	})
+
+	return items, err
+}
+
+// LPush inserts values to the head of the list, it returns the length of the list
+func (m *Memory) LPush(ctx context.Context, key string, values []string) (int, error) {
+	return m.push(ctx, "lpush", key, true, values)
+}
+
+// RPush inserts values to the tail of the list, it returns the length of the list
+func (m *Memory) RPush(ctx context.Context, key string, values []string) (int, error) {
+	return m.push(ctx, "rpush", key, false, values)
+}
+
+// LPop removes up to count values from the head of the list
+func (m *Memory) LPop(ctx context.Context, key string, count int) ([]string, error) {
+	return m.pop(ctx, "lpop", key, true, count)
+}
+
+// RPop removes up to count values from the tail of the list
+func (m *Memory) RPop(ctx context.Context, key string, count int) ([]string, error) {
+	return m.pop(ctx, "rpop", key, false, count)
+}
+
+// LRange returns values of the list from start to stop inclusive,
+// negative indexes are counted from the tail of the list
+func (m *Memory) LRange(ctx context.Context, key string, start, stop int) ([]string, error) {
+	var values []string
+	err := m.inspect(ctx, "lrange", key, common.ListType, func(list container) {
+		values = list.(*listValue).slice(start, stop)
+	})
+
+	return values, err
+}
+
+// SAdd adds members to the set, it returns the number of added members
+func (m *Memory) SAdd(ctx context.Context, key string, members []string) (int, error) {
+	added := 0
+	err := m.update(ctx, "sadd", key, common.SetType, newSetValue, func(value container) {
+		set := value.(*setValue)
+		for _, member := range members {
+			if set.add(member) {
+				added++
+			}
+		}
+	})
+
+	return added, err
+}
+
+// SRem removes members from the set, it returns the number of removed members
+func (m *Memory) SRem(ctx context.Context, key string, members []string) (int, error) {
+	removed := 0
+	err := m.update(ctx, "srem", key, common.SetType, nil, func(value container) {
+		set := value.(*setValue)
+		for _, member := range members {
+			if set.rem(member) {
+				removed++
+			}
+		}
+	})
+
+	return removed, err
+}
+
+// SMembers returns members of the set in order
+func (m *Memory) SMembers(ctx context.Context, key string) ([]string, error) {
+	var members []string
+	err := m.inspect(ctx, "smembers", key, common.SetType, func(set container) {
+		members = set.items()
+	})
+
+	return members, err
+}
+
+// SIsMember ...
+func (m *Memory) SIsMember(ctx context.Context, key, member string) (bool, error) {
+	var found bool
+	err := m.inspect(ctx, "sismember", key, common.SetType, func(set container) {
+		found = set.(*setValue).has(member)
+	})
+
+	return found, err
+}
+
+// ZAdd sets scores of members of the sorted set, it returns the number of added members
+func (m *Memory) ZAdd(ctx context.Context, key string, members []common.ScoredMember) (int, error) {
+	added := 0
+	err := m.update(ctx, "zadd", key, common.SortedSetType, newSortedSetValue, func(value container) {
+		sortedSet := value.(*sortedSetValue)
+		for _, member := range members {
+			if sortedSet.add(member.Member, member.Score) {
+				added++
+			}
+		}
+	})
+
+	return added, err
+}
+
+// ZRem removes members from the sorted set, it returns the number of removed members
+func (m *Memory) ZRem(ctx context.Context, key string, members []string) (int, error) {
+	removed := 0
+	err := m.update(ctx, "zrem", key, common.SortedSetType, nil, func(value container) {
+		sortedSet := value.(*sortedSetValue)
+		for _, member := range members {
+			if sortedSet.rem(member) {
+				removed++
+			}
+		}
+	})
+
+	return removed, err
+}
+
+// ZIncrBy adds the increment to the score of the member, it returns the new score
+func (m *Memory) ZIncrBy(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	var score float64
+	var errIncr error
+	err := m.update(ctx, "zincrby", key, common.SortedSetType, newSortedSetValue, func(value container) {
+		score, errIncr = value.(*sortedSetValue).incr(member, increment)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return score, errIncr
+}
+
+// ZScore ...
+func (m *Memory) ZScore(ctx context.Context, key, member string) (float64, bool, error) {
+	var score float64
+	var found bool
+	err := m.inspect(ctx, "zscore", key, common.SortedSetType, func(sortedSet container) {
+		score, found = sortedSet.(*sortedSetValue).score(member)
+	})
+
+	return score, found, err
+}
+
+// ZRank returns the zero-based position of the member in order of scores
+func (m *Memory) ZRank(ctx context.Context, key, member string) (int, bool, error) {
+	var rank int
+	var found bool
+	err := m.inspect(ctx, "zrank", key, common.SortedSetType, func(sortedSet container) {
+		rank, found = sortedSet.(*sortedSetValue).rank(member)
+	})
+
+	return rank, found, err
+}
+
+// ZRange returns members of the sorted set from start to stop rank inclusive,
+// negative ranks are counted from the member with the highest score
+func (m *Memory) ZRange(ctx context.Context, key string, start, stop int) ([]common.ScoredMember, error) {
+	var members []common.ScoredMember
+	err := m.inspect(ctx, "zrange", key, common.SortedSetType, func(sortedSet container) {
+		members = sortedSet.(*sortedSetValue).slice(start, stop)
+	})
+
+	return members, err
+}
+
+// ZRangeByScore returns members of the sorted set with scores in the range skipping
+// offset members, the number of members is not limited if limit isn't positive
+func (m *Memory) ZRangeByScore(ctx context.Context, key string, scores common.ScoreRange, offset, limit int) ([]common.ScoredMember, error) {
+	var members []common.ScoredMember
+	err := m.inspect(ctx, "zrangebyscore", key, common.SortedSetType, func(sortedSet container) {
+		members = sortedSet.(*sortedSetValue).rangeByScore(scores, offset, limit)
+	})
+
+	return members, err
+}
+
+func (m *Memory) push(ctx context.Context, command, key string, head bool, values []string) (int, error) {
+	length := 0
+	err := m.update(ctx, command, key, common.ListType, newListValue, func(list container) {
+		list.(*listValue).push(head, values)
+		length = list.len()
+	})
+
+	return length, err
+}
+
+func (m *Memory) pop(ctx context.Context, command, key string, head bool, count int) ([]string, error) {
+	var values []string
+	err := m.update(ctx, command, key, common.ListType, nil, func(list container) {
+		values = list.(*listValue).pop(head, count)
+	})
+
+	return values, err
+}
+
+func (m *Memory) update(ctx context.Context, command, key, typ string, create func() container, action func(container)) error {
+	txID := common.GetTxIDFromContext(ctx)
+
+	var err error
+	m.write(key, func(partition *HashTable) {
+		err = partition.update(txID, key, typ, create, action)
+	})
+
+	m.logger.Debug("successful "+command+" query", zap.Int64("tx", txID))
+	return err
+}
+
+func (m *Memory) inspect(ctx context.Context, command, key, typ string, action func(container)) error {
+	txID := common.GetTxIDFromContext(ctx)
+
+	var err error
+	m.read(key, func(partition *HashTable) bool {
+		var found bool
+		found, err = partition.inspect(txID, key, typ, action)
+		return found
+	})
+
+	m.logger.Debug("successful "+command+" query", zap.Int64("tx", txID))
+	return err
+}
+
+// Type returns the type of the key visible for the transaction txID
+func (ht *HashTable) Type(txID int64, key string) (string, bool) {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+
+	current := ht.alive(txID, key)
+	if current == nil {
+		return "", false
+	} else if current.container == nil {
+		return common.StringType, true
+	}
+
+	return current.container.typ(), true
+}
+
+// update applies action to the container of the key visible for the transaction txID,
+// a missing key is created by create or it's left as is if create is nil. The key is
+// deleted when its container becomes empty
+func (ht *HashTable) update(txID int64, key, typ string, create func() container, action func(container)) error {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	current := ht.alive(txID, key)
+	if current == nil {
+		if create != nil {
+			value := create()
+			action(value)
+			if value.len() == 0 {
+				return nil
+			}
+			ht.write(key, &version{container: value, txID: txID})
+			ht.evict(key)
+		}
+		return nil
+	} else if current.container == nil || current.container.typ() != typ {
+		return common.ErrorWrongType
+	}
+
+	if ht.mvcc {
+		value := current.container.clone()
+		action(value)
+		if value.len() == 0 {
+			ht.write(key, &version{txID: txID, deleted: true})
+		} else {
+			ht.write(key, &version{container: value, deadline: current.deadline, txID: txID})
+		}
+		return nil
+	}
+
+	previousSize := current.container.size()
+	action(current.container)
+	ht.size += current.container.size() - previousSize
+
+	if current.container.len() == 0 {
+		ht.remove(key)
+		return nil
+	}
+
+	if ht.maxSize > 0 {
+		current.touch()
+	}
+	ht.evict(key)
+	return nil
+}
+
+// inspect calls action for the container of the key visible for the transaction
+// txID under the read lock, it reports whether the key exists
+func (ht *HashTable) inspect(txID int64, key, typ string, action func(container)) (bool, error) {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+
+	current := ht.alive(txID, key)
+	if current == nil {
+		return false, nil
+	} else if current.container == nil || current.container.typ() != typ {
+		return true, common.ErrorWrongType
+	}
+
+	action(current.container)
+	if ht.maxSize > 0 {
+		current.touch()
+	}
+
+	return true, nil
+}
+
+// setContainer replaces the value of the key by the container
+func (ht *HashTable) setContainer(txID int64, key string, value container, deadline time.Time) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	ht.write(key, &version{container: value, deadline: deadline, txID: txID})
+	ht.evict(key)
+}
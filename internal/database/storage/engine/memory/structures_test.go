@@ -0,0 +1,289 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+)
+
+func TestEngineHash(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	ctx := common.ContextWithTxID(context.Background(), 1)
+
+	added, err := engine.HSet(ctx, "user", []string{"name", "alice", "age", "30"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	added, err = engine.HSet(ctx, "user", []string{"age", "31", "city", "paris"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	value, found, err := engine.HGet(ctx, "user", "age")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "31", value)
+
+	items, err := engine.HGetAll(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"age", "31", "city", "paris", "name", "alice"}, items)
+
+	deleted, err := engine.HDel(ctx, "user", []string{"age", "city", "name", "unknown"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+
+	// the empty hash is deleted
+	_, found = engine.Type(ctx, "user")
+	assert.False(t, found)
+	assert.Zero(t, engine.partitions[0].size)
+}
+
+func TestEngineList(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	ctx := common.ContextWithTxID(context.Background(), 1)
+
+	length, err := engine.RPush(ctx, "queue", []string{"c", "d"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, length)
+
+	length, err = engine.LPush(ctx, "queue", []string{"b", "a"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, length)
+
+	tests := map[string]struct {
+		start          int
+		stop           int
+		expectedValues []string
+	}{
+		"whole list":            {start: 0, stop: -1, expectedValues: []string{"a", "b", "c", "d"}},
+		"middle of list":        {start: 1, stop: 2, expectedValues: []string{"b", "c"}},
+		"stop after the tail":   {start: -2, stop: 10, expectedValues: []string{"c", "d"}},
+		"start after the stop":  {start: 3, stop: 1},
+		"start before the head": {start: -10, stop: 0, expectedValues: []string{"a"}},
+	}
+
+	for name, test := range tests {
+		values, err := engine.LRange(ctx, "queue", test.start, test.stop)
+		require.NoError(t, err, name)
+		assert.Equal(t, test.expectedValues, values, name)
+	}
+
+	values, err := engine.LPop(ctx, "queue", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, values)
+
+	values, err = engine.RPop(ctx, "queue", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d", "c", "b"}, values)
+
+	values, err = engine.LPop(ctx, "queue", 1)
+	require.NoError(t, err)
+	assert.Empty(t, values)
+	assert.Zero(t, engine.partitions[0].size)
+}
+
+func TestEngineSetMembers(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	ctx := common.ContextWithTxID(context.Background(), 1)
+
+	added, err := engine.SAdd(ctx, "tags", []string{"go", "db", "go"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	found, err := engine.SIsMember(ctx, "tags", "db")
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	members, err := engine.SMembers(ctx, "tags")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"db", "go"}, members)
+
+	removed, err := engine.SRem(ctx, "tags", []string{"db", "kv"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	found, err = engine.SIsMember(ctx, "tags", "db")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestEngineSortedSet(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	ctx := common.ContextWithTxID(context.Background(), 1)
+
+	added, err := engine.ZAdd(ctx, "board", []common.ScoredMember{
+		{Member: "alice", Score: 30},
+		{Member: "bob", Score: 10},
+		{Member: "carol", Score: 20},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, added)
+
+	score, err := engine.ZIncrBy(ctx, "board", 25, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, float64(35), score)
+
+	rank, found, err := engine.ZRank(ctx, "board", "bob")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 2, rank)
+
+	members, err := engine.ZRange(ctx, "board", 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []common.ScoredMember{{Member: "carol", Score: 20}, {Member: "alice", Score: 30}}, members)
+
+	members, err = engine.ZRangeByScore(ctx, "board", common.ScoreRange{Min: 20, Max: 35, MaxExclusive: true}, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []common.ScoredMember{{Member: "carol", Score: 20}, {Member: "alice", Score: 30}}, members)
+
+	removed, err := engine.ZRem(ctx, "board", []string{"alice", "dave"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, found, err = engine.ZScore(ctx, "board", "alice")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = engine.ZAdd(ctx, "queue", nil)
+	require.NoError(t, err)
+	_, err = engine.RPush(ctx, "queue", []string{"a"})
+	require.NoError(t, err)
+	_, err = engine.ZIncrBy(ctx, "queue", 1, "a")
+	assert.Equal(t, common.ErrorWrongType, err)
+}
+
+func TestEngineWrongType(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	ctx := common.ContextWithTxID(context.Background(), 1)
+
+	engine.Set(ctx, "string", "value")
+	_, err = engine.SAdd(ctx, "set", []string{"member"})
+	require.NoError(t, err)
+
+	_, err = engine.HSet(ctx, "string", []string{"field", "value"})
+	assert.Equal(t, common.ErrorWrongType, err)
+	_, err = engine.LRange(ctx, "set", 0, -1)
+	assert.Equal(t, common.ErrorWrongType, err)
+
+	// strings are not found if the key keeps a data structure
+	_, found := engine.Get(ctx, "set")
+	assert.False(t, found)
+	typ, found := engine.Type(ctx, "set")
+	assert.True(t, found)
+	assert.Equal(t, common.SetType, typ)
+
+	// SET replaces the data structure
+	engine.Set(ctx, "set", "value")
+	typ, _ = engine.Type(ctx, "set")
+	assert.Equal(t, common.StringType, typ)
+}
+
+func TestEngineRangeSkipsStructures(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop(), WithOrdered())
+	require.NoError(t, err)
+	ctx := common.ContextWithTxID(context.Background(), 1)
+
+	engine.Set(ctx, "a", "value")
+	_, err = engine.HSet(ctx, "b", []string{"field", "value"})
+	require.NoError(t, err)
+	engine.Set(ctx, "c", "")
+	_, err = engine.RPush(ctx, "d", []string{"value"})
+	require.NoError(t, err)
+	engine.Set(ctx, "e", "value")
+
+	// the limit counts only returned keys
+	var entries []string
+	engine.Range(ctx, "", "", 3, func(key, value string) {
+		entries = append(entries, key+"="+value)
+	})
+	assert.Equal(t, []string{"a=value", "c=", "e=value"}, entries)
+}
+
+func TestEngineStructuresMVCC(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop(), WithMVCC())
+	require.NoError(t, err)
+
+	_, err = engine.RPush(common.ContextWithTxID(context.Background(), 1), "queue", []string{"a", "b"})
+	require.NoError(t, err)
+	_, err = engine.LPop(common.ContextWithTxID(context.Background(), 2), "queue", 2)
+	require.NoError(t, err)
+
+	// the snapshot of the first transaction sees the list before popping
+	values, err := engine.LRange(common.ContextWithTxID(context.Background(), 1), "queue", 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, values)
+
+	_, found := engine.Type(common.ContextWithTxID(context.Background(), 2), "queue")
+	assert.False(t, found)
+}
+
+func TestEngineSnapshotStructures(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	ctx := common.ContextWithTxID(context.Background(), 1)
+
+	_, err = engine.HSet(ctx, "user", []string{"name", "alice"})
+	require.NoError(t, err)
+	_, err = engine.RPush(ctx, "queue", []string{"a", "b"})
+	require.NoError(t, err)
+	_, err = engine.SAdd(ctx, "tags", []string{"go"})
+	require.NoError(t, err)
+	_, err = engine.ZAdd(ctx, "board", []common.ScoredMember{{Member: "alice", Score: 0.1}, {Member: "bob", Score: math.Inf(-1)}})
+	require.NoError(t, err)
+	deadline := time.UnixMilli(time.Now().Add(time.Hour).UnixMilli())
+	assert.True(t, engine.Expire(ctx, "tags", deadline))
+
+	data, err := engine.Snapshot(ctx)
+	require.NoError(t, err)
+
+	restored, err := NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, restored.Restore(ctx, data))
+
+	items, err := restored.HGetAll(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name", "alice"}, items)
+
+	values, err := restored.LRange(ctx, "queue", 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, values)
+
+	members, err := restored.SMembers(ctx, "tags")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go"}, members)
+
+	scored, err := restored.ZRange(ctx, "board", 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []common.ScoredMember{{Member: "bob", Score: math.Inf(-1)}, {Member: "alice", Score: 0.1}}, scored)
+
+	expiration, found := restored.Expiration(ctx, "tags")
+	assert.True(t, found)
+	assert.Equal(t, deadline, expiration)
+}
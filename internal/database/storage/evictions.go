@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+)
+
+// evictingEngine evicts keys itself when its memory is limited
+type evictingEngine interface {
+	OnEviction(allowed func() bool, evicted func(key string))
+}
+
+// evictionAllowed reports whether the engine can evict keys, replicas delete
+// keys which are evicted by the master, so they don't evict keys themselves
+func (s *Storage) evictionAllowed() bool {
+	return s.replica == nil || s.replica.IsMaster()
+}
+
+// evicted queues the key evicted by the engine, it's called under the lock of the partition
+func (s *Storage) evicted(key string) {
+	concurrency.WithLock(&s.evictionsMutex, func() {
+		s.evictedKeys = append(s.evictedKeys, key)
+	})
+
+	select {
+	case s.evictions <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Storage) runEvictions(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.evictions:
+			s.logEvictions(ctx)
+		}
+	}
+}
+
+// logEvictions writes DEL of evicted keys to the WAL, so replicas and recovery delete them too
+func (s *Storage) logEvictions(ctx context.Context) {
+	var keys []string
+	concurrency.WithLock(&s.evictionsMutex, func() {
+		keys, s.evictedKeys = s.evictedKeys, nil
+	})
+
+	for _, key := range keys {
+		if err := s.logEviction(ctx, key); err != nil {
+			s.logger.Error("failed to log evicted key", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+// logEviction locks the key, so DEL is logged in order with writes of the key,
+// it's skipped if the key is written again after the eviction
+func (s *Storage) logEviction(ctx context.Context, key string) error {
+	if !s.evictionAllowed() {
+		return ErrorMutableTX
+	}
+
+	unlock := s.keyLocks.lock(key)
+	defer unlock()
+
+	txID := s.snapshots.beginWrite()
+	defer s.endWrite(txID)
+	ctx = common.ContextWithTxID(ctx, txID)
+
+	var found bool
+	var err error
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		_, found, err = s.lookup(ctx, key)
+	})
+
+	// the key keeps a data structure if the error is returned
+	if found || err != nil {
+		return nil
+	}
+
+	futureResponse := s.wal.Del(ctx, key)
+	if err = futureResponse.Get(); !persisted(err) {
+		return err
+	}
+
+	s.committed(ctx, txID)
+	return err
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/storage/engine/memory"
+)
+
+// entrySize is the size of a key of one byte with a value of one byte
+const entrySize = 1 + 48 + 1 + 64
+
+func TestStorage_EvictionsLogged(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	logged := func(...any) concurrency.FutureError {
+		promise := concurrency.NewPromise[error]()
+		promise.Set(nil)
+		return promise.GetFuture()
+	}
+
+	var evicted []string
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, nil)
+	writeAheadLog.EXPECT().
+		Set(gomock.Any(), gomock.Any(), "1").
+		DoAndReturn(func(_ context.Context, _, _ string) concurrency.FutureError { return logged() }).
+		Times(3)
+	writeAheadLog.EXPECT().
+		Del(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, key string) concurrency.FutureError {
+			evicted = append(evicted, key)
+			return logged()
+		})
+
+	eng, err := memory.NewMemory(zap.NewNop(), memory.WithMaxMemory(2*entrySize, memory.AllKeysRandom))
+	require.NoError(t, err)
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, stor.Set(ctx, key, "1"))
+	}
+
+	stor.logEvictions(ctx)
+	require.Len(t, evicted, 1)
+	assert.NotEqual(t, "c", evicted[0])
+	_, err = stor.Get(ctx, evicted[0])
+	assert.ErrorIs(t, err, ErrorNotFound)
+}
+
+func TestStorage_EvictionsSkipped(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		isMaster bool
+		prepare  func(context.Context, *Storage)
+	}{
+		"key is written again": {
+			isMaster: true,
+			prepare: func(ctx context.Context, stor *Storage) {
+				stor.evicted("a")
+				stor.engine.Set(common.ContextWithTxID(ctx, 1), "a", "1")
+			},
+		},
+		"replica doesn't evict keys": {
+			prepare: func(ctx context.Context, stor *Storage) {
+				for idx, key := range []string{"a", "b", "c"} {
+					stor.engine.Set(common.ContextWithTxID(ctx, int64(idx+1)), key, "1")
+				}
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			controller := gomock.NewController(t)
+			writeAheadLog := NewMockwalI(controller)
+			writeAheadLog.EXPECT().
+				LastCheckpoint().
+				Return(int64(0), nil, nil)
+			writeAheadLog.EXPECT().
+				Recover().
+				Return(nil, nil)
+			replica := NewMockreplica(controller)
+			replica.EXPECT().
+				IsMaster().
+				Return(test.isMaster).
+				AnyTimes()
+
+			eng, err := memory.NewMemory(zap.NewNop(), memory.WithMaxMemory(2*entrySize, memory.AllKeysRandom))
+			require.NoError(t, err)
+			stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog), WithReplication(replica))
+			require.NoError(t, err)
+			ctx := context.Background()
+
+			test.prepare(ctx, stor)
+			stor.logEvictions(ctx)
+
+			for _, key := range []string{"a", "b", "c"} {
+				_, found := eng.Get(common.ContextWithTxID(ctx, 4), key)
+				assert.Equal(t, key == "a" || !test.isMaster, found)
+			}
+		})
+	}
+}
@@ -23,3 +23,19 @@ func (g *IDGenerator) Generate() int64 {
 	g.counter.CompareAndSwap(math.MaxInt64, 0)
 	return g.counter.Add(1)
 }
+
+// Current returns the last generated identifier
+func (g *IDGenerator) Current() int64 {
+	return g.counter.Load()
+}
+
+// Advance moves the generator forward to id if it is behind, it's used
+// for identifiers generated outside, e.g. replicated from master
+func (g *IDGenerator) Advance(id int64) {
+	for {
+		current := g.counter.Load()
+		if current >= id || g.counter.CompareAndSwap(current, id) {
+			return
+		}
+	}
+}
@@ -53,3 +53,16 @@ func TestGenerateIDOverflow(t *testing.T) {
 	nextID := generator.Generate()
 	assert.Equal(t, int64(1), nextID)
 }
+
+func TestAdvanceID(t *testing.T) {
+	t.Parallel()
+
+	generator := NewIDGenerator(10)
+
+	generator.Advance(5)
+	assert.Equal(t, int64(10), generator.Current())
+
+	generator.Advance(20)
+	assert.Equal(t, int64(20), generator.Current())
+	assert.Equal(t, int64(21), generator.Generate())
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"hash/fnv"
+	"slices"
+	"sync"
+)
+
+const keyLocksNumber = 256
+
+// keyLocks serialize writes of keys, so read-modify-write operations see no
+// concurrent writes of their keys and the WAL keeps writes of a key in order
+// of applying. Every write of strings and data structures locks its keys before
+// it's logged and unlocks them after it's applied. Locks are striped, so
+// different keys can share a mutex
+type keyLocks [keyLocksNumber]sync.Mutex
+
+// lock locks mutexes of keys in order of their indexes, so writers of
+// several keys don't deadlock, it returns the function unlocking them
+func (l *keyLocks) lock(keys ...string) func() {
+	indexes := make([]uint32, 0, len(keys))
+	for _, key := range keys {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(key))
+		indexes = append(indexes, hash.Sum32()%keyLocksNumber)
+	}
+
+	slices.Sort(indexes)
+	indexes = slices.Compact(indexes)
+	for _, idx := range indexes {
+		l[idx].Lock()
+	}
+
+	return func() {
+		for _, idx := range indexes {
+			l[idx].Unlock()
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"database-simon/internal/common"
+)
+
+// snapshottingEngine persists records itself and dumps its data
+type snapshottingEngine interface {
+	PersistedLSN() int64
+	Snapshot(context.Context) ([]byte, error)
+}
+
+// PersistedSnapshots are snapshots for replicas of the engine which persists records
+// itself. WAL segments of such engine are removed after flushes without checkpoints,
+// so the engine is dumped when a replica is behind removed segments
+type PersistedSnapshots struct {
+	engine snapshottingEngine
+}
+
+// NewPersistedSnapshots ...
+func NewPersistedSnapshots(engine snapshottingEngine) (*PersistedSnapshots, error) {
+	if engine == nil {
+		return nil, errors.New("engine is invalid")
+	}
+
+	return &PersistedSnapshots{engine: engine}, nil
+}
+
+// LastLSN returns the persisted LSN, segments are removed only up to it
+func (p *PersistedSnapshots) LastLSN() (int64, error) {
+	return p.engine.PersistedLSN(), nil
+}
+
+// Last dumps the engine with the persisted LSN, the snapshot can contain later
+// records too, but they are kept in the WAL and replaying of them is idempotent
+func (p *PersistedSnapshots) Last() (int64, []byte, error) {
+	lsn := p.engine.PersistedLSN()
+	data, err := p.engine.Snapshot(common.ContextWithTxID(context.Background(), lsn))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return lsn, data, nil
+}
@@ -0,0 +1,216 @@
+package raft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"database-simon/internal/database/storage/wal"
+)
+
+// entryHeaderSize is a size of the length and the checksum of the entry
+const entryHeaderSize = 8
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+var errIncorrectEntry = errors.New("incorrect log entry")
+
+type logFile interface {
+	Read() ([]byte, error)
+	Append([]byte) error
+	Truncate(int64) error
+}
+
+// Entry is a WAL record with the term of the leader which created it
+type Entry struct {
+	Term   int64
+	Record wal.Log
+}
+
+// raftLog keeps all entries in memory and persists them in the append-only
+// file, indexes of entries start from one, zero index is the empty log
+type raftLog struct {
+	file    logFile
+	entries []Entry
+	// offsets keeps the end of every entry in the file for truncation
+	offsets []int64
+}
+
+// newRaftLog loads entries from the file, a torn or corrupted tail is cut,
+// entries after it are not acknowledged because they are written before
+// responding, so the leader sends them again
+func newRaftLog(file logFile) (*raftLog, int64, error) {
+	data, err := file.Read()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries, offsets := decodeEntries(data)
+
+	var valid int64
+	if len(offsets) != 0 {
+		valid = offsets[len(offsets)-1]
+	}
+
+	cut := int64(len(data)) - valid
+	if cut != 0 {
+		if err = file.Truncate(valid); err != nil {
+			return nil, 0, fmt.Errorf("failed to cut corrupted tail: %w", err)
+		}
+	}
+
+	return &raftLog{
+		file:    file,
+		entries: entries,
+		offsets: offsets,
+	}, cut, nil
+}
+
+func (l *raftLog) lastIndex() int64 {
+	return int64(len(l.entries))
+}
+
+// term returns zero for the empty prefix and for missing entries
+func (l *raftLog) term(index int64) int64 {
+	if index <= 0 || index > l.lastIndex() {
+		return 0
+	}
+
+	return l.entries[index-1].Term
+}
+
+func (l *raftLog) entry(index int64) Entry {
+	return l.entries[index-1]
+}
+
+func (l *raftLog) append(entries ...Entry) error {
+	var size int64
+	if len(l.offsets) != 0 {
+		size = l.offsets[len(l.offsets)-1]
+	}
+
+	var data []byte
+	offsets := make([]int64, 0, len(entries))
+	for idx := range entries {
+		data = appendEntry(data, &entries[idx])
+		offsets = append(offsets, size+int64(len(data)))
+	}
+
+	if err := l.file.Append(data); err != nil {
+		return err
+	}
+
+	l.entries = append(l.entries, entries...)
+	l.offsets = append(l.offsets, offsets...)
+	return nil
+}
+
+// truncate removes the entry with the index and all following entries
+func (l *raftLog) truncate(index int64) error {
+	if index > l.lastIndex() {
+		return nil
+	}
+
+	var size int64
+	if index > 1 {
+		size = l.offsets[index-2]
+	}
+
+	if err := l.file.Truncate(size); err != nil {
+		return err
+	}
+
+	l.entries = l.entries[:index-1]
+	l.offsets = l.offsets[:index-1]
+	return nil
+}
+
+// slice returns entries from the index limited by the encoded size,
+// at least one entry is returned if it exists
+func (l *raftLog) slice(from int64, maxSize int) []Entry {
+	if from <= 0 || from > l.lastIndex() {
+		return nil
+	}
+
+	var start int64
+	if from > 1 {
+		start = l.offsets[from-2]
+	}
+
+	to := from
+	for to < l.lastIndex() && l.offsets[to]-start <= int64(maxSize) {
+		to++
+	}
+
+	return l.entries[from-1 : to]
+}
+
+func encodeEntries(entries []Entry) []byte {
+	var data []byte
+	for idx := range entries {
+		data = appendEntry(data, &entries[idx])
+	}
+
+	return data
+}
+
+// appendEntry frames the entry as [length][crc32c][uvarint term][record]
+func appendEntry(data []byte, entry *Entry) []byte {
+	start := len(data)
+	data = append(data, make([]byte, entryHeaderSize)...)
+	data = binary.AppendUvarint(data, uint64(entry.Term)) // nolint : G115: integer overflow conversion int64 -> uint64
+	data = entry.Record.AppendBinary(data)
+
+	payload := data[start+entryHeaderSize:]
+	binary.LittleEndian.PutUint32(data[start:], uint32(len(payload)))                // nolint : G115: integer overflow conversion int -> uint32
+	binary.LittleEndian.PutUint32(data[start+4:], crc32.Checksum(payload, crcTable)) // nolint : G115: integer overflow conversion int -> uint32
+	return data
+}
+
+// decodeEntries returns entries of the valid prefix of data
+// and the end offset of every entry
+func decodeEntries(data []byte) ([]Entry, []int64) {
+	var entries []Entry
+	var offsets []int64
+
+	offset := 0
+	for offset+entryHeaderSize <= len(data) {
+		length := int(binary.LittleEndian.Uint32(data[offset:]))
+		checksum := binary.LittleEndian.Uint32(data[offset+4:])
+		if length > len(data)-offset-entryHeaderSize {
+			break
+		}
+
+		payload := data[offset+entryHeaderSize : offset+entryHeaderSize+length]
+		if crc32.Checksum(payload, crcTable) != checksum {
+			break
+		}
+
+		entry, err := decodeEntry(payload)
+		if err != nil {
+			break
+		}
+
+		offset += entryHeaderSize + length
+		entries = append(entries, entry)
+		offsets = append(offsets, int64(offset))
+	}
+
+	return entries, offsets
+}
+
+func decodeEntry(payload []byte) (Entry, error) {
+	term, size := binary.Uvarint(payload)
+	if size <= 0 {
+		return Entry{}, errIncorrectEntry
+	}
+
+	var entry Entry
+	entry.Term = int64(term) // nolint : G115: integer overflow conversion uint64 -> int64
+	if err := entry.Record.DecodeBinary(payload[size:]); err != nil {
+		return Entry{}, errIncorrectEntry
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,72 @@
+package raft
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"database-simon/internal/database/filesystem"
+	"database-simon/internal/database/storage/wal"
+)
+
+func testEntry(term, lsn int64) Entry {
+	return Entry{
+		Term:   term,
+		Record: wal.Log{LSN: lsn, CommandID: "SET", Arguments: []string{"key", "value"}},
+	}
+}
+
+func TestRaftLog(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	file := filesystem.NewLogFile(directory, "raft_log")
+	defer func() { _ = file.Close() }()
+
+	log, cut, err := newRaftLog(file)
+	require.NoError(t, err)
+	assert.Zero(t, cut)
+	assert.Equal(t, int64(0), log.lastIndex())
+
+	require.NoError(t, log.append(testEntry(1, 1), testEntry(1, 2)))
+	require.NoError(t, log.append(testEntry(2, 3)))
+
+	// conflicting entries are replaced
+	require.NoError(t, log.truncate(2))
+	require.NoError(t, log.append(testEntry(3, 4)))
+
+	log, cut, err = newRaftLog(filesystem.NewLogFile(directory, "raft_log"))
+	require.NoError(t, err)
+	assert.Zero(t, cut)
+	assert.Equal(t, []Entry{testEntry(1, 1), testEntry(3, 4)}, log.entries)
+	assert.Equal(t, int64(3), log.term(2))
+	assert.Equal(t, int64(0), log.term(3))
+
+	assert.Len(t, log.slice(1, 0), 1)
+	assert.Len(t, log.slice(1, 1<<10), 2)
+	assert.Empty(t, log.slice(3, 1<<10))
+}
+
+func TestRaftLogTornTail(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	file := filesystem.NewLogFile(directory, "raft_log")
+	defer func() { _ = file.Close() }()
+
+	data := encodeEntries([]Entry{testEntry(1, 1), testEntry(1, 2)})
+	require.NoError(t, file.Append(data[:len(data)-3]))
+
+	log, cut, err := newRaftLog(file)
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{testEntry(1, 1)}, log.entries)
+	assert.NotZero(t, cut)
+
+	require.NoError(t, log.append(testEntry(2, 3)))
+
+	log, cut, err = newRaftLog(filesystem.NewLogFile(directory, "raft_log"))
+	require.NoError(t, err)
+	assert.Zero(t, cut)
+	assert.Equal(t, []Entry{testEntry(1, 1), testEntry(2, 3)}, log.entries)
+}
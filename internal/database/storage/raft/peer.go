@@ -0,0 +1,94 @@
+package raft
+
+import (
+	"sync"
+)
+
+// Client ...
+type Client interface {
+	Send([]byte) ([]byte, error)
+	Close()
+}
+
+// Dialer connects to the peer on the address
+type Dialer func(address string) (Client, error)
+
+// peer keeps the connection to another node of the cluster, requests to
+// the peer are sent one by one, the connection is restored on the next request
+type peer struct {
+	id      string
+	address string
+	dial    Dialer
+
+	mutex  sync.Mutex
+	client Client
+
+	// indexes are guarded by the mutex of the node
+	nextIndex  int64
+	matchIndex int64
+	trigger    chan struct{}
+}
+
+func newPeer(id, address string, dial Dialer) *peer {
+	return &peer{
+		id:      id,
+		address: address,
+		dial:    dial,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+func (p *peer) send(request *Request) (Response, error) {
+	requestData, err := Encode(request)
+	if err != nil {
+		return Response{}, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.client == nil {
+		client, err := p.dial(p.address)
+		if err != nil {
+			return Response{}, err
+		}
+
+		p.client = client
+	}
+
+	responseData, err := p.client.Send(requestData)
+	if err != nil {
+		p.disconnect()
+		return Response{}, err
+	}
+
+	var response Response
+	if err = Decode(&response, responseData); err != nil {
+		p.disconnect()
+		return Response{}, err
+	}
+
+	return response, nil
+}
+
+func (p *peer) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.client != nil {
+		p.disconnect()
+	}
+}
+
+func (p *peer) disconnect() {
+	p.client.Close()
+	p.client = nil
+}
+
+// notify wakes up replication to the peer
+func (p *peer) notify() {
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,63 @@
+package raft
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// VoteRequest asks a peer to vote for the candidate in the term
+type VoteRequest struct {
+	Term        int64
+	CandidateID string
+	LastIndex   int64
+	LastTerm    int64
+}
+
+// AppendRequest replicates entries which follow the entry with
+// PrevIndex, requests without entries are heartbeats of the leader
+type AppendRequest struct {
+	Term         int64
+	LeaderID     string
+	PrevIndex    int64
+	PrevTerm     int64
+	Entries      []byte
+	LeaderCommit int64
+}
+
+// Request contains one of the requests
+type Request struct {
+	Vote   *VoteRequest
+	Append *AppendRequest
+}
+
+// Response is a common response to requests, LastIndex is a hint of
+// the follower, it has no entries after it which match the leader
+type Response struct {
+	Term      int64
+	Granted   bool
+	Success   bool
+	LastIndex int64
+}
+
+// Encode ...
+func Encode[ProtocolObject Request | Response](object *ProtocolObject) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := gob.NewEncoder(&buffer)
+	if err := encoder.Encode(object); err != nil {
+		return nil, fmt.Errorf("failed to encode object: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Decode ...
+func Decode[ProtocolObject Request | Response](object *ProtocolObject, data []byte) error {
+	buffer := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(object); err != nil {
+		return fmt.Errorf("failed to decode object: %w", err)
+	}
+
+	return nil
+}
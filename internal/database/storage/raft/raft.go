@@ -0,0 +1,778 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/compute"
+	"database-simon/internal/database/storage/wal"
+)
+
+var (
+	// ErrorNotLeader ...
+	ErrorNotLeader = errors.New("node is not leader")
+	// ErrorCommitTimeout ...
+	ErrorCommitTimeout = errors.New("entry is not committed by majority in time")
+	// ErrorNoCheckpoints ...
+	ErrorNoCheckpoints = errors.New("checkpoints are not supported in consensus mode")
+)
+
+// requestOverhead is reserved in the message for encoding
+// of the request, the rest is used for entries
+const requestOverhead = 1 << 10
+
+// noopCommand is appended by a new leader, entries of previous
+// terms are committed only with an entry of the current term
+const noopCommand = "NOOP"
+
+type role int
+
+const (
+	follower role = iota
+	candidate
+	leader
+)
+
+// TCPServer ...
+type TCPServer interface {
+	HandleQueries(context.Context, func(context.Context, []byte) []byte)
+}
+
+type proposal struct {
+	promise concurrency.PromiseError
+	timer   *time.Timer
+}
+
+// Raft replicates WAL records of the cluster, one node is elected as leader
+// and accepts writes, a write is committed when the majority persisted it.
+// Raft replaces WAL of the storage, committed entries of other nodes
+// are applied through the replication stream
+type Raft struct {
+	id     string
+	peers  []*peer
+	server TCPServer
+	state  stateFile
+	stream chan []wal.Log
+	logger *zap.Logger
+
+	electionTimeout   time.Duration
+	heartbeatInterval time.Duration
+	commitTimeout     time.Duration
+	maxMessageSize    int
+	onLeader          func(int64)
+
+	mutex    sync.Mutex
+	log      *raftLog
+	term     int64
+	votedFor string
+	role     role
+	leaderID string
+	votes    int
+	deadline time.Time
+
+	commitIndex int64
+	lastApplied int64
+	applied     chan struct{}
+
+	// the leader accepts writes after entries of previous terms are applied
+	ready        bool
+	noopIndex    int64
+	pending      map[int64]*proposal
+	ctx          context.Context
+	leaderCancel context.CancelFunc
+}
+
+// NewRaft creates a node of the cluster, peers are other nodes by their identifiers
+func NewRaft(
+	id string,
+	peers map[string]string,
+	server TCPServer,
+	dial Dialer,
+	logFile logFile,
+	stateFile stateFile,
+	logger *zap.Logger,
+	options ...Option,
+) (*Raft, error) {
+	if id == "" {
+		return nil, errors.New("node id is invalid")
+	}
+
+	if server == nil {
+		return nil, errors.New("server is invalid")
+	}
+
+	if dial == nil && len(peers) != 0 {
+		return nil, errors.New("dialer is invalid")
+	}
+
+	if logFile == nil || stateFile == nil {
+		return nil, errors.New("files are invalid")
+	}
+
+	if logger == nil {
+		return nil, errors.New("logger is invalid")
+	}
+
+	log, cut, err := newRaftLog(logFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load log: %w", err)
+	} else if cut != 0 {
+		logger.Warn("corrupted tail of raft log is cut", zap.Int64("size", cut))
+	}
+
+	state, err := loadState(stateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	r := &Raft{
+		id:                id,
+		server:            server,
+		state:             stateFile,
+		stream:            make(chan []wal.Log),
+		logger:            logger,
+		electionTimeout:   defaultElectionTimeout,
+		heartbeatInterval: defaultHeartbeatInterval,
+		commitTimeout:     defaultCommitTimeout,
+		maxMessageSize:    defaultMaxMessageSize,
+		log:               log,
+		term:              state.Term,
+		votedFor:          state.VotedFor,
+		applied:           make(chan struct{}, 1),
+		pending:           make(map[int64]*proposal),
+	}
+
+	for peerID, address := range peers {
+		if peerID == id {
+			return nil, errors.New("node is listed in peers")
+		}
+
+		r.peers = append(r.peers, newPeer(peerID, address, dial))
+	}
+
+	for _, option := range options {
+		option(r)
+	}
+
+	if r.heartbeatInterval >= r.electionTimeout {
+		return nil, errors.New("heartbeat interval must be less than election timeout")
+	}
+
+	if r.maxMessageSize <= requestOverhead {
+		return nil, errors.New("max message size is too small")
+	}
+
+	return r, nil
+}
+
+// Start serves requests of peers, runs elections and applies committed entries
+func (r *Raft) Start(ctx context.Context) {
+	r.mutex.Lock()
+	r.ctx = ctx
+	r.resetDeadline()
+	r.mutex.Unlock()
+
+	go r.server.HandleQueries(ctx, func(ctx context.Context, requestData []byte) []byte {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		var request Request
+		if err := Decode(&request, requestData); err != nil {
+			r.logger.Error("failed to decode raft request", zap.Error(err))
+			return nil
+		}
+
+		response := r.handle(&request)
+		responseData, err := Encode(&response)
+		if err != nil {
+			r.logger.Error("failed to encode raft response", zap.Error(err))
+		}
+
+		return responseData
+	})
+
+	go r.apply(ctx)
+
+	go func() {
+		ticker := time.NewTicker(r.heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				r.stop()
+				return
+			case <-ticker.C:
+				r.tick()
+			}
+		}
+	}()
+}
+
+// IsMaster returns true if the node is the leader which accepts writes
+func (r *Raft) IsMaster() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.role == leader && r.ready
+}
+
+// Leader returns the identifier of the known leader
+func (r *Raft) Leader() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.leaderID
+}
+
+// ReplicationStream returns committed entries which are not written through this node
+func (r *Raft) ReplicationStream() <-chan []wal.Log {
+	return r.stream
+}
+
+// Recover returns nothing, committed entries are replayed through the
+// replication stream when the cluster commits them again. The log isn't
+// compacted, so the whole log is kept and replayed on each start
+func (r *Raft) Recover() ([]wal.Log, error) {
+	return nil, nil
+}
+
+// Checkpoint ...
+func (r *Raft) Checkpoint(int64, []byte) error {
+	return ErrorNoCheckpoints
+}
+
+// LastCheckpoint ...
+func (r *Raft) LastCheckpoint() (int64, []byte, error) {
+	return 0, nil, nil
+}
+
+// Set ...
+func (r *Raft) Set(ctx context.Context, key, value string) concurrency.FutureError {
+	return r.propose(ctx, compute.SetCommand, []string{key, value})
+}
+
+// SetWithExpiration ...
+func (r *Raft) SetWithExpiration(ctx context.Context, key, value string, deadline time.Time) concurrency.FutureError {
+	return r.propose(ctx, compute.SetCommand, []string{key, value, wal.EncodeDeadline(deadline)})
+}
+
+// Del ...
+func (r *Raft) Del(ctx context.Context, key string) concurrency.FutureError {
+	return r.propose(ctx, compute.DelCommand, []string{key})
+}
+
+// Expire ...
+func (r *Raft) Expire(ctx context.Context, key string, deadline time.Time) concurrency.FutureError {
+	return r.propose(ctx, compute.PExpireAtCommand, []string{key, wal.EncodeDeadline(deadline)})
+}
+
+// Persist ...
+func (r *Raft) Persist(ctx context.Context, key string) concurrency.FutureError {
+	return r.propose(ctx, compute.PersistCommand, []string{key})
+}
+
+// Write ...
+func (r *Raft) Write(ctx context.Context, operation wal.Operation) concurrency.FutureError {
+	return r.propose(ctx, operation.CommandID, operation.Arguments)
+}
+
+// Commit ...
+func (r *Raft) Commit(ctx context.Context, operations []wal.Operation) concurrency.FutureError {
+	return r.propose(ctx, compute.CommitCommand, wal.EncodeOperations(operations))
+}
+
+// propose appends the record to the log of the leader, the future is resolved
+// when the entry is committed, the caller applies the record itself
+func (r *Raft) propose(ctx context.Context, commandID string, args []string) concurrency.FutureError {
+	txID := common.GetTxIDFromContext(ctx)
+	promise := concurrency.NewPromise[error]()
+	future := promise.GetFuture()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.role != leader || !r.ready {
+		promise.Set(ErrorNotLeader)
+		return future
+	}
+
+	entry := Entry{
+		Term:   r.term,
+		Record: wal.Log{LSN: txID, CommandID: commandID, Arguments: args},
+	}
+
+	if err := r.log.append(entry); err != nil {
+		promise.Set(fmt.Errorf("failed to append entry: %w", err))
+		return future
+	}
+
+	index := r.log.lastIndex()
+	p := &proposal{promise: promise}
+	p.timer = time.AfterFunc(r.commitTimeout, func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		// the entry can be committed later, then it's applied through the stream
+		r.resolve(index, p, ErrorCommitTimeout)
+	})
+
+	r.pending[index] = p
+	for _, peer := range r.peers {
+		peer.notify()
+	}
+
+	r.advanceCommit()
+	return future
+}
+
+func (r *Raft) resolve(index int64, p *proposal, err error) {
+	if r.pending[index] != p {
+		return
+	}
+
+	delete(r.pending, index)
+	p.timer.Stop()
+	p.promise.Set(err)
+}
+
+func (r *Raft) handle(request *Request) Response {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch {
+	case request.Vote != nil:
+		return r.handleVote(request.Vote)
+	case request.Append != nil:
+		return r.handleAppend(request.Append)
+	default:
+		return Response{Term: r.term}
+	}
+}
+
+func (r *Raft) handleVote(request *VoteRequest) Response {
+	if request.Term > r.term {
+		if err := r.becomeFollower(request.Term); err != nil {
+			return Response{Term: r.term}
+		}
+	}
+
+	response := Response{Term: r.term}
+	if request.Term < r.term || (r.votedFor != "" && r.votedFor != request.CandidateID) {
+		return response
+	}
+
+	// the candidate must have all committed entries, so its log is not older
+	lastIndex := r.log.lastIndex()
+	lastTerm := r.log.term(lastIndex)
+	if request.LastTerm < lastTerm || (request.LastTerm == lastTerm && request.LastIndex < lastIndex) {
+		return response
+	}
+
+	r.votedFor = request.CandidateID
+	if err := r.persist(); err != nil {
+		r.votedFor = ""
+		return response
+	}
+
+	r.resetDeadline()
+	response.Granted = true
+	return response
+}
+
+func (r *Raft) handleAppend(request *AppendRequest) Response {
+	if request.Term < r.term {
+		return Response{Term: r.term, LastIndex: r.log.lastIndex()}
+	}
+
+	if request.Term > r.term || r.role != follower {
+		if err := r.becomeFollower(request.Term); err != nil {
+			return Response{Term: r.term, LastIndex: r.log.lastIndex()}
+		}
+	}
+
+	r.leaderID = request.LeaderID
+	r.resetDeadline()
+
+	response := Response{Term: r.term, LastIndex: r.log.lastIndex()}
+	entries, offsets := decodeEntries(request.Entries)
+	if len(request.Entries) != 0 && (len(offsets) == 0 || offsets[len(offsets)-1] != int64(len(request.Entries))) {
+		r.logger.Warn("incorrect entries from leader", zap.String("leader", request.LeaderID))
+		return response
+	}
+
+	if request.PrevIndex > r.log.lastIndex() {
+		return response
+	} else if r.log.term(request.PrevIndex) != request.PrevTerm {
+		response.LastIndex = request.PrevIndex - 1
+		return response
+	}
+
+	for idx := range entries {
+		index := request.PrevIndex + int64(idx) + 1
+		if r.log.term(index) == entries[idx].Term {
+			continue
+		}
+
+		// conflicting entries are never committed, they are replaced by entries of the leader
+		err := r.log.truncate(index)
+		if err == nil {
+			err = r.log.append(entries[idx:]...)
+		}
+
+		if err != nil {
+			r.logger.Error("failed to write raft log", zap.Error(err))
+			response.LastIndex = min(r.log.lastIndex(), request.PrevIndex)
+			return response
+		}
+
+		break
+	}
+
+	lastNewIndex := request.PrevIndex + int64(len(entries))
+	if request.LeaderCommit > r.commitIndex {
+		r.commitIndex = max(r.commitIndex, min(request.LeaderCommit, lastNewIndex))
+		r.notifyApplier()
+	}
+
+	response.Success = true
+	response.LastIndex = r.log.lastIndex()
+	return response
+}
+
+func (r *Raft) tick() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.role != leader && time.Now().After(r.deadline) {
+		r.startElection()
+	}
+}
+
+func (r *Raft) startElection() {
+	r.term++
+	r.votedFor = r.id
+	if err := r.persist(); err != nil {
+		r.term--
+		r.votedFor = ""
+		r.resetDeadline()
+		return
+	}
+
+	r.role = candidate
+	r.leaderID = ""
+	r.votes = 1
+	r.resetDeadline()
+	r.logger.Info("election is started", zap.String("node", r.id), zap.Int64("term", r.term))
+
+	lastIndex := r.log.lastIndex()
+	request := &Request{Vote: &VoteRequest{
+		Term:        r.term,
+		CandidateID: r.id,
+		LastIndex:   lastIndex,
+		LastTerm:    r.log.term(lastIndex),
+	}}
+
+	if r.votes >= r.majority() {
+		r.becomeLeader()
+		return
+	}
+
+	for _, peer := range r.peers {
+		go func() {
+			response, err := peer.send(request)
+			if err != nil {
+				return
+			}
+
+			r.mutex.Lock()
+			defer r.mutex.Unlock()
+
+			if response.Term > r.term {
+				// the error is logged, the greater term is learned from the next response
+				_ = r.becomeFollower(response.Term)
+				return
+			}
+
+			if r.role != candidate || r.term != request.Vote.Term || !response.Granted {
+				return
+			}
+
+			r.votes++
+			if r.votes == r.majority() {
+				r.becomeLeader()
+			}
+		}()
+	}
+}
+
+func (r *Raft) becomeLeader() {
+	r.role = leader
+	r.leaderID = r.id
+	r.ready = false
+	r.logger.Info("node is elected leader", zap.String("node", r.id), zap.Int64("term", r.term))
+
+	if r.onLeader != nil {
+		r.onLeader(r.term)
+	}
+
+	if err := r.log.append(Entry{Term: r.term, Record: wal.Log{CommandID: noopCommand}}); err != nil {
+		r.logger.Error("failed to write raft log", zap.Error(err))
+		r.role = follower
+		r.resetDeadline()
+		return
+	}
+
+	r.noopIndex = r.log.lastIndex()
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.leaderCancel = cancel
+	for _, peer := range r.peers {
+		peer.nextIndex = r.noopIndex
+		peer.matchIndex = 0
+		go r.replicate(ctx, peer, r.term)
+	}
+
+	r.advanceCommit()
+}
+
+// becomeFollower steps down even if the greater term isn't persisted, the term
+// isn't adopted then, so the node doesn't respond in it and learns it again
+func (r *Raft) becomeFollower(term int64) error {
+	var err error
+	if term > r.term {
+		previousTerm, previousVote := r.term, r.votedFor
+		r.term = term
+		r.votedFor = ""
+		if err = r.persist(); err != nil {
+			r.term, r.votedFor = previousTerm, previousVote
+		}
+	}
+
+	if r.role == leader {
+		r.logger.Info("leader steps down", zap.String("node", r.id), zap.Int64("term", r.term))
+		r.leaderCancel()
+		for index, p := range r.pending {
+			r.resolve(index, p, ErrorNotLeader)
+		}
+	}
+
+	r.role = follower
+	r.ready = false
+	r.resetDeadline()
+	return err
+}
+
+// replicate sends entries to the peer while the node is leader of the term,
+// heartbeats are sent when there are no new entries
+func (r *Raft) replicate(ctx context.Context, peer *peer, term int64) {
+	ticker := time.NewTicker(r.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		r.sendEntries(peer, term)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-peer.trigger:
+		}
+	}
+}
+
+func (r *Raft) sendEntries(peer *peer, term int64) {
+	r.mutex.Lock()
+	if r.role != leader || r.term != term {
+		r.mutex.Unlock()
+		return
+	}
+
+	prevIndex := peer.nextIndex - 1
+	entries := r.log.slice(peer.nextIndex, r.maxMessageSize-requestOverhead)
+	request := &Request{Append: &AppendRequest{
+		Term:         term,
+		LeaderID:     r.id,
+		PrevIndex:    prevIndex,
+		PrevTerm:     r.log.term(prevIndex),
+		Entries:      encodeEntries(entries),
+		LeaderCommit: r.commitIndex,
+	}}
+	r.mutex.Unlock()
+
+	response, err := peer.send(request)
+	if err != nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if response.Term > r.term {
+		// the error is logged, the greater term is learned from the next response
+		_ = r.becomeFollower(response.Term)
+		return
+	} else if r.role != leader || r.term != term {
+		return
+	}
+
+	if !response.Success {
+		// the follower has no matching entries after its last index
+		peer.nextIndex = max(1, min(prevIndex, response.LastIndex+1))
+		peer.notify()
+		return
+	}
+
+	matchIndex := prevIndex + int64(len(entries))
+	peer.matchIndex = max(peer.matchIndex, matchIndex)
+	peer.nextIndex = max(peer.nextIndex, matchIndex+1)
+	if peer.nextIndex <= r.log.lastIndex() {
+		peer.notify()
+	}
+
+	r.advanceCommit()
+}
+
+// advanceCommit commits the last entry of the current term which
+// is persisted by the majority, with all entries before it
+func (r *Raft) advanceCommit() {
+	if r.role != leader {
+		return
+	}
+
+	for index := r.log.lastIndex(); index > r.commitIndex && r.log.term(index) == r.term; index-- {
+		replicas := 1
+		for _, peer := range r.peers {
+			if peer.matchIndex >= index {
+				replicas++
+			}
+		}
+
+		if replicas >= r.majority() {
+			r.commitIndex = index
+			r.notifyApplier()
+			return
+		}
+	}
+}
+
+// apply delivers committed entries, proposals of the node are resolved and
+// the rest is written to the stream in order of the log
+func (r *Raft) apply(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.applied:
+		}
+
+		for ctx.Err() == nil {
+			logs, barrier, applied := r.nextCommitted()
+			if !applied {
+				break
+			}
+
+			if len(logs) != 0 {
+				r.send(ctx, logs)
+			}
+
+			if barrier != 0 {
+				// the empty batch is received after the previous batch is applied
+				r.send(ctx, nil)
+				r.mutex.Lock()
+				r.ready = r.role == leader && r.term == barrier
+				r.mutex.Unlock()
+			}
+		}
+	}
+}
+
+// nextCommitted returns records of committed entries up to the next proposal
+// of the node, the barrier is the term of the reached entry of the new leader
+func (r *Raft) nextCommitted() ([]wal.Log, int64, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.lastApplied >= r.commitIndex {
+		return nil, 0, false
+	}
+
+	var logs []wal.Log
+	for r.lastApplied < r.commitIndex {
+		index := r.lastApplied + 1
+		entry := r.log.entry(index)
+
+		if p, found := r.pending[index]; found {
+			if len(logs) != 0 {
+				break
+			}
+
+			r.resolve(index, p, nil)
+		} else if entry.Record.CommandID == noopCommand {
+			if r.role == leader && index == r.noopIndex {
+				r.lastApplied = index
+				return logs, entry.Term, true
+			}
+		} else {
+			logs = append(logs, entry.Record)
+		}
+
+		r.lastApplied = index
+	}
+
+	return logs, 0, true
+}
+
+func (r *Raft) send(ctx context.Context, logs []wal.Log) {
+	select {
+	case r.stream <- logs:
+	case <-ctx.Done():
+	}
+}
+
+func (r *Raft) stop() {
+	r.mutex.Lock()
+	if r.role == leader {
+		// the term isn't changed, so nothing is persisted
+		_ = r.becomeFollower(r.term)
+	}
+	r.mutex.Unlock()
+
+	for _, peer := range r.peers {
+		peer.close()
+	}
+}
+
+func (r *Raft) persist() error {
+	err := storeState(r.state, persistentState{Term: r.term, VotedFor: r.votedFor})
+	if err != nil {
+		r.logger.Error("failed to persist raft state", zap.Error(err))
+	}
+
+	return err
+}
+
+func (r *Raft) notifyApplier() {
+	select {
+	case r.applied <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Raft) resetDeadline() {
+	timeout := r.electionTimeout + rand.N(r.electionTimeout) // nolint : G404: Use of weak random number generator
+	r.deadline = time.Now().Add(timeout)
+}
+
+func (r *Raft) majority() int {
+	return (len(r.peers)+1)/2 + 1
+}
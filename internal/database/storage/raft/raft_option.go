@@ -0,0 +1,53 @@
+package raft
+
+import (
+	"time"
+)
+
+const (
+	defaultElectionTimeout   = 300 * time.Millisecond
+	defaultHeartbeatInterval = 50 * time.Millisecond
+	defaultCommitTimeout     = time.Second
+	defaultMaxMessageSize    = 4 << 20
+)
+
+// Option ...
+type Option func(*Raft)
+
+// WithElectionTimeout sets the minimal timeout, the actual timeout is
+// chosen randomly up to the doubled value, so nodes rarely split votes
+func WithElectionTimeout(timeout time.Duration) Option {
+	return func(raft *Raft) {
+		raft.electionTimeout = timeout
+	}
+}
+
+// WithHeartbeatInterval ...
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(raft *Raft) {
+		raft.heartbeatInterval = interval
+	}
+}
+
+// WithCommitTimeout limits waiting for the majority by writes
+func WithCommitTimeout(timeout time.Duration) Option {
+	return func(raft *Raft) {
+		raft.commitTimeout = timeout
+	}
+}
+
+// WithMaxMessageSize limits the size of requests, entries
+// are sent to followers in chunks of this size
+func WithMaxMessageSize(size int) Option {
+	return func(raft *Raft) {
+		raft.maxMessageSize = size
+	}
+}
+
+// WithOnLeader sets the handler which is called with the term
+// when the node becomes leader, before it accepts writes
+func WithOnLeader(handler func(int64)) Option {
+	return func(raft *Raft) {
+		raft.onLeader = handler
+	}
+}
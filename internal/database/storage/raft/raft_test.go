@@ -0,0 +1,294 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/database/filesystem"
+	"database-simon/internal/database/storage/wal"
+	"database-simon/internal/network/client"
+	"database-simon/internal/network/server"
+)
+
+// testNode is a node of the in-process cluster over loopback,
+// records of its replication stream are collected for checks
+type testNode struct {
+	id        string
+	directory string
+	raft      *Raft
+	cancel    context.CancelFunc
+
+	mutex sync.Mutex
+	keys  []string
+}
+
+type testCluster struct {
+	t         *testing.T
+	addresses map[string]string
+	nodes     map[string]*testNode
+}
+
+func newTestCluster(t *testing.T, port int) *testCluster {
+	cluster := &testCluster{
+		t:         t,
+		addresses: make(map[string]string),
+		nodes:     make(map[string]*testNode),
+	}
+
+	for idx := 1; idx <= 3; idx++ {
+		id := fmt.Sprintf("node_%d", idx)
+		cluster.addresses[id] = fmt.Sprintf("127.0.0.1:%d", port+idx)
+	}
+
+	directory := t.TempDir()
+	for id := range cluster.addresses {
+		cluster.nodes[id] = &testNode{id: id, directory: directory + "/" + id}
+		cluster.start(id)
+	}
+
+	t.Cleanup(func() {
+		for _, node := range cluster.nodes {
+			if node.cancel != nil {
+				node.cancel()
+			}
+		}
+	})
+
+	return cluster
+}
+
+func (c *testCluster) start(id string) {
+	node := c.nodes[id]
+	peers := make(map[string]string)
+	for peerID, address := range c.addresses {
+		if peerID != id {
+			peers[peerID] = address
+		}
+	}
+
+	s, err := server.NewTCPServer(c.addresses[id], zap.NewNop())
+	require.NoError(c.t, err)
+
+	dial := func(address string) (Client, error) {
+		return client.NewTCPClient(address, client.WithClientRequestTimeout(100*time.Millisecond))
+	}
+
+	node.raft, err = NewRaft(
+		id,
+		peers,
+		s,
+		dial,
+		filesystem.NewLogFile(node.directory, "raft_log"),
+		filesystem.NewStateFile(node.directory, "raft_state"),
+		zap.NewNop(),
+		WithElectionTimeout(150*time.Millisecond),
+		WithHeartbeatInterval(20*time.Millisecond),
+		WithCommitTimeout(500*time.Millisecond),
+	)
+	require.NoError(c.t, err)
+
+	node.mutex.Lock()
+	node.keys = nil
+	node.mutex.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	node.cancel = cancel
+	node.raft.Start(ctx)
+
+	stream := node.raft.ReplicationStream()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case logs := <-stream:
+				node.mutex.Lock()
+				for _, log := range logs {
+					node.keys = append(node.keys, log.Arguments[0])
+				}
+				node.mutex.Unlock()
+			}
+		}
+	}()
+}
+
+func (c *testCluster) stop(id string) {
+	c.nodes[id].cancel()
+	c.nodes[id].cancel = nil
+}
+
+// leader waits for a single leader among running nodes
+func (c *testCluster) leader() *testNode {
+	var found *testNode
+	require.Eventually(c.t, func() bool {
+		found = nil
+		for _, node := range c.nodes {
+			if node.cancel != nil && node.raft.IsMaster() {
+				if found != nil {
+					return false
+				}
+				found = node
+			}
+		}
+		return found != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return found
+}
+
+func (n *testNode) set(lsn int64, key string) error {
+	ctx := common.ContextWithTxID(context.Background(), lsn)
+	future := n.raft.Set(ctx, key, "value")
+	return future.Get()
+}
+
+func (n *testNode) term() int64 {
+	n.raft.mutex.Lock()
+	defer n.raft.mutex.Unlock()
+
+	return n.raft.term
+}
+
+func (n *testNode) replicated() []string {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	return append([]string(nil), n.keys...)
+}
+
+func TestRaftCluster(t *testing.T) {
+	t.Parallel()
+
+	cluster := newTestCluster(t, 17000)
+
+	first := cluster.leader()
+	require.NoError(t, first.set(1, "key_1"))
+
+	for _, node := range cluster.nodes {
+		if node != first {
+			assert.Eventually(t, func() bool {
+				return assert.ObjectsAreEqual([]string{"key_1"}, node.replicated())
+			}, time.Second, 10*time.Millisecond)
+		}
+	}
+
+	// followers reject writes
+	for _, node := range cluster.nodes {
+		if node != first {
+			assert.Equal(t, ErrorNotLeader, node.set(2, "key_2"))
+		}
+	}
+
+	// a new leader is elected automatically and commits with the majority
+	cluster.stop(first.id)
+	second := cluster.leader()
+	term := second.term()
+	assert.Greater(t, term, int64(1))
+	require.NoError(t, second.set(term<<40+1, "key_2"))
+
+	// the old leader catches up after restart, committed entries are replayed
+	cluster.start(first.id)
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"key_1", "key_2"}, first.replicated())
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// a write is not committed without the majority
+	for _, node := range cluster.nodes {
+		if node != second {
+			cluster.stop(node.id)
+		}
+	}
+
+	err := second.set(term<<40+2, "key_3")
+	assert.Contains(t, []error{ErrorNotLeader, ErrorCommitTimeout}, err)
+}
+
+func TestRaftSingleNode(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	s, err := server.NewTCPServer("127.0.0.1:17010", zap.NewNop())
+	require.NoError(t, err)
+
+	r, err := NewRaft(
+		"node_1",
+		nil,
+		s,
+		nil,
+		filesystem.NewLogFile(directory, "raft_log"),
+		filesystem.NewStateFile(directory, "raft_state"),
+		zap.NewNop(),
+		WithElectionTimeout(50*time.Millisecond),
+		WithHeartbeatInterval(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Start(ctx)
+
+	go func() {
+		for range r.ReplicationStream() {
+		}
+	}()
+
+	require.Eventually(t, r.IsMaster, time.Second, 10*time.Millisecond)
+
+	future := r.Commit(common.ContextWithTxID(ctx, 1), []wal.Operation{wal.NewOperation("SET", []string{"key", "value"})})
+	assert.NoError(t, future.Get())
+	assert.Equal(t, int64(2), r.log.lastIndex())
+}
+
+// idleServer doesn't listen, requests are handled by calling the node directly
+type idleServer struct{}
+
+func (idleServer) HandleQueries(ctx context.Context, _ func(context.Context, []byte) []byte) {
+	<-ctx.Done()
+}
+
+type failedStateFile struct{}
+
+func (failedStateFile) Load() ([]byte, error) {
+	return nil, nil
+}
+
+func (failedStateFile) Store([]byte) error {
+	return errors.New("disk is full")
+}
+
+func TestRaftGreaterTermNotPersisted(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRaft(
+		"node_1",
+		nil,
+		idleServer{},
+		nil,
+		filesystem.NewLogFile(t.TempDir(), "raft_log"),
+		failedStateFile{},
+		zap.NewNop(),
+	)
+	require.NoError(t, err)
+
+	// the candidate steps down, but doesn't grant the vote in the term which isn't persisted
+	r.role = candidate
+	response := r.handleVote(&VoteRequest{Term: 2, CandidateID: "node_2"})
+	assert.Equal(t, Response{}, response)
+	assert.Equal(t, follower, r.role)
+	assert.Empty(t, r.votedFor)
+
+	// entries of the leader of the greater term aren't accepted
+	response = r.handleAppend(&AppendRequest{Term: 2, LeaderID: "node_2"})
+	assert.Equal(t, Response{LastIndex: r.log.lastIndex()}, response)
+	assert.Empty(t, r.leaderID)
+	assert.Equal(t, int64(0), r.term)
+}
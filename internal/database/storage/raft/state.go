@@ -0,0 +1,42 @@
+package raft
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+type stateFile interface {
+	Load() ([]byte, error)
+	Store([]byte) error
+}
+
+// persistentState is stored before responding to peers,
+// so a node never votes twice in the same term after restart
+type persistentState struct {
+	Term     int64
+	VotedFor string
+}
+
+func loadState(file stateFile) (persistentState, error) {
+	var state persistentState
+	data, err := file.Load()
+	if err != nil || data == nil {
+		return state, err
+	}
+
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&state); err != nil {
+		return state, fmt.Errorf("failed to decode state: %w", err)
+	}
+
+	return state, nil
+}
+
+func storeState(file stateFile, state persistentState) error {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(state); err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	return file.Store(buffer.Bytes())
+}
@@ -0,0 +1,180 @@
+package replication
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+)
+
+// staleReplicaTimeout is a time after which a silent replica is forgotten
+const staleReplicaTimeout = time.Minute
+
+type waiter struct {
+	acks int
+	done chan struct{}
+}
+
+// replicaState keeps LSN of records sent to the replica in the last response,
+// they are persisted by the replica when it asks for records after the last of them
+type replicaState struct {
+	sent     []int64
+	caughtUp bool
+	lastSeen time.Time
+}
+
+// Acknowledgements counts replicas which persisted records, writes wait for
+// the required number of replicas, on timeout they fail or, if degrading is
+// allowed, replication becomes asynchronous until replicas catch up
+type Acknowledgements struct {
+	required int
+	timeout  time.Duration
+	degrade  bool
+	logger   *zap.Logger
+
+	mutex    sync.Mutex
+	degraded bool
+	waiters  map[int64]*waiter
+	replicas map[string]*replicaState
+}
+
+// NewAcknowledgements ...
+func NewAcknowledgements(required int, timeout time.Duration, degrade bool, logger *zap.Logger) (*Acknowledgements, error) {
+	if required <= 0 {
+		return nil, errors.New("number of synchronous replicas is invalid")
+	}
+
+	if timeout <= 0 {
+		return nil, errors.New("timeout is invalid")
+	}
+
+	if logger == nil {
+		return nil, errors.New("logger is invalid")
+	}
+
+	return &Acknowledgements{
+		required: required,
+		timeout:  timeout,
+		degrade:  degrade,
+		logger:   logger,
+		waiters:  make(map[int64]*waiter),
+		replicas: make(map[string]*replicaState),
+	}, nil
+}
+
+// Register starts counting acknowledgements of the record, it's called
+// before writing of the record, so fast replicas are not missed
+func (a *Acknowledgements) Register(lsn int64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.waiters[lsn] = &waiter{done: make(chan struct{})}
+}
+
+// Unregister stops counting acknowledgements of the record
+func (a *Acknowledgements) Unregister(lsn int64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	delete(a.waiters, lsn)
+}
+
+// Wait blocks until the record is persisted by the required number of replicas
+func (a *Acknowledgements) Wait(lsn int64) error {
+	defer a.Unregister(lsn)
+
+	a.mutex.Lock()
+	w, found := a.waiters[lsn]
+	degraded := a.degraded
+	a.mutex.Unlock()
+
+	if !found || degraded {
+		return nil
+	}
+
+	timer := time.NewTimer(a.timeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-timer.C:
+	}
+
+	if !a.degrade {
+		return common.ErrorReplicationTimeout
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.degraded {
+		a.degraded = true
+		a.logger.Warn("replication is degraded to asynchronous mode", zap.Int64("lsn", lsn))
+	}
+
+	return nil
+}
+
+// acknowledge is called on the request of the replica, records
+// of the previous response are persisted if the replica asks for
+// records after them
+func (a *Acknowledgements) acknowledge(replicaID string, lastLSN int64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	replica, found := a.replicas[replicaID]
+	if !found || len(replica.sent) == 0 || replica.sent[len(replica.sent)-1] != lastLSN {
+		return
+	}
+
+	for _, lsn := range replica.sent {
+		if w, found := a.waiters[lsn]; found {
+			w.acks++
+			if w.acks == a.required {
+				close(w.done)
+			}
+		}
+	}
+
+	replica.sent = nil
+}
+
+// send remembers records of the response to the replica, the empty
+// response means that the replica has caught up with the master
+func (a *Acknowledgements) send(replicaID string, sent []int64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := time.Now()
+	for id, replica := range a.replicas {
+		if now.Sub(replica.lastSeen) > staleReplicaTimeout {
+			delete(a.replicas, id)
+		}
+	}
+
+	a.replicas[replicaID] = &replicaState{
+		sent:     sent,
+		caughtUp: len(sent) == 0,
+		lastSeen: now,
+	}
+
+	if a.degraded && a.caughtUpReplicas() >= a.required {
+		a.degraded = false
+		a.logger.Info("replication is synchronous again")
+	}
+}
+
+func (a *Acknowledgements) caughtUpReplicas() int {
+	replicas := 0
+	for _, replica := range a.replicas {
+		if replica.caughtUp {
+			replicas++
+		}
+	}
+
+	return replicas
+}
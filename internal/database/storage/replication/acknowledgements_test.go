@@ -0,0 +1,132 @@
+package replication
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+)
+
+func TestNewAcknowledgements(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		required int
+		timeout  time.Duration
+		logger   *zap.Logger
+
+		expectedErr error
+	}{
+		"create without replicas": {
+			timeout:     time.Second,
+			logger:      zap.NewNop(),
+			expectedErr: errors.New("number of synchronous replicas is invalid"),
+		},
+		"create without timeout": {
+			required:    1,
+			logger:      zap.NewNop(),
+			expectedErr: errors.New("timeout is invalid"),
+		},
+		"create without logger": {
+			required:    1,
+			timeout:     time.Second,
+			expectedErr: errors.New("logger is invalid"),
+		},
+		"create acknowledgements": {
+			required: 1,
+			timeout:  time.Second,
+			logger:   zap.NewNop(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			acknowledgements, err := NewAcknowledgements(test.required, test.timeout, false, test.logger)
+			assert.Equal(t, test.expectedErr, err)
+			if test.expectedErr != nil {
+				assert.Nil(t, acknowledgements)
+			} else {
+				assert.NotNil(t, acknowledgements)
+			}
+		})
+	}
+}
+
+func TestAcknowledgementsQuorum(t *testing.T) {
+	t.Parallel()
+
+	acknowledgements, err := NewAcknowledgements(2, time.Second, false, zap.NewNop())
+	require.NoError(t, err)
+
+	acknowledgements.Register(1)
+	acknowledgements.Register(2)
+
+	acknowledgements.send("replica_1", []int64{2, 1})
+	acknowledgements.send("replica_2", []int64{2})
+	acknowledgements.send("replica_3", []int64{1, 3})
+
+	// the replica persisted records only if it asks for records after the last sent one
+	acknowledgements.acknowledge("replica_1", 1)
+	acknowledgements.acknowledge("replica_2", 2)
+	acknowledgements.acknowledge("replica_3", 2)
+
+	assert.NoError(t, acknowledgements.Wait(2))
+
+	waited := make(chan error)
+	go func() {
+		waited <- acknowledgements.Wait(1)
+	}()
+
+	select {
+	case <-waited:
+		assert.Fail(t, "record is acknowledged by one replica")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	acknowledgements.send("replica_2", []int64{1})
+	acknowledgements.acknowledge("replica_2", 1)
+	assert.NoError(t, <-waited)
+}
+
+func TestAcknowledgementsTimeout(t *testing.T) {
+	t.Parallel()
+
+	acknowledgements, err := NewAcknowledgements(1, 10*time.Millisecond, false, zap.NewNop())
+	require.NoError(t, err)
+
+	acknowledgements.Register(1)
+	assert.Equal(t, common.ErrorReplicationTimeout, acknowledgements.Wait(1))
+
+	// unregistered records are not waited
+	assert.NoError(t, acknowledgements.Wait(2))
+}
+
+func TestAcknowledgementsDegrade(t *testing.T) {
+	t.Parallel()
+
+	acknowledgements, err := NewAcknowledgements(1, 10*time.Millisecond, true, zap.NewNop())
+	require.NoError(t, err)
+
+	acknowledgements.Register(1)
+	assert.NoError(t, acknowledgements.Wait(1))
+
+	// writes don't wait in asynchronous mode
+	acknowledgements.Register(2)
+	assert.NoError(t, acknowledgements.Wait(2))
+	assert.True(t, acknowledgements.degraded)
+
+	acknowledgements.send("replica_1", []int64{1, 2})
+	assert.True(t, acknowledgements.degraded)
+
+	// replication becomes synchronous when the replica catches up
+	acknowledgements.acknowledge("replica_1", 2)
+	acknowledgements.send("replica_1", nil)
+	assert.False(t, acknowledgements.degraded)
+}
@@ -4,41 +4,104 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"math"
+	"sync"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 
-	"database-simon/internal/database/filesystem"
+	"database-simon/internal/database/storage/wal"
 )
 
+// responseOverhead is reserved in the message for encoding
+// of the response, the rest is used for records
+const responseOverhead = 1 << 10
+
 // TCPServer ...
 type TCPServer interface {
 	HandleQueries(context.Context, func(context.Context, []byte) []byte)
 }
 
+type logsReader interface {
+	ReadAfter(int64, int) ([]byte, bool, error)
+	Contains(int64) (bool, error)
+	CountAfter(int64) (int, error)
+}
+
+type snapshotSource interface {
+	LastLSN() (int64, error)
+	Last() (int64, []byte, error)
+}
+
+// transferredSnapshot is kept while replicas download it
+// by chunks, so the snapshot is not read for each chunk
+type transferredSnapshot struct {
+	lsn  int64
+	data []byte
+}
+
 // Master ...
 type Master struct {
-	server       TCPServer
-	walDirectory string
-	logger       *zap.Logger
+	server         TCPServer
+	reader         logsReader
+	maxRecordsSize int
+	logger         *zap.Logger
+
+	acknowledgements *Acknowledgements
+	watermark        func() int64
+
+	snapshots       snapshotSource
+	currentSnapshot func() (int64, []byte, error)
+	snapshotMutex   sync.Mutex
+	snapshot        *transferredSnapshot
+
+	replicasMutex sync.Mutex
+	replicas      replicasStatus
+
+	// the master is fenced when a replica knows a greater term,
+	// it means that another node has been promoted
+	term   *Term
+	fenced atomic.Bool
 }
 
 // NewMaster ...
-func NewMaster(server TCPServer, walDirectory string, logger *zap.Logger) (*Master, error) {
+func NewMaster(
+	server TCPServer,
+	reader logsReader,
+	maxMessageSize int,
+	logger *zap.Logger,
+	options ...MasterOption,
+) (*Master, error) {
 	if server == nil {
 		return nil, errors.New("server is invalid")
 	}
 
+	if reader == nil {
+		return nil, errors.New("logs reader is invalid")
+	}
+
+	if maxMessageSize <= responseOverhead {
+		return nil, errors.New("max message size is too small")
+	}
+
 	if logger == nil {
 		return nil, errors.New("logger is invalid")
 	}
 
-	return &Master{
-		server:       server,
-		walDirectory: walDirectory,
-		logger:       logger,
-	}, nil
+	master := &Master{
+		server:         server,
+		reader:         reader,
+		maxRecordsSize: maxMessageSize - responseOverhead,
+		logger:         logger,
+		term:           &Term{},
+		replicas:       make(replicasStatus),
+	}
+
+	for _, option := range options {
+		option(master)
+	}
+
+	return master, nil
 }
 
 // Start ...
@@ -66,33 +129,195 @@ func (m *Master) Start(ctx context.Context) {
 
 // IsMaster ...
 func (m *Master) IsMaster() bool {
-	return true
+	return !m.fenced.Load()
+}
+
+// Replicas returns replicas which have requested records recently
+func (m *Master) Replicas() []ReplicaStatus {
+	m.replicasMutex.Lock()
+	defer m.replicasMutex.Unlock()
+
+	return m.replicas.list()
 }
 
 func (m *Master) synchronize(request Request) Response {
-	var response Response
-	segmentName, err := filesystem.SegmentNext(m.walDirectory, request.LastSegmentName)
+	term := m.term.Get()
+	if request.Term > term {
+		if m.fenced.CompareAndSwap(false, true) {
+			m.logger.Warn("master is fenced by greater term of replica", zap.Int64("term", term), zap.Int64("replica_term", request.Term))
+		}
+		return NewResponse(false, nil, false, term)
+	}
+
+	m.replicasMutex.Lock()
+	m.replicas.update(request.ReplicaID, request.LastLSN)
+	m.replicasMutex.Unlock()
+
+	if m.snapshots != nil || m.currentSnapshot != nil {
+		response, transferred, err := m.transferSnapshot(request, term)
+		if err != nil {
+			m.logger.Error("failed to transfer snapshot", zap.Int64("last_lsn", request.LastLSN), zap.Error(err))
+			return NewResponse(false, nil, false, term)
+		} else if transferred {
+			return response
+		}
+	}
+
+	if m.acknowledgements != nil {
+		m.acknowledgements.acknowledge(request.ReplicaID, request.LastLSN)
+	}
+
+	// the watermark is taken before reading, so records up to it are in the log
+	watermark := int64(math.MaxInt64)
+	if m.watermark != nil {
+		watermark = m.watermark()
+	}
+
+	records, hasMore, err := m.reader.ReadAfter(request.LastLSN, m.maxRecordsSize)
 	if err != nil {
-		m.logger.Error("failed to find WAL segment", zap.Error(err))
-		return response
+		m.logger.Error("failed to read WAL records", zap.Int64("last_lsn", request.LastLSN), zap.Error(err))
+		return NewResponse(false, nil, false, term)
+	}
+
+	response := NewResponse(true, records, hasMore, term)
+	if !hasMore {
+		response.Watermark = watermark
 	}
 
-	if segmentName == "" {
-		response.Succeed = true
+	if m.acknowledgements == nil && !hasMore {
 		return response
 	}
 
-	filename := fmt.Sprintf("%s/%s", m.walDirectory, segmentName)
-	data, err := os.ReadFile(filepath.Clean(filename))
-	//data, err := os.ReadFile(filename)
+	logs, err := wal.DecodeRecords(records)
 	if err != nil {
-		m.logger.Error("failed to read WAL segment", zap.Error(err))
-		return response
+		m.logger.Error("failed to decode WAL records", zap.Error(err))
+		return NewResponse(false, nil, false, term)
 	}
 
-	response.Succeed = true
-	response.SegmentData = data
-	response.SegmentName = segmentName
+	if m.acknowledgements != nil {
+		sent := make([]int64, 0, len(logs))
+		for idx := range logs {
+			sent = append(sent, logs[idx].LSN)
+		}
+
+		m.acknowledgements.send(request.ReplicaID, sent)
+	}
+
+	if hasMore && len(logs) != 0 {
+		// the lag is reported to the replica, it's counted only while the replica catches up
+		pending, err := m.reader.CountAfter(logs[len(logs)-1].LSN)
+		if err != nil {
+			m.logger.Warn("failed to count pending WAL records", zap.Error(err))
+		}
+
+		response.Pending = int64(pending)
+	}
 
 	return response
 }
+
+// transferSnapshot sends the next chunk of the snapshot if the replica downloads
+// it or it's too far behind, i.e. records after its position are removed. The
+// diverged replica gets the snapshot of the current state, its LSN is greater
+// than LSN of records which the replica has and the master doesn't have
+func (m *Master) transferSnapshot(request Request, term int64) (Response, bool, error) {
+	diverged, err := m.diverged(request.LastLSN)
+	if err != nil {
+		return Response{}, false, err
+	}
+
+	if request.SnapshotLSN == 0 && !diverged {
+		required, err := m.requiresSnapshot(request.LastLSN)
+		if err != nil || !required {
+			return Response{}, false, err
+		}
+	}
+
+	m.snapshotMutex.Lock()
+	defer m.snapshotMutex.Unlock()
+
+	offset := request.SnapshotOffset
+	if m.snapshot == nil || m.snapshot.lsn != request.SnapshotLSN {
+		load := m.currentSnapshot
+		if !diverged && m.snapshots != nil {
+			load = m.snapshots.Last
+		} else if !diverged {
+			return Response{}, false, nil
+		}
+
+		lsn, data, err := load()
+		if err != nil {
+			return Response{}, false, fmt.Errorf("failed to load snapshot: %w", err)
+		} else if data == nil {
+			return Response{}, false, nil
+		}
+
+		m.snapshot = &transferredSnapshot{lsn: lsn, data: data}
+		m.logger.Info("snapshot is sent to replica", zap.String("replica", request.ReplicaID), zap.Int64("lsn", lsn))
+	}
+
+	// the transfer starts again if the snapshot is replaced by a newer one
+	size := int64(len(m.snapshot.data))
+	if m.snapshot.lsn != request.SnapshotLSN || offset > size {
+		offset = 0
+	}
+
+	end := min(offset+int64(m.maxRecordsSize), size)
+	response := NewSnapshotResponse(m.snapshot.data[offset:end], m.snapshot.lsn, offset, size, term)
+	if end == size {
+		m.snapshot = nil
+	}
+
+	return response, true, nil
+}
+
+func (m *Master) requiresSnapshot(lastLSN int64) (bool, error) {
+	if m.snapshots == nil {
+		return false, nil
+	}
+
+	snapshotLSN, err := m.snapshots.LastLSN()
+	if err != nil {
+		return false, fmt.Errorf("failed to find snapshot: %w", err)
+	} else if snapshotLSN <= lastLSN {
+		return false, nil
+	}
+
+	if lastLSN == 0 {
+		return true, nil
+	}
+
+	contains, err := m.reader.Contains(lastLSN)
+	if err != nil {
+		return false, err
+	}
+
+	return !contains, nil
+}
+
+// diverged checks if the replica has records which the master doesn't have, e.g. the
+// demoted master with unreplicated writes, records up to the last snapshot can be removed
+func (m *Master) diverged(lastLSN int64) (bool, error) {
+	if m.currentSnapshot == nil || lastLSN == 0 {
+		return false, nil
+	}
+
+	var snapshotLSN int64
+	if m.snapshots != nil {
+		var err error
+		if snapshotLSN, err = m.snapshots.LastLSN(); err != nil {
+			return false, fmt.Errorf("failed to find snapshot: %w", err)
+		}
+	}
+
+	if lastLSN <= snapshotLSN {
+		return false, nil
+	}
+
+	contains, err := m.reader.Contains(lastLSN)
+	if err != nil {
+		return false, err
+	}
+
+	return !contains, nil
+}
@@ -0,0 +1,45 @@
+package replication
+
+// MasterOption ...
+type MasterOption func(*Master)
+
+// WithAcknowledgements makes replication synchronous,
+// writes wait for acknowledgements of replicas
+func WithAcknowledgements(acknowledgements *Acknowledgements) MasterOption {
+	return func(master *Master) {
+		master.acknowledgements = acknowledgements
+	}
+}
+
+// WithMasterTerm ...
+func WithMasterTerm(term *Term) MasterOption {
+	return func(master *Master) {
+		master.term = term
+	}
+}
+
+// WithWatermark sets LSN up to which all writes of the master are completed,
+// replicas don't apply records after it, because they can miss earlier ones.
+// Without it records are sent in the order of LSN
+func WithWatermark(watermark func() int64) MasterOption {
+	return func(master *Master) {
+		master.watermark = watermark
+	}
+}
+
+// WithCurrentSnapshot makes the master send the snapshot of the current state to
+// replicas which have records that the master doesn't have, e.g. unreplicated
+// writes of the demoted master, the snapshot replaces the state of the replica
+func WithCurrentSnapshot(snapshot func() (int64, []byte, error)) MasterOption {
+	return func(master *Master) {
+		master.currentSnapshot = snapshot
+	}
+}
+
+// WithMasterSnapshots makes the master send the snapshot to
+// replicas which are behind records removed by checkpoints
+func WithMasterSnapshots(snapshots snapshotSource) MasterOption {
+	return func(master *Master) {
+		master.snapshots = snapshots
+	}
+}
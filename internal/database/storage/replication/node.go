@@ -0,0 +1,360 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"database-simon/internal/database/storage/wal"
+)
+
+var (
+	// ErrorAlreadyMaster ...
+	ErrorAlreadyMaster = errors.New("node is already master")
+	// ErrorNodeNotStarted ...
+	ErrorNodeNotStarted = errors.New("node is not started")
+	// ErrorNoAddress ...
+	ErrorNoAddress = errors.New("address is not set")
+)
+
+// MasterFactory creates a master which serves replicas on the address
+type MasterFactory func(address string, options ...MasterOption) (*Master, error)
+
+// SlaveFactory creates a slave which replicates the master from the address
+type SlaveFactory func(address string, options ...SlaveOption) (*Slave, error)
+
+// Node switches between master and slave roles at runtime, records of all
+// slaves are written to the same stream, so the storage is not recreated
+type Node struct {
+	newMaster     MasterFactory
+	newSlave      SlaveFactory
+	listenAddress string
+	term          *Term
+	stream        chan []wal.Log
+	logger        *zap.Logger
+	onPromote     func(int64)
+	cascading     bool
+
+	// current mirrors master, so IsMaster doesn't wait for a role switch
+	current atomic.Pointer[Master]
+
+	mutex  sync.Mutex
+	master *Master
+	slave  *Slave
+	// masterAddress is the address of the master, it's the own address of the master node
+	masterAddress string
+	// relay serves records received by the slave to downstream replicas
+	relay  *Master
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewNode creates the initial role of the node, the master listens on
+// listenAddress, the slave replicates the master from masterAddress
+func NewNode(
+	isMaster bool,
+	listenAddress string,
+	masterAddress string,
+	term *Term,
+	newMaster MasterFactory,
+	newSlave SlaveFactory,
+	logger *zap.Logger,
+	options ...NodeOption,
+) (*Node, error) {
+	if term == nil {
+		return nil, errors.New("term is invalid")
+	}
+
+	if newMaster == nil || newSlave == nil {
+		return nil, errors.New("factories are invalid")
+	}
+
+	if logger == nil {
+		return nil, errors.New("logger is invalid")
+	}
+
+	node := &Node{
+		newMaster:     newMaster,
+		newSlave:      newSlave,
+		listenAddress: listenAddress,
+		term:          term,
+		stream:        make(chan []wal.Log),
+		logger:        logger,
+	}
+
+	for _, option := range options {
+		option(node)
+	}
+
+	// the relay of the cascading slave serves replicas on the listen address
+	if node.cascading && listenAddress == "" {
+		return nil, errors.New("listen address of cascading replication is not set")
+	}
+
+	var err error
+	if isMaster {
+		var master *Master
+		if master, err = node.createMaster(listenAddress); err == nil {
+			node.setMaster(master, listenAddress)
+		}
+	} else {
+		err = node.createSlave(masterAddress)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// Start ...
+func (n *Node) Start(ctx context.Context) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.ctx = ctx
+	if n.master != nil {
+		n.promoted(n.term.Get())
+	}
+
+	n.start()
+}
+
+// IsMaster doesn't lock the node, it's called by the storage while a
+// role switch waits for records of the slave to be applied
+func (n *Node) IsMaster() bool {
+	master := n.current.Load()
+	return master != nil && master.IsMaster()
+}
+
+// Status describes the current role of the node
+func (n *Node) Status() Status {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	status := Status{Term: n.term.Get()}
+	if n.master != nil {
+		status.IsMaster = n.master.IsMaster()
+		status.Replicas = n.master.Replicas()
+		return status
+	}
+
+	if n.relay != nil {
+		status.Replicas = n.relay.Replicas()
+	}
+
+	if n.slave != nil {
+		slave := n.slave.Status()
+		slave.MasterAddress = n.masterAddress
+		status.Slave = &slave
+	}
+
+	return status
+}
+
+// ReplicationStream ...
+func (n *Node) ReplicationStream() <-chan []wal.Log {
+	return n.stream
+}
+
+// Promote stops replication and makes the node a master in the new
+// term, replicas are served on the address or on the listen address
+func (n *Node) Promote(address string) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.ctx == nil {
+		return ErrorNodeNotStarted
+	} else if n.master != nil && n.master.IsMaster() {
+		return ErrorAlreadyMaster
+	}
+
+	if address == "" {
+		address = n.listenAddress
+	}
+
+	if address == "" {
+		return ErrorNoAddress
+	}
+
+	previous := n.role()
+	n.stop()
+
+	// the term is incremented after the master is created, so the restored
+	// slave doesn't reject records of its master as records of a stale one
+	master, err := n.createMaster(address)
+	if err != nil {
+		n.restore(previous)
+		return err
+	}
+
+	term, err := n.term.Increment()
+	if err != nil {
+		release(master)
+		n.restore(previous)
+		return err
+	}
+
+	n.setMaster(master, address)
+	n.promoted(term)
+	n.start()
+	n.logger.Info("node is promoted to master", zap.Int64("term", term), zap.String("address", address))
+	return nil
+}
+
+// Demote makes the node a slave of the master on the address, it also
+// re-points the slave to another master. Records of the old master which
+// have not been replicated are replaced by the snapshot of the new master
+func (n *Node) Demote(address string) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.ctx == nil {
+		return ErrorNodeNotStarted
+	} else if address == "" {
+		return ErrorNoAddress
+	}
+
+	previous := n.role()
+	n.stop()
+
+	if err := n.createSlave(address); err != nil {
+		n.restore(previous)
+		return err
+	}
+
+	n.start()
+	n.logger.Info("node replicates master", zap.Int64("term", n.term.Get()), zap.String("address", address))
+	return nil
+}
+
+func (n *Node) createMaster(address string) (*Master, error) {
+	master, err := n.newMaster(address, WithMasterTerm(n.term))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create master: %w", err)
+	}
+
+	return master, nil
+}
+
+// createSlave creates the slave and, if replication is cascading, the relay on
+// the listen address, the relay has the same term, so downstream replicas follow
+// terms of the master and keep replicating if the node is promoted
+func (n *Node) createSlave(address string) error {
+	slave, err := n.newSlave(address, WithSlaveTerm(n.term), WithStream(n.stream))
+	if err != nil {
+		return fmt.Errorf("failed to create slave: %w", err)
+	}
+
+	var relay *Master
+	if n.cascading {
+		if relay, err = n.newMaster(n.listenAddress, WithMasterTerm(n.term)); err != nil {
+			return fmt.Errorf("failed to create relay: %w", err)
+		}
+	}
+
+	n.slave = slave
+	n.relay = relay
+	n.masterAddress = address
+	return nil
+}
+
+func (n *Node) setMaster(master *Master, address string) {
+	n.master = master
+	n.masterAddress = address
+	n.current.Store(master)
+}
+
+// role describes the current role of the node, so it's restored if a switch fails
+type role struct {
+	isMaster bool
+	fenced   bool
+	address  string
+}
+
+func (n *Node) role() role {
+	return role{
+		isMaster: n.master != nil,
+		fenced:   n.master != nil && !n.master.IsMaster(),
+		address:  n.masterAddress,
+	}
+}
+
+// restore recreates the previous role after a failed switch, so the node isn't
+// left without a role, the restored master stays fenced if it was fenced
+func (n *Node) restore(previous role) {
+	var err error
+	if previous.isMaster {
+		var master *Master
+		if master, err = n.createMaster(previous.address); err == nil {
+			master.fenced.Store(previous.fenced)
+			n.setMaster(master, previous.address)
+		}
+	} else {
+		err = n.createSlave(previous.address)
+	}
+
+	if err != nil {
+		n.logger.Error("failed to restore role of the node", zap.Error(err))
+		return
+	}
+
+	n.start()
+}
+
+// release closes the listener of the master which isn't started
+func release(master *Master) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	master.Start(ctx)
+}
+
+func (n *Node) promoted(term int64) {
+	if n.onPromote != nil {
+		n.onPromote(TermFirstLSN(term))
+	}
+}
+
+func (n *Node) start() {
+	ctx, cancel := context.WithCancel(n.ctx)
+	done := make(chan struct{})
+	n.cancel = cancel
+	n.done = done
+
+	master, slave, relay := n.master, n.slave, n.relay
+	go func() {
+		defer close(done)
+
+		if master != nil {
+			master.Start(ctx)
+			return
+		}
+
+		slave.Start(ctx)
+		if relay != nil {
+			relay.Start(ctx)
+		}
+		<-slave.Done()
+	}()
+}
+
+// stop waits for the current role, so the address of the master is
+// released and records of the slave are applied before the switch
+func (n *Node) stop() {
+	if n.cancel != nil {
+		n.cancel()
+		<-n.done
+	}
+
+	n.master = nil
+	n.current.Store(nil)
+	n.slave = nil
+	n.relay = nil
+	n.cancel = nil
+}
@@ -0,0 +1,20 @@
+package replication
+
+// NodeOption ...
+type NodeOption func(*Node)
+
+// WithOnPromote sets the handler which is called with the first LSN
+// of the term when the node becomes master, writes must get greater LSN
+func WithOnPromote(handler func(int64)) NodeOption {
+	return func(node *Node) {
+		node.onPromote = handler
+	}
+}
+
+// WithCascading makes the slave serve replicas on the listen address,
+// so replicas can be organized in a tree without loading the master
+func WithCascading() NodeOption {
+	return func(node *Node) {
+		node.cascading = true
+	}
+}
@@ -0,0 +1,283 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"database-simon/internal/database/storage/wal"
+)
+
+type testServer struct{}
+
+func (testServer) HandleQueries(ctx context.Context, _ func(context.Context, []byte) []byte) {
+	<-ctx.Done()
+}
+
+type testClient struct{}
+
+func (testClient) Send([]byte) ([]byte, error) {
+	return nil, errors.New("master is unavailable")
+}
+
+func (testClient) Close() {}
+
+type testLogs struct{}
+
+func (testLogs) ReadAfter(int64, int) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (testLogs) Contains(int64) (bool, error) {
+	return false, nil
+}
+
+func (testLogs) CountAfter(int64) (int, error) {
+	return 0, nil
+}
+
+func (testLogs) LastLSN() (int64, error) {
+	return 0, nil
+}
+
+func (testLogs) Write([]byte) error {
+	return nil
+}
+
+func newTestNode(t *testing.T, isMaster bool, term *Term, options ...NodeOption) (*Node, *[]string) {
+	t.Helper()
+
+	var addresses []string
+	newMaster := func(address string, options ...MasterOption) (*Master, error) {
+		addresses = append(addresses, "master "+address)
+		return NewMaster(testServer{}, testLogs{}, 4<<10, zap.NewNop(), options...)
+	}
+
+	newSlave := func(address string, options ...SlaveOption) (*Slave, error) {
+		addresses = append(addresses, "slave "+address)
+		return NewSlave(testClient{}, testLogs{}, testLogs{}, time.Millisecond, zap.NewNop(), options...)
+	}
+
+	node, err := NewNode(isMaster, "127.0.0.1:8083", "127.0.0.1:8082", term, newMaster, newSlave, zap.NewNop(), options...)
+	require.NoError(t, err)
+
+	return node, &addresses
+}
+
+func TestNodePromote(t *testing.T) {
+	t.Parallel()
+
+	var firstLSN int64
+	term := &Term{}
+	node, addresses := newTestNode(t, false, term, WithOnPromote(func(lsn int64) {
+		firstLSN = lsn
+	}))
+	assert.False(t, node.IsMaster())
+	assert.Equal(t, ErrorNodeNotStarted, node.Promote(""))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	node.Start(ctx)
+	require.NoError(t, node.Promote(""))
+	assert.True(t, node.IsMaster())
+	assert.Equal(t, int64(1), term.Get())
+	assert.Equal(t, TermFirstLSN(1), firstLSN)
+	assert.Equal(t, ErrorAlreadyMaster, node.Promote(""))
+
+	require.NoError(t, node.Demote("127.0.0.1:8084"))
+	assert.False(t, node.IsMaster())
+	assert.Equal(t, ErrorNoAddress, node.Demote(""))
+
+	// the slave is re-pointed to another master
+	require.NoError(t, node.Demote("127.0.0.1:8085"))
+	assert.Equal(t, []string{
+		"slave 127.0.0.1:8082",
+		"master 127.0.0.1:8083",
+		"slave 127.0.0.1:8084",
+		"slave 127.0.0.1:8085",
+	}, *addresses)
+}
+
+func TestNodeCascading(t *testing.T) {
+	t.Parallel()
+
+	node, addresses := newTestNode(t, false, &Term{}, WithCascading())
+	assert.False(t, node.IsMaster())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	node.Start(ctx)
+
+	// the relay is replaced by the master on the same address
+	require.NoError(t, node.Promote(""))
+	assert.True(t, node.IsMaster())
+
+	require.NoError(t, node.Demote("127.0.0.1:8084"))
+	assert.False(t, node.IsMaster())
+	assert.Equal(t, []string{
+		"slave 127.0.0.1:8082",
+		"master 127.0.0.1:8083",
+		"master 127.0.0.1:8083",
+		"slave 127.0.0.1:8084",
+		"master 127.0.0.1:8083",
+	}, *addresses)
+}
+
+type failedTermStore struct{}
+
+func (failedTermStore) Load() (int64, error) {
+	return 0, nil
+}
+
+func (failedTermStore) Store(int64) error {
+	return errors.New("disk is full")
+}
+
+func TestNodeCascadingWithoutListenAddress(t *testing.T) {
+	t.Parallel()
+
+	newMaster := func(string, ...MasterOption) (*Master, error) {
+		return NewMaster(testServer{}, testLogs{}, 4<<10, zap.NewNop())
+	}
+
+	newSlave := func(string, ...SlaveOption) (*Slave, error) {
+		return NewSlave(testClient{}, testLogs{}, testLogs{}, time.Millisecond, zap.NewNop())
+	}
+
+	node, err := NewNode(false, "", "127.0.0.1:8082", &Term{}, newMaster, newSlave, zap.NewNop(), WithCascading())
+	assert.EqualError(t, err, "listen address of cascading replication is not set")
+	assert.Nil(t, node)
+}
+
+func TestNodeSwitchFailed(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		isMaster     bool
+		term         *Term
+		masterFailed bool
+		slaveFailed  bool
+		switchRole   func(*Node) error
+
+		expectedAddresses []string
+	}{
+		"master is not created": {
+			term:              &Term{},
+			masterFailed:      true,
+			switchRole:        func(node *Node) error { return node.Promote("") },
+			expectedAddresses: []string{"slave 127.0.0.1:8082", "master 127.0.0.1:8083", "slave 127.0.0.1:8082"},
+		},
+		"term is not incremented": {
+			term:              &Term{store: failedTermStore{}},
+			switchRole:        func(node *Node) error { return node.Promote("") },
+			expectedAddresses: []string{"slave 127.0.0.1:8082", "master 127.0.0.1:8083", "slave 127.0.0.1:8082"},
+		},
+		"slave is not created": {
+			isMaster:          true,
+			term:              &Term{},
+			slaveFailed:       true,
+			switchRole:        func(node *Node) error { return node.Demote("127.0.0.1:8084") },
+			expectedAddresses: []string{"master 127.0.0.1:8083", "slave 127.0.0.1:8084", "master 127.0.0.1:8083"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var addresses []string
+			newMaster := func(address string, options ...MasterOption) (*Master, error) {
+				addresses = append(addresses, "master "+address)
+				if test.masterFailed && len(addresses) > 1 {
+					return nil, errors.New("address is in use")
+				}
+				return NewMaster(testServer{}, testLogs{}, 4<<10, zap.NewNop(), options...)
+			}
+
+			newSlave := func(address string, options ...SlaveOption) (*Slave, error) {
+				addresses = append(addresses, "slave "+address)
+				if test.slaveFailed && len(addresses) > 1 {
+					return nil, errors.New("address is invalid")
+				}
+				return NewSlave(testClient{}, testLogs{}, testLogs{}, time.Millisecond, zap.NewNop(), options...)
+			}
+
+			node, err := NewNode(test.isMaster, "127.0.0.1:8083", "127.0.0.1:8082", test.term, newMaster, newSlave, zap.NewNop())
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			node.Start(ctx)
+			assert.Error(t, test.switchRole(node))
+
+			// the previous role is restored in the same term
+			status := node.Status()
+			assert.Equal(t, test.isMaster, node.IsMaster())
+			assert.Equal(t, test.isMaster, status.Slave == nil)
+			assert.Equal(t, int64(0), test.term.Get())
+			assert.Equal(t, test.expectedAddresses, addresses)
+		})
+	}
+}
+
+func TestMasterFencing(t *testing.T) {
+	t.Parallel()
+
+	term := &Term{}
+	require.NoError(t, term.Advance(2))
+
+	master, err := NewMaster(testServer{}, testLogs{}, 4<<10, zap.NewNop(), WithMasterTerm(term))
+	require.NoError(t, err)
+
+	response := master.synchronize(NewRequest("replica", 0, 2))
+	assert.True(t, response.Succeed)
+	assert.Equal(t, int64(2), response.Term)
+	assert.True(t, master.IsMaster())
+
+	// the replica has seen a newer master, so writes of this one are fenced
+	response = master.synchronize(NewRequest("replica", 0, 3))
+	assert.False(t, response.Succeed)
+	assert.False(t, master.IsMaster())
+}
+
+func TestSlaveRejectsStaleMaster(t *testing.T) {
+	t.Parallel()
+
+	term := &Term{}
+	require.NoError(t, term.Advance(2))
+
+	stream := make(chan []wal.Log, 1)
+	slave, err := NewSlave(testClient{}, testLogs{}, testLogs{}, time.Millisecond, zap.NewNop(), WithSlaveTerm(term), WithStream(stream))
+	require.NoError(t, err)
+
+	records, err := wal.EncodeRecords([]wal.Log{{LSN: 1, CommandID: "DEL", Arguments: []string{"key"}}})
+	require.NoError(t, err)
+
+	slave.client = responseClient{NewResponse(true, records, false, 1)}
+	assert.False(t, slave.synchronize())
+	assert.Empty(t, stream)
+
+	slave.client = responseClient{NewResponse(true, records, false, 3)}
+	assert.False(t, slave.synchronize())
+	assert.Len(t, stream, 1)
+	assert.Equal(t, int64(3), term.Get())
+	assert.Equal(t, int64(1), slave.lastLSN)
+}
+
+type responseClient struct {
+	response Response
+}
+
+func (c responseClient) Send([]byte) ([]byte, error) {
+	return Encode(&c.response)
+}
+
+func (responseClient) Close() {}
@@ -6,31 +6,69 @@ import (
 	"fmt"
 )
 
-// Request ...
+// Request asks the master for records which follow the
+// record with LastLSN, zero LSN means the beginning of the log,
+// records up to LastLSN are persisted by the replica. During the
+// transfer of a snapshot the replica asks for the snapshot with
+// SnapshotLSN from SnapshotOffset
 type Request struct {
-	LastSegmentName string
+	ReplicaID      string
+	LastLSN        int64
+	Term           int64
+	SnapshotLSN    int64
+	SnapshotOffset int64
 }
 
 // NewRequest ...
-func NewRequest(lastSegmentName string) Request {
+func NewRequest(replicaID string, lastLSN int64, term int64) Request {
 	return Request{
-		LastSegmentName: lastSegmentName,
+		ReplicaID: replicaID,
+		LastLSN:   lastLSN,
+		Term:      term,
 	}
 }
 
-// Response ...
+// Response contains a chunk of encoded WAL records, HasMore is set
+// when the chunk is limited by the size and the slave can ask again.
+// A replica which is too far behind gets a chunk of the snapshot at
+// SnapshotOffset instead of records, records follow the snapshot.
+// Pending is the number of records left after the chunk. Writes
+// of the master up to Watermark are completed and their records
+// are sent, it's set by the last chunk
 type Response struct {
-	Succeed     bool
-	SegmentName string
-	SegmentData []byte
+	Succeed   bool
+	Records   []byte
+	HasMore   bool
+	Term      int64
+	Pending   int64
+	Watermark int64
+
+	Snapshot       []byte
+	SnapshotLSN    int64
+	SnapshotOffset int64
+	SnapshotSize   int64
 }
 
 // NewResponse ...
-func NewResponse(succeed bool, segmentName string, segmentData []byte) Response {
+func NewResponse(succeed bool, records []byte, hasMore bool, term int64) Response {
+	return Response{
+		Succeed: succeed,
+		Records: records,
+		HasMore: hasMore,
+		Term:    term,
+	}
+}
+
+// NewSnapshotResponse ...
+func NewSnapshotResponse(chunk []byte, lsn, offset, size int64, term int64) Response {
 	return Response{
-		Succeed:     succeed,
-		SegmentName: segmentName,
-		SegmentData: segmentData,
+		Succeed:        true,
+		HasMore:        true,
+		Term:           term,
+		Snapshot:       chunk,
+		SnapshotLSN:    lsn,
+		SnapshotOffset: offset,
+		SnapshotSize:   size,
 	}
 }
 
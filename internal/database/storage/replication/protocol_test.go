@@ -7,13 +7,10 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-const sName = "wal_1000.log"
-
 func TestRequest(t *testing.T) {
 	t.Parallel()
 
-	lastSegmentName := sName
-	initialRequest := NewRequest(lastSegmentName)
+	initialRequest := NewRequest("replica", 1000, 2)
 	data, err := Encode(&initialRequest)
 	require.NoError(t, err)
 	require.NotNil(t, data)
@@ -28,10 +25,7 @@ func TestRequest(t *testing.T) {
 func TestResponse(t *testing.T) {
 	t.Parallel()
 
-	succeed := true
-	segmentName := sName
-	segmentData := []byte{'s', 'y', 'n', 'c'}
-	initialResponse := NewResponse(succeed, segmentName, segmentData)
+	initialResponse := NewResponse(true, []byte{'s', 'y', 'n', 'c'}, true, 2)
 	data, err := Encode(&initialResponse)
 	require.NoError(t, err)
 	require.NotNil(t, data)
@@ -1,15 +1,16 @@
 package replication
 
 import (
-	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
-	"database-simon/internal/database/filesystem"
 	"database-simon/internal/database/storage/wal"
 )
 
@@ -18,14 +19,45 @@ type tcpClient interface {
 	Close()
 }
 
+type segment interface {
+	Write([]byte) error
+}
+
+type positionReader interface {
+	LastLSN() (int64, error)
+}
+
+type snapshotStore interface {
+	Save(int64, []byte) error
+	LastLSN() (int64, error)
+}
+
 // Slave ...
 type Slave struct {
-	client tcpClient
-	stream chan []wal.Log
+	client  tcpClient
+	segment segment
+	stream  chan []wal.Log
+
+	// id distinguishes requests of replicas for counting their acknowledgements
+	id string
+	// responses of masters with smaller term are rejected
+	term *Term
+	done chan struct{}
+
+	syncInterval time.Duration
+	// lastLSN is LSN of the last record written to the local WAL, records
+	// are written before applying, so the position survives restarts
+	lastLSN int64
+	// watermark is the last watermark of the master sent to the stream
+	watermark int64
 
-	syncInterval    time.Duration
-	walDirectory    string
-	lastSegmentName string
+	// the snapshot is downloaded by chunks before applying
+	snapshots   snapshotStore
+	snapshotLSN int64
+	snapshot    []byte
+
+	statusMutex sync.Mutex
+	status      SlaveStatus
 
 	logger *zap.Logger
 }
@@ -33,31 +65,67 @@ type Slave struct {
 // NewSlave ...
 func NewSlave(
 	client tcpClient,
-	walDirectory string,
+	segment segment,
+	reader positionReader,
 	syncInterval time.Duration,
 	logger *zap.Logger,
+	options ...SlaveOption,
 ) (*Slave, error) {
 	if client == nil {
 		return nil, errors.New("tcp client must be set")
 	}
 
+	if segment == nil {
+		return nil, errors.New("segment must be set")
+	}
+
+	if reader == nil {
+		return nil, errors.New("logs reader must be set")
+	}
+
 	if logger == nil {
 		return nil, errors.New("logger must be set")
 	}
 
-	segmentName, err := filesystem.SegmentLast(walDirectory)
+	lastLSN, err := reader.LastLSN()
 	if err != nil {
-		logger.Error("failed to find last WAL segment", zap.Error(err))
+		return nil, fmt.Errorf("failed to find replication position: %w", err)
+	}
+
+	id := make([]byte, 8)
+	if _, err = rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate replica id: %w", err)
 	}
 
-	return &Slave{
-		client:          client,
-		stream:          make(chan []wal.Log),
-		syncInterval:    syncInterval,
-		walDirectory:    walDirectory,
-		lastSegmentName: segmentName,
-		logger:          logger,
-	}, nil
+	slave := &Slave{
+		id:           hex.EncodeToString(id),
+		client:       client,
+		segment:      segment,
+		stream:       make(chan []wal.Log),
+		term:         &Term{},
+		done:         make(chan struct{}),
+		syncInterval: syncInterval,
+		lastLSN:      lastLSN,
+		logger:       logger,
+	}
+
+	for _, option := range options {
+		option(slave)
+	}
+
+	// records which follow the received snapshot are not written yet
+	if slave.snapshots != nil {
+		snapshotLSN, err := slave.snapshots.LastLSN()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find snapshot: %w", err)
+		}
+
+		slave.lastLSN = max(slave.lastLSN, snapshotLSN)
+	}
+
+	slave.status.LastLSN = slave.lastLSN
+
+	return slave, nil
 }
 
 // Start ...
@@ -68,6 +136,7 @@ func (s *Slave) Start(ctx context.Context) {
 		defer func() {
 			ticker.Stop()
 			s.client.Close()
+			close(s.done)
 		}()
 
 		for {
@@ -81,7 +150,10 @@ func (s *Slave) Start(ctx context.Context) {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				s.synchronize()
+				// chunks are requested one by one until the slave catches up
+				for hasMore := true; hasMore && ctx.Err() == nil; {
+					hasMore = s.synchronize()
+				}
 			}
 		}
 	}()
@@ -92,79 +164,150 @@ func (s *Slave) IsMaster() bool {
 	return false
 }
 
+// Done is closed when the synchronization is stopped
+func (s *Slave) Done() <-chan struct{} {
+	return s.done
+}
+
 // ReplicationStream ...
 func (s *Slave) ReplicationStream() <-chan []wal.Log {
 	return s.stream
 }
 
-func (s *Slave) synchronize() {
-	request := NewRequest(s.lastSegmentName)
+// Status ...
+func (s *Slave) Status() SlaveStatus {
+	s.statusMutex.Lock()
+	defer s.statusMutex.Unlock()
+
+	return s.status
+}
+
+// synchronize returns true if the master has more records for the slave
+func (s *Slave) synchronize() bool {
+	response, err := s.replicate()
+
+	s.statusMutex.Lock()
+	defer s.statusMutex.Unlock()
+
+	s.status.LastLSN = s.lastLSN
+	if err != nil {
+		s.logger.Error("failed to synchronize with master", zap.Error(err))
+		s.status.LastError = err.Error()
+		s.status.LastErrorTime = time.Now()
+		return false
+	}
+
+	s.status.LastSync = time.Now()
+	s.status.LagRecords = response.Pending
+	if !response.HasMore {
+		s.status.SyncedAt = s.status.LastSync
+	}
+
+	return response.HasMore
+}
+
+func (s *Slave) replicate() (Response, error) {
+	request := NewRequest(s.id, s.lastLSN, s.term.Get())
+	request.SnapshotLSN = s.snapshotLSN
+	request.SnapshotOffset = int64(len(s.snapshot))
 	requestData, err := Encode(&request)
 	if err != nil {
-		s.logger.Error("failed to encode replication request", zap.Error(err))
-		return
+		return Response{}, fmt.Errorf("failed to encode replication request: %w", err)
 	}
 
-	responseData, errE := s.client.Send(requestData)
-	if errE != nil {
-		s.logger.Error("failed to send replication request", zap.Error(errE))
-		return
+	responseData, err := s.client.Send(requestData)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to send replication request: %w", err)
 	}
 
 	var response Response
 	if err = Decode(&response, responseData); err != nil {
-		s.logger.Error("failed to decode replication response", zap.Error(err))
-		return
+		return Response{}, fmt.Errorf("failed to decode replication response: %w", err)
+	}
+
+	if term := s.term.Get(); response.Term < term {
+		return Response{}, fmt.Errorf("response of stale master is rejected: term %d, master term %d", term, response.Term)
+	} else if err = s.term.Advance(response.Term); err != nil {
+		return Response{}, fmt.Errorf("failed to advance term: %w", err)
 	}
 
-	if response.Succeed {
-		s.handleResponse(response)
+	if !response.Succeed {
+		return Response{}, errors.New("failed to apply replication data: master error")
+	}
+
+	if response.SnapshotLSN != 0 {
+		err = s.handleSnapshot(response)
 	} else {
-		s.logger.Error("failed to apply replication data: master error")
+		err = s.handleResponse(response)
+	}
+
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to apply replication data: %w", err)
 	}
+
+	return response, nil
 }
 
-func (s *Slave) handleResponse(response Response) {
-	if response.SegmentName == "" {
-		s.logger.Debug("no changes from replication")
-		return
+func (s *Slave) handleResponse(response Response) error {
+	var logs []wal.Log
+	if len(response.Records) != 0 {
+		var err error
+		if logs, err = wal.DecodeRecords(response.Records); err != nil {
+			return fmt.Errorf("failed to decode records: %w", err)
+		}
 	}
 
-	if err := s.saveWALSegment(response.SegmentName, response.SegmentData); err != nil {
-		s.logger.Error("failed to apply replication data", zap.Error(err))
-		return
+	if len(logs) == 0 {
+		s.logger.Debug("no changes from replication")
+		// the watermark can pass records which are received with previous chunks
+		if response.Watermark > s.watermark {
+			s.stream <- []wal.Log{{LSN: response.Watermark, CommandID: wal.WatermarkCommand}}
+			s.watermark = response.Watermark
+		}
+		return nil
 	}
 
-	if err := s.writeDataToStream(response.SegmentData); err != nil {
-		s.logger.Error("failed to write data to stream", zap.Error(err))
-		return
+	// records have the format of segments, so they are written as is
+	if err := s.segment.Write(response.Records); err != nil {
+		return fmt.Errorf("failed to write records to WAL: %w", err)
 	}
 
-	s.lastSegmentName = response.SegmentName
+	// records are applied in the order of the master WAL, but they are visible only up to the watermark
+	s.stream <- append(logs, wal.Log{LSN: response.Watermark, CommandID: wal.WatermarkCommand})
+	s.lastLSN = logs[len(logs)-1].LSN
+	s.watermark = max(s.watermark, response.Watermark)
+	return nil
 }
 
-func (s *Slave) saveWALSegment(segmentName string, segmentData []byte) error {
-	filename := fmt.Sprintf("%s/%s", s.walDirectory, segmentName)
-	file, err := filesystem.CreateFile(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create wal segment: %w", err)
+// handleSnapshot collects chunks of the snapshot, the complete snapshot is saved
+// and replaces the state of the storage, replication continues after it
+func (s *Slave) handleSnapshot(response Response) error {
+	if response.SnapshotOffset == 0 {
+		s.snapshotLSN = response.SnapshotLSN
+		s.snapshot = nil
+	} else if response.SnapshotLSN != s.snapshotLSN || response.SnapshotOffset != int64(len(s.snapshot)) {
+		s.snapshotLSN = 0
+		s.snapshot = nil
+		return errors.New("unexpected chunk of snapshot")
 	}
 
-	_, err = filesystem.WriteFile(file, segmentData)
-	return err
-}
+	s.snapshot = append(s.snapshot, response.Snapshot...)
+	if int64(len(s.snapshot)) < response.SnapshotSize {
+		return nil
+	}
 
-func (s *Slave) writeDataToStream(segmentData []byte) error {
-	var logs []wal.Log
-	buffer := bytes.NewBuffer(segmentData)
-	for buffer.Len() > 0 {
-		var log wal.Log
-		if err := log.Decode(buffer); err != nil {
-			return fmt.Errorf("failed to decode data: %w", err)
-		}
+	lsn, data := s.snapshotLSN, s.snapshot
+	s.snapshotLSN = 0
+	s.snapshot = nil
 
-		logs = append(logs, log)
+	if s.snapshots != nil {
+		if err := s.snapshots.Save(lsn, data); err != nil {
+			return fmt.Errorf("failed to save snapshot: %w", err)
+		}
 	}
-	s.stream <- logs
+
+	s.stream <- []wal.Log{{LSN: lsn, CommandID: wal.RestoreCommand, Arguments: []string{string(data)}}}
+	s.lastLSN = lsn
+	s.logger.Info("snapshot is received from master", zap.Int64("lsn", lsn), zap.Int("size", len(data)))
 	return nil
 }
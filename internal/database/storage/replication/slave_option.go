@@ -0,0 +1,29 @@
+package replication
+
+import "database-simon/internal/database/storage/wal"
+
+// SlaveOption ...
+type SlaveOption func(*Slave)
+
+// WithSlaveTerm ...
+func WithSlaveTerm(term *Term) SlaveOption {
+	return func(slave *Slave) {
+		slave.term = term
+	}
+}
+
+// WithStream makes the slave write records to the stream, it's shared by
+// slaves which replace each other when the master is changed
+func WithStream(stream chan []wal.Log) SlaveOption {
+	return func(slave *Slave) {
+		slave.stream = stream
+	}
+}
+
+// WithSlaveSnapshots makes the slave save snapshots received from the master,
+// so they are restored after restart as checkpoints of the own WAL
+func WithSlaveSnapshots(snapshots snapshotStore) SlaveOption {
+	return func(slave *Slave) {
+		slave.snapshots = snapshots
+	}
+}
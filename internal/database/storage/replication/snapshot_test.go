@@ -0,0 +1,309 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"database-simon/internal/database/filesystem"
+	"database-simon/internal/database/storage"
+	"database-simon/internal/database/storage/engine/lsm"
+	"database-simon/internal/database/storage/engine/memory"
+	"database-simon/internal/database/storage/wal"
+)
+
+type testSnapshots struct {
+	lsn  int64
+	data []byte
+}
+
+func (s *testSnapshots) LastLSN() (int64, error) {
+	return s.lsn, nil
+}
+
+func (s *testSnapshots) Last() (int64, []byte, error) {
+	return s.lsn, s.data, nil
+}
+
+func (s *testSnapshots) Save(lsn int64, data []byte) error {
+	s.lsn, s.data = lsn, data
+	return nil
+}
+
+// masterLogs keeps records after the snapshot, older records are removed
+type masterLogs struct {
+	records []byte
+}
+
+func (l masterLogs) ReadAfter(lsn int64, _ int) ([]byte, bool, error) {
+	if lsn >= 11 {
+		return nil, false, nil
+	}
+
+	return l.records, false, nil
+}
+
+func (masterLogs) Contains(lsn int64) (bool, error) {
+	return lsn >= 11, nil
+}
+
+func (masterLogs) CountAfter(int64) (int, error) {
+	return 0, nil
+}
+
+// loopbackClient sends requests to the master directly
+type loopbackClient struct {
+	master *Master
+}
+
+func (c loopbackClient) Send(requestData []byte) ([]byte, error) {
+	var request Request
+	if err := Decode(&request, requestData); err != nil {
+		return nil, err
+	}
+
+	response := c.master.synchronize(request)
+	return Encode(&response)
+}
+
+func (loopbackClient) Close() {}
+
+func TestSnapshotTransfer(t *testing.T) {
+	t.Parallel()
+
+	records, err := wal.EncodeRecords([]wal.Log{{LSN: 11, CommandID: "DEL", Arguments: []string{"key"}}})
+	require.NoError(t, err)
+
+	snapshot := bytes.Repeat([]byte("snapshot"), 500)
+	masterSnapshots := &testSnapshots{lsn: 10, data: snapshot}
+	master, err := NewMaster(testServer{}, masterLogs{records: records}, 2<<10, zap.NewNop(), WithMasterSnapshots(masterSnapshots))
+	require.NoError(t, err)
+
+	stream := make(chan []wal.Log, 10)
+	slaveSnapshots := &testSnapshots{}
+	slave, err := NewSlave(loopbackClient{master}, testLogs{}, testLogs{}, time.Millisecond, zap.NewNop(), WithStream(stream), WithSlaveSnapshots(slaveSnapshots))
+	require.NoError(t, err)
+
+	// the snapshot doesn't fit into one message, so it's sent by chunks
+	requests := 0
+	for hasMore := true; hasMore; requests++ {
+		hasMore = slave.synchronize()
+	}
+
+	assert.Equal(t, 5, requests)
+	assert.Equal(t, int64(10), slaveSnapshots.lsn)
+	assert.Equal(t, snapshot, slaveSnapshots.data)
+	assert.Equal(t, int64(11), slave.lastLSN)
+
+	require.Len(t, stream, 2)
+	assert.Equal(t, []wal.Log{{LSN: 10, CommandID: wal.RestoreCommand, Arguments: []string{string(snapshot)}}}, <-stream)
+	// the master without the watermark sends records in the order of LSN
+	assert.Equal(t, []wal.Log{{LSN: 11, CommandID: "DEL", Arguments: []string{"key"}}, {LSN: math.MaxInt64, CommandID: wal.WatermarkCommand}}, <-stream)
+
+	// the restarted slave continues after the saved snapshot
+	slave, err = NewSlave(loopbackClient{master}, testLogs{}, testLogs{}, time.Millisecond, zap.NewNop(), WithSlaveSnapshots(slaveSnapshots))
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), slave.lastLSN)
+}
+
+func TestSnapshotIsNotSentToCurrentReplica(t *testing.T) {
+	t.Parallel()
+
+	records, err := wal.EncodeRecords([]wal.Log{{LSN: 12, CommandID: "DEL", Arguments: []string{"key"}}})
+	require.NoError(t, err)
+
+	masterSnapshots := &testSnapshots{lsn: 10, data: []byte("snapshot")}
+	currentSnapshot := func() (int64, []byte, error) {
+		return 0, nil, errors.New("replica hasn't diverged")
+	}
+	master, err := NewMaster(testServer{}, masterLogs{records: records}, 2<<10, zap.NewNop(), WithMasterSnapshots(masterSnapshots), WithCurrentSnapshot(currentSnapshot))
+	require.NoError(t, err)
+
+	// the record after the replica position is kept, so it's sent as is
+	response := master.synchronize(NewRequest("replica", 11, 0))
+	assert.True(t, response.Succeed)
+	assert.Zero(t, response.SnapshotLSN)
+	assert.Empty(t, response.Snapshot)
+}
+
+// replicaOfMaster is the storage of the replica, writes come from the master
+type replicaOfMaster struct{}
+
+func (replicaOfMaster) IsMaster() bool {
+	return false
+}
+
+func TestSnapshotOfPersistedEngine(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// segments of the master are removed after flushes of the engine
+	walDirectory, lsmDirectory := t.TempDir(), t.TempDir()
+	reader, err := wal.NewLogsReader(filesystem.NewSegmentsDirectory(walDirectory))
+	require.NoError(t, err)
+	writer, err := wal.NewLogsWriter(filesystem.NewSegment(walDirectory, 1<<10, filesystem.WithSegmentHeader(wal.SegmentHeader())), zap.NewNop())
+	require.NoError(t, err)
+	masterWAL, err := wal.NewWAL(writer, reader, time.Millisecond, 1)
+	require.NoError(t, err)
+	masterWAL.Start(ctx)
+
+	lsmEngine, err := lsm.NewLSM(
+		filesystem.NewTablesDirectory(lsmDirectory),
+		filesystem.NewStateFile(lsmDirectory, "MANIFEST"),
+		zap.NewNop(),
+		lsm.WithMemtableSize(2<<10),
+	)
+	require.NoError(t, err)
+	lsmEngine.Start(ctx)
+
+	masterStorage, err := storage.NewStorage(lsmEngine, zap.NewNop(), storage.WithWAL(masterWAL))
+	require.NoError(t, err)
+	masterStorage.Start(ctx)
+
+	// records are persisted by flushes after garbage collection, so keys are
+	// written until the first segment is removed
+	value := string(bytes.Repeat([]byte("v"), 64))
+	keysNumber := 0
+	require.Eventually(t, func() bool {
+		require.NoError(t, masterStorage.Set(ctx, fmt.Sprintf("key%d", keysNumber), value))
+		keysNumber++
+
+		contains, err := reader.Contains(1)
+		return err == nil && !contains
+	}, 10*time.Second, 10*time.Millisecond)
+
+	persistedSnapshots, err := storage.NewPersistedSnapshots(lsmEngine)
+	require.NoError(t, err)
+	master, err := NewMaster(testServer{}, reader, 4<<10, zap.NewNop(), WithMasterSnapshots(persistedSnapshots))
+	require.NoError(t, err)
+
+	// the fresh replica gets the dump of the engine and records after it
+	stream := make(chan []wal.Log, keysNumber)
+	slave, err := NewSlave(loopbackClient{master}, testLogs{}, testLogs{}, time.Millisecond, zap.NewNop(), WithStream(stream), WithSlaveSnapshots(&testSnapshots{}))
+	require.NoError(t, err)
+
+	memoryEngine, err := memory.NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	replicaStorage, err := storage.NewStorage(memoryEngine, zap.NewNop(), storage.WithReplication(replicaOfMaster{}), storage.WithReplicationStream(stream))
+	require.NoError(t, err)
+
+	for hasMore := true; hasMore; {
+		hasMore = slave.synchronize()
+	}
+
+	assert.Equal(t, int64(keysNumber), slave.lastLSN)
+	require.Eventually(t, func() bool {
+		for idx := range keysNumber {
+			if _, err := replicaStorage.Get(ctx, fmt.Sprintf("key%d", idx)); err != nil {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, time.Millisecond)
+}
+
+// switchedRole is the role of the storage which is demoted from master
+type switchedRole struct {
+	isMaster atomic.Bool
+}
+
+func (r *switchedRole) IsMaster() bool {
+	return r.isMaster.Load()
+}
+
+// newWALStorage creates the storage of the in-memory engine with the WAL in the directory
+func newWALStorage(ctx context.Context, t *testing.T, directory string, options ...storage.Option) (*storage.Storage, *wal.LogsReader) {
+	t.Helper()
+
+	reader, err := wal.NewLogsReader(filesystem.NewSegmentsDirectory(directory))
+	require.NoError(t, err)
+	writer, err := wal.NewLogsWriter(filesystem.NewSegment(directory, 1<<10, filesystem.WithSegmentHeader(wal.SegmentHeader())), zap.NewNop())
+	require.NoError(t, err)
+	writeAheadLog, err := wal.NewWAL(writer, reader, time.Millisecond, 1, wal.WithSnapshots(filesystem.NewSnapshotsDirectory(directory)))
+	require.NoError(t, err)
+	writeAheadLog.Start(ctx)
+
+	memoryEngine, err := memory.NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	stor, err := storage.NewStorage(memoryEngine, zap.NewNop(), append([]storage.Option{storage.WithWAL(writeAheadLog)}, options...)...)
+	require.NoError(t, err)
+
+	return stor, reader
+}
+
+func TestSnapshotOfDemotedMaster(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the old master has the write which isn't replicated to the new master
+	oldDirectory, newDirectory := t.TempDir(), t.TempDir()
+	role := &switchedRole{}
+	role.isMaster.Store(true)
+	stream := make(chan []wal.Log, 10)
+	oldStorage, oldReader := newWALStorage(ctx, t, oldDirectory, storage.WithReplication(role), storage.WithReplicationStream(stream))
+	require.NoError(t, oldStorage.Set(ctx, "replicated", "1"))
+	require.NoError(t, oldStorage.Set(ctx, "unreplicated", "2"))
+
+	newStorage, newReader := newWALStorage(ctx, t, newDirectory)
+	require.NoError(t, newStorage.Set(ctx, "replicated", "1"))
+
+	term := &Term{}
+	newTerm, err := term.Increment()
+	require.NoError(t, err)
+	newStorage.AdvanceLSN(TermFirstLSN(newTerm))
+	require.NoError(t, newStorage.Set(ctx, "new", "3"))
+
+	master, err := NewMaster(testServer{}, newReader, 4<<10, zap.NewNop(),
+		WithMasterTerm(term),
+		WithMasterSnapshots(filesystem.NewSnapshotsDirectory(newDirectory)),
+		WithCurrentSnapshot(func() (int64, []byte, error) {
+			return newStorage.Snapshot(ctx)
+		}),
+	)
+	require.NoError(t, err)
+
+	// the demoted master replicates the new master
+	role.isMaster.Store(false)
+	segment := filesystem.NewSegment(oldDirectory, 1<<10, filesystem.WithSegmentHeader(wal.SegmentHeader()))
+	slave, err := NewSlave(loopbackClient{master}, segment, oldReader, time.Millisecond, zap.NewNop(),
+		WithStream(stream),
+		WithSlaveSnapshots(filesystem.NewSnapshotsDirectory(oldDirectory)),
+	)
+	require.NoError(t, err)
+
+	for hasMore := true; hasMore; {
+		hasMore = slave.synchronize()
+	}
+
+	require.Eventually(t, func() bool {
+		value, err := oldStorage.Get(ctx, "new")
+		return err == nil && value == "3"
+	}, 5*time.Second, time.Millisecond)
+
+	_, err = oldStorage.Get(ctx, "unreplicated")
+	assert.ErrorIs(t, err, storage.ErrorNotFound)
+
+	// the unreplicated write isn't recovered after restart
+	restarted, _ := newWALStorage(ctx, t, oldDirectory)
+	for key, expected := range map[string]string{"replicated": "1", "new": "3"} {
+		value, err := restarted.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, expected, value)
+	}
+
+	_, err = restarted.Get(ctx, "unreplicated")
+	assert.ErrorIs(t, err, storage.ErrorNotFound)
+}
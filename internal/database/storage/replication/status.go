@@ -0,0 +1,62 @@
+package replication
+
+import (
+	"sort"
+	"time"
+)
+
+// ReplicaStatus is a position of the replica known by the master, it's
+// updated on each request, so LastLSN is persisted by the replica
+type ReplicaStatus struct {
+	ID       string
+	LastLSN  int64
+	LastSeen time.Time
+}
+
+// SlaveStatus describes how far the slave is behind the master, the
+// lag in time is counted from the last moment when it was in sync
+type SlaveStatus struct {
+	MasterAddress string
+	LastLSN       int64
+	LagRecords    int64
+	LastSync      time.Time
+	SyncedAt      time.Time
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// Status describes the replication role of the node, replicas
+// are served by the master or by the relay of the cascading slave
+type Status struct {
+	IsMaster bool
+	Term     int64
+	Replicas []ReplicaStatus
+	Slave    *SlaveStatus
+}
+
+// replicasStatus keeps positions of replicas which are not stale
+type replicasStatus map[string]ReplicaStatus
+
+func (r replicasStatus) update(replicaID string, lastLSN int64) {
+	now := time.Now()
+	for id, replica := range r {
+		if now.Sub(replica.LastSeen) > staleReplicaTimeout {
+			delete(r, id)
+		}
+	}
+
+	r[replicaID] = ReplicaStatus{ID: replicaID, LastLSN: lastLSN, LastSeen: now}
+}
+
+func (r replicasStatus) list() []ReplicaStatus {
+	replicas := make([]ReplicaStatus, 0, len(r))
+	for _, replica := range r {
+		replicas = append(replicas, replica)
+	}
+
+	sort.Slice(replicas, func(i, j int) bool {
+		return replicas[i].ID < replicas[j].ID
+	})
+
+	return replicas
+}
@@ -0,0 +1,111 @@
+package replication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"database-simon/internal/database/storage/wal"
+)
+
+// chunkedLogs returns records one by one, so the replica catches up by several requests
+type chunkedLogs struct {
+	logs []wal.Log
+}
+
+func (l chunkedLogs) ReadAfter(lsn int64, _ int) ([]byte, bool, error) {
+	for idx := range l.logs {
+		if l.logs[idx].LSN > lsn {
+			records, err := wal.EncodeRecords(l.logs[idx : idx+1])
+			return records, idx != len(l.logs)-1, err
+		}
+	}
+
+	return nil, false, nil
+}
+
+func (chunkedLogs) Contains(int64) (bool, error) {
+	return true, nil
+}
+
+func (l chunkedLogs) CountAfter(lsn int64) (int, error) {
+	count := 0
+	for idx := range l.logs {
+		if l.logs[idx].LSN > lsn {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func TestReplicationStatus(t *testing.T) {
+	t.Parallel()
+
+	logs := []wal.Log{
+		{LSN: 1, CommandID: "DEL", Arguments: []string{"key_1"}},
+		{LSN: 2, CommandID: "DEL", Arguments: []string{"key_2"}},
+		{LSN: 3, CommandID: "DEL", Arguments: []string{"key_3"}},
+	}
+
+	master, err := NewMaster(testServer{}, chunkedLogs{logs: logs}, 4<<10, zap.NewNop())
+	require.NoError(t, err)
+
+	stream := make(chan []wal.Log, 10)
+	slave, err := NewSlave(loopbackClient{master}, testLogs{}, testLogs{}, time.Millisecond, zap.NewNop(), WithStream(stream))
+	require.NoError(t, err)
+
+	assert.True(t, slave.synchronize())
+	status := slave.Status()
+	assert.Equal(t, int64(1), status.LastLSN)
+	assert.Equal(t, int64(2), status.LagRecords)
+	assert.False(t, status.LastSync.IsZero())
+	assert.True(t, status.SyncedAt.IsZero())
+
+	assert.True(t, slave.synchronize())
+	assert.False(t, slave.synchronize())
+	status = slave.Status()
+	assert.Equal(t, int64(3), status.LastLSN)
+	assert.Zero(t, status.LagRecords)
+	assert.False(t, status.SyncedAt.IsZero())
+	assert.Empty(t, status.LastError)
+
+	replicas := master.Replicas()
+	require.Len(t, replicas, 1)
+	assert.Equal(t, slave.id, replicas[0].ID)
+	assert.Equal(t, int64(2), replicas[0].LastLSN)
+
+	// failures of synchronization are kept in the status
+	slave.client = testClient{}
+	assert.False(t, slave.synchronize())
+	status = slave.Status()
+	assert.Contains(t, status.LastError, "master is unavailable")
+	assert.False(t, status.LastErrorTime.IsZero())
+}
+
+func TestNodeStatus(t *testing.T) {
+	t.Parallel()
+
+	term := &Term{}
+	node, _ := newTestNode(t, false, term)
+
+	status := node.Status()
+	assert.False(t, status.IsMaster)
+	require.NotNil(t, status.Slave)
+	assert.Equal(t, "127.0.0.1:8082", status.Slave.MasterAddress)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	node.Start(ctx)
+	require.NoError(t, node.Promote(""))
+
+	status = node.Status()
+	assert.True(t, status.IsMaster)
+	assert.Equal(t, int64(1), status.Term)
+	assert.Nil(t, status.Slave)
+}
@@ -0,0 +1,89 @@
+package replication
+
+import (
+	"fmt"
+	"sync"
+)
+
+// termLSNBits is a number of LSN bits for records of one term
+const termLSNBits = 40
+
+// TermFirstLSN returns LSN after which records of the term are numbered, so
+// records of a new master never repeat LSN of unreplicated records of the old one
+func TermFirstLSN(term int64) int64 {
+	return term << termLSNBits
+}
+
+type termStore interface {
+	Load() (int64, error)
+	Store(int64) error
+}
+
+// Term is an epoch of replication, it's increased by each promotion,
+// so replicas can reject records of a master which has been replaced.
+// Zero value is a term which is not persisted
+type Term struct {
+	mutex sync.Mutex
+	value int64
+	store termStore
+}
+
+// NewTerm loads the term from the store
+func NewTerm(store termStore) (*Term, error) {
+	if store == nil {
+		return &Term{}, nil
+	}
+
+	value, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Term{
+		value: value,
+		store: store,
+	}, nil
+}
+
+// Get ...
+func (t *Term) Get() int64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.value
+}
+
+// Advance moves the term forward, smaller terms are ignored
+func (t *Term) Advance(value int64) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if value <= t.value {
+		return nil
+	}
+
+	return t.set(value)
+}
+
+// Increment starts a new term
+func (t *Term) Increment() (int64, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if err := t.set(t.value + 1); err != nil {
+		return 0, err
+	}
+
+	return t.value, nil
+}
+
+func (t *Term) set(value int64) error {
+	if t.store != nil {
+		if err := t.store.Store(value); err != nil {
+			return fmt.Errorf("failed to store term: %w", err)
+		}
+	}
+
+	t.value = value
+	return nil
+}
@@ -0,0 +1,70 @@
+package replication
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"database-simon/internal/database/storage/wal"
+)
+
+// reorderedLogs keeps the record 10 before the record 9, they are read by chunks
+type reorderedLogs struct {
+	first, second []byte
+}
+
+func (l reorderedLogs) ReadAfter(lsn int64, _ int) ([]byte, bool, error) {
+	switch lsn {
+	case 0:
+		return l.first, true, nil
+	case 10:
+		return l.second, false, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+func (reorderedLogs) Contains(int64) (bool, error) {
+	return true, nil
+}
+
+func (reorderedLogs) CountAfter(int64) (int, error) {
+	return 1, nil
+}
+
+func TestWatermark(t *testing.T) {
+	t.Parallel()
+
+	first, err := wal.EncodeRecords([]wal.Log{{LSN: 10, CommandID: "DEL", Arguments: []string{"key"}}})
+	require.NoError(t, err)
+	second, err := wal.EncodeRecords([]wal.Log{{LSN: 9, CommandID: "DEL", Arguments: []string{"key"}}})
+	require.NoError(t, err)
+
+	watermark := int64(10)
+	master, err := NewMaster(testServer{}, reorderedLogs{first: first, second: second}, 2<<10, zap.NewNop(), WithWatermark(func() int64 {
+		return watermark
+	}))
+	require.NoError(t, err)
+
+	stream := make(chan []wal.Log, 10)
+	slave, err := NewSlave(loopbackClient{master}, testLogs{}, testLogs{}, time.Millisecond, zap.NewNop(), WithStream(stream))
+	require.NoError(t, err)
+
+	// records of the chunk aren't visible until the last chunk sends the watermark
+	assert.True(t, slave.synchronize())
+	assert.Equal(t, []wal.Log{{LSN: 10, CommandID: "DEL", Arguments: []string{"key"}}, {LSN: 0, CommandID: wal.WatermarkCommand}}, <-stream)
+
+	assert.False(t, slave.synchronize())
+	assert.Equal(t, []wal.Log{{LSN: 9, CommandID: "DEL", Arguments: []string{"key"}}, {LSN: 10, CommandID: wal.WatermarkCommand}}, <-stream)
+
+	// the watermark is sent without records only when it advances
+	assert.False(t, slave.synchronize())
+	assert.Empty(t, stream)
+
+	watermark = 11
+	assert.False(t, slave.synchronize())
+	assert.Equal(t, []wal.Log{{LSN: 11, CommandID: wal.WatermarkCommand}}, <-stream)
+}
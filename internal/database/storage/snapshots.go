@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"sync"
+
+	"database-simon/internal/concurrency"
+)
+
+// snapshots tracks writes in progress and active readers, a snapshot contains
+// only transactions which are completely applied to the engine, so the same
+// snapshot always returns the same data
+type snapshots struct {
+	mutex     sync.Mutex
+	generator *IDGenerator
+	writers   map[int64]struct{}
+	readers   map[int64]int
+}
+
+func newSnapshots(generator *IDGenerator) *snapshots {
+	return &snapshots{
+		generator: generator,
+		writers:   make(map[int64]struct{}),
+		readers:   make(map[int64]int),
+	}
+}
+
+// beginWrite generates an identifier for a new write transaction
+func (s *snapshots) beginWrite() int64 {
+	var txID int64
+	concurrency.WithLock(&s.mutex, func() {
+		txID = s.generator.Generate()
+		s.writers[txID] = struct{}{}
+	})
+
+	return txID
+}
+
+// endWrite completes the write and returns the newest identifier before which all writes are completed
+func (s *snapshots) endWrite(txID int64) int64 {
+	var visible int64
+	concurrency.WithLock(&s.mutex, func() {
+		delete(s.writers, txID)
+		visible = s.visible()
+	})
+
+	return visible
+}
+
+// acquire returns a snapshot for a reader, the snapshot must be released
+func (s *snapshots) acquire() int64 {
+	var snapshot int64
+	concurrency.WithLock(&s.mutex, func() {
+		snapshot = s.visible()
+		s.readers[snapshot]++
+	})
+
+	return snapshot
+}
+
+func (s *snapshots) release(snapshot int64) {
+	concurrency.WithLock(&s.mutex, func() {
+		s.readers[snapshot]--
+		if s.readers[snapshot] <= 0 {
+			delete(s.readers, snapshot)
+		}
+	})
+}
+
+// oldest returns the oldest snapshot which can be used by readers,
+// older versions of keys are not needed anymore
+func (s *snapshots) oldest() int64 {
+	var oldest int64
+	concurrency.WithLock(&s.mutex, func() {
+		oldest = s.visible()
+		for snapshot := range s.readers {
+			oldest = min(oldest, snapshot)
+		}
+	})
+
+	return oldest
+}
+
+// visible returns the newest identifier before which all writes are completed
+func (s *snapshots) visible() int64 {
+	snapshot := s.generator.Current()
+	for txID := range s.writers {
+		snapshot = min(snapshot, txID-1)
+	}
+
+	return snapshot
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotsWithWritesInProgress(t *testing.T) {
+	t.Parallel()
+
+	registry := newSnapshots(NewIDGenerator(10))
+	assert.Equal(t, int64(10), registry.oldest())
+
+	firstWrite := registry.beginWrite()
+	secondWrite := registry.beginWrite()
+	assert.Equal(t, int64(11), firstWrite)
+	assert.Equal(t, int64(12), secondWrite)
+
+	// snapshot doesn't contain writes in progress
+	assert.Equal(t, int64(10), registry.endWrite(secondWrite))
+	snapshot := registry.acquire()
+	assert.Equal(t, int64(10), snapshot)
+
+	assert.Equal(t, int64(12), registry.endWrite(firstWrite))
+	assert.Equal(t, int64(12), registry.acquire())
+
+	// the oldest active reader holds versions
+	assert.Equal(t, int64(10), registry.oldest())
+	registry.release(snapshot)
+	assert.Equal(t, int64(12), registry.oldest())
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -13,23 +15,115 @@ import (
 	"database-simon/internal/database/storage/wal"
 )
 
+const garbageCollectionInterval = time.Second
+
 var (
 	// ErrorNotFound ...
 	ErrorNotFound = errors.New("not found")
 	// ErrorMutableTX ...
 	ErrorMutableTX = errors.New("mutable transaction on slave")
+	// ErrorUnordered ...
+	ErrorUnordered = errors.New("engine doesn't support range queries")
+	// ErrorNoResharding ...
+	ErrorNoResharding = errors.New("engine doesn't support resharding")
+	// ErrorNoStructures ...
+	ErrorNoStructures = errors.New("engine doesn't support data structures")
+	// ErrorNoCounters ...
+	ErrorNoCounters = errors.New("engine doesn't support counters")
+	// ErrorOutOfMemory ...
+	ErrorOutOfMemory = errors.New("memory limit is reached")
 )
 
+// KeyValue ...
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
 type walI interface {
 	Recover() ([]wal.Log, error)
 	Set(context.Context, string, string) concurrency.FutureError
+	SetWithExpiration(context.Context, string, string, time.Time) concurrency.FutureError
 	Del(context.Context, string) concurrency.FutureError
+	Expire(context.Context, string, time.Time) concurrency.FutureError
+	Persist(context.Context, string) concurrency.FutureError
+	Write(context.Context, wal.Operation) concurrency.FutureError
+	Commit(context.Context, []wal.Operation) concurrency.FutureError
+	Checkpoint(int64, []byte) error
+	LastCheckpoint() (int64, []byte, error)
+}
+
+// truncatingWAL removes records which are persisted by the engine, the log
+// of the consensus cluster keeps all records, so it doesn't implement it
+type truncatingWAL interface {
+	Truncate(int64) error
 }
 
 type engine interface {
 	Set(context.Context, string, string)
+	SetWithExpiration(context.Context, string, string, time.Time)
 	Get(context.Context, string) (string, bool)
 	Del(context.Context, string)
+	Expire(context.Context, string, time.Time) bool
+	Expiration(context.Context, string) (time.Time, bool)
+	Persist(context.Context, string) bool
+	CollectGarbage(context.Context, int64)
+	Versioned() bool
+	Snapshot(context.Context) ([]byte, error)
+	Restore(context.Context, []byte) error
+	Ordered() bool
+	Range(context.Context, string, string, int, func(string, string))
+}
+
+// persistentEngine keeps data on disk itself, so records up to the persisted
+// LSN are not replayed from the WAL on recovery and they are truncated
+type persistentEngine interface {
+	PersistedLSN() int64
+	OnPersisted(func(int64))
+}
+
+// boundedEngine limits memory, writes which add data are rejected
+// when the limit is reached and keys can't be evicted
+type boundedEngine interface {
+	OutOfMemory(string) bool
+}
+
+// structuredEngine keeps hashes, lists, sets and sorted sets, operations
+// return common.ErrorWrongType for keys of other types
+type structuredEngine interface {
+	Type(context.Context, string) (string, bool)
+	HSet(context.Context, string, []string) (int, error)
+	HGet(context.Context, string, string) (string, bool, error)
+	HDel(context.Context, string, []string) (int, error)
+	HGetAll(context.Context, string) ([]string, error)
+	LPush(context.Context, string, []string) (int, error)
+	RPush(context.Context, string, []string) (int, error)
+	LPop(context.Context, string, int) ([]string, error)
+	RPop(context.Context, string, int) ([]string, error)
+	LRange(context.Context, string, int, int) ([]string, error)
+	SAdd(context.Context, string, []string) (int, error)
+	SRem(context.Context, string, []string) (int, error)
+	SMembers(context.Context, string) ([]string, error)
+	SIsMember(context.Context, string, string) (bool, error)
+	ZAdd(context.Context, string, []common.ScoredMember) (int, error)
+	ZRem(context.Context, string, []string) (int, error)
+	ZIncrBy(context.Context, string, float64, string) (float64, error)
+	ZScore(context.Context, string, string) (float64, bool, error)
+	ZRank(context.Context, string, string) (int, bool, error)
+	ZRange(context.Context, string, int, int) ([]common.ScoredMember, error)
+	ZRangeByScore(context.Context, string, common.ScoreRange, int, int) ([]common.ScoredMember, error)
+}
+
+// counterEngine evaluates increments of values, the results are written by
+// storage like SET, so the WAL keeps values instead of increments
+type counterEngine interface {
+	Increment(context.Context, string, int64) (string, time.Time, error)
+	IncrementFloat(context.Context, string, float64) (string, time.Time, error)
+}
+
+// reshardableEngine changes the number of its partitions online
+type reshardableEngine interface {
+	Reshard(int) error
 }
 
 type replica interface {
@@ -44,6 +138,26 @@ type Storage struct {
 	wal       walI
 	stream    <-chan []wal.Log
 	generator *IDGenerator
+	snapshots *snapshots
+	applied   *appliedLSN
+
+	// single operations are applied under the read lock and transactions
+	// under the write lock, so partially applied transactions are invisible
+	mutex sync.RWMutex
+
+	transactions      map[int64]*transaction
+	transactionsMutex sync.Mutex
+
+	// operations of data structures with the same key are applied in order of LSN
+	keyLocks keyLocks
+
+	checkpointInterval time.Duration
+	checkpointMutex    sync.Mutex
+
+	// keys evicted by the engine wait for logging of their deletion
+	evictedKeys    []string
+	evictionsMutex sync.Mutex
+	evictions      chan struct{}
 }
 
 // NewStorage ...
@@ -57,8 +171,10 @@ func NewStorage(engine engine, logger *zap.Logger, options ...Option) (*Storage,
 	}
 
 	st := &Storage{
-		engine: engine,
-		logger: logger,
+		engine:       engine,
+		logger:       logger,
+		transactions: make(map[int64]*transaction),
+		evictions:    make(chan struct{}, 1),
 	}
 
 	for _, option := range options {
@@ -67,27 +183,116 @@ func NewStorage(engine engine, logger *zap.Logger, options ...Option) (*Storage,
 
 	var lastLSN int64
 	if st.wal != nil {
-		logs, err := st.wal.Recover()
-		if err != nil {
-			logger.Error("failed to recover data from WAL", zap.Error(err))
-		} else {
-			lastLSN = st.applyData(logs)
+		var err error
+		if lastLSN, err = st.recover(); err != nil {
+			return nil, err
+		}
+	}
+
+	if engine, ok := st.engine.(evictingEngine); ok {
+		evicted := func(string) {}
+		if st.wal != nil {
+			evicted = st.evicted
 		}
+		engine.OnEviction(st.evictionAllowed, evicted)
 	}
 
+	if engine, ok := st.engine.(persistentEngine); ok && st.truncatable() {
+		engine.OnPersisted(func(lsn int64) {
+			_ = st.truncate(lsn) // the error is logged, segments are removed by the next flush
+		})
+	}
+
+	st.generator = NewIDGenerator(lastLSN)
+	st.snapshots = newSnapshots(st.generator)
+	st.applied = newAppliedLSN(lastLSN)
+
 	if st.stream != nil {
 		go func() {
+			received := lastLSN
 			for logs := range st.stream {
-				_ = st.applyData(logs)
+				// replicated transactions become visible for snapshots after applying,
+				// records of replication are followed by the watermark of the master,
+				// so a record isn't visible before earlier records are received
+				received = max(received, st.applyData(logs))
+				lsn := received
+				if count := len(logs); count != 0 && logs[count-1].CommandID == wal.WatermarkCommand {
+					lsn = min(lsn, logs[count-1].LSN)
+				}
+
+				st.generator.Advance(lsn)
+				st.applied.advance(lsn)
 			}
 		}()
 	}
 
-	st.generator = NewIDGenerator(lastLSN)
-
 	return st, nil
 }
 
+// Start runs garbage collection of versions which are not visible for active
+// snapshots and periodic checkpoints if they are enabled
+func (s *Storage) Start(ctx context.Context) {
+	if s.wal != nil && s.checkpointInterval > 0 {
+		go s.runCheckpoints(ctx)
+	}
+
+	if s.wal != nil {
+		go s.runEvictions(ctx)
+	}
+
+	go func() {
+		ticker := time.NewTicker(garbageCollectionInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.engine.CollectGarbage(ctx, s.snapshots.oldest())
+			}
+		}
+	}()
+}
+
+// outOfMemory checks limits of the engine for keys which are written
+func (s *Storage) outOfMemory(keys ...string) bool {
+	engine, ok := s.engine.(boundedEngine)
+	if !ok {
+		return false
+	}
+
+	for _, key := range keys {
+		if engine.OutOfMemory(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AdvanceLSN makes following writes get LSN greater than lsn
+func (s *Storage) AdvanceLSN(lsn int64) {
+	s.generator.Advance(lsn)
+}
+
+// AppliedLSN returns LSN up to which all writes are completed and applied
+func (s *Storage) AppliedLSN() int64 {
+	return s.applied.get()
+}
+
+// WaitLSN blocks until the record with lsn is applied, so the following
+// reads see it, zero timeout means that waiting is limited only by ctx
+func (s *Storage) WaitLSN(ctx context.Context, lsn int64, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return s.applied.wait(ctx, lsn)
+}
+
 // Set ...
 func (s *Storage) Set(ctx context.Context, key, value string) error {
 	if s.replica != nil && !s.replica.IsMaster() {
@@ -96,19 +301,79 @@ func (s *Storage) Set(ctx context.Context, key, value string) error {
 		return ctx.Err()
 	}
 
-	txID := s.generator.Generate()
+	if tx := s.transaction(ctx); tx != nil {
+		tx.set(key, value, wal.NewOperation(compute.SetCommand, []string{key, value}))
+		return nil
+	}
+
+	unlock := s.keyLocks.lock(key)
+	defer unlock()
+
+	if s.outOfMemory(key) {
+		return ErrorOutOfMemory
+	}
+
+	txID := s.snapshots.beginWrite()
+	defer s.endWrite(txID)
 	ctx = common.ContextWithTxID(ctx, txID)
 
+	var errLog error
 	if s.wal != nil {
 		futureResponse := s.wal.Set(ctx, key, value)
-		if err := futureResponse.Get(); err != nil {
-			return err
+		if errLog = futureResponse.Get(); !persisted(errLog) {
+			return errLog
 		}
 	}
 
-	s.engine.Set(ctx, key, value)
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		s.engine.Set(ctx, key, value)
+	})
 
-	return nil
+	s.committed(ctx, txID)
+
+	return errLog
+}
+
+// SetWithExpiration ...
+func (s *Storage) SetWithExpiration(ctx context.Context, key, value string, deadline time.Time) error {
+	if s.replica != nil && !s.replica.IsMaster() {
+		return ErrorMutableTX
+	} else if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if tx := s.transaction(ctx); tx != nil {
+		arguments := []string{key, value, wal.EncodeDeadline(deadline)}
+		tx.set(key, value, wal.NewOperation(compute.SetCommand, arguments))
+		return nil
+	}
+
+	unlock := s.keyLocks.lock(key)
+	defer unlock()
+
+	if s.outOfMemory(key) {
+		return ErrorOutOfMemory
+	}
+
+	txID := s.snapshots.beginWrite()
+	defer s.endWrite(txID)
+	ctx = common.ContextWithTxID(ctx, txID)
+
+	var errLog error
+	if s.wal != nil {
+		futureResponse := s.wal.SetWithExpiration(ctx, key, value, deadline)
+		if errLog = futureResponse.Get(); !persisted(errLog) {
+			return errLog
+		}
+	}
+
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		s.engine.SetWithExpiration(ctx, key, value, deadline)
+	})
+
+	s.committed(ctx, txID)
+
+	return errLog
 }
 
 // Get ...
@@ -117,17 +382,98 @@ func (s *Storage) Get(ctx context.Context, key string) (string, error) {
 		return "", ctx.Err()
 	}
 
-	txID := s.generator.Generate()
-	ctx = common.ContextWithTxID(ctx, txID)
+	if tx := s.transaction(ctx); tx != nil {
+		if write, found := tx.get(key); found {
+			if write.deleted {
+				return "", ErrorNotFound
+			}
+			return write.value, nil
+		}
 
-	val, found := s.engine.Get(ctx, key)
-	if !found {
-		return "", fmt.Errorf("not found")
+		ctx = common.ContextWithTxID(ctx, tx.snapshot)
+	} else {
+		snapshot := s.snapshots.acquire()
+		defer s.snapshots.release(snapshot)
+		ctx = common.ContextWithTxID(ctx, snapshot)
+	}
+
+	var val string
+	var found bool
+	var err error
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		val, found, err = s.lookup(ctx, key)
+	})
+
+	if err != nil {
+		return "", err
+	} else if !found {
+		return "", ErrorNotFound
 	}
 
 	return val, nil
 }
 
+// Range returns keys with values in order of keys from from up to to (exclusive,
+// empty to means no bound), limit restricts the number of keys if it's positive
+func (s *Storage) Range(ctx context.Context, from, to string, limit int) ([]KeyValue, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	} else if !s.engine.Ordered() {
+		return nil, ErrorUnordered
+	}
+
+	tx := s.transaction(ctx)
+	engineLimit := limit
+	if tx != nil {
+		// pending writes of the transaction can hide keys of the engine
+		if limit > 0 {
+			engineLimit += len(tx.writes)
+		}
+		ctx = common.ContextWithTxID(ctx, tx.snapshot)
+	} else {
+		snapshot := s.snapshots.acquire()
+		defer s.snapshots.release(snapshot)
+		ctx = common.ContextWithTxID(ctx, snapshot)
+	}
+
+	var entries []KeyValue
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		s.engine.Range(ctx, from, to, engineLimit, func(key, value string) {
+			entries = append(entries, KeyValue{Key: key, Value: value})
+		})
+	})
+
+	if tx != nil {
+		// keys after the last one of the limited range are unknown
+		bound := to
+		if engineLimit > 0 && len(entries) == engineLimit {
+			bound = entries[len(entries)-1].Key + "\x00"
+		}
+		entries = tx.overlay(entries, from, bound)
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries, nil
+}
+
+// Reshard changes the number of partitions of the engine online, the layout
+// of partitions is local for the node, so it isn't written to the WAL
+func (s *Storage) Reshard(ctx context.Context, partitions int) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	engine, ok := s.engine.(reshardableEngine)
+	if !ok {
+		return ErrorNoResharding
+	}
+
+	return engine.Reshard(partitions)
+}
+
 // Del ...
 func (s *Storage) Del(ctx context.Context, key string) error {
 	if s.replica != nil && !s.replica.IsMaster() {
@@ -136,33 +482,260 @@ func (s *Storage) Del(ctx context.Context, key string) error {
 		return ctx.Err()
 	}
 
-	txID := s.generator.Generate()
+	if tx := s.transaction(ctx); tx != nil {
+		tx.del(key, wal.NewOperation(compute.DelCommand, []string{key}))
+		return nil
+	}
+
+	unlock := s.keyLocks.lock(key)
+	defer unlock()
+
+	txID := s.snapshots.beginWrite()
+	defer s.endWrite(txID)
 	ctx = common.ContextWithTxID(ctx, txID)
 
+	var errLog error
 	if s.wal != nil {
 		futureResponse := s.wal.Del(ctx, key)
-		if err := futureResponse.Get(); err != nil {
-			return err
+		if errLog = futureResponse.Get(); !persisted(errLog) {
+			return errLog
+		}
+	}
+
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		s.engine.Del(ctx, key)
+	})
+
+	s.committed(ctx, txID)
+
+	return errLog
+}
+
+// Expire ...
+func (s *Storage) Expire(ctx context.Context, key string, deadline time.Time) error {
+	if s.replica != nil && !s.replica.IsMaster() {
+		return ErrorMutableTX
+	} else if ctx.Err() != nil {
+		return ctx.Err()
+	} else if s.transaction(ctx) != nil {
+		return ErrorTXCommand
+	}
+
+	unlock := s.keyLocks.lock(key)
+	defer unlock()
+
+	txID := s.snapshots.beginWrite()
+	defer s.endWrite(txID)
+	ctx = common.ContextWithTxID(ctx, txID)
+
+	if _, found := s.engine.Expiration(ctx, key); !found {
+		return ErrorNotFound
+	}
+
+	var errLog error
+	if s.wal != nil {
+		futureResponse := s.wal.Expire(ctx, key, deadline)
+		if errLog = futureResponse.Get(); !persisted(errLog) {
+			return errLog
+		}
+	}
+
+	var found bool
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		found = s.engine.Expire(ctx, key, deadline)
+	})
+
+	s.committed(ctx, txID)
+
+	if !found {
+		return ErrorNotFound
+	}
+
+	return errLog
+}
+
+// Expiration returns a deadline of the key, zero deadline means that
+// the key has no associated expiration
+func (s *Storage) Expiration(ctx context.Context, key string) (time.Time, error) {
+	if ctx.Err() != nil {
+		return time.Time{}, ctx.Err()
+	}
+
+	if tx := s.transaction(ctx); tx != nil {
+		ctx = common.ContextWithTxID(ctx, tx.snapshot)
+	} else {
+		snapshot := s.snapshots.acquire()
+		defer s.snapshots.release(snapshot)
+		ctx = common.ContextWithTxID(ctx, snapshot)
+	}
+
+	var deadline time.Time
+	var found bool
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		deadline, found = s.engine.Expiration(ctx, key)
+	})
+
+	if !found {
+		return time.Time{}, ErrorNotFound
+	}
+
+	return deadline, nil
+}
+
+// Persist ...
+func (s *Storage) Persist(ctx context.Context, key string) error {
+	if s.replica != nil && !s.replica.IsMaster() {
+		return ErrorMutableTX
+	} else if ctx.Err() != nil {
+		return ctx.Err()
+	} else if s.transaction(ctx) != nil {
+		return ErrorTXCommand
+	}
+
+	unlock := s.keyLocks.lock(key)
+	defer unlock()
+
+	txID := s.snapshots.beginWrite()
+	defer s.endWrite(txID)
+	ctx = common.ContextWithTxID(ctx, txID)
+
+	if _, found := s.engine.Expiration(ctx, key); !found {
+		return ErrorNotFound
+	}
+
+	var errLog error
+	if s.wal != nil {
+		futureResponse := s.wal.Persist(ctx, key)
+		if errLog = futureResponse.Get(); !persisted(errLog) {
+			return errLog
 		}
 	}
 
-	s.engine.Del(ctx, key)
+	var found bool
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		found = s.engine.Persist(ctx, key)
+	})
+
+	s.committed(ctx, txID)
+
+	if !found {
+		return ErrorNotFound
+	}
+
+	return errLog
+}
+
+// persisted reports whether the record is persisted by the WAL of the master, writes
+// which aren't acknowledged by replicas in time are still applied to keep the engine
+// consistent with the WAL, but common.ErrorReplicationTimeout is returned to the client
+func persisted(err error) bool {
+	return err == nil || errors.Is(err, common.ErrorReplicationTimeout)
+}
 
-	return nil
+// committed reports LSN of the write which is applied by the session
+func (s *Storage) committed(ctx context.Context, lsn int64) {
+	common.SetCommitLSN(ctx, lsn)
+}
+
+// endWrite advances applied LSN only up to the newest LSN before which all writes
+// are completed, so WAIT LSN doesn't return while earlier writes aren't applied yet
+func (s *Storage) endWrite(txID int64) {
+	s.applied.advance(s.snapshots.endWrite(txID))
 }
 
 func (s *Storage) applyData(logs []wal.Log) int64 {
 	var lastLSN int64
 	for _, log := range logs {
+		if log.CommandID == wal.WatermarkCommand {
+			continue
+		}
+
 		lastLSN = max(lastLSN, log.LSN)
 		ctx := common.ContextWithTxID(context.Background(), log.LSN)
-		switch log.CommandID {
-		case compute.SetCommand:
-			s.engine.Set(ctx, log.Arguments[0], log.Arguments[1])
-		case compute.DelCommand:
-			s.engine.Del(ctx, log.Arguments[0])
+
+		if log.CommandID == wal.RestoreCommand {
+			s.restore(ctx, log)
+			continue
 		}
+
+		if log.CommandID != compute.CommitCommand {
+			concurrency.WithLock(s.mutex.RLocker(), func() {
+				s.applyOperation(ctx, log.LSN, wal.NewOperation(log.CommandID, log.Arguments))
+			})
+			continue
+		}
+
+		operations, err := wal.DecodeOperations(log.Arguments)
+		if err != nil {
+			s.logger.Warn("failed to decode transaction", zap.Int64("lsn", log.LSN), zap.Error(err))
+			continue
+		}
+
+		concurrency.WithLock(&s.mutex, func() {
+			for _, operation := range operations {
+				s.applyOperation(ctx, log.LSN, operation)
+			}
+		})
 	}
 
 	return lastLSN
 }
+
+// restore replaces the state by the snapshot of the master, all
+// records up to the snapshot are contained in it
+func (s *Storage) restore(ctx context.Context, log wal.Log) {
+	if len(log.Arguments) != 1 {
+		s.logger.Warn("incorrect snapshot record", zap.Int64("lsn", log.LSN))
+		return
+	}
+
+	var err error
+	concurrency.WithLock(&s.mutex, func() {
+		err = s.engine.Restore(ctx, []byte(log.Arguments[0]))
+	})
+
+	if err != nil {
+		s.logger.Error("failed to restore snapshot", zap.Int64("lsn", log.LSN), zap.Error(err))
+		return
+	}
+
+	s.logger.Info("snapshot is restored", zap.Int64("lsn", log.LSN))
+}
+
+func (s *Storage) applyOperation(ctx context.Context, lsn int64, operation wal.Operation) {
+	arguments := operation.Arguments
+	switch operation.CommandID {
+	case compute.SetCommand:
+		if len(arguments) == 3 {
+			if deadline, ok := s.decodeDeadline(lsn, arguments[2]); ok {
+				s.engine.SetWithExpiration(ctx, arguments[0], arguments[1], deadline)
+			}
+		} else {
+			s.engine.Set(ctx, arguments[0], arguments[1])
+		}
+	case compute.DelCommand:
+		s.engine.Del(ctx, arguments[0])
+	case compute.PExpireAtCommand:
+		if deadline, ok := s.decodeDeadline(lsn, arguments[1]); ok {
+			s.engine.Expire(ctx, arguments[0], deadline)
+		}
+	case compute.PersistCommand:
+		s.engine.Persist(ctx, arguments[0])
+	case compute.HSetCommand, compute.HDelCommand, compute.LPushCommand, compute.RPushCommand,
+		compute.LPopCommand, compute.RPopCommand, compute.SAddCommand, compute.SRemCommand,
+		compute.ZAddCommand, compute.ZRemCommand, compute.ZIncrByCommand:
+		if len(arguments) != 0 {
+			s.applyStructureOperation(ctx, lsn, operation)
+		}
+	}
+}
+
+func (s *Storage) decodeDeadline(lsn int64, data string) (time.Time, bool) {
+	deadline, err := wal.DecodeDeadline(data)
+	if err != nil {
+		s.logger.Warn("failed to decode deadline", zap.Int64("lsn", lsn), zap.Error(err))
+		return time.Time{}, false
+	}
+
+	return deadline, true
+}
@@ -11,9 +11,11 @@ package storage
 
 import (
 	context "context"
+	common "database-simon/internal/common"
 	concurrency "database-simon/internal/concurrency"
 	wal "database-simon/internal/database/storage/wal"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -42,6 +44,34 @@ func (m *MockwalI) EXPECT() *MockwalIMockRecorder {
 	return m.recorder
 }
 
+// Checkpoint mocks base method.
+func (m *MockwalI) Checkpoint(arg0 int64, arg1 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Checkpoint", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Checkpoint indicates an expected call of Checkpoint.
+func (mr *MockwalIMockRecorder) Checkpoint(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Checkpoint", reflect.TypeOf((*MockwalI)(nil).Checkpoint), arg0, arg1)
+}
+
+// Commit mocks base method.
+func (m *MockwalI) Commit(arg0 context.Context, arg1 []wal.Operation) concurrency.FutureError {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Commit", arg0, arg1)
+	ret0, _ := ret[0].(concurrency.FutureError)
+	return ret0
+}
+
+// Commit indicates an expected call of Commit.
+func (mr *MockwalIMockRecorder) Commit(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Commit", reflect.TypeOf((*MockwalI)(nil).Commit), arg0, arg1)
+}
+
 // Del mocks base method.
 func (m *MockwalI) Del(arg0 context.Context, arg1 string) concurrency.FutureError {
 	m.ctrl.T.Helper()
@@ -56,6 +86,50 @@ func (mr *MockwalIMockRecorder) Del(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Del", reflect.TypeOf((*MockwalI)(nil).Del), arg0, arg1)
 }
 
+// Expire mocks base method.
+func (m *MockwalI) Expire(arg0 context.Context, arg1 string, arg2 time.Time) concurrency.FutureError {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Expire", arg0, arg1, arg2)
+	ret0, _ := ret[0].(concurrency.FutureError)
+	return ret0
+}
+
+// Expire indicates an expected call of Expire.
+func (mr *MockwalIMockRecorder) Expire(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expire", reflect.TypeOf((*MockwalI)(nil).Expire), arg0, arg1, arg2)
+}
+
+// LastCheckpoint mocks base method.
+func (m *MockwalI) LastCheckpoint() (int64, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastCheckpoint")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// LastCheckpoint indicates an expected call of LastCheckpoint.
+func (mr *MockwalIMockRecorder) LastCheckpoint() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastCheckpoint", reflect.TypeOf((*MockwalI)(nil).LastCheckpoint))
+}
+
+// Persist mocks base method.
+func (m *MockwalI) Persist(arg0 context.Context, arg1 string) concurrency.FutureError {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Persist", arg0, arg1)
+	ret0, _ := ret[0].(concurrency.FutureError)
+	return ret0
+}
+
+// Persist indicates an expected call of Persist.
+func (mr *MockwalIMockRecorder) Persist(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Persist", reflect.TypeOf((*MockwalI)(nil).Persist), arg0, arg1)
+}
+
 // Recover mocks base method.
 func (m *MockwalI) Recover() ([]wal.Log, error) {
 	m.ctrl.T.Helper()
@@ -85,6 +159,72 @@ func (mr *MockwalIMockRecorder) Set(arg0, arg1, arg2 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockwalI)(nil).Set), arg0, arg1, arg2)
 }
 
+// SetWithExpiration mocks base method.
+func (m *MockwalI) SetWithExpiration(arg0 context.Context, arg1, arg2 string, arg3 time.Time) concurrency.FutureError {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWithExpiration", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(concurrency.FutureError)
+	return ret0
+}
+
+// SetWithExpiration indicates an expected call of SetWithExpiration.
+func (mr *MockwalIMockRecorder) SetWithExpiration(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithExpiration", reflect.TypeOf((*MockwalI)(nil).SetWithExpiration), arg0, arg1, arg2, arg3)
+}
+
+// Write mocks base method.
+func (m *MockwalI) Write(arg0 context.Context, arg1 wal.Operation) concurrency.FutureError {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Write", arg0, arg1)
+	ret0, _ := ret[0].(concurrency.FutureError)
+	return ret0
+}
+
+// Write indicates an expected call of Write.
+func (mr *MockwalIMockRecorder) Write(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockwalI)(nil).Write), arg0, arg1)
+}
+
+// MocktruncatingWAL is a mock of truncatingWAL interface.
+type MocktruncatingWAL struct {
+	ctrl     *gomock.Controller
+	recorder *MocktruncatingWALMockRecorder
+	isgomock struct{}
+}
+
+// MocktruncatingWALMockRecorder is the mock recorder for MocktruncatingWAL.
+type MocktruncatingWALMockRecorder struct {
+	mock *MocktruncatingWAL
+}
+
+// NewMocktruncatingWAL creates a new mock instance.
+func NewMocktruncatingWAL(ctrl *gomock.Controller) *MocktruncatingWAL {
+	mock := &MocktruncatingWAL{ctrl: ctrl}
+	mock.recorder = &MocktruncatingWALMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktruncatingWAL) EXPECT() *MocktruncatingWALMockRecorder {
+	return m.recorder
+}
+
+// Truncate mocks base method.
+func (m *MocktruncatingWAL) Truncate(arg0 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Truncate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Truncate indicates an expected call of Truncate.
+func (mr *MocktruncatingWALMockRecorder) Truncate(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Truncate", reflect.TypeOf((*MocktruncatingWAL)(nil).Truncate), arg0)
+}
+
 // Mockengine is a mock of engine interface.
 type Mockengine struct {
 	ctrl     *gomock.Controller
@@ -109,6 +249,18 @@ func (m *Mockengine) EXPECT() *MockengineMockRecorder {
 	return m.recorder
 }
 
+// CollectGarbage mocks base method.
+func (m *Mockengine) CollectGarbage(arg0 context.Context, arg1 int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CollectGarbage", arg0, arg1)
+}
+
+// CollectGarbage indicates an expected call of CollectGarbage.
+func (mr *MockengineMockRecorder) CollectGarbage(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectGarbage", reflect.TypeOf((*Mockengine)(nil).CollectGarbage), arg0, arg1)
+}
+
 // Del mocks base method.
 func (m *Mockengine) Del(arg0 context.Context, arg1 string) {
 	m.ctrl.T.Helper()
@@ -121,6 +273,35 @@ func (mr *MockengineMockRecorder) Del(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Del", reflect.TypeOf((*Mockengine)(nil).Del), arg0, arg1)
 }
 
+// Expiration mocks base method.
+func (m *Mockengine) Expiration(arg0 context.Context, arg1 string) (time.Time, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Expiration", arg0, arg1)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Expiration indicates an expected call of Expiration.
+func (mr *MockengineMockRecorder) Expiration(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expiration", reflect.TypeOf((*Mockengine)(nil).Expiration), arg0, arg1)
+}
+
+// Expire mocks base method.
+func (m *Mockengine) Expire(arg0 context.Context, arg1 string, arg2 time.Time) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Expire", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Expire indicates an expected call of Expire.
+func (mr *MockengineMockRecorder) Expire(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expire", reflect.TypeOf((*Mockengine)(nil).Expire), arg0, arg1, arg2)
+}
+
 // Get mocks base method.
 func (m *Mockengine) Get(arg0 context.Context, arg1 string) (string, bool) {
 	m.ctrl.T.Helper()
@@ -136,6 +317,60 @@ func (mr *MockengineMockRecorder) Get(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*Mockengine)(nil).Get), arg0, arg1)
 }
 
+// Ordered mocks base method.
+func (m *Mockengine) Ordered() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ordered")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Ordered indicates an expected call of Ordered.
+func (mr *MockengineMockRecorder) Ordered() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ordered", reflect.TypeOf((*Mockengine)(nil).Ordered))
+}
+
+// Persist mocks base method.
+func (m *Mockengine) Persist(arg0 context.Context, arg1 string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Persist", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Persist indicates an expected call of Persist.
+func (mr *MockengineMockRecorder) Persist(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Persist", reflect.TypeOf((*Mockengine)(nil).Persist), arg0, arg1)
+}
+
+// Range mocks base method.
+func (m *Mockengine) Range(arg0 context.Context, arg1, arg2 string, arg3 int, arg4 func(string, string)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Range", arg0, arg1, arg2, arg3, arg4)
+}
+
+// Range indicates an expected call of Range.
+func (mr *MockengineMockRecorder) Range(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Range", reflect.TypeOf((*Mockengine)(nil).Range), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Restore mocks base method.
+func (m *Mockengine) Restore(arg0 context.Context, arg1 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockengineMockRecorder) Restore(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*Mockengine)(nil).Restore), arg0, arg1)
+}
+
 // Set mocks base method.
 func (m *Mockengine) Set(arg0 context.Context, arg1, arg2 string) {
 	m.ctrl.T.Helper()
@@ -148,6 +383,571 @@ func (mr *MockengineMockRecorder) Set(arg0, arg1, arg2 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*Mockengine)(nil).Set), arg0, arg1, arg2)
 }
 
+// SetWithExpiration mocks base method.
+func (m *Mockengine) SetWithExpiration(arg0 context.Context, arg1, arg2 string, arg3 time.Time) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetWithExpiration", arg0, arg1, arg2, arg3)
+}
+
+// SetWithExpiration indicates an expected call of SetWithExpiration.
+func (mr *MockengineMockRecorder) SetWithExpiration(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithExpiration", reflect.TypeOf((*Mockengine)(nil).SetWithExpiration), arg0, arg1, arg2, arg3)
+}
+
+// Snapshot mocks base method.
+func (m *Mockengine) Snapshot(arg0 context.Context) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Snapshot", arg0)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Snapshot indicates an expected call of Snapshot.
+func (mr *MockengineMockRecorder) Snapshot(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Snapshot", reflect.TypeOf((*Mockengine)(nil).Snapshot), arg0)
+}
+
+// Versioned mocks base method.
+func (m *Mockengine) Versioned() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Versioned")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Versioned indicates an expected call of Versioned.
+func (mr *MockengineMockRecorder) Versioned() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Versioned", reflect.TypeOf((*Mockengine)(nil).Versioned))
+}
+
+// MockpersistentEngine is a mock of persistentEngine interface.
+type MockpersistentEngine struct {
+	ctrl     *gomock.Controller
+	recorder *MockpersistentEngineMockRecorder
+	isgomock struct{}
+}
+
+// MockpersistentEngineMockRecorder is the mock recorder for MockpersistentEngine.
+type MockpersistentEngineMockRecorder struct {
+	mock *MockpersistentEngine
+}
+
+// NewMockpersistentEngine creates a new mock instance.
+func NewMockpersistentEngine(ctrl *gomock.Controller) *MockpersistentEngine {
+	mock := &MockpersistentEngine{ctrl: ctrl}
+	mock.recorder = &MockpersistentEngineMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockpersistentEngine) EXPECT() *MockpersistentEngineMockRecorder {
+	return m.recorder
+}
+
+// OnPersisted mocks base method.
+func (m *MockpersistentEngine) OnPersisted(arg0 func(int64)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnPersisted", arg0)
+}
+
+// OnPersisted indicates an expected call of OnPersisted.
+func (mr *MockpersistentEngineMockRecorder) OnPersisted(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPersisted", reflect.TypeOf((*MockpersistentEngine)(nil).OnPersisted), arg0)
+}
+
+// PersistedLSN mocks base method.
+func (m *MockpersistentEngine) PersistedLSN() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PersistedLSN")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// PersistedLSN indicates an expected call of PersistedLSN.
+func (mr *MockpersistentEngineMockRecorder) PersistedLSN() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PersistedLSN", reflect.TypeOf((*MockpersistentEngine)(nil).PersistedLSN))
+}
+
+// MockboundedEngine is a mock of boundedEngine interface.
+type MockboundedEngine struct {
+	ctrl     *gomock.Controller
+	recorder *MockboundedEngineMockRecorder
+	isgomock struct{}
+}
+
+// MockboundedEngineMockRecorder is the mock recorder for MockboundedEngine.
+type MockboundedEngineMockRecorder struct {
+	mock *MockboundedEngine
+}
+
+// NewMockboundedEngine creates a new mock instance.
+func NewMockboundedEngine(ctrl *gomock.Controller) *MockboundedEngine {
+	mock := &MockboundedEngine{ctrl: ctrl}
+	mock.recorder = &MockboundedEngineMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockboundedEngine) EXPECT() *MockboundedEngineMockRecorder {
+	return m.recorder
+}
+
+// OutOfMemory mocks base method.
+func (m *MockboundedEngine) OutOfMemory(arg0 string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OutOfMemory", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// OutOfMemory indicates an expected call of OutOfMemory.
+func (mr *MockboundedEngineMockRecorder) OutOfMemory(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OutOfMemory", reflect.TypeOf((*MockboundedEngine)(nil).OutOfMemory), arg0)
+}
+
+// MockstructuredEngine is a mock of structuredEngine interface.
+type MockstructuredEngine struct {
+	ctrl     *gomock.Controller
+	recorder *MockstructuredEngineMockRecorder
+	isgomock struct{}
+}
+
+// MockstructuredEngineMockRecorder is the mock recorder for MockstructuredEngine.
+type MockstructuredEngineMockRecorder struct {
+	mock *MockstructuredEngine
+}
+
+// NewMockstructuredEngine creates a new mock instance.
+func NewMockstructuredEngine(ctrl *gomock.Controller) *MockstructuredEngine {
+	mock := &MockstructuredEngine{ctrl: ctrl}
+	mock.recorder = &MockstructuredEngineMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockstructuredEngine) EXPECT() *MockstructuredEngineMockRecorder {
+	return m.recorder
+}
+
+// HDel mocks base method.
+func (m *MockstructuredEngine) HDel(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HDel", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HDel indicates an expected call of HDel.
+func (mr *MockstructuredEngineMockRecorder) HDel(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HDel", reflect.TypeOf((*MockstructuredEngine)(nil).HDel), arg0, arg1, arg2)
+}
+
+// HGet mocks base method.
+func (m *MockstructuredEngine) HGet(arg0 context.Context, arg1, arg2 string) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HGet", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// HGet indicates an expected call of HGet.
+func (mr *MockstructuredEngineMockRecorder) HGet(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HGet", reflect.TypeOf((*MockstructuredEngine)(nil).HGet), arg0, arg1, arg2)
+}
+
+// HGetAll mocks base method.
+func (m *MockstructuredEngine) HGetAll(arg0 context.Context, arg1 string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HGetAll", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HGetAll indicates an expected call of HGetAll.
+func (mr *MockstructuredEngineMockRecorder) HGetAll(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HGetAll", reflect.TypeOf((*MockstructuredEngine)(nil).HGetAll), arg0, arg1)
+}
+
+// HSet mocks base method.
+func (m *MockstructuredEngine) HSet(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HSet", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HSet indicates an expected call of HSet.
+func (mr *MockstructuredEngineMockRecorder) HSet(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HSet", reflect.TypeOf((*MockstructuredEngine)(nil).HSet), arg0, arg1, arg2)
+}
+
+// LPop mocks base method.
+func (m *MockstructuredEngine) LPop(arg0 context.Context, arg1 string, arg2 int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LPop", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LPop indicates an expected call of LPop.
+func (mr *MockstructuredEngineMockRecorder) LPop(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LPop", reflect.TypeOf((*MockstructuredEngine)(nil).LPop), arg0, arg1, arg2)
+}
+
+// LPush mocks base method.
+func (m *MockstructuredEngine) LPush(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LPush", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LPush indicates an expected call of LPush.
+func (mr *MockstructuredEngineMockRecorder) LPush(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LPush", reflect.TypeOf((*MockstructuredEngine)(nil).LPush), arg0, arg1, arg2)
+}
+
+// LRange mocks base method.
+func (m *MockstructuredEngine) LRange(arg0 context.Context, arg1 string, arg2, arg3 int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LRange", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LRange indicates an expected call of LRange.
+func (mr *MockstructuredEngineMockRecorder) LRange(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LRange", reflect.TypeOf((*MockstructuredEngine)(nil).LRange), arg0, arg1, arg2, arg3)
+}
+
+// RPop mocks base method.
+func (m *MockstructuredEngine) RPop(arg0 context.Context, arg1 string, arg2 int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RPop", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RPop indicates an expected call of RPop.
+func (mr *MockstructuredEngineMockRecorder) RPop(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RPop", reflect.TypeOf((*MockstructuredEngine)(nil).RPop), arg0, arg1, arg2)
+}
+
+// RPush mocks base method.
+func (m *MockstructuredEngine) RPush(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RPush", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RPush indicates an expected call of RPush.
+func (mr *MockstructuredEngineMockRecorder) RPush(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RPush", reflect.TypeOf((*MockstructuredEngine)(nil).RPush), arg0, arg1, arg2)
+}
+
+// SAdd mocks base method.
+func (m *MockstructuredEngine) SAdd(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SAdd", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SAdd indicates an expected call of SAdd.
+func (mr *MockstructuredEngineMockRecorder) SAdd(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SAdd", reflect.TypeOf((*MockstructuredEngine)(nil).SAdd), arg0, arg1, arg2)
+}
+
+// SIsMember mocks base method.
+func (m *MockstructuredEngine) SIsMember(arg0 context.Context, arg1, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SIsMember", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SIsMember indicates an expected call of SIsMember.
+func (mr *MockstructuredEngineMockRecorder) SIsMember(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SIsMember", reflect.TypeOf((*MockstructuredEngine)(nil).SIsMember), arg0, arg1, arg2)
+}
+
+// SMembers mocks base method.
+func (m *MockstructuredEngine) SMembers(arg0 context.Context, arg1 string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SMembers", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SMembers indicates an expected call of SMembers.
+func (mr *MockstructuredEngineMockRecorder) SMembers(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SMembers", reflect.TypeOf((*MockstructuredEngine)(nil).SMembers), arg0, arg1)
+}
+
+// SRem mocks base method.
+func (m *MockstructuredEngine) SRem(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SRem", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SRem indicates an expected call of SRem.
+func (mr *MockstructuredEngineMockRecorder) SRem(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SRem", reflect.TypeOf((*MockstructuredEngine)(nil).SRem), arg0, arg1, arg2)
+}
+
+// Type mocks base method.
+func (m *MockstructuredEngine) Type(arg0 context.Context, arg1 string) (string, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Type", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Type indicates an expected call of Type.
+func (mr *MockstructuredEngineMockRecorder) Type(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Type", reflect.TypeOf((*MockstructuredEngine)(nil).Type), arg0, arg1)
+}
+
+// ZAdd mocks base method.
+func (m *MockstructuredEngine) ZAdd(arg0 context.Context, arg1 string, arg2 []common.ScoredMember) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZAdd", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ZAdd indicates an expected call of ZAdd.
+func (mr *MockstructuredEngineMockRecorder) ZAdd(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZAdd", reflect.TypeOf((*MockstructuredEngine)(nil).ZAdd), arg0, arg1, arg2)
+}
+
+// ZIncrBy mocks base method.
+func (m *MockstructuredEngine) ZIncrBy(arg0 context.Context, arg1 string, arg2 float64, arg3 string) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZIncrBy", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ZIncrBy indicates an expected call of ZIncrBy.
+func (mr *MockstructuredEngineMockRecorder) ZIncrBy(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZIncrBy", reflect.TypeOf((*MockstructuredEngine)(nil).ZIncrBy), arg0, arg1, arg2, arg3)
+}
+
+// ZRange mocks base method.
+func (m *MockstructuredEngine) ZRange(arg0 context.Context, arg1 string, arg2, arg3 int) ([]common.ScoredMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZRange", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]common.ScoredMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ZRange indicates an expected call of ZRange.
+func (mr *MockstructuredEngineMockRecorder) ZRange(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRange", reflect.TypeOf((*MockstructuredEngine)(nil).ZRange), arg0, arg1, arg2, arg3)
+}
+
+// ZRangeByScore mocks base method.
+func (m *MockstructuredEngine) ZRangeByScore(arg0 context.Context, arg1 string, arg2 common.ScoreRange, arg3, arg4 int) ([]common.ScoredMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZRangeByScore", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].([]common.ScoredMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ZRangeByScore indicates an expected call of ZRangeByScore.
+func (mr *MockstructuredEngineMockRecorder) ZRangeByScore(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRangeByScore", reflect.TypeOf((*MockstructuredEngine)(nil).ZRangeByScore), arg0, arg1, arg2, arg3, arg4)
+}
+
+// ZRank mocks base method.
+func (m *MockstructuredEngine) ZRank(arg0 context.Context, arg1, arg2 string) (int, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZRank", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ZRank indicates an expected call of ZRank.
+func (mr *MockstructuredEngineMockRecorder) ZRank(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRank", reflect.TypeOf((*MockstructuredEngine)(nil).ZRank), arg0, arg1, arg2)
+}
+
+// ZRem mocks base method.
+func (m *MockstructuredEngine) ZRem(arg0 context.Context, arg1 string, arg2 []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZRem", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ZRem indicates an expected call of ZRem.
+func (mr *MockstructuredEngineMockRecorder) ZRem(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZRem", reflect.TypeOf((*MockstructuredEngine)(nil).ZRem), arg0, arg1, arg2)
+}
+
+// ZScore mocks base method.
+func (m *MockstructuredEngine) ZScore(arg0 context.Context, arg1, arg2 string) (float64, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZScore", arg0, arg1, arg2)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ZScore indicates an expected call of ZScore.
+func (mr *MockstructuredEngineMockRecorder) ZScore(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZScore", reflect.TypeOf((*MockstructuredEngine)(nil).ZScore), arg0, arg1, arg2)
+}
+
+// MockcounterEngine is a mock of counterEngine interface.
+type MockcounterEngine struct {
+	ctrl     *gomock.Controller
+	recorder *MockcounterEngineMockRecorder
+	isgomock struct{}
+}
+
+// MockcounterEngineMockRecorder is the mock recorder for MockcounterEngine.
+type MockcounterEngineMockRecorder struct {
+	mock *MockcounterEngine
+}
+
+// NewMockcounterEngine creates a new mock instance.
+func NewMockcounterEngine(ctrl *gomock.Controller) *MockcounterEngine {
+	mock := &MockcounterEngine{ctrl: ctrl}
+	mock.recorder = &MockcounterEngineMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockcounterEngine) EXPECT() *MockcounterEngineMockRecorder {
+	return m.recorder
+}
+
+// Increment mocks base method.
+func (m *MockcounterEngine) Increment(arg0 context.Context, arg1 string, arg2 int64) (string, time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Increment", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(time.Time)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Increment indicates an expected call of Increment.
+func (mr *MockcounterEngineMockRecorder) Increment(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Increment", reflect.TypeOf((*MockcounterEngine)(nil).Increment), arg0, arg1, arg2)
+}
+
+// IncrementFloat mocks base method.
+func (m *MockcounterEngine) IncrementFloat(arg0 context.Context, arg1 string, arg2 float64) (string, time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementFloat", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(time.Time)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// IncrementFloat indicates an expected call of IncrementFloat.
+func (mr *MockcounterEngineMockRecorder) IncrementFloat(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementFloat", reflect.TypeOf((*MockcounterEngine)(nil).IncrementFloat), arg0, arg1, arg2)
+}
+
+// MockreshardableEngine is a mock of reshardableEngine interface.
+type MockreshardableEngine struct {
+	ctrl     *gomock.Controller
+	recorder *MockreshardableEngineMockRecorder
+	isgomock struct{}
+}
+
+// MockreshardableEngineMockRecorder is the mock recorder for MockreshardableEngine.
+type MockreshardableEngineMockRecorder struct {
+	mock *MockreshardableEngine
+}
+
+// NewMockreshardableEngine creates a new mock instance.
+func NewMockreshardableEngine(ctrl *gomock.Controller) *MockreshardableEngine {
+	mock := &MockreshardableEngine{ctrl: ctrl}
+	mock.recorder = &MockreshardableEngineMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockreshardableEngine) EXPECT() *MockreshardableEngineMockRecorder {
+	return m.recorder
+}
+
+// Reshard mocks base method.
+func (m *MockreshardableEngine) Reshard(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reshard", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reshard indicates an expected call of Reshard.
+func (mr *MockreshardableEngineMockRecorder) Reshard(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reshard", reflect.TypeOf((*MockreshardableEngine)(nil).Reshard), arg0)
+}
+
 // Mockreplica is a mock of replica interface.
 type Mockreplica struct {
 	ctrl     *gomock.Controller
@@ -1,6 +1,10 @@
 package storage
 
-import "database-simon/internal/database/storage/wal"
+import (
+	"time"
+
+	"database-simon/internal/database/storage/wal"
+)
 
 // Option ...
 type Option func(*Storage)
@@ -25,3 +29,10 @@ func WithReplicationStream(stream <-chan []wal.Log) Option {
 		storage.stream = stream
 	}
 }
+
+// WithCheckpointInterval ...
+func WithCheckpointInterval(interval time.Duration) Option {
+	return func(storage *Storage) {
+		storage.checkpointInterval = interval
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
 
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/compute"
 	"database-simon/internal/database/storage/wal"
 )
 
@@ -19,6 +23,9 @@ func TestStorage_New(t *testing.T) {
 	controller := gomock.NewController(t)
 
 	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
 	writeAheadLog.EXPECT().
 		Recover().
 		Return(nil, nil)
@@ -84,6 +91,12 @@ func TestStorage_New(t *testing.T) {
 	}
 }
 
+// boundedMockEngine is an engine with a memory limit
+type boundedMockEngine struct {
+	*Mockengine
+	*MockboundedEngine
+}
+
 func TestStorage_Set(t *testing.T) {
 	t.Parallel()
 
@@ -103,6 +116,28 @@ func TestStorage_Set(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		"set within memory limit": {
+			engine: func() engine {
+				eng := boundedMockEngine{NewMockengine(controller), NewMockboundedEngine(controller)}
+				eng.MockboundedEngine.EXPECT().
+					OutOfMemory("key").
+					Return(false)
+				eng.Mockengine.EXPECT().
+					Set(gomock.Any(), "key", "value")
+				return eng
+			},
+			expectedErr: nil,
+		},
+		"set out of memory": {
+			engine: func() engine {
+				eng := boundedMockEngine{NewMockengine(controller), NewMockboundedEngine(controller)}
+				eng.MockboundedEngine.EXPECT().
+					OutOfMemory("key").
+					Return(true)
+				return eng
+			},
+			expectedErr: ErrorOutOfMemory,
+		},
 	}
 
 	for name, test := range tests {
@@ -118,6 +153,42 @@ func TestStorage_Set(t *testing.T) {
 	}
 }
 
+func TestStorage_SetNotReplicated(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	resolved := func(err error) concurrency.FutureError {
+		promise := concurrency.NewPromise[error]()
+		promise.Set(err)
+		return promise.GetFuture()
+	}
+
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, nil)
+	gomock.InOrder(
+		writeAheadLog.EXPECT().Set(gomock.Any(), "key", "value").Return(resolved(common.ErrorReplicationTimeout)),
+		writeAheadLog.EXPECT().Set(gomock.Any(), "key", "other").Return(resolved(errors.New("disk is full"))),
+	)
+
+	// the write persisted by the master is applied even if replicas don't acknowledge it
+	eng := NewMockengine(controller)
+	eng.EXPECT().
+		Set(gomock.Any(), "key", "value")
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+
+	err = stor.Set(context.Background(), "key", "value")
+	assert.Equal(t, common.ErrorReplicationTimeout, err)
+	err = stor.Set(context.Background(), "key", "other")
+	assert.EqualError(t, err, "disk is full")
+}
+
 func TestStorage_Get(t *testing.T) {
 	t.Parallel()
 
@@ -200,3 +271,317 @@ func TestStorage_Del(t *testing.T) {
 		})
 	}
 }
+
+func TestStorage_SetWithExpiration(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	deadline := time.Now().Add(time.Minute)
+
+	eng := NewMockengine(controller)
+	eng.EXPECT().
+		SetWithExpiration(gomock.Any(), "key", "value", deadline)
+
+	stor, err := NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+
+	err = stor.SetWithExpiration(context.Background(), "key", "value", deadline)
+	assert.NoError(t, err)
+}
+
+func TestStorage_Expire(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	deadline := time.Now().Add(time.Minute)
+
+	tests := map[string]struct {
+		engine func() engine
+
+		expectedErr error
+	}{
+		"expire non-existent key": {
+			engine: func() engine {
+				eng := NewMockengine(controller)
+				eng.EXPECT().
+					Expiration(gomock.Any(), "key").
+					Return(time.Time{}, false)
+				return eng
+			},
+			expectedErr: ErrorNotFound,
+		},
+		"expire existing key": {
+			engine: func() engine {
+				eng := NewMockengine(controller)
+				eng.EXPECT().
+					Expiration(gomock.Any(), "key").
+					Return(time.Time{}, true)
+				eng.EXPECT().
+					Expire(gomock.Any(), "key", deadline).
+					Return(true)
+				return eng
+			},
+			expectedErr: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stor, err := NewStorage(test.engine(), zap.NewNop())
+			require.NoError(t, err)
+
+			err = stor.Expire(context.Background(), "key", deadline)
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
+func TestStorage_Expiration(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	deadline := time.Now().Add(time.Minute)
+
+	tests := map[string]struct {
+		engine func() engine
+
+		expectedDeadline time.Time
+		expectedErr      error
+	}{
+		"expiration of non-existent key": {
+			engine: func() engine {
+				eng := NewMockengine(controller)
+				eng.EXPECT().
+					Expiration(gomock.Any(), "key").
+					Return(time.Time{}, false)
+				return eng
+			},
+			expectedErr: ErrorNotFound,
+		},
+		"expiration of existing key": {
+			engine: func() engine {
+				eng := NewMockengine(controller)
+				eng.EXPECT().
+					Expiration(gomock.Any(), "key").
+					Return(deadline, true)
+				return eng
+			},
+			expectedDeadline: deadline,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stor, err := NewStorage(test.engine(), zap.NewNop())
+			require.NoError(t, err)
+
+			expiration, err := stor.Expiration(context.Background(), "key")
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedDeadline, expiration)
+		})
+	}
+}
+
+func TestStorage_Persist(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	tests := map[string]struct {
+		engine func() engine
+
+		expectedErr error
+	}{
+		"persist non-existent key": {
+			engine: func() engine {
+				eng := NewMockengine(controller)
+				eng.EXPECT().
+					Expiration(gomock.Any(), "key").
+					Return(time.Time{}, false)
+				return eng
+			},
+			expectedErr: ErrorNotFound,
+		},
+		"persist existing key": {
+			engine: func() engine {
+				eng := NewMockengine(controller)
+				eng.EXPECT().
+					Expiration(gomock.Any(), "key").
+					Return(time.Now(), true)
+				eng.EXPECT().
+					Persist(gomock.Any(), "key").
+					Return(true)
+				return eng
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stor, err := NewStorage(test.engine(), zap.NewNop())
+			require.NoError(t, err)
+
+			err = stor.Persist(context.Background(), "key")
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
+func TestStorage_RecoverExpirations(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	deadline := time.UnixMilli(time.Now().Add(time.Minute).UnixMilli())
+
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return([]wal.Log{
+			{LSN: 1, CommandID: compute.SetCommand, Arguments: []string{"key_1", "value_1", wal.EncodeDeadline(deadline)}},
+			{LSN: 2, CommandID: compute.SetCommand, Arguments: []string{"key_2", "value_2"}},
+			{LSN: 3, CommandID: compute.PExpireAtCommand, Arguments: []string{"key_2", wal.EncodeDeadline(deadline)}},
+			{LSN: 4, CommandID: compute.PersistCommand, Arguments: []string{"key_1"}},
+			{LSN: 5, CommandID: compute.PExpireAtCommand, Arguments: []string{"key_1", "incorrect"}},
+		}, nil)
+
+	eng := NewMockengine(controller)
+	gomock.InOrder(
+		eng.EXPECT().SetWithExpiration(gomock.Any(), "key_1", "value_1", deadline),
+		eng.EXPECT().Set(gomock.Any(), "key_2", "value_2"),
+		eng.EXPECT().Expire(gomock.Any(), "key_2", deadline).Return(true),
+		eng.EXPECT().Persist(gomock.Any(), "key_1").Return(true),
+	)
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), stor.generator.Generate())
+}
+
+func TestStorage_WaitLSN(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	eng := NewMockengine(controller)
+	eng.EXPECT().
+		Set(gomock.Any(), "key", "value")
+
+	stream := make(chan []wal.Log)
+	stor, err := NewStorage(eng, zap.NewNop(), WithReplicationStream(stream))
+	require.NoError(t, err)
+
+	// the record is not replicated yet
+	assert.Equal(t, ErrorLSNTimeout, stor.WaitLSN(context.Background(), 5, 10*time.Millisecond))
+
+	waited := make(chan error)
+	go func() {
+		waited <- stor.WaitLSN(context.Background(), 5, time.Second)
+	}()
+
+	stream <- []wal.Log{{LSN: 5, CommandID: compute.SetCommand, Arguments: []string{"key", "value"}}}
+	assert.NoError(t, <-waited)
+
+	// applied records are not waited
+	assert.NoError(t, stor.WaitLSN(context.Background(), 3, 0))
+}
+
+func TestStorage_CommitLSN(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	eng := NewMockengine(controller)
+	eng.EXPECT().
+		Del(gomock.Any(), "key")
+
+	stor, err := NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+
+	var lsn int64
+	require.NoError(t, stor.Del(common.ContextWithCommitLSN(context.Background(), &lsn), "key"))
+	assert.Equal(t, int64(1), lsn)
+	assert.NoError(t, stor.WaitLSN(context.Background(), lsn, 0))
+}
+
+func TestStorage_WaitLSNWithEarlierWrite(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	eng := NewMockengine(controller)
+	eng.EXPECT().
+		Del(gomock.Any(), "key")
+
+	stor, err := NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+
+	// the earlier write isn't completed, so the later one isn't waited
+	earlierWrite := stor.snapshots.beginWrite()
+	var lsn int64
+	require.NoError(t, stor.Del(common.ContextWithCommitLSN(context.Background(), &lsn), "key"))
+	assert.Equal(t, earlierWrite+1, lsn)
+	assert.Equal(t, ErrorLSNTimeout, stor.WaitLSN(context.Background(), lsn, 10*time.Millisecond))
+
+	stor.endWrite(earlierWrite)
+	assert.NoError(t, stor.WaitLSN(context.Background(), lsn, 0))
+}
+
+func TestStorage_WaitLSNWithReorderedRecords(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	eng := NewMockengine(controller)
+	eng.EXPECT().
+		Set(gomock.Any(), "key", gomock.Any()).
+		Times(2)
+
+	stream := make(chan []wal.Log)
+	stor, err := NewStorage(eng, zap.NewNop(), WithReplicationStream(stream))
+	require.NoError(t, err)
+
+	// the master logs the record 10 before the record 9, so it's received first
+	stream <- []wal.Log{
+		{LSN: 10, CommandID: compute.SetCommand, Arguments: []string{"key", "10"}},
+		{LSN: 8, CommandID: wal.WatermarkCommand},
+	}
+	require.NoError(t, stor.WaitLSN(context.Background(), 8, time.Second))
+	assert.Equal(t, ErrorLSNTimeout, stor.WaitLSN(context.Background(), 9, 10*time.Millisecond))
+	assert.Equal(t, int64(8), stor.generator.Current())
+
+	stream <- []wal.Log{
+		{LSN: 9, CommandID: compute.SetCommand, Arguments: []string{"key", "9"}},
+		{LSN: 10, CommandID: wal.WatermarkCommand},
+	}
+	assert.NoError(t, stor.WaitLSN(context.Background(), 10, time.Second))
+}
+
+// reshardableMockEngine is an engine with partitions
+type reshardableMockEngine struct {
+	*Mockengine
+	*MockreshardableEngine
+}
+
+func TestStorage_Reshard(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	stor, err := NewStorage(NewMockengine(controller), zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, ErrorNoResharding, stor.Reshard(context.Background(), 4))
+
+	eng := reshardableMockEngine{NewMockengine(controller), NewMockreshardableEngine(controller)}
+	eng.MockreshardableEngine.EXPECT().
+		Reshard(4).
+		Return(nil)
+
+	stor, err = NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+	assert.NoError(t, stor.Reshard(context.Background(), 4))
+}
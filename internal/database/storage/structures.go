@@ -0,0 +1,448 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/compute"
+	"database-simon/internal/database/storage/wal"
+)
+
+// HSet sets fields of the hash from pairs of fields and values,
+// it returns the number of added fields
+func (s *Storage) HSet(ctx context.Context, key string, pairs []string) (int, error) {
+	var added int
+	operation := wal.NewOperation(compute.HSetCommand, append([]string{key}, pairs...))
+	err := s.mutate(ctx, key, common.HashType, true, operation, func(ctx context.Context, engine structuredEngine) (err error) {
+		added, err = engine.HSet(ctx, key, pairs)
+		return err
+	})
+
+	return added, err
+}
+
+// HGet ...
+func (s *Storage) HGet(ctx context.Context, key, field string) (string, error) {
+	var value string
+	var found bool
+	err := s.inspect(ctx, func(ctx context.Context, engine structuredEngine) (err error) {
+		value, found, err = engine.HGet(ctx, key, field)
+		return err
+	})
+
+	if err != nil {
+		return "", err
+	} else if !found {
+		return "", ErrorNotFound
+	}
+
+	return value, nil
+}
+
+// HDel deletes fields of the hash, it returns the number of deleted fields
+func (s *Storage) HDel(ctx context.Context, key string, fields []string) (int, error) {
+	var deleted int
+	operation := wal.NewOperation(compute.HDelCommand, append([]string{key}, fields...))
+	err := s.mutate(ctx, key, common.HashType, false, operation, func(ctx context.Context, engine structuredEngine) (err error) {
+		deleted, err = engine.HDel(ctx, key, fields)
+		return err
+	})
+
+	if errors.Is(err, ErrorNotFound) {
+		return 0, nil
+	}
+
+	return deleted, err
+}
+
+// HGetAll returns fields with values of the hash in order of fields
+func (s *Storage) HGetAll(ctx context.Context, key string) ([]string, error) {
+	var items []string
+	err := s.inspect(ctx, func(ctx context.Context, engine structuredEngine) (err error) {
+		items, err = engine.HGetAll(ctx, key)
+		return err
+	})
+
+	return items, err
+}
+
+// LPush inserts values to the head of the list, it returns the length of the list
+func (s *Storage) LPush(ctx context.Context, key string, values []string) (int, error) {
+	var length int
+	operation := wal.NewOperation(compute.LPushCommand, append([]string{key}, values...))
+	err := s.mutate(ctx, key, common.ListType, true, operation, func(ctx context.Context, engine structuredEngine) (err error) {
+		length, err = engine.LPush(ctx, key, values)
+		return err
+	})
+
+	return length, err
+}
+
+// RPush inserts values to the tail of the list, it returns the length of the list
+func (s *Storage) RPush(ctx context.Context, key string, values []string) (int, error) {
+	var length int
+	operation := wal.NewOperation(compute.RPushCommand, append([]string{key}, values...))
+	err := s.mutate(ctx, key, common.ListType, true, operation, func(ctx context.Context, engine structuredEngine) (err error) {
+		length, err = engine.RPush(ctx, key, values)
+		return err
+	})
+
+	return length, err
+}
+
+// LPop removes up to count values from the head of the list
+func (s *Storage) LPop(ctx context.Context, key string, count int) ([]string, error) {
+	var values []string
+	operation := wal.NewOperation(compute.LPopCommand, []string{key, strconv.Itoa(count)})
+	err := s.mutate(ctx, key, common.ListType, false, operation, func(ctx context.Context, engine structuredEngine) (err error) {
+		values, err = engine.LPop(ctx, key, count)
+		return err
+	})
+
+	return values, err
+}
+
+// RPop removes up to count values from the tail of the list
+func (s *Storage) RPop(ctx context.Context, key string, count int) ([]string, error) {
+	var values []string
+	operation := wal.NewOperation(compute.RPopCommand, []string{key, strconv.Itoa(count)})
+	err := s.mutate(ctx, key, common.ListType, false, operation, func(ctx context.Context, engine structuredEngine) (err error) {
+		values, err = engine.RPop(ctx, key, count)
+		return err
+	})
+
+	return values, err
+}
+
+// LRange returns values of the list from start to stop inclusive,
+// negative indexes are counted from the tail of the list
+func (s *Storage) LRange(ctx context.Context, key string, start, stop int) ([]string, error) {
+	var values []string
+	err := s.inspect(ctx, func(ctx context.Context, engine structuredEngine) (err error) {
+		values, err = engine.LRange(ctx, key, start, stop)
+		return err
+	})
+
+	return values, err
+}
+
+// SAdd adds members to the set, it returns the number of added members
+func (s *Storage) SAdd(ctx context.Context, key string, members []string) (int, error) {
+	var added int
+	operation := wal.NewOperation(compute.SAddCommand, append([]string{key}, members...))
+	err := s.mutate(ctx, key, common.SetType, true, operation, func(ctx context.Context, engine structuredEngine) (err error) {
+		added, err = engine.SAdd(ctx, key, members)
+		return err
+	})
+
+	return added, err
+}
+
+// SRem removes members from the set, it returns the number of removed members
+func (s *Storage) SRem(ctx context.Context, key string, members []string) (int, error) {
+	var removed int
+	operation := wal.NewOperation(compute.SRemCommand, append([]string{key}, members...))
+	err := s.mutate(ctx, key, common.SetType, false, operation, func(ctx context.Context, engine structuredEngine) (err error) {
+		removed, err = engine.SRem(ctx, key, members)
+		return err
+	})
+
+	if errors.Is(err, ErrorNotFound) {
+		return 0, nil
+	}
+
+	return removed, err
+}
+
+// SMembers returns members of the set in order
+func (s *Storage) SMembers(ctx context.Context, key string) ([]string, error) {
+	var members []string
+	err := s.inspect(ctx, func(ctx context.Context, engine structuredEngine) (err error) {
+		members, err = engine.SMembers(ctx, key)
+		return err
+	})
+
+	return members, err
+}
+
+// SIsMember ...
+func (s *Storage) SIsMember(ctx context.Context, key, member string) (bool, error) {
+	var found bool
+	err := s.inspect(ctx, func(ctx context.Context, engine structuredEngine) (err error) {
+		found, err = engine.SIsMember(ctx, key, member)
+		return err
+	})
+
+	return found, err
+}
+
+// ZAdd sets scores of members of the sorted set, it returns the number of added members
+func (s *Storage) ZAdd(ctx context.Context, key string, members []common.ScoredMember) (int, error) {
+	arguments := make([]string, 0, 2*len(members)+1)
+	arguments = append(arguments, key)
+	for _, member := range members {
+		arguments = append(arguments, compute.FormatScore(member.Score), member.Member)
+	}
+
+	var added int
+	operation := wal.NewOperation(compute.ZAddCommand, arguments)
+	err := s.mutate(ctx, key, common.SortedSetType, true, operation, func(ctx context.Context, engine structuredEngine) (err error) {
+		added, err = engine.ZAdd(ctx, key, members)
+		return err
+	})
+
+	return added, err
+}
+
+// ZRem removes members from the sorted set, it returns the number of removed members
+func (s *Storage) ZRem(ctx context.Context, key string, members []string) (int, error) {
+	var removed int
+	operation := wal.NewOperation(compute.ZRemCommand, append([]string{key}, members...))
+	err := s.mutate(ctx, key, common.SortedSetType, false, operation, func(ctx context.Context, engine structuredEngine) (err error) {
+		removed, err = engine.ZRem(ctx, key, members)
+		return err
+	})
+
+	if errors.Is(err, ErrorNotFound) {
+		return 0, nil
+	}
+
+	return removed, err
+}
+
+// ZIncrBy adds the increment to the score of the member, it returns the new score.
+// The increment is logged even if the score becomes NaN, it fails the same way on replaying
+func (s *Storage) ZIncrBy(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	var score float64
+	operation := wal.NewOperation(compute.ZIncrByCommand, []string{key, compute.FormatScore(increment), member})
+	err := s.mutate(ctx, key, common.SortedSetType, true, operation, func(ctx context.Context, engine structuredEngine) (err error) {
+		score, err = engine.ZIncrBy(ctx, key, increment, member)
+		return err
+	})
+
+	return score, err
+}
+
+// ZScore ...
+func (s *Storage) ZScore(ctx context.Context, key, member string) (float64, error) {
+	var score float64
+	var found bool
+	err := s.inspect(ctx, func(ctx context.Context, engine structuredEngine) (err error) {
+		score, found, err = engine.ZScore(ctx, key, member)
+		return err
+	})
+
+	if err != nil {
+		return 0, err
+	} else if !found {
+		return 0, ErrorNotFound
+	}
+
+	return score, nil
+}
+
+// ZRank returns the zero-based position of the member in order of scores
+func (s *Storage) ZRank(ctx context.Context, key, member string) (int, error) {
+	var rank int
+	var found bool
+	err := s.inspect(ctx, func(ctx context.Context, engine structuredEngine) (err error) {
+		rank, found, err = engine.ZRank(ctx, key, member)
+		return err
+	})
+
+	if err != nil {
+		return 0, err
+	} else if !found {
+		return 0, ErrorNotFound
+	}
+
+	return rank, nil
+}
+
+// ZRange returns members of the sorted set from start to stop rank inclusive,
+// negative ranks are counted from the member with the highest score
+func (s *Storage) ZRange(ctx context.Context, key string, start, stop int) ([]common.ScoredMember, error) {
+	var members []common.ScoredMember
+	err := s.inspect(ctx, func(ctx context.Context, engine structuredEngine) (err error) {
+		members, err = engine.ZRange(ctx, key, start, stop)
+		return err
+	})
+
+	return members, err
+}
+
+// ZRangeByScore returns members of the sorted set with scores in the range skipping
+// offset members, the number of members is not limited if limit isn't positive
+func (s *Storage) ZRangeByScore(ctx context.Context, key string, scores common.ScoreRange, offset, limit int) ([]common.ScoredMember, error) {
+	var members []common.ScoredMember
+	err := s.inspect(ctx, func(ctx context.Context, engine structuredEngine) (err error) {
+		members, err = engine.ZRangeByScore(ctx, key, scores, offset, limit)
+		return err
+	})
+
+	return members, err
+}
+
+// mutate writes the operation to the WAL and applies it under the lock of the key, so
+// results of the operation are the same on replaying. The operation isn't written if
+// the key has another type, if it's missing and create is false or if memory is over
+func (s *Storage) mutate(
+	ctx context.Context,
+	key, typ string,
+	create bool,
+	operation wal.Operation,
+	apply func(context.Context, structuredEngine) error,
+) error {
+	if s.replica != nil && !s.replica.IsMaster() {
+		return ErrorMutableTX
+	} else if ctx.Err() != nil {
+		return ctx.Err()
+	} else if s.transaction(ctx) != nil {
+		return ErrorTXCommand
+	}
+
+	engine, ok := s.engine.(structuredEngine)
+	if !ok {
+		return ErrorNoStructures
+	}
+
+	unlock := s.keyLocks.lock(key)
+	defer unlock()
+
+	txID := s.snapshots.beginWrite()
+	defer s.endWrite(txID)
+	ctx = common.ContextWithTxID(ctx, txID)
+
+	if current, found := engine.Type(ctx, key); found && current != typ {
+		return common.ErrorWrongType
+	} else if !found && !create {
+		return ErrorNotFound
+	} else if create && s.outOfMemory(key) {
+		return ErrorOutOfMemory
+	}
+
+	var errLog error
+	if s.wal != nil {
+		futureResponse := s.wal.Write(ctx, operation)
+		if errLog = futureResponse.Get(); !persisted(errLog) {
+			return errLog
+		}
+	}
+
+	var err error
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		err = apply(ctx, engine)
+	})
+
+	s.committed(ctx, txID)
+
+	if err != nil {
+		return err
+	}
+
+	return errLog
+}
+
+// inspect reads a data structure from the snapshot of the transaction or from the latest one
+func (s *Storage) inspect(ctx context.Context, apply func(context.Context, structuredEngine) error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	engine, ok := s.engine.(structuredEngine)
+	if !ok {
+		return ErrorNoStructures
+	}
+
+	if tx := s.transaction(ctx); tx != nil {
+		ctx = common.ContextWithTxID(ctx, tx.snapshot)
+	} else {
+		snapshot := s.snapshots.acquire()
+		defer s.snapshots.release(snapshot)
+		ctx = common.ContextWithTxID(ctx, snapshot)
+	}
+
+	var err error
+	concurrency.WithLock(s.mutex.RLocker(), func() {
+		err = apply(ctx, engine)
+	})
+
+	return err
+}
+
+// applyStructureOperation replays the operation of a data structure
+func (s *Storage) applyStructureOperation(ctx context.Context, lsn int64, operation wal.Operation) {
+	engine, ok := s.engine.(structuredEngine)
+	if !ok {
+		s.logger.Warn("data structures are not supported by the engine", zap.Int64("lsn", lsn))
+		return
+	}
+
+	key, arguments := operation.Arguments[0], operation.Arguments[1:]
+
+	var err error
+	switch operation.CommandID {
+	case compute.HSetCommand:
+		_, err = engine.HSet(ctx, key, arguments)
+	case compute.HDelCommand:
+		_, err = engine.HDel(ctx, key, arguments)
+	case compute.LPushCommand:
+		_, err = engine.LPush(ctx, key, arguments)
+	case compute.RPushCommand:
+		_, err = engine.RPush(ctx, key, arguments)
+	case compute.SAddCommand:
+		_, err = engine.SAdd(ctx, key, arguments)
+	case compute.SRemCommand:
+		_, err = engine.SRem(ctx, key, arguments)
+	case compute.ZAddCommand:
+		var members []common.ScoredMember
+		if members, err = decodeScoredMembers(arguments); err == nil {
+			_, err = engine.ZAdd(ctx, key, members)
+		}
+	case compute.ZRemCommand:
+		_, err = engine.ZRem(ctx, key, arguments)
+	case compute.ZIncrByCommand:
+		var increment float64
+		if len(arguments) != 2 {
+			err = errors.New("incorrect arguments number")
+		} else if increment, err = compute.ParseScore(arguments[0]); err == nil {
+			_, err = engine.ZIncrBy(ctx, key, increment, arguments[1])
+		}
+	case compute.LPopCommand, compute.RPopCommand:
+		var count int
+		if len(arguments) != 1 {
+			err = errors.New("incorrect arguments number")
+		} else if count, err = strconv.Atoi(arguments[0]); err == nil && operation.CommandID == compute.LPopCommand {
+			_, err = engine.LPop(ctx, key, count)
+		} else if err == nil {
+			_, err = engine.RPop(ctx, key, count)
+		}
+	}
+
+	if err != nil {
+		s.logger.Warn("failed to apply operation", zap.Int64("lsn", lsn), zap.String("command", operation.CommandID), zap.Error(err))
+	}
+}
+
+// decodeScoredMembers parses pairs of scores and members of the ZADD operation
+func decodeScoredMembers(pairs []string) ([]common.ScoredMember, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("incorrect arguments number")
+	}
+
+	members := make([]common.ScoredMember, 0, len(pairs)/2)
+	for idx := 0; idx < len(pairs); idx += 2 {
+		score, err := compute.ParseScore(pairs[idx])
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, common.ScoredMember{Member: pairs[idx+1], Score: score})
+	}
+
+	return members, nil
+}
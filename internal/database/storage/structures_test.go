@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/compute"
+	"database-simon/internal/database/storage/engine/memory"
+	"database-simon/internal/database/storage/wal"
+)
+
+// structuredMockEngine is an engine with data structures
+type structuredMockEngine struct {
+	*Mockengine
+	*MockstructuredEngine
+}
+
+func TestStorage_HSet(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	tests := map[string]struct {
+		engine func() engine
+		wal    func() walI
+
+		expectedAdded int
+		expectedErr   error
+	}{
+		"hset": {
+			engine: func() engine {
+				eng := structuredMockEngine{NewMockengine(controller), NewMockstructuredEngine(controller)}
+				eng.MockstructuredEngine.EXPECT().
+					Type(gomock.Any(), "key").
+					Return("", false)
+				eng.MockstructuredEngine.EXPECT().
+					HSet(gomock.Any(), "key", []string{"field", "value"}).
+					Return(1, nil)
+				return eng
+			},
+			wal: func() walI {
+				writeAheadLog := NewMockwalI(controller)
+				writeAheadLog.EXPECT().
+					LastCheckpoint().
+					Return(int64(0), nil, nil)
+				writeAheadLog.EXPECT().
+					Recover().
+					Return(nil, nil)
+				writeAheadLog.EXPECT().
+					Write(gomock.Any(), wal.NewOperation(compute.HSetCommand, []string{"key", "field", "value"})).
+					DoAndReturn(func(context.Context, wal.Operation) concurrency.FutureError {
+						promise := concurrency.NewPromise[error]()
+						promise.Set(nil)
+						return promise.GetFuture()
+					})
+				return writeAheadLog
+			},
+			expectedAdded: 1,
+		},
+		"hset wrong type": {
+			engine: func() engine {
+				eng := structuredMockEngine{NewMockengine(controller), NewMockstructuredEngine(controller)}
+				eng.MockstructuredEngine.EXPECT().
+					Type(gomock.Any(), "key").
+					Return(common.ListType, true)
+				return eng
+			},
+			wal: func() walI {
+				writeAheadLog := NewMockwalI(controller)
+				writeAheadLog.EXPECT().
+					LastCheckpoint().
+					Return(int64(0), nil, nil)
+				writeAheadLog.EXPECT().
+					Recover().
+					Return(nil, nil)
+				return writeAheadLog
+			},
+			expectedErr: common.ErrorWrongType,
+		},
+		"hset without data structures": {
+			engine: func() engine {
+				return NewMockengine(controller)
+			},
+			wal: func() walI {
+				writeAheadLog := NewMockwalI(controller)
+				writeAheadLog.EXPECT().
+					LastCheckpoint().
+					Return(int64(0), nil, nil)
+				writeAheadLog.EXPECT().
+					Recover().
+					Return(nil, nil)
+				return writeAheadLog
+			},
+			expectedErr: ErrorNoStructures,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stor, err := NewStorage(test.engine(), zap.NewNop(), WithWAL(test.wal()))
+			require.NoError(t, err)
+
+			added, err := stor.HSet(context.Background(), "key", []string{"field", "value"})
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedAdded, added)
+		})
+	}
+}
+
+func TestStorage_ZAdd(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	members := []common.ScoredMember{{Member: "alice", Score: 0.1}, {Member: "bob", Score: math.Inf(1)}}
+
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, nil)
+	writeAheadLog.EXPECT().
+		Write(gomock.Any(), wal.NewOperation(compute.ZAddCommand, []string{"board", "0.1", "alice", "inf", "bob"})).
+		DoAndReturn(func(context.Context, wal.Operation) concurrency.FutureError {
+			promise := concurrency.NewPromise[error]()
+			promise.Set(nil)
+			return promise.GetFuture()
+		})
+
+	eng := structuredMockEngine{NewMockengine(controller), NewMockstructuredEngine(controller)}
+	eng.MockstructuredEngine.EXPECT().
+		Type(gomock.Any(), "board").
+		Return(common.SortedSetType, true)
+	eng.MockstructuredEngine.EXPECT().
+		ZAdd(gomock.Any(), "board", members).
+		Return(1, nil)
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+
+	added, err := stor.ZAdd(context.Background(), "board", members)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, added)
+}
+
+// orderedMemoryEngine records the order in which writes are applied
+type orderedMemoryEngine struct {
+	*memory.Memory
+
+	mutex   sync.Mutex
+	applied []string
+}
+
+func (e *orderedMemoryEngine) Del(ctx context.Context, key string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.Memory.Del(ctx, key)
+	e.applied = append(e.applied, compute.DelCommand)
+}
+
+func (e *orderedMemoryEngine) LPush(ctx context.Context, key string, values []string) (int, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.applied = append(e.applied, compute.LPushCommand)
+	return e.Memory.LPush(ctx, key, values)
+}
+
+func TestStorage_StructuresOrderedWithPlainWrites(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	var mutex sync.Mutex
+	var logged []string
+	written := func(command string) concurrency.FutureError {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		logged = append(logged, command)
+		promise := concurrency.NewPromise[error]()
+		promise.Set(nil)
+		return promise.GetFuture()
+	}
+
+	pushLogged := make(chan struct{})
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, nil)
+	writeAheadLog.EXPECT().
+		Write(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, operation wal.Operation) concurrency.FutureError {
+			future := written(operation.CommandID)
+			pushLogged <- struct{}{}
+			// DEL of the key would be logged and applied meanwhile without key locks
+			time.Sleep(time.Millisecond)
+			return future
+		}).
+		Times(10)
+	writeAheadLog.EXPECT().
+		Del(gomock.Any(), "list").
+		DoAndReturn(func(context.Context, string) concurrency.FutureError {
+			return written(compute.DelCommand)
+		}).
+		Times(10)
+
+	mem, err := memory.NewMemory(zap.NewNop())
+	require.NoError(t, err)
+	eng := &orderedMemoryEngine{Memory: mem}
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+
+	// writes of the key are applied in order of the WAL, so replaying gives the same list
+	for range 10 {
+		pushed := make(chan struct{})
+		go func() {
+			defer close(pushed)
+			_, err := stor.LPush(context.Background(), "list", []string{"value"})
+			assert.NoError(t, err)
+		}()
+
+		<-pushLogged
+		assert.NoError(t, stor.Del(context.Background(), "list"))
+		<-pushed
+	}
+
+	assert.Equal(t, logged, eng.applied)
+}
+
+func TestStorage_SRemMissingKey(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	eng := structuredMockEngine{NewMockengine(controller), NewMockstructuredEngine(controller)}
+	eng.MockstructuredEngine.EXPECT().
+		Type(gomock.Any(), "key").
+		Return("", false)
+
+	stor, err := NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+
+	removed, err := stor.SRem(context.Background(), "key", []string{"member"})
+	assert.NoError(t, err)
+	assert.Zero(t, removed)
+}
+
+func TestStorage_StructuresInTransaction(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	eng := structuredMockEngine{NewMockengine(controller), NewMockstructuredEngine(controller)}
+	eng.MockstructuredEngine.EXPECT().
+		SMembers(gomock.Any(), "key").
+		Return([]string{"member"}, nil)
+
+	stor, err := NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx := common.ContextWithSessionID(context.Background(), 1)
+	require.NoError(t, stor.Begin(ctx))
+
+	_, err = stor.SAdd(ctx, "key", []string{"member"})
+	assert.Equal(t, ErrorTXCommand, err)
+
+	// reads are allowed within transactions
+	members, err := stor.SMembers(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"member"}, members)
+}
+
+func TestStorage_RecoverStructures(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return([]wal.Log{
+			{LSN: 1, CommandID: compute.HSetCommand, Arguments: []string{"hash", "field", "value"}},
+			{LSN: 2, CommandID: compute.RPushCommand, Arguments: []string{"list", "a", "b"}},
+			{LSN: 3, CommandID: compute.LPopCommand, Arguments: []string{"list", "1"}},
+			{LSN: 4, CommandID: compute.SAddCommand, Arguments: []string{"set", "member"}},
+			{LSN: 5, CommandID: compute.RPopCommand, Arguments: []string{"list", "incorrect"}},
+			{LSN: 6, CommandID: compute.ZAddCommand, Arguments: []string{"board", "1.5", "alice", "-inf", "bob"}},
+			{LSN: 7, CommandID: compute.ZIncrByCommand, Arguments: []string{"board", "2", "alice"}},
+			{LSN: 8, CommandID: compute.ZAddCommand, Arguments: []string{"board", "1.5"}},
+		}, nil)
+
+	eng := structuredMockEngine{NewMockengine(controller), NewMockstructuredEngine(controller)}
+	gomock.InOrder(
+		eng.MockstructuredEngine.EXPECT().HSet(gomock.Any(), "hash", []string{"field", "value"}).Return(1, nil),
+		eng.MockstructuredEngine.EXPECT().RPush(gomock.Any(), "list", []string{"a", "b"}).Return(2, nil),
+		eng.MockstructuredEngine.EXPECT().LPop(gomock.Any(), "list", 1).Return([]string{"a"}, nil),
+		eng.MockstructuredEngine.EXPECT().SAdd(gomock.Any(), "set", []string{"member"}).Return(1, nil),
+		eng.MockstructuredEngine.EXPECT().ZAdd(gomock.Any(), "board", []common.ScoredMember{
+			{Member: "alice", Score: 1.5},
+			{Member: "bob", Score: math.Inf(-1)},
+		}).Return(2, nil),
+		eng.MockstructuredEngine.EXPECT().ZIncrBy(gomock.Any(), "board", float64(2), "alice").Return(3.5, nil),
+	)
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), stor.generator.Generate())
+}
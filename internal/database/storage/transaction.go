@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/storage/wal"
+)
+
+var (
+	// ErrorNoSession ...
+	ErrorNoSession = errors.New("session is not found")
+	// ErrorTXStarted ...
+	ErrorTXStarted = errors.New("transaction is already started")
+	// ErrorTXNotStarted ...
+	ErrorTXNotStarted = errors.New("transaction is not started")
+	// ErrorTXCommand ...
+	ErrorTXCommand = errors.New("command is not allowed in transaction")
+)
+
+type pendingWrite struct {
+	value   string
+	deleted bool
+}
+
+// transaction buffers writes of a session until COMMIT
+type transaction struct {
+	operations []wal.Operation
+	writes     map[string]pendingWrite
+	snapshot   int64
+	stop       func() bool
+}
+
+func newTransaction(snapshot int64) *transaction {
+	return &transaction{
+		writes:   make(map[string]pendingWrite),
+		snapshot: snapshot,
+	}
+}
+
+func (tx *transaction) set(key, value string, operation wal.Operation) {
+	tx.operations = append(tx.operations, operation)
+	tx.writes[key] = pendingWrite{value: value}
+}
+
+func (tx *transaction) del(key string, operation wal.Operation) {
+	tx.operations = append(tx.operations, operation)
+	tx.writes[key] = pendingWrite{deleted: true}
+}
+
+// written returns keys which are set by the transaction
+func (tx *transaction) written() []string {
+	keys := make([]string, 0, len(tx.writes))
+	for key, write := range tx.writes {
+		if !write.deleted {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// keys returns all keys written by the transaction including deleted ones
+func (tx *transaction) keys() []string {
+	keys := make([]string, 0, len(tx.writes))
+	for key := range tx.writes {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// merge appends writes of the batch to the transaction
+func (tx *transaction) merge(batch *transaction) {
+	tx.operations = append(tx.operations, batch.operations...)
+	for key, write := range batch.writes {
+		tx.writes[key] = write
+	}
+}
+
+func (tx *transaction) get(key string) (pendingWrite, bool) {
+	write, found := tx.writes[key]
+	return write, found
+}
+
+// overlay applies pending writes with keys from from up to to (exclusive,
+// empty to means no bound) to entries of the range
+func (tx *transaction) overlay(entries []KeyValue, from, to string) []KeyValue {
+	pending := make(map[string]int, len(entries))
+	for idx := range entries {
+		pending[entries[idx].Key] = idx
+	}
+
+	var merged []KeyValue
+	for key, write := range tx.writes {
+		if key < from || (to != "" && key >= to) {
+			continue
+		}
+
+		if idx, found := pending[key]; found {
+			entries[idx].Value = write.value
+			if write.deleted {
+				delete(pending, key)
+			}
+		} else if !write.deleted {
+			merged = append(merged, KeyValue{Key: key, Value: write.value})
+		}
+	}
+
+	for idx := range entries {
+		if _, found := pending[entries[idx].Key]; found {
+			merged = append(merged, entries[idx])
+		}
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Key < merged[j].Key
+	})
+
+	return merged
+}
+
+// Begin opens a transaction for the session from the context, reads of the
+// transaction use the snapshot taken at the beginning, the transaction
+// is discarded if the session is closed before COMMIT
+func (s *Storage) Begin(ctx context.Context) error {
+	sessionID, found := common.GetSessionIDFromContext(ctx)
+	if !found {
+		return ErrorNoSession
+	}
+
+	var err error
+	concurrency.WithLock(&s.transactionsMutex, func() {
+		if _, started := s.transactions[sessionID]; started {
+			err = ErrorTXStarted
+			return
+		}
+
+		tx := newTransaction(s.snapshots.acquire())
+		tx.stop = context.AfterFunc(ctx, func() {
+			s.takeTransaction(ctx)
+		})
+		s.transactions[sessionID] = tx
+	})
+
+	return err
+}
+
+// Commit writes all operations of the transaction to the WAL as one record
+// and applies them to the engine atomically
+func (s *Storage) Commit(ctx context.Context) error {
+	tx := s.takeTransaction(ctx)
+	if tx == nil {
+		return ErrorTXNotStarted
+	}
+
+	tx.stop()
+	return s.commit(ctx, tx)
+}
+
+// commit writes operations of the transaction to the WAL as a single record and
+// applies them under the exclusive lock, so reads see either all of them or none
+func (s *Storage) commit(ctx context.Context, tx *transaction) error {
+	if len(tx.operations) == 0 {
+		return nil
+	}
+
+	if s.replica != nil && !s.replica.IsMaster() {
+		return ErrorMutableTX
+	} else if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	unlock := s.keyLocks.lock(tx.keys()...)
+	defer unlock()
+
+	if s.outOfMemory(tx.written()...) {
+		return ErrorOutOfMemory
+	}
+
+	txID := s.snapshots.beginWrite()
+	defer s.endWrite(txID)
+	ctx = common.ContextWithTxID(ctx, txID)
+
+	var errLog error
+	if s.wal != nil {
+		futureResponse := s.wal.Commit(ctx, tx.operations)
+		if errLog = futureResponse.Get(); !persisted(errLog) {
+			return errLog
+		}
+	}
+
+	concurrency.WithLock(&s.mutex, func() {
+		for _, operation := range tx.operations {
+			s.applyOperation(ctx, txID, operation)
+		}
+	})
+
+	s.committed(ctx, txID)
+	return errLog
+}
+
+// Rollback ...
+func (s *Storage) Rollback(ctx context.Context) error {
+	tx := s.takeTransaction(ctx)
+	if tx == nil {
+		return ErrorTXNotStarted
+	}
+
+	tx.stop()
+	return nil
+}
+
+func (s *Storage) transaction(ctx context.Context) *transaction {
+	sessionID, found := common.GetSessionIDFromContext(ctx)
+	if !found {
+		return nil
+	}
+
+	var tx *transaction
+	concurrency.WithLock(&s.transactionsMutex, func() {
+		tx = s.transactions[sessionID]
+	})
+
+	return tx
+}
+
+func (s *Storage) takeTransaction(ctx context.Context) *transaction {
+	sessionID, found := common.GetSessionIDFromContext(ctx)
+	if !found {
+		return nil
+	}
+
+	var tx *transaction
+	concurrency.WithLock(&s.transactionsMutex, func() {
+		tx = s.transactions[sessionID]
+		delete(s.transactions, sessionID)
+	})
+
+	if tx != nil {
+		s.snapshots.release(tx.snapshot)
+	}
+
+	return tx
+}
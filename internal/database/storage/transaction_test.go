@@ -0,0 +1,340 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/concurrency"
+	"database-simon/internal/database/compute"
+	"database-simon/internal/database/storage/engine/memory"
+	"database-simon/internal/database/storage/wal"
+)
+
+func TestStorage_BeginWithoutSession(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	stor, err := NewStorage(NewMockengine(controller), zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, ErrorNoSession, stor.Begin(context.Background()))
+	assert.Equal(t, ErrorTXNotStarted, stor.Commit(context.Background()))
+	assert.Equal(t, ErrorTXNotStarted, stor.Rollback(context.Background()))
+}
+
+func TestStorage_BeginTwice(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	stor, err := NewStorage(NewMockengine(controller), zap.NewNop())
+	require.NoError(t, err)
+
+	ctx := common.ContextWithSessionID(context.Background(), 1)
+	require.NoError(t, stor.Begin(ctx))
+	assert.Equal(t, ErrorTXStarted, stor.Begin(ctx))
+
+	otherCtx := common.ContextWithSessionID(context.Background(), 2)
+	assert.NoError(t, stor.Begin(otherCtx))
+}
+
+func TestStorage_TransactionReadsOwnWrites(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	eng := NewMockengine(controller)
+	eng.EXPECT().
+		Get(gomock.Any(), "key_3").
+		Return("value_3", true)
+
+	stor, err := NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx := common.ContextWithSessionID(context.Background(), 1)
+	require.NoError(t, stor.Begin(ctx))
+
+	require.NoError(t, stor.Set(ctx, "key_1", "value_1"))
+	require.NoError(t, stor.Set(ctx, "key_2", "value_2"))
+	require.NoError(t, stor.Del(ctx, "key_2"))
+
+	value, err := stor.Get(ctx, "key_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value_1", value)
+
+	_, err = stor.Get(ctx, "key_2")
+	assert.Equal(t, ErrorNotFound, err)
+
+	value, err = stor.Get(ctx, "key_3")
+	assert.NoError(t, err)
+	assert.Equal(t, "value_3", value)
+
+	assert.Equal(t, ErrorTXCommand, stor.Expire(ctx, "key_1", time.Now()))
+	assert.Equal(t, ErrorTXCommand, stor.Persist(ctx, "key_1"))
+}
+
+func TestStorage_Commit(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	deadline := time.UnixMilli(time.Now().Add(time.Minute).UnixMilli())
+
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, nil)
+	writeAheadLog.EXPECT().
+		Commit(gomock.Any(), []wal.Operation{
+			wal.NewOperation(compute.SetCommand, []string{"key_1", "value_1"}),
+			wal.NewOperation(compute.SetCommand, []string{"key_2", "value_2", wal.EncodeDeadline(deadline)}),
+			wal.NewOperation(compute.DelCommand, []string{"key_3"}),
+		}).
+		DoAndReturn(func(context.Context, []wal.Operation) concurrency.FutureError {
+			promise := concurrency.NewPromise[error]()
+			promise.Set(nil)
+			return promise.GetFuture()
+		})
+
+	eng := NewMockengine(controller)
+	gomock.InOrder(
+		eng.EXPECT().Set(gomock.Any(), "key_1", "value_1"),
+		eng.EXPECT().SetWithExpiration(gomock.Any(), "key_2", "value_2", deadline),
+		eng.EXPECT().Del(gomock.Any(), "key_3"),
+	)
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+
+	ctx := common.ContextWithSessionID(context.Background(), 1)
+	require.NoError(t, stor.Begin(ctx))
+	require.NoError(t, stor.Set(ctx, "key_1", "value_1"))
+	require.NoError(t, stor.SetWithExpiration(ctx, "key_2", "value_2", deadline))
+	require.NoError(t, stor.Del(ctx, "key_3"))
+	require.NoError(t, stor.Commit(ctx))
+
+	assert.Equal(t, ErrorTXNotStarted, stor.Commit(ctx))
+}
+
+func TestStorage_CommitOutOfMemory(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	// deleted keys don't add data, so they are not checked
+	eng := boundedMockEngine{NewMockengine(controller), NewMockboundedEngine(controller)}
+	eng.MockboundedEngine.EXPECT().
+		OutOfMemory("key_1").
+		Return(true)
+
+	stor, err := NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx := common.ContextWithSessionID(context.Background(), 1)
+	require.NoError(t, stor.Begin(ctx))
+	require.NoError(t, stor.Set(ctx, "key_1", "value_1"))
+	require.NoError(t, stor.Del(ctx, "key_2"))
+	assert.Equal(t, ErrorOutOfMemory, stor.Commit(ctx))
+}
+
+func TestStorage_Rollback(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	eng := NewMockengine(controller)
+	eng.EXPECT().
+		Get(gomock.Any(), "key_1").
+		Return("", false)
+
+	stor, err := NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx := common.ContextWithSessionID(context.Background(), 1)
+	require.NoError(t, stor.Begin(ctx))
+	require.NoError(t, stor.Set(ctx, "key_1", "value_1"))
+	require.NoError(t, stor.Rollback(ctx))
+
+	_, err = stor.Get(ctx, "key_1")
+	assert.Equal(t, ErrorNotFound, err)
+	assert.Equal(t, ErrorTXNotStarted, stor.Commit(ctx))
+}
+
+func TestStorage_TransactionOnSlave(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	slave := NewMockreplica(controller)
+	slave.EXPECT().
+		IsMaster().
+		Return(false).
+		AnyTimes()
+
+	stor, err := NewStorage(NewMockengine(controller), zap.NewNop(), WithReplication(slave))
+	require.NoError(t, err)
+
+	ctx := common.ContextWithSessionID(context.Background(), 1)
+	require.NoError(t, stor.Begin(ctx))
+	assert.Equal(t, ErrorMutableTX, stor.Set(ctx, "key_1", "value_1"))
+	assert.NoError(t, stor.Commit(ctx))
+}
+
+func TestStorage_TransactionIsDiscardedWithSession(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	stor, err := NewStorage(NewMockengine(controller), zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(common.ContextWithSessionID(context.Background(), 1))
+	require.NoError(t, stor.Begin(ctx))
+	require.NoError(t, stor.Set(ctx, "key_1", "value_1"))
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		return stor.transaction(ctx) == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStorage_RecoverTransaction(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	writeAheadLog := NewMockwalI(controller)
+	writeAheadLog.EXPECT().
+		LastCheckpoint().
+		Return(int64(0), nil, nil)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return([]wal.Log{
+			{LSN: 1, CommandID: compute.SetCommand, Arguments: []string{"key_1", "value_1"}},
+			{LSN: 2, CommandID: compute.CommitCommand, Arguments: wal.EncodeOperations([]wal.Operation{
+				wal.NewOperation(compute.SetCommand, []string{"key_2", "value_2"}),
+				wal.NewOperation(compute.DelCommand, []string{"key_1"}),
+			})},
+			{LSN: 3, CommandID: compute.CommitCommand, Arguments: []string{compute.SetCommand, "2", "key_3"}},
+		}, nil)
+
+	eng := NewMockengine(controller)
+	gomock.InOrder(
+		eng.EXPECT().Set(gomock.Any(), "key_1", "value_1"),
+		eng.EXPECT().Set(gomock.Any(), "key_2", "value_2"),
+		eng.EXPECT().Del(gomock.Any(), "key_1"),
+	)
+
+	stor, err := NewStorage(eng, zap.NewNop(), WithWAL(writeAheadLog))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), stor.generator.Generate())
+}
+
+func TestStorage_TransactionReadsSnapshot(t *testing.T) {
+	t.Parallel()
+
+	eng, err := memory.NewMemory(zap.NewNop(), memory.WithMVCC())
+	require.NoError(t, err)
+
+	stor, err := NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, stor.Set(ctx, "key_1", "value_1"))
+	require.NoError(t, stor.Set(ctx, "key_2", "value_2"))
+
+	txCtx := common.ContextWithSessionID(ctx, 1)
+	require.NoError(t, stor.Begin(txCtx))
+
+	require.NoError(t, stor.Set(ctx, "key_1", "new_value"))
+	require.NoError(t, stor.Del(ctx, "key_2"))
+	require.NoError(t, stor.Set(ctx, "key_3", "value_3"))
+
+	// writes committed after the beginning are invisible for the transaction
+	value, err := stor.Get(txCtx, "key_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value_1", value)
+
+	value, err = stor.Get(txCtx, "key_2")
+	assert.NoError(t, err)
+	assert.Equal(t, "value_2", value)
+
+	_, err = stor.Get(txCtx, "key_3")
+	assert.Equal(t, ErrorNotFound, err)
+
+	// garbage collection keeps versions of the active snapshot
+	eng.CollectGarbage(ctx, stor.snapshots.oldest())
+	value, err = stor.Get(txCtx, "key_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value_1", value)
+
+	require.NoError(t, stor.Rollback(txCtx))
+
+	value, err = stor.Get(ctx, "key_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "new_value", value)
+
+	_, err = stor.Get(ctx, "key_2")
+	assert.Equal(t, ErrorNotFound, err)
+}
+
+func TestStorage_TransactionRange(t *testing.T) {
+	t.Parallel()
+
+	engine, err := memory.NewMemory(zap.NewNop(), memory.WithMVCC(), memory.WithOrdered())
+	require.NoError(t, err)
+
+	stor, err := NewStorage(engine, zap.NewNop())
+	require.NoError(t, err)
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, stor.Set(context.Background(), key, "value"))
+	}
+
+	ctx := common.ContextWithSessionID(context.Background(), 1)
+	require.NoError(t, stor.Begin(ctx))
+	require.NoError(t, stor.Del(ctx, "a"))
+	require.NoError(t, stor.Set(ctx, "b", "new"))
+	require.NoError(t, stor.Set(ctx, "bb", "new"))
+	require.NoError(t, stor.Set(ctx, "e", "new"))
+
+	entries, err := stor.Range(ctx, "", "", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []KeyValue{{Key: "b", Value: "new"}, {Key: "bb", Value: "new"}, {Key: "c", Value: "value"}}, entries)
+
+	entries, err = stor.Range(ctx, "c", "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []KeyValue{{Key: "c", Value: "value"}, {Key: "d", Value: "value"}, {Key: "e", Value: "new"}}, entries)
+
+	// other sessions don't see pending writes
+	entries, err = stor.Range(context.Background(), "", "b\x00", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []KeyValue{{Key: "a", Value: "value"}, {Key: "b", Value: "value"}}, entries)
+}
+
+func TestStorage_RangeWithoutOrder(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+
+	eng := NewMockengine(controller)
+	eng.EXPECT().
+		Ordered().
+		Return(false)
+
+	stor, err := NewStorage(eng, zap.NewNop())
+	require.NoError(t, err)
+
+	_, err = stor.Range(context.Background(), "", "", 0)
+	assert.Equal(t, ErrorUnordered, err)
+}
@@ -2,9 +2,55 @@ package wal
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
+	"errors"
+	"strconv"
+	"time"
+
+	"database-simon/internal/database/compute"
 )
 
+// RestoreCommand marks a record of the replication stream which replaces
+// the state by the snapshot from the argument, it's never written to segments
+const RestoreCommand = "RESTORE"
+
+// WatermarkCommand marks a record of the replication stream with LSN up to which
+// writes of the master are received, records of the master come in the order of
+// its WAL, so they are visible only up to it, it's never written to segments
+const WatermarkCommand = "WATERMARK"
+
+// commandCodes keeps one byte codes of logged commands, other commands
+// are written with zero code followed by the name of the command
+var commandCodes = map[string]byte{
+	compute.SetCommand:       1,
+	compute.DelCommand:       2,
+	compute.PExpireAtCommand: 3,
+	compute.PersistCommand:   4,
+	compute.CommitCommand:    5,
+	compute.HSetCommand:      6,
+	compute.HDelCommand:      7,
+	compute.LPushCommand:     8,
+	compute.RPushCommand:     9,
+	compute.LPopCommand:      10,
+	compute.RPopCommand:      11,
+	compute.SAddCommand:      12,
+	compute.SRemCommand:      13,
+	compute.ZAddCommand:      14,
+	compute.ZRemCommand:      15,
+	compute.ZIncrByCommand:   16,
+}
+
+var commandIDs = func() map[byte]string {
+	ids := make(map[byte]string, len(commandCodes))
+	for commandID, code := range commandCodes {
+		ids[code] = commandID
+	}
+	return ids
+}()
+
+var errIncorrectRecord = errors.New("incorrect record")
+
 // Log ...
 type Log struct {
 	LSN       int64
@@ -12,7 +58,7 @@ type Log struct {
 	Arguments []string
 }
 
-// Encode ...
+// Encode writes the log in gob format, it's used by segments of the first version
 func (l *Log) Encode(buffer *bytes.Buffer) error {
 	encoder := gob.NewEncoder(buffer)
 	return encoder.Encode(*l)
@@ -23,3 +69,96 @@ func (l *Log) Decode(buffer *bytes.Buffer) error {
 	decoder := gob.NewDecoder(buffer)
 	return decoder.Decode(l)
 }
+
+// AppendBinary appends the log in the compact format: varint LSN,
+// command code and arguments prefixed by their lengths
+func (l *Log) AppendBinary(data []byte) []byte {
+	data = binary.AppendVarint(data, l.LSN)
+
+	code := commandCodes[l.CommandID]
+	data = append(data, code)
+	if code == 0 {
+		data = appendString(data, l.CommandID)
+	}
+
+	data = binary.AppendUvarint(data, uint64(len(l.Arguments)))
+	for _, argument := range l.Arguments {
+		data = appendString(data, argument)
+	}
+
+	return data
+}
+
+// DecodeBinary ...
+func (l *Log) DecodeBinary(data []byte) error {
+	lsn, size := binary.Varint(data)
+	if size <= 0 || size == len(data) {
+		return errIncorrectRecord
+	}
+
+	data = data[size:]
+	code := data[0]
+	data = data[1:]
+
+	commandID, found := commandIDs[code]
+	if code == 0 {
+		if commandID, data, found = readString(data); !found {
+			return errIncorrectRecord
+		}
+	} else if !found {
+		return errIncorrectRecord
+	}
+
+	argumentsNumber, size := binary.Uvarint(data)
+	if size <= 0 || argumentsNumber > uint64(len(data)) {
+		return errIncorrectRecord
+	}
+
+	data = data[size:]
+	arguments := make([]string, argumentsNumber)
+	for idx := range arguments {
+		if arguments[idx], data, found = readString(data); !found {
+			return errIncorrectRecord
+		}
+	}
+
+	if len(data) != 0 {
+		return errIncorrectRecord
+	}
+
+	l.LSN = lsn
+	l.CommandID = commandID
+	l.Arguments = arguments
+	return nil
+}
+
+func appendString(data []byte, value string) []byte {
+	data = binary.AppendUvarint(data, uint64(len(value)))
+	return append(data, value...)
+}
+
+func readString(data []byte) (string, []byte, bool) {
+	length, size := binary.Uvarint(data)
+	if size <= 0 || length > uint64(len(data)-size) {
+		return "", nil, false
+	}
+
+	data = data[size:]
+	return string(data[:length]), data[length:], true
+}
+
+// EncodeDeadline keeps deadline as absolute unix time in milliseconds,
+// so replaying of the log doesn't depend on the time of replaying
+func EncodeDeadline(deadline time.Time) string {
+	return strconv.FormatInt(deadline.UnixMilli(), 10)
+}
+
+// DecodeDeadline ...
+func DecodeDeadline(data string) (time.Time, error) {
+	milliseconds, err := strconv.ParseInt(data, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.UnixMilli(milliseconds), nil
+}
@@ -1,13 +1,15 @@
 package wal
 
 import (
+	"bytes"
 	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-
 	"go.uber.org/mock/gomock"
+
+	"database-simon/internal/database/compute"
 )
 
 func TestNewLogsReader(t *testing.T) {
@@ -78,3 +80,334 @@ func TestRead(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Nil(t, logs)
 }
+
+func TestTruncate(t *testing.T) {
+	t.Parallel()
+
+	segment := func(lsns ...int64) []byte {
+		var buffer bytes.Buffer
+		for _, lsn := range lsns {
+			log := Log{LSN: lsn, CommandID: "SET", Arguments: []string{"key", "value"}}
+			require.NoError(t, log.Encode(&buffer))
+		}
+		return buffer.Bytes()
+	}
+
+	tests := map[string]struct {
+		lsn             int64
+		expectedRemoved []string
+	}{
+		"nothing is covered": {
+			lsn: 1,
+		},
+		"covered prefix of segments": {
+			lsn:             4,
+			expectedRemoved: []string{"wal_1.log"},
+		},
+		"segments after not covered one are kept": {
+			lsn:             5,
+			expectedRemoved: []string{"wal_1.log"},
+		},
+		"last segment is kept": {
+			lsn:             100,
+			expectedRemoved: []string{"wal_1.log", "wal_2.log", "wal_3.log"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			controller := gomock.NewController(t)
+			directory := NewMocksegmentsDirectory(controller)
+			directory.EXPECT().
+				ForEach(gomock.Any()).
+				DoAndReturn(func(action func(string, []byte) error) error {
+					require.NoError(t, action("wal_1.log", segment(1, 2)))
+					require.NoError(t, action("wal_2.log", segment(3, 6)))
+					require.NoError(t, action("wal_3.log", segment(5, 7)))
+					return action("wal_4.log", segment(8))
+				})
+
+			for _, segmentName := range test.expectedRemoved {
+				directory.EXPECT().
+					Remove(segmentName).
+					Return(nil)
+			}
+
+			reader, err := NewLogsReader(directory)
+			require.NoError(t, err)
+			assert.NoError(t, reader.Truncate(test.lsn))
+		})
+	}
+}
+
+func TestReadTornSegment(t *testing.T) {
+	t.Parallel()
+
+	first := encodeTestSegment(t, Log{LSN: 1, CommandID: compute.SetCommand, Arguments: []string{"key", "value"}})
+	second := encodeTestSegment(t,
+		Log{LSN: 2, CommandID: compute.SetCommand, Arguments: []string{"key", "value"}},
+		Log{LSN: 3, CommandID: compute.DelCommand, Arguments: []string{"key"}},
+	)
+	tornSecond := second[:len(second)-1]
+	firstRecordEnd := len(encodeTestSegment(t, Log{LSN: 2, CommandID: compute.SetCommand, Arguments: []string{"key", "value"}}))
+
+	t.Run("torn tail of the last segment is truncated", func(t *testing.T) {
+		t.Parallel()
+
+		controller := gomock.NewController(t)
+		directory := NewMocksegmentsDirectory(controller)
+		directory.EXPECT().
+			ForEach(gomock.Any()).
+			DoAndReturn(func(action func(string, []byte) error) error {
+				require.NoError(t, action("wal_1.log", first))
+				return action("wal_2.log", tornSecond)
+			})
+		directory.EXPECT().
+			Truncate("wal_2.log", int64(firstRecordEnd)).
+			Return(nil)
+
+		reader, err := NewLogsReader(directory)
+		require.NoError(t, err)
+
+		logs, err := reader.Read()
+		require.NoError(t, err)
+		require.Len(t, logs, 2)
+		assert.Equal(t, int64(1), logs[0].LSN)
+		assert.Equal(t, int64(2), logs[1].LSN)
+	})
+
+	t.Run("torn tail of not last segment is corruption", func(t *testing.T) {
+		t.Parallel()
+
+		controller := gomock.NewController(t)
+		directory := NewMocksegmentsDirectory(controller)
+		directory.EXPECT().
+			ForEach(gomock.Any()).
+			DoAndReturn(func(action func(string, []byte) error) error {
+				require.NoError(t, action("wal_1.log", tornSecond))
+				return action("wal_2.log", first)
+			})
+
+		reader, err := NewLogsReader(directory)
+		require.NoError(t, err)
+
+		logs, err := reader.Read()
+		assert.Nil(t, logs)
+
+		var corruptionErr *CorruptionError
+		require.True(t, errors.As(err, &corruptionErr))
+		assert.Equal(t, "wal_1.log", corruptionErr.Segment)
+		assert.Equal(t, firstRecordEnd, corruptionErr.Offset)
+	})
+
+	t.Run("damaged record in the middle is corruption", func(t *testing.T) {
+		t.Parallel()
+
+		damaged := bytes.Clone(second)
+		damaged[firstRecordEnd-1] ^= 0xFF
+
+		controller := gomock.NewController(t)
+		directory := NewMocksegmentsDirectory(controller)
+		directory.EXPECT().
+			ForEach(gomock.Any()).
+			DoAndReturn(func(action func(string, []byte) error) error {
+				return action("wal_2.log", damaged)
+			})
+
+		reader, err := NewLogsReader(directory)
+		require.NoError(t, err)
+
+		_, err = reader.Read()
+		assert.ErrorContains(t, err, "segment wal_2.log is corrupted at offset 5")
+	})
+}
+
+func newTestSegmentsDirectory(controller *gomock.Controller, names []string, segments map[string][]byte) *MocksegmentsDirectory {
+	directory := NewMocksegmentsDirectory(controller)
+	directory.EXPECT().
+		Names().
+		Return(names, nil).
+		AnyTimes()
+	directory.EXPECT().
+		Read(gomock.Any()).
+		DoAndReturn(func(name string) ([]byte, error) {
+			return segments[name], nil
+		}).
+		AnyTimes()
+
+	return directory
+}
+
+func TestReadAfter(t *testing.T) {
+	t.Parallel()
+
+	set := func(lsn int64) Log {
+		return Log{LSN: lsn, CommandID: compute.SetCommand, Arguments: []string{"key", "value"}}
+	}
+
+	names := []string{"wal_1.log", "wal_2.log", "wal_3.log"}
+	segments := map[string][]byte{
+		"wal_1.log": encodeTestSegment(t, set(1), set(2)),
+		// records are written in order of pushing to WAL, so LSN can be unordered
+		"wal_2.log": encodeTestSegment(t, set(4), set(3)),
+		// the active segment can be written at the moment
+		"wal_3.log": append(encodeTestSegment(t, set(5)), 0, 0, 0),
+	}
+
+	recordSize := len(encodeTestSegment(t, set(1))) - segmentHeaderSize
+
+	tests := map[string]struct {
+		lsn     int64
+		maxSize int
+
+		expectedLSN     []int64
+		expectedHasMore bool
+	}{
+		"read from the beginning": {
+			maxSize:     1 << 10,
+			expectedLSN: []int64{1, 2, 4, 3, 5},
+		},
+		"read after record in order of segments": {
+			lsn:         4,
+			maxSize:     1 << 10,
+			expectedLSN: []int64{3, 5},
+		},
+		"read after the last record": {
+			lsn:     5,
+			maxSize: 1 << 10,
+		},
+		"read limited chunk": {
+			lsn:             1,
+			maxSize:         2 * recordSize,
+			expectedLSN:     []int64{2, 4},
+			expectedHasMore: true,
+		},
+		"read at least one record": {
+			maxSize:         1,
+			expectedLSN:     []int64{1},
+			expectedHasMore: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			controller := gomock.NewController(t)
+			reader, err := NewLogsReader(newTestSegmentsDirectory(controller, names, segments))
+			require.NoError(t, err)
+
+			// the second reading uses ranges of closed segments
+			for range 2 {
+				data, hasMore, err := reader.ReadAfter(test.lsn, test.maxSize)
+				require.NoError(t, err)
+				assert.Equal(t, test.expectedHasMore, hasMore)
+
+				logs, err := DecodeRecords(data)
+				require.NoError(t, err)
+
+				var lsn []int64
+				for _, log := range logs {
+					lsn = append(lsn, log.LSN)
+				}
+				assert.Equal(t, test.expectedLSN, lsn)
+			}
+		})
+	}
+}
+
+func TestCountAfter(t *testing.T) {
+	t.Parallel()
+
+	set := func(lsn int64) Log {
+		return Log{LSN: lsn, CommandID: compute.SetCommand, Arguments: []string{"key", "value"}}
+	}
+
+	controller := gomock.NewController(t)
+	names := []string{"wal_1.log", "wal_2.log", "wal_3.log"}
+	segments := map[string][]byte{
+		"wal_1.log": encodeTestSegment(t, set(1), set(2)),
+		"wal_2.log": encodeTestSegment(t, set(4), set(3)),
+		"wal_3.log": encodeTestSegment(t, set(5)),
+	}
+
+	reader, err := NewLogsReader(newTestSegmentsDirectory(controller, names, segments))
+	require.NoError(t, err)
+
+	// the second pass counts closed segments by their ranges
+	for range 2 {
+		for lsn, expected := range map[int64]int{0: 5, 1: 4, 4: 2, 5: 0} {
+			count, err := reader.CountAfter(lsn)
+			require.NoError(t, err)
+			assert.Equal(t, expected, count, "lsn %d", lsn)
+		}
+	}
+}
+
+func TestReadAfterCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	set := func(lsn int64) Log {
+		return Log{LSN: lsn, CommandID: compute.SetCommand, Arguments: []string{"key", "value"}}
+	}
+
+	controller := gomock.NewController(t)
+	names := []string{"wal_2.log", "wal_3.log"}
+	segments := map[string][]byte{
+		"wal_2.log": encodeTestSegment(t, set(3), set(5)),
+		"wal_3.log": encodeTestSegment(t, set(6), set(4)),
+	}
+
+	reader, err := NewLogsReader(newTestSegmentsDirectory(controller, names, segments))
+	require.NoError(t, err)
+
+	// the record is removed with its segment, so only greater LSN are returned
+	data, _, err := reader.ReadAfter(2, 1<<10)
+	require.NoError(t, err)
+
+	logs, err := DecodeRecords(data)
+	require.NoError(t, err)
+	assert.Equal(t, []Log{set(3), set(5), set(6), set(4)}, logs)
+
+	data, _, err = reader.ReadAfter(4, 1<<10)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	contains, err := reader.Contains(5)
+	require.NoError(t, err)
+	assert.True(t, contains)
+
+	contains, err = reader.Contains(2)
+	require.NoError(t, err)
+	assert.False(t, contains)
+}
+
+func TestLastLSN(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	names := []string{"wal_1.log", "wal_2.log"}
+	segments := map[string][]byte{
+		"wal_1.log": encodeTestSegment(t,
+			Log{LSN: 2, CommandID: compute.DelCommand, Arguments: []string{"key"}},
+			Log{LSN: 1, CommandID: compute.DelCommand, Arguments: []string{"key"}},
+		),
+		"wal_2.log": SegmentHeader(),
+	}
+
+	reader, err := NewLogsReader(newTestSegmentsDirectory(controller, names, segments))
+	require.NoError(t, err)
+
+	lsn, err := reader.LastLSN()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), lsn)
+
+	reader, err = NewLogsReader(newTestSegmentsDirectory(controller, nil, nil))
+	require.NoError(t, err)
+
+	lsn, err = reader.LastLSN()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), lsn)
+}
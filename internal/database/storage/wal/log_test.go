@@ -0,0 +1,57 @@
+package wal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"database-simon/internal/database/compute"
+)
+
+func TestLogBinary(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		log Log
+	}{
+		"log with arguments": {
+			log: Log{LSN: 100, CommandID: compute.SetCommand, Arguments: []string{"key", "value"}},
+		},
+		"log with empty arguments": {
+			log: Log{LSN: 1 << 40, CommandID: compute.CommitCommand, Arguments: []string{"", ""}},
+		},
+		"log of command without code": {
+			log: Log{LSN: 1, CommandID: compute.GetCommand, Arguments: []string{"key"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data := test.log.AppendBinary(nil)
+
+			var log Log
+			require.NoError(t, log.DecodeBinary(data))
+			assert.Equal(t, test.log, log)
+
+			// any incomplete record is incorrect
+			for size := 0; size < len(data); size++ {
+				assert.Error(t, log.DecodeBinary(data[:size]))
+			}
+		})
+	}
+}
+
+func TestLogBinaryIncorrect(t *testing.T) {
+	t.Parallel()
+
+	data := (&Log{LSN: 1, CommandID: compute.DelCommand, Arguments: []string{"key"}}).AppendBinary(nil)
+
+	var log Log
+	assert.Error(t, log.DecodeBinary(append(data, 0)))
+
+	data[1] = 100
+	assert.Error(t, log.DecodeBinary(data))
+}
@@ -2,17 +2,35 @@ package wal
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"sort"
+	"sync"
 )
 
 type segmentsDirectory interface {
-	ForEach(func([]byte) error) error
+	ForEach(func(string, []byte) error) error
+	Names() ([]string, error)
+	Read(string) ([]byte, error)
+	Remove(string) error
+	Truncate(string, int64) error
+}
+
+// lsnRange is a range of LSN of records in the segment
+type lsnRange struct {
+	first int64
+	last  int64
+	count int
 }
 
 // LogsReader ...
 type LogsReader struct {
 	segmentsDirectory segmentsDirectory
+
+	// ranges of closed segments, they are immutable, so
+	// segments out of the range are skipped without reading
+	mutex  sync.Mutex
+	ranges map[string]lsnRange
 }
 
 // NewLogsReader ...
@@ -23,22 +41,44 @@ func NewLogsReader(segmentsDirectory segmentsDirectory) (*LogsReader, error) {
 
 	return &LogsReader{
 		segmentsDirectory: segmentsDirectory,
+		ranges:            make(map[string]lsnRange),
 	}, nil
 }
 
-// Read ...
+// Read returns records of all segments, the torn tail of the last segment
+// is truncated, other damaged records fail reading with CorruptionError
 func (r *LogsReader) Read() ([]Log, error) {
 	var logs []Log
-	err := r.segmentsDirectory.ForEach(func(data []byte) error {
-		var err error
-		logs, err = r.readSegment(logs, data)
-		return err
+	var tornSegment *CorruptionError
+	err := r.segmentsDirectory.ForEach(func(name string, data []byte) error {
+		if tornSegment != nil {
+			// only the last segment can be written at the moment of failure
+			return tornSegment
+		}
+
+		segmentLogs, size, err := decodeSegment(data)
+		if err != nil {
+			return r.corruption(name, err)
+		}
+
+		if size < len(data) {
+			tornSegment = &CorruptionError{Segment: name, Offset: size, Reason: "incomplete record"}
+		}
+
+		logs = append(logs, segmentLogs...)
+		return nil
 	})
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to read segments: %w", err)
 	}
 
+	if tornSegment != nil {
+		if err = r.segmentsDirectory.Truncate(tornSegment.Segment, int64(tornSegment.Offset)); err != nil {
+			return nil, fmt.Errorf("failed to truncate torn segment: %w", err)
+		}
+	}
+
 	// TODO: need to check invariant for sorting
 	sort.Slice(logs, func(i, j int) bool {
 		return logs[i].LSN < logs[j].LSN
@@ -47,16 +87,273 @@ func (r *LogsReader) Read() ([]Log, error) {
 	return logs, nil
 }
 
-func (r *LogsReader) readSegment(logs []Log, data []byte) ([]Log, error) {
-	buffer := bytes.NewBuffer(data)
-	for buffer.Len() > 0 {
-		var log Log
-		if err := log.Decode(buffer); err != nil {
-			return nil, fmt.Errorf("failed to parse logs data: %w", err)
+// Truncate removes segments which contain only records up to lsn, the last
+// segment is always kept, because it can be written at the moment
+func (r *LogsReader) Truncate(lsn int64) error {
+	var segments []string
+	var covered []bool
+	err := r.segmentsDirectory.ForEach(func(name string, data []byte) error {
+		logs, _, err := decodeSegment(data)
+		if err != nil {
+			return r.corruption(name, err)
+		}
+
+		isCovered := true
+		for idx := range logs {
+			isCovered = isCovered && logs[idx].LSN <= lsn
+		}
+
+		segments = append(segments, name)
+		covered = append(covered, isCovered)
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to read segments: %w", err)
+	}
+
+	for idx := 0; idx < len(segments)-1 && covered[idx]; idx++ {
+		if err = r.segmentsDirectory.Remove(segments[idx]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadAfter returns encoded records which follow the record with lsn in order of
+// segments, records are returned from the beginning if lsn is zero or the record is
+// removed by a checkpoint. Size of records is limited by maxSize, but at least one
+// record is returned, the flag reports that some records are left for the next call
+func (r *LogsReader) ReadAfter(lsn int64, maxSize int) ([]byte, bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	names, err := r.segmentsDirectory.Names()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list segments: %w", err)
+	}
+
+	r.forgetRemoved(names)
+
+	start, logs, minLSN, err := r.seek(names, lsn)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var buffer bytes.Buffer
+	for idx := start; idx < len(names); idx++ {
+		if idx != start {
+			if logs, err = r.readSegment(names, idx); err != nil {
+				return nil, false, err
+			}
+		}
+
+		for logIdx := range logs {
+			if logs[logIdx].LSN <= minLSN {
+				continue
+			}
+
+			size := buffer.Len()
+			if err = encodeRecord(&buffer, &logs[logIdx]); err != nil {
+				return nil, false, err
+			}
+
+			if size != 0 && buffer.Len() > maxSize {
+				buffer.Truncate(size)
+				return buffer.Bytes(), true, nil
+			}
+		}
+	}
+
+	return buffer.Bytes(), false, nil
+}
+
+// CountAfter returns the number of records which follow the record with lsn
+// in order of segments, it's a replication lag of the replica at the position
+func (r *LogsReader) CountAfter(lsn int64) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	names, err := r.segmentsDirectory.Names()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list segments: %w", err)
+	}
+
+	r.forgetRemoved(names)
+
+	start, logs, minLSN, err := r.seek(names, lsn)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for idx := start; idx < len(names); idx++ {
+		if idx != start {
+			// closed segments are counted without reading
+			if rng, found := r.ranges[names[idx]]; found && rng.first > minLSN {
+				count += rng.count
+				continue
+			}
+
+			if logs, err = r.readSegment(names, idx); err != nil {
+				return 0, err
+			}
+		}
+
+		for logIdx := range logs {
+			if logs[logIdx].LSN > minLSN {
+				count++
+			}
 		}
+	}
 
-		logs = append(logs, log)
+	return count, nil
+}
+
+// LastLSN returns LSN of the last record in order of segments,
+// that is a position of the replica in the stream of the master
+func (r *LogsReader) LastLSN() (int64, error) {
+	names, err := r.segmentsDirectory.Names()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list segments: %w", err)
+	}
+
+	for idx := len(names) - 1; idx >= 0; idx-- {
+		data, err := r.segmentsDirectory.Read(names[idx])
+		if err != nil {
+			return 0, err
+		}
+
+		logs, _, err := decodeSegment(data)
+		if err != nil {
+			return 0, r.corruption(names[idx], err)
+		}
+
+		if len(logs) != 0 {
+			return logs[len(logs)-1].LSN, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// Contains reports that the record with lsn is kept in segments,
+// so replication can continue after it without a snapshot
+func (r *LogsReader) Contains(lsn int64) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	names, err := r.segmentsDirectory.Names()
+	if err != nil {
+		return false, fmt.Errorf("failed to list segments: %w", err)
+	}
+
+	r.forgetRemoved(names)
+
+	idx, _, err := r.find(names, lsn)
+	return idx < len(names), err
+}
+
+// seek returns index of the segment and its records which follow the record
+// with lsn, if there is no such record all records with greater LSN follow it
+func (r *LogsReader) seek(names []string, lsn int64) (int, []Log, int64, error) {
+	if lsn != 0 {
+		idx, logs, err := r.find(names, lsn)
+		if err != nil {
+			return 0, nil, 0, err
+		} else if idx < len(names) {
+			return idx, logs, 0, nil
+		}
+	}
+
+	for idx := range names {
+		if rng, found := r.ranges[names[idx]]; found && rng.last <= lsn {
+			continue
+		}
+
+		logs, err := r.readSegment(names, idx)
+		if err != nil {
+			return 0, nil, 0, err
+		}
+
+		return idx, logs, lsn, nil
+	}
+
+	return len(names), nil, lsn, nil
+}
+
+// find returns index of the segment with the record and records which follow
+// it, index is equal to the number of segments if there is no such record
+func (r *LogsReader) find(names []string, lsn int64) (int, []Log, error) {
+	for idx := range names {
+		if rng, found := r.ranges[names[idx]]; found && (lsn < rng.first || lsn > rng.last) {
+			continue
+		}
+
+		logs, err := r.readSegment(names, idx)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		for logIdx := range logs {
+			if logs[logIdx].LSN == lsn {
+				return idx, logs[logIdx+1:], nil
+			}
+		}
+	}
+
+	return len(names), nil, nil
+}
+
+// readSegment ignores a torn tail, because the last segment can be
+// written at the moment, ranges are remembered only for closed segments
+func (r *LogsReader) readSegment(names []string, idx int) ([]Log, error) {
+	data, err := r.segmentsDirectory.Read(names[idx])
+	if err != nil {
+		return nil, err
+	}
+
+	logs, _, err := decodeSegment(data)
+	if err != nil {
+		return nil, r.corruption(names[idx], err)
+	}
+
+	if idx != len(names)-1 {
+		var rng lsnRange
+		for logIdx := range logs {
+			if logIdx == 0 || logs[logIdx].LSN < rng.first {
+				rng.first = logs[logIdx].LSN
+			}
+			rng.last = max(rng.last, logs[logIdx].LSN)
+		}
+
+		rng.count = len(logs)
+
+		r.ranges[names[idx]] = rng
 	}
 
 	return logs, nil
 }
+
+func (r *LogsReader) forgetRemoved(names []string) {
+	existing := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		existing[name] = struct{}{}
+	}
+
+	for name := range r.ranges {
+		if _, found := existing[name]; !found {
+			delete(r.ranges, name)
+		}
+	}
+}
+
+func (r *LogsReader) corruption(segmentName string, err error) error {
+	var corruptionErr *CorruptionError
+	if errors.As(err, &corruptionErr) {
+		corruptionErr.Segment = segmentName
+	}
+
+	return err
+}
@@ -40,7 +40,7 @@ func (m *MocksegmentsDirectory) EXPECT() *MocksegmentsDirectoryMockRecorder {
 }
 
 // ForEach mocks base method.
-func (m *MocksegmentsDirectory) ForEach(arg0 func([]byte) error) error {
+func (m *MocksegmentsDirectory) ForEach(arg0 func(string, []byte) error) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ForEach", arg0)
 	ret0, _ := ret[0].(error)
@@ -52,3 +52,61 @@ func (mr *MocksegmentsDirectoryMockRecorder) ForEach(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForEach", reflect.TypeOf((*MocksegmentsDirectory)(nil).ForEach), arg0)
 }
+
+// Names mocks base method.
+func (m *MocksegmentsDirectory) Names() ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Names")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Names indicates an expected call of Names.
+func (mr *MocksegmentsDirectoryMockRecorder) Names() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Names", reflect.TypeOf((*MocksegmentsDirectory)(nil).Names))
+}
+
+// Read mocks base method.
+func (m *MocksegmentsDirectory) Read(arg0 string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", arg0)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MocksegmentsDirectoryMockRecorder) Read(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MocksegmentsDirectory)(nil).Read), arg0)
+}
+
+// Remove mocks base method.
+func (m *MocksegmentsDirectory) Remove(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Remove", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Remove indicates an expected call of Remove.
+func (mr *MocksegmentsDirectoryMockRecorder) Remove(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MocksegmentsDirectory)(nil).Remove), arg0)
+}
+
+// Truncate mocks base method.
+func (m *MocksegmentsDirectory) Truncate(arg0 string, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Truncate", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Truncate indicates an expected call of Truncate.
+func (mr *MocksegmentsDirectoryMockRecorder) Truncate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Truncate", reflect.TypeOf((*MocksegmentsDirectory)(nil).Truncate), arg0, arg1)
+}
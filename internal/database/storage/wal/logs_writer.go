@@ -37,7 +37,7 @@ func (w *LogsWriter) Write(requests []WriteRequest) {
 	var buffer bytes.Buffer
 	for idx := range requests {
 		log := requests[idx].Log()
-		if err := log.Encode(&buffer); err != nil {
+		if err := encodeRecord(&buffer, &log); err != nil {
 			w.logger.Warn("failed to encode logs data", zap.Error(err))
 			w.acknowledgeWrite(requests, err)
 			return
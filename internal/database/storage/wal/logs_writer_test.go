@@ -68,7 +68,7 @@ func TestWriteWithErrors(t *testing.T) {
 	var buffer bytes.Buffer
 	for idx := range requests {
 		log := requests[idx].log
-		err := log.Encode(&buffer)
+		err := encodeRecord(&buffer, &log)
 		require.NoError(t, err)
 	}
 
@@ -100,7 +100,7 @@ func TestWrite(t *testing.T) {
 	var buffer bytes.Buffer
 	for idx := range requests {
 		log := requests[idx].log
-		err := log.Encode(&buffer)
+		err := encodeRecord(&buffer, &log)
 		require.NoError(t, err)
 	}
 
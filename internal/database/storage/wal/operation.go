@@ -0,0 +1,53 @@
+package wal
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Operation is a single command of a transaction, operations of a transaction
+// are written to the WAL as arguments of the one COMMIT record, so a transaction
+// can be recovered only entirely
+type Operation struct {
+	CommandID string
+	Arguments []string
+}
+
+// NewOperation ...
+func NewOperation(commandID string, arguments []string) Operation {
+	return Operation{
+		CommandID: commandID,
+		Arguments: arguments,
+	}
+}
+
+// EncodeOperations flattens operations to the list: [command, arguments number, arguments...]
+func EncodeOperations(operations []Operation) []string {
+	var arguments []string
+	for _, operation := range operations {
+		arguments = append(arguments, operation.CommandID, strconv.Itoa(len(operation.Arguments)))
+		arguments = append(arguments, operation.Arguments...)
+	}
+
+	return arguments
+}
+
+// DecodeOperations ...
+func DecodeOperations(arguments []string) ([]Operation, error) {
+	var operations []Operation
+	for idx := 0; idx < len(arguments); {
+		if idx+1 >= len(arguments) {
+			return nil, errors.New("incorrect operation header")
+		}
+
+		argumentsNumber, err := strconv.Atoi(arguments[idx+1])
+		if err != nil || argumentsNumber < 0 || idx+2+argumentsNumber > len(arguments) {
+			return nil, errors.New("incorrect operation arguments number")
+		}
+
+		operations = append(operations, NewOperation(arguments[idx], arguments[idx+2:idx+2+argumentsNumber]))
+		idx += 2 + argumentsNumber
+	}
+
+	return operations, nil
+}
@@ -0,0 +1,70 @@
+package wal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"database-simon/internal/database/compute"
+)
+
+func TestEncodeOperations(t *testing.T) {
+	t.Parallel()
+
+	operations := []Operation{
+		NewOperation(compute.SetCommand, []string{"key_1", "value_1"}),
+		NewOperation(compute.DelCommand, []string{"key_2"}),
+		NewOperation(compute.SetCommand, []string{"key_3", "value_3", "1700000000000"}),
+	}
+
+	arguments := EncodeOperations(operations)
+	assert.Equal(t, []string{
+		compute.SetCommand, "2", "key_1", "value_1",
+		compute.DelCommand, "1", "key_2",
+		compute.SetCommand, "3", "key_3", "value_3", "1700000000000",
+	}, arguments)
+
+	decoded, err := DecodeOperations(arguments)
+	assert.NoError(t, err)
+	assert.Equal(t, operations, decoded)
+}
+
+func TestDecodeOperations(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		arguments []string
+
+		expectedOperations []Operation
+		expectedErr        error
+	}{
+		"decode empty operations": {},
+		"decode operations without arguments number": {
+			arguments:   []string{compute.DelCommand},
+			expectedErr: errors.New("incorrect operation header"),
+		},
+		"decode operations with incorrect arguments number": {
+			arguments:   []string{compute.DelCommand, "one", "key"},
+			expectedErr: errors.New("incorrect operation arguments number"),
+		},
+		"decode operations with missing arguments": {
+			arguments:   []string{compute.SetCommand, "2", "key"},
+			expectedErr: errors.New("incorrect operation arguments number"),
+		},
+		"decode operations": {
+			arguments:          []string{compute.DelCommand, "1", "key"},
+			expectedOperations: []Operation{NewOperation(compute.DelCommand, []string{"key"})},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			operations, err := DecodeOperations(test.arguments)
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedOperations, operations)
+		})
+	}
+}
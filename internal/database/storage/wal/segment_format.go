@@ -0,0 +1,181 @@
+package wal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+)
+
+// segment starts with the header: magic bytes and version of records format,
+// segments without the header contain gob records one by one
+const (
+	segmentMagic      = "WALS"
+	segmentHeaderSize = len(segmentMagic) + 1
+
+	// segmentVersion1 records are [length of payload][CRC32C of LSN and payload][LSN][gob payload]
+	segmentVersion1 byte = 1
+	// segmentVersion2 records are [length of payload][CRC32C of payload][binary payload]
+	segmentVersion2 byte = 2
+
+	recordHeaderSize = 4 + 4
+	lsnSize          = 8
+)
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// CorruptionError is returned when a damaged record is not the tail of the last segment
+type CorruptionError struct {
+	Segment string
+	Offset  int
+	Reason  string
+}
+
+func (e *CorruptionError) Error() string {
+	return fmt.Sprintf("segment %s is corrupted at offset %d: %s", e.Segment, e.Offset, e.Reason)
+}
+
+// SegmentHeader returns the header which is written at the beginning of each segment
+func SegmentHeader() []byte {
+	return append([]byte(segmentMagic), segmentVersion2)
+}
+
+func encodeRecord(buffer *bytes.Buffer, log *Log) error {
+	payload := log.AppendBinary(nil)
+	if len(payload) > math.MaxUint32 {
+		return errors.New("record is too large")
+	}
+
+	header := make([]byte, recordHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload))) // nolint : G115: integer overflow conversion int -> uint32 (gosec)
+	binary.BigEndian.PutUint32(header[4:8], crc32.Checksum(payload, castagnoliTable))
+
+	buffer.Write(header)
+	buffer.Write(payload)
+	return nil
+}
+
+// DecodeSegment returns records of the segment, an incomplete record
+// at the end of the segment is ignored
+func DecodeSegment(data []byte) ([]Log, error) {
+	logs, _, err := decodeSegment(data)
+	return logs, err
+}
+
+// decodeSegment returns records of the segment and size of its valid part, the
+// valid part is shorter than data if the tail of the segment is torn by a failure
+func decodeSegment(data []byte) ([]Log, int, error) {
+	if len(data) < segmentHeaderSize && bytes.HasPrefix([]byte(segmentMagic), data) {
+		return nil, 0, nil
+	} else if !bytes.HasPrefix(data, []byte(segmentMagic)) {
+		return decodeLegacySegment(data)
+	}
+
+	switch version := data[len(segmentMagic)]; version {
+	case segmentVersion1:
+		return decodeRecords(data, segmentHeaderSize, lsnSize, decodeRecordV1)
+	case segmentVersion2:
+		return decodeRecords(data, segmentHeaderSize, 0, decodeRecordV2)
+	default:
+		return nil, 0, &CorruptionError{Offset: len(segmentMagic), Reason: fmt.Sprintf("unsupported version %d", version)}
+	}
+}
+
+// EncodeRecords returns records in the format of the current segment version
+// without the segment header, such records are streamed to replicas
+func EncodeRecords(logs []Log) ([]byte, error) {
+	var buffer bytes.Buffer
+	for idx := range logs {
+		if err := encodeRecord(&buffer, &logs[idx]); err != nil {
+			return nil, err
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// DecodeRecords reads records encoded by EncodeRecords, unlike
+// segments an incomplete record at the end is an error
+func DecodeRecords(data []byte) ([]Log, error) {
+	logs, size, err := decodeRecords(data, 0, 0, decodeRecordV2)
+	if err != nil {
+		return nil, err
+	} else if size < len(data) {
+		return nil, &CorruptionError{Offset: size, Reason: "incomplete record"}
+	}
+
+	return logs, nil
+}
+
+// decodeRecords reads records from the offset, the checksum of each record
+// covers the extra part of the record header and the payload
+func decodeRecords(data []byte, offset int, extraHeaderSize int, decode func([]byte) (Log, error)) ([]Log, int, error) {
+	var logs []Log
+	for offset < len(data) {
+		if len(data)-offset < recordHeaderSize+extraHeaderSize {
+			return logs, offset, nil
+		}
+
+		header := data[offset : offset+recordHeaderSize]
+		end := offset + recordHeaderSize + extraHeaderSize + int(binary.BigEndian.Uint32(header[0:4]))
+		if end > len(data) {
+			return logs, offset, nil
+		}
+
+		checked := data[offset+recordHeaderSize : end]
+		if crc32.Checksum(checked, castagnoliTable) != binary.BigEndian.Uint32(header[4:8]) {
+			if end == len(data) {
+				return logs, offset, nil
+			}
+
+			return nil, offset, &CorruptionError{Offset: offset, Reason: "checksum mismatch"}
+		}
+
+		log, err := decode(checked)
+		if err != nil {
+			return nil, offset, &CorruptionError{Offset: offset, Reason: err.Error()}
+		}
+
+		logs = append(logs, log)
+		offset = end
+	}
+
+	return logs, offset, nil
+}
+
+func decodeRecordV1(data []byte) (Log, error) {
+	var log Log
+	if err := log.Decode(bytes.NewBuffer(data[lsnSize:])); err != nil {
+		return Log{}, err
+	}
+
+	log.LSN = int64(binary.BigEndian.Uint64(data[:lsnSize])) // nolint : G115: integer overflow conversion uint64 -> int64 (gosec)
+	return log, nil
+}
+
+func decodeRecordV2(data []byte) (Log, error) {
+	var log Log
+	err := log.DecodeBinary(data)
+	return log, err
+}
+
+// decodeLegacySegment reads segments written before headers of records,
+// such segments have no checksums, so any error is corruption
+func decodeLegacySegment(data []byte) ([]Log, int, error) {
+	var logs []Log
+	buffer := bytes.NewBuffer(data)
+	for buffer.Len() > 0 {
+		offset := len(data) - buffer.Len()
+
+		var log Log
+		if err := log.Decode(buffer); err != nil {
+			return nil, offset, &CorruptionError{Offset: offset, Reason: err.Error()}
+		}
+
+		logs = append(logs, log)
+	}
+
+	return logs, len(data), nil
+}
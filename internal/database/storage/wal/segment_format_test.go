@@ -0,0 +1,205 @@
+package wal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"database-simon/internal/database/compute"
+)
+
+func encodeTestSegment(t *testing.T, logs ...Log) []byte {
+	t.Helper()
+
+	buffer := bytes.NewBuffer(SegmentHeader())
+	for idx := range logs {
+		require.NoError(t, encodeRecord(buffer, &logs[idx]))
+	}
+
+	return buffer.Bytes()
+}
+
+// encodeTestSegmentV1 writes records with gob payload as segments of the first version
+func encodeTestSegmentV1(t *testing.T, logs ...Log) []byte {
+	t.Helper()
+
+	data := append([]byte(segmentMagic), segmentVersion1)
+	for idx := range logs {
+		var payload bytes.Buffer
+		require.NoError(t, logs[idx].Encode(&payload))
+
+		checked := binary.BigEndian.AppendUint64(nil, uint64(logs[idx].LSN))
+		checked = append(checked, payload.Bytes()...)
+
+		data = binary.BigEndian.AppendUint32(data, uint32(payload.Len()))
+		data = binary.BigEndian.AppendUint32(data, crc32.Checksum(checked, castagnoliTable))
+		data = append(data, checked...)
+	}
+
+	return data
+}
+
+func TestDecodeSegment(t *testing.T) {
+	t.Parallel()
+
+	logs := []Log{
+		{LSN: 1, CommandID: compute.SetCommand, Arguments: []string{"key_1", "value_1"}},
+		{LSN: 2, CommandID: compute.DelCommand, Arguments: []string{"key_1"}},
+	}
+
+	segment := encodeTestSegment(t, logs...)
+	firstRecordEnd := len(encodeTestSegment(t, logs[0]))
+
+	var legacySegment bytes.Buffer
+	for idx := range logs {
+		require.NoError(t, logs[idx].Encode(&legacySegment))
+	}
+
+	segmentV1 := encodeTestSegmentV1(t, logs...)
+
+	damaged := func(offset int) []byte {
+		data := bytes.Clone(segment)
+		data[offset] ^= 0xFF
+		return data
+	}
+
+	tests := map[string]struct {
+		data []byte
+
+		expectedLogs   []Log
+		expectedSize   int
+		expectedOffset int
+		expectedErr    bool
+	}{
+		"empty segment": {},
+		"torn header": {
+			data: []byte(segmentMagic[:2]),
+		},
+		"segment without records": {
+			data:         SegmentHeader(),
+			expectedSize: segmentHeaderSize,
+		},
+		"segment with records": {
+			data:         segment,
+			expectedLogs: logs,
+			expectedSize: len(segment),
+		},
+		"segment of the first version": {
+			data:         segmentV1,
+			expectedLogs: logs,
+			expectedSize: len(segmentV1),
+		},
+		"torn segment of the first version": {
+			data:         segmentV1[:len(segmentV1)-1],
+			expectedLogs: logs[:1],
+			expectedSize: len(encodeTestSegmentV1(t, logs[0])),
+		},
+		"legacy segment": {
+			data:         legacySegment.Bytes(),
+			expectedLogs: logs,
+			expectedSize: legacySegment.Len(),
+		},
+		"torn header of record": {
+			data:         segment[:firstRecordEnd+recordHeaderSize-1],
+			expectedLogs: logs[:1],
+			expectedSize: firstRecordEnd,
+		},
+		"torn payload of record": {
+			data:         segment[:len(segment)-1],
+			expectedLogs: logs[:1],
+			expectedSize: firstRecordEnd,
+		},
+		"damaged last record": {
+			data:         damaged(len(segment) - 1),
+			expectedLogs: logs[:1],
+			expectedSize: firstRecordEnd,
+		},
+		"damaged record in the middle": {
+			data:           damaged(firstRecordEnd - 1),
+			expectedOffset: segmentHeaderSize,
+			expectedErr:    true,
+		},
+		"damaged checksum in the middle": {
+			data:           damaged(segmentHeaderSize + recordHeaderSize - 1),
+			expectedOffset: segmentHeaderSize,
+			expectedErr:    true,
+		},
+		"unsupported version": {
+			data:           append([]byte(segmentMagic), 100),
+			expectedOffset: len(segmentMagic),
+			expectedErr:    true,
+		},
+		"damaged legacy segment": {
+			data:        legacySegment.Bytes()[:legacySegment.Len()-1],
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			logs, size, err := decodeSegment(test.data)
+			if test.expectedErr {
+				var corruptionErr *CorruptionError
+				require.True(t, errors.As(err, &corruptionErr))
+				if test.expectedOffset != 0 {
+					assert.Equal(t, test.expectedOffset, corruptionErr.Offset)
+				}
+				assert.Nil(t, logs)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedLogs, logs)
+			assert.Equal(t, test.expectedSize, size)
+		})
+	}
+}
+
+func TestSegmentSize(t *testing.T) {
+	t.Parallel()
+
+	var logs []Log
+	for lsn := int64(1); lsn <= 100; lsn++ {
+		logs = append(logs, Log{LSN: lsn, CommandID: compute.SetCommand, Arguments: []string{"key", "value"}})
+	}
+
+	var legacySegment bytes.Buffer
+	for idx := range logs {
+		require.NoError(t, logs[idx].Encode(&legacySegment))
+	}
+
+	segment := encodeTestSegment(t, logs...)
+	assert.Less(t, len(segment)*4, legacySegment.Len())
+}
+
+func TestEncodeRecords(t *testing.T) {
+	t.Parallel()
+
+	logs := []Log{
+		{LSN: 1, CommandID: compute.SetCommand, Arguments: []string{"key_1", "value_1"}},
+		{LSN: 2, CommandID: compute.DelCommand, Arguments: []string{"key_1"}},
+	}
+
+	data, err := EncodeRecords(logs)
+	require.NoError(t, err)
+
+	decodedLogs, err := DecodeRecords(data)
+	require.NoError(t, err)
+	assert.Equal(t, logs, decodedLogs)
+
+	// records are streamed as a whole, so an incomplete record is an error
+	_, err = DecodeRecords(data[:len(data)-1])
+	var corruptionErr *CorruptionError
+	assert.ErrorAs(t, err, &corruptionErr)
+
+	decodedLogs, err = DecodeRecords(nil)
+	require.NoError(t, err)
+	assert.Empty(t, decodedLogs)
+}
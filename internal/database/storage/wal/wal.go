@@ -3,6 +3,7 @@ package wal
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -11,18 +12,35 @@ import (
 	"database-simon/internal/database/compute"
 )
 
+// ErrorNoSnapshots ...
+var ErrorNoSnapshots = errors.New("snapshots directory is not set")
+
 type logsWriter interface {
 	Write([]WriteRequest)
 }
 
 type logsReader interface {
 	Read() ([]Log, error)
+	Truncate(int64) error
+}
+
+type snapshotsDirectory interface {
+	Save(int64, []byte) error
+	Last() (int64, []byte, error)
+}
+
+type replicas interface {
+	Register(int64)
+	Unregister(int64)
+	Wait(int64) error
 }
 
 // WAL ...
 type WAL struct {
 	logsWriter logsWriter
 	logsReader logsReader
+	snapshots  snapshotsDirectory
+	replicas   replicas
 
 	flushTimeout time.Duration
 	maxBatchSize int
@@ -38,6 +56,7 @@ func NewWAL(
 	reader logsReader,
 	flushTimeout time.Duration,
 	maxBatchSize int,
+	options ...Option,
 ) (*WAL, error) {
 	if writer == nil {
 		return nil, errors.New("writer is invalid")
@@ -46,13 +65,19 @@ func NewWAL(
 		return nil, errors.New("reader is invalid")
 	}
 
-	return &WAL{
+	w := &WAL{
 		logsWriter:   writer,
 		logsReader:   reader,
 		flushTimeout: flushTimeout,
 		maxBatchSize: maxBatchSize,
 		batches:      make(chan []WriteRequest, 1),
-	}, nil
+	}
+
+	for _, option := range options {
+		option(w)
+	}
+
+	return w, nil
 }
 
 // Start ...
@@ -85,24 +110,91 @@ func (w *WAL) Start(ctx context.Context) {
 
 // Recover ...
 func (w *WAL) Recover() ([]Log, error) {
-	// TODO: need to compact WAL segments
 	return w.logsReader.Read()
 }
 
+// Checkpoint saves the snapshot which contains all records up to lsn
+// and removes segments covered by the snapshot
+func (w *WAL) Checkpoint(lsn int64, data []byte) error {
+	if w.snapshots == nil {
+		return ErrorNoSnapshots
+	}
+
+	if err := w.snapshots.Save(lsn, data); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	if err := w.logsReader.Truncate(lsn); err != nil {
+		return fmt.Errorf("failed to truncate segments: %w", err)
+	}
+
+	return nil
+}
+
+// Truncate removes segments which records are all up to lsn, it's used
+// instead of checkpoints by engines which persist records themselves
+func (w *WAL) Truncate(lsn int64) error {
+	if err := w.logsReader.Truncate(lsn); err != nil {
+		return fmt.Errorf("failed to truncate segments: %w", err)
+	}
+
+	return nil
+}
+
+// LastCheckpoint returns the newest snapshot and its LSN,
+// nil data means that there are no snapshots
+func (w *WAL) LastCheckpoint() (int64, []byte, error) {
+	if w.snapshots == nil {
+		return 0, nil, nil
+	}
+
+	return w.snapshots.Last()
+}
+
 // Set ...
 func (w *WAL) Set(ctx context.Context, key, value string) concurrency.FutureError {
 	return w.push(ctx, compute.SetCommand, []string{key, value})
 }
 
+// SetWithExpiration ...
+func (w *WAL) SetWithExpiration(ctx context.Context, key, value string, deadline time.Time) concurrency.FutureError {
+	return w.push(ctx, compute.SetCommand, []string{key, value, EncodeDeadline(deadline)})
+}
+
 // Del ...
 func (w *WAL) Del(ctx context.Context, key string) concurrency.FutureError {
 	return w.push(ctx, compute.DelCommand, []string{key})
 }
 
+// Expire ...
+func (w *WAL) Expire(ctx context.Context, key string, deadline time.Time) concurrency.FutureError {
+	return w.push(ctx, compute.PExpireAtCommand, []string{key, EncodeDeadline(deadline)})
+}
+
+// Persist ...
+func (w *WAL) Persist(ctx context.Context, key string) concurrency.FutureError {
+	return w.push(ctx, compute.PersistCommand, []string{key})
+}
+
+// Write writes the operation as a single record, it's used by
+// commands of data structures which have no dedicated methods
+func (w *WAL) Write(ctx context.Context, operation Operation) concurrency.FutureError {
+	return w.push(ctx, operation.CommandID, operation.Arguments)
+}
+
+// Commit writes all operations of a transaction as a single record
+func (w *WAL) Commit(ctx context.Context, operations []Operation) concurrency.FutureError {
+	return w.push(ctx, compute.CommitCommand, EncodeOperations(operations))
+}
+
 func (w *WAL) push(ctx context.Context, commandID string, args []string) concurrency.FutureError {
 	txID := common.GetTxIDFromContext(ctx)
 	record := NewWriteRequest(txID, commandID, args)
 
+	if w.replicas != nil {
+		w.replicas.Register(txID)
+	}
+
 	concurrency.WithLock(&w.mutex, func() {
 		w.batch = append(w.batch, record)
 		if len(w.batch) == w.maxBatchSize {
@@ -111,7 +203,27 @@ func (w *WAL) push(ctx context.Context, commandID string, args []string) concurr
 		}
 	})
 
-	return record.FutureResponse()
+	if w.replicas == nil {
+		return record.FutureResponse()
+	}
+
+	return w.waitReplicas(txID, record.FutureResponse())
+}
+
+// waitReplicas resolves the future after the record is persisted by replicas
+func (w *WAL) waitReplicas(lsn int64, future concurrency.FutureError) concurrency.FutureError {
+	promise := concurrency.NewPromise[error]()
+	go func() {
+		if err := future.Get(); err != nil {
+			w.replicas.Unregister(lsn)
+			promise.Set(err)
+			return
+		}
+
+		promise.Set(w.replicas.Wait(lsn))
+	}()
+
+	return promise.GetFuture()
 }
 
 func (w *WAL) flushBatch() {
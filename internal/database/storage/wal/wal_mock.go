@@ -89,3 +89,133 @@ func (mr *MocklogsReaderMockRecorder) Read() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MocklogsReader)(nil).Read))
 }
+
+// Truncate mocks base method.
+func (m *MocklogsReader) Truncate(arg0 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Truncate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Truncate indicates an expected call of Truncate.
+func (mr *MocklogsReaderMockRecorder) Truncate(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Truncate", reflect.TypeOf((*MocklogsReader)(nil).Truncate), arg0)
+}
+
+// MocksnapshotsDirectory is a mock of snapshotsDirectory interface.
+type MocksnapshotsDirectory struct {
+	ctrl     *gomock.Controller
+	recorder *MocksnapshotsDirectoryMockRecorder
+	isgomock struct{}
+}
+
+// MocksnapshotsDirectoryMockRecorder is the mock recorder for MocksnapshotsDirectory.
+type MocksnapshotsDirectoryMockRecorder struct {
+	mock *MocksnapshotsDirectory
+}
+
+// NewMocksnapshotsDirectory creates a new mock instance.
+func NewMocksnapshotsDirectory(ctrl *gomock.Controller) *MocksnapshotsDirectory {
+	mock := &MocksnapshotsDirectory{ctrl: ctrl}
+	mock.recorder = &MocksnapshotsDirectoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocksnapshotsDirectory) EXPECT() *MocksnapshotsDirectoryMockRecorder {
+	return m.recorder
+}
+
+// Last mocks base method.
+func (m *MocksnapshotsDirectory) Last() (int64, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Last")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Last indicates an expected call of Last.
+func (mr *MocksnapshotsDirectoryMockRecorder) Last() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Last", reflect.TypeOf((*MocksnapshotsDirectory)(nil).Last))
+}
+
+// Save mocks base method.
+func (m *MocksnapshotsDirectory) Save(arg0 int64, arg1 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MocksnapshotsDirectoryMockRecorder) Save(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MocksnapshotsDirectory)(nil).Save), arg0, arg1)
+}
+
+// Mockreplicas is a mock of replicas interface.
+type Mockreplicas struct {
+	ctrl     *gomock.Controller
+	recorder *MockreplicasMockRecorder
+	isgomock struct{}
+}
+
+// MockreplicasMockRecorder is the mock recorder for Mockreplicas.
+type MockreplicasMockRecorder struct {
+	mock *Mockreplicas
+}
+
+// NewMockreplicas creates a new mock instance.
+func NewMockreplicas(ctrl *gomock.Controller) *Mockreplicas {
+	mock := &Mockreplicas{ctrl: ctrl}
+	mock.recorder = &MockreplicasMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockreplicas) EXPECT() *MockreplicasMockRecorder {
+	return m.recorder
+}
+
+// Register mocks base method.
+func (m *Mockreplicas) Register(arg0 int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Register", arg0)
+}
+
+// Register indicates an expected call of Register.
+func (mr *MockreplicasMockRecorder) Register(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*Mockreplicas)(nil).Register), arg0)
+}
+
+// Unregister mocks base method.
+func (m *Mockreplicas) Unregister(arg0 int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Unregister", arg0)
+}
+
+// Unregister indicates an expected call of Unregister.
+func (mr *MockreplicasMockRecorder) Unregister(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unregister", reflect.TypeOf((*Mockreplicas)(nil).Unregister), arg0)
+}
+
+// Wait mocks base method.
+func (m *Mockreplicas) Wait(arg0 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Wait", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Wait indicates an expected call of Wait.
+func (mr *MockreplicasMockRecorder) Wait(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Wait", reflect.TypeOf((*Mockreplicas)(nil).Wait), arg0)
+}
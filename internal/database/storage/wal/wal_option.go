@@ -0,0 +1,18 @@
+package wal
+
+// Option ...
+type Option func(*WAL)
+
+// WithSnapshots ...
+func WithSnapshots(snapshots snapshotsDirectory) Option {
+	return func(wal *WAL) {
+		wal.snapshots = snapshots
+	}
+}
+
+// WithReplicas makes writes wait for acknowledgements of synchronous replicas
+func WithReplicas(replicas replicas) Option {
+	return func(wal *WAL) {
+		wal.replicas = replicas
+	}
+}
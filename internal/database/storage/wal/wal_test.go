@@ -11,6 +11,7 @@ import (
 	"go.uber.org/mock/gomock"
 
 	"database-simon/internal/common"
+	"database-simon/internal/database/compute"
 )
 
 // mockgen -source=wal.go -destination=wal_mock.go -package=wal
@@ -117,3 +118,134 @@ func TestWALFlushBySize(t *testing.T) {
 	assert.NoError(t, future1.Get())
 	assert.NoError(t, future2.Get())
 }
+
+func TestWALExpirationRecords(t *testing.T) {
+	t.Parallel()
+
+	deadline := time.UnixMilli(1700000000123)
+
+	var logs []Log
+	ctrl := gomock.NewController(t)
+	logsReader := NewMocklogsReader(ctrl)
+	logsWriter := NewMocklogsWriter(ctrl)
+	logsWriter.EXPECT().
+		Write(gomock.Any()).
+		Do(func(requests []WriteRequest) {
+			for _, request := range requests {
+				logs = append(logs, request.Log())
+				request.SetResponse(nil)
+			}
+		})
+
+	wal, err := NewWAL(logsWriter, logsReader, time.Minute, 3)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wal.Start(ctx)
+
+	future1 := wal.SetWithExpiration(common.ContextWithTxID(context.Background(), 10), "key1", "value1", deadline)
+	future2 := wal.Expire(common.ContextWithTxID(context.Background(), 20), "key2", deadline)
+	future3 := wal.Persist(common.ContextWithTxID(context.Background(), 30), "key3")
+	assert.NoError(t, future1.Get())
+	assert.NoError(t, future2.Get())
+	assert.NoError(t, future3.Get())
+
+	assert.Equal(t, []Log{
+		{LSN: 10, CommandID: compute.SetCommand, Arguments: []string{"key1", "value1", "1700000000123"}},
+		{LSN: 20, CommandID: compute.PExpireAtCommand, Arguments: []string{"key2", "1700000000123"}},
+		{LSN: 30, CommandID: compute.PersistCommand, Arguments: []string{"key3"}},
+	}, logs)
+
+	decoded, err := DecodeDeadline(logs[0].Arguments[2])
+	require.NoError(t, err)
+	assert.True(t, deadline.Equal(decoded))
+}
+
+func TestWALCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	controller := gomock.NewController(t)
+	logsReader := NewMocklogsReader(controller)
+	logsWriter := NewMocklogsWriter(controller)
+	snapshots := NewMocksnapshotsDirectory(controller)
+
+	gomock.InOrder(
+		snapshots.EXPECT().Save(int64(10), []byte("data")).Return(nil),
+		logsReader.EXPECT().Truncate(int64(10)).Return(nil),
+		snapshots.EXPECT().Save(int64(20), []byte("data")).Return(errors.New("save error")),
+	)
+	snapshots.EXPECT().
+		Last().
+		Return(int64(10), []byte("data"), nil)
+
+	wal, err := NewWAL(logsWriter, logsReader, time.Minute, 100, WithSnapshots(snapshots))
+	require.NoError(t, err)
+
+	require.NoError(t, wal.Checkpoint(10, []byte("data")))
+	// segments are kept if the snapshot is not saved
+	assert.Error(t, wal.Checkpoint(20, []byte("data")))
+
+	lsn, data, err := wal.LastCheckpoint()
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), lsn)
+	assert.Equal(t, []byte("data"), data)
+
+	walWithoutSnapshots, err := NewWAL(logsWriter, logsReader, time.Minute, 100)
+	require.NoError(t, err)
+	assert.ErrorIs(t, walWithoutSnapshots.Checkpoint(10, nil), ErrorNoSnapshots)
+
+	_, data, err = walWithoutSnapshots.LastCheckpoint()
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	// segments are truncated without snapshots for engines which persist records
+	logsReader.EXPECT().Truncate(int64(30)).Return(nil)
+	assert.NoError(t, walWithoutSnapshots.Truncate(30))
+}
+
+func TestWALWaitsReplicas(t *testing.T) {
+	t.Parallel()
+
+	writeErr := errors.New("write error")
+	replicationErr := errors.New("replication error")
+
+	controller := gomock.NewController(t)
+	logsWriter := NewMocklogsWriter(controller)
+	logsWriter.EXPECT().
+		Write(gomock.Any()).
+		Do(func(requests []WriteRequest) {
+			for _, request := range requests {
+				if request.Log().LSN == 30 {
+					request.SetResponse(writeErr)
+				} else {
+					request.SetResponse(nil)
+				}
+			}
+		})
+
+	replicas := NewMockreplicas(controller)
+	replicas.EXPECT().Register(int64(10))
+	replicas.EXPECT().Register(int64(20))
+	replicas.EXPECT().Register(int64(30))
+	replicas.EXPECT().Wait(int64(10)).Return(nil)
+	replicas.EXPECT().Wait(int64(20)).Return(replicationErr)
+	replicas.EXPECT().Unregister(int64(30))
+
+	wal, err := NewWAL(logsWriter, NewMocklogsReader(controller), time.Minute, 3, WithReplicas(replicas))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wal.Start(ctx)
+
+	future1 := wal.Set(common.ContextWithTxID(context.Background(), 10), "key1", "value1")
+	future2 := wal.Del(common.ContextWithTxID(context.Background(), 20), "key2")
+	future3 := wal.Del(common.ContextWithTxID(context.Background(), 30), "key3")
+
+	assert.NoError(t, future1.Get())
+	assert.Equal(t, replicationErr, future2.Get())
+	assert.Equal(t, writeErr, future3.Get())
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"database-simon/internal/common"
+	"database-simon/internal/database/compute"
+	"database-simon/internal/database/storage"
+)
+
+// handlerStructureQuery returns counts of changed items for writes, single values as is
+// and lists of values one per line quoted if it's required to parse them
+func (db *Database) handlerStructureQuery(ctx context.Context, query compute.Query) (string, error) {
+	arguments := query.Arguments()
+	key := arguments[0]
+
+	switch query.Command() {
+	case compute.HSetCommand:
+		return countResult(db.stor.HSet(ctx, key, arguments[1:]))
+	case compute.HGetCommand:
+		return db.stor.HGet(ctx, key, arguments[1])
+	case compute.HDelCommand:
+		return countResult(db.stor.HDel(ctx, key, arguments[1:]))
+	case compute.HGetAllCommand:
+		items, err := db.stor.HGetAll(ctx, key)
+		var lines []string
+		for idx := 0; idx+1 < len(items); idx += 2 {
+			lines = append(lines, compute.Quote(items[idx])+" "+compute.Quote(items[idx+1]))
+		}
+		return strings.Join(lines, "\n"), err
+	case compute.LPushCommand:
+		return countResult(db.stor.LPush(ctx, key, arguments[1:]))
+	case compute.RPushCommand:
+		return countResult(db.stor.RPush(ctx, key, arguments[1:]))
+	case compute.LPopCommand, compute.RPopCommand:
+		return db.handlerPopQuery(ctx, query)
+	case compute.LRangeCommand:
+		start, _ := strconv.Atoi(arguments[1]) // validated by compute layer
+		stop, _ := strconv.Atoi(arguments[2])  // validated by compute layer
+		return linesResult(db.stor.LRange(ctx, key, start, stop))
+	case compute.SAddCommand:
+		return countResult(db.stor.SAdd(ctx, key, arguments[1:]))
+	case compute.SRemCommand:
+		return countResult(db.stor.SRem(ctx, key, arguments[1:]))
+	case compute.SMembersCommand:
+		return linesResult(db.stor.SMembers(ctx, key))
+	case compute.SIsMemberCommand:
+		found, err := db.stor.SIsMember(ctx, key, arguments[1])
+		if found {
+			return "1", err
+		}
+		return "0", err
+	default:
+		return db.handlerSortedSetQuery(ctx, query)
+	}
+}
+
+// handlerSortedSetQuery returns scores as they are parsed back and members one per
+// line, members are followed by their scores on the same line with WITHSCORES
+func (db *Database) handlerSortedSetQuery(ctx context.Context, query compute.Query) (string, error) {
+	arguments := query.Arguments()
+	key := arguments[0]
+
+	switch query.Command() {
+	case compute.ZAddCommand:
+		members := make([]common.ScoredMember, 0, len(arguments)/2)
+		for idx := 1; idx+1 < len(arguments); idx += 2 {
+			score, _ := compute.ParseScore(arguments[idx]) // validated by compute layer
+			members = append(members, common.ScoredMember{Member: arguments[idx+1], Score: score})
+		}
+		return countResult(db.stor.ZAdd(ctx, key, members))
+	case compute.ZRemCommand:
+		return countResult(db.stor.ZRem(ctx, key, arguments[1:]))
+	case compute.ZIncrByCommand:
+		increment, _ := compute.ParseScore(arguments[1]) // validated by compute layer
+		score, err := db.stor.ZIncrBy(ctx, key, increment, arguments[2])
+		return compute.FormatScore(score), err
+	case compute.ZScoreCommand:
+		score, err := db.stor.ZScore(ctx, key, arguments[1])
+		return compute.FormatScore(score), err
+	case compute.ZRankCommand:
+		return countResult(db.stor.ZRank(ctx, key, arguments[1]))
+	case compute.ZRangeCommand:
+		start, _ := strconv.Atoi(arguments[1]) // validated by compute layer
+		stop, _ := strconv.Atoi(arguments[2])  // validated by compute layer
+		members, err := db.stor.ZRange(ctx, key, start, stop)
+		return scoredMembersResult(members, len(arguments) == 4), err
+	default:
+		minScore, minExclusive, _ := compute.ParseScoreBound(arguments[1]) // validated by compute layer
+		maxScore, maxExclusive, _ := compute.ParseScoreBound(arguments[2]) // validated by compute layer
+		scores := common.ScoreRange{Min: minScore, Max: maxScore, MinExclusive: minExclusive, MaxExclusive: maxExclusive}
+
+		withScores := len(arguments) > 3 && strings.EqualFold(arguments[3], compute.WithScoresOption)
+		var offset, limit int
+		if idx := len(arguments) - 3; idx >= 3 && strings.EqualFold(arguments[idx], compute.LimitOption) {
+			offset, _ = strconv.Atoi(arguments[idx+1]) // validated by compute layer
+			limit, _ = strconv.Atoi(arguments[idx+2])  // validated by compute layer
+		}
+
+		members, err := db.stor.ZRangeByScore(ctx, key, scores, offset, limit)
+		return scoredMembersResult(members, withScores), err
+	}
+}
+
+// handlerPopQuery returns the value as is without count like GET
+// and values one per line with count
+func (db *Database) handlerPopQuery(ctx context.Context, query compute.Query) (string, error) {
+	arguments := query.Arguments()
+	count := 1
+	if len(arguments) == 2 {
+		count, _ = strconv.Atoi(arguments[1]) // validated by compute layer
+	}
+
+	var values []string
+	var err error
+	if query.Command() == compute.LPopCommand {
+		values, err = db.stor.LPop(ctx, arguments[0], count)
+	} else {
+		values, err = db.stor.RPop(ctx, arguments[0], count)
+	}
+
+	if err != nil || len(arguments) == 2 {
+		return linesResult(values, err)
+	} else if len(values) == 0 {
+		// the list is deleted by a concurrent write of another type
+		return "", storage.ErrorNotFound
+	}
+
+	return values[0], nil
+}
+
+func countResult(count int, err error) (string, error) {
+	return strconv.Itoa(count), err
+}
+
+func scoredMembersResult(members []common.ScoredMember, withScores bool) string {
+	lines := make([]string, 0, len(members))
+	for _, member := range members {
+		line := compute.Quote(member.Member)
+		if withScores {
+			line += " " + compute.FormatScore(member.Score)
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func linesResult(values []string, err error) (string, error) {
+	lines := make([]string, 0, len(values))
+	for _, value := range values {
+		lines = append(lines, compute.Quote(value))
+	}
+
+	return strings.Join(lines, "\n"), err
+}
@@ -22,3 +22,19 @@ func WithClientBufferSize(size uint) TCPClientOption {
 		client.bufferSize = int(size) // nolint : G115: integer overflow conversion uint -> int
 	}
 }
+
+// WithClientRequestTimeout limits time of each request, unlike
+// the idle timeout the deadline is moved by every request
+func WithClientRequestTimeout(timeout time.Duration) TCPClientOption {
+	return func(client *TCPClient) {
+		client.requestTimeout = timeout
+	}
+}
+
+// WithClientReconnection makes the client restore the connection
+// by the next request after a failed one
+func WithClientReconnection() TCPClientOption {
+	return func(client *TCPClient) {
+		client.reconnect = true
+	}
+}
@@ -30,3 +30,26 @@ func TestWithClientBufferSize(t *testing.T) {
 
 	assert.Equal(t, bufferSize, uint(client.bufferSize)) // nolint : G115: integer overflow conversion int -> uint
 }
+
+func TestWithClientRequestTimeout(t *testing.T) {
+	t.Parallel()
+
+	requestTimeout := time.Second
+	option := WithClientRequestTimeout(requestTimeout)
+
+	var client TCPClient
+	option(&client)
+
+	assert.Equal(t, requestTimeout, client.requestTimeout)
+}
+
+func TestWithClientReconnection(t *testing.T) {
+	t.Parallel()
+
+	option := WithClientReconnection()
+
+	var client TCPClient
+	option(&client)
+
+	assert.True(t, client.reconnect)
+}
@@ -3,27 +3,26 @@ package client
 import (
 	"errors"
 	"fmt"
-	"io"
 	"net"
 	"time"
+
+	"database-simon/internal/network/protocol"
 )
 
 // TCPClient ...
 type TCPClient struct {
-	connection  net.Conn
-	idleTimeout time.Duration
-	bufferSize  int
+	address        string
+	connection     net.Conn
+	idleTimeout    time.Duration
+	requestTimeout time.Duration
+	bufferSize     int
+	reconnect      bool
 }
 
 // NewTCPClient ...
 func NewTCPClient(address string, options ...TCPClientOption) (*TCPClient, error) {
-	connection, err := net.Dial("tcp", address)
-	if err != nil {
-		return nil, fmt.Errorf("failed to dial: %w", err)
-	}
-
 	client := &TCPClient{
-		connection: connection,
+		address:    address,
 		bufferSize: defaultBufferSize,
 	}
 
@@ -31,10 +30,8 @@ func NewTCPClient(address string, options ...TCPClientOption) (*TCPClient, error
 		option(client)
 	}
 
-	if client.idleTimeout != 0 {
-		if err = connection.SetDeadline(time.Now().Add(client.idleTimeout)); err != nil {
-			return nil, fmt.Errorf("failed to set deadline for connection: %w", err)
-		}
+	if err := client.connect(); err != nil {
+		return nil, err
 	}
 
 	return client, nil
@@ -42,19 +39,20 @@ func NewTCPClient(address string, options ...TCPClientOption) (*TCPClient, error
 
 // Send ...
 func (c *TCPClient) Send(request []byte) ([]byte, error) {
-	if _, err := c.connection.Write(request); err != nil {
-		return nil, err
+	if c.connection == nil {
+		if err := c.connect(); err != nil {
+			return nil, err
+		}
 	}
 
-	response := make([]byte, c.bufferSize)
-	count, err := c.connection.Read(response)
-	if err != nil && err != io.EOF {
-		return nil, err
-	} else if count == c.bufferSize {
-		return nil, errors.New("small buffer size")
+	response, err := c.send(request)
+	if err != nil && c.reconnect {
+		// the state of the connection is unknown, so the next request uses a new one
+		c.Close()
+		c.connection = nil
 	}
 
-	return response[:count], nil
+	return response, err
 }
 
 // Close ...
@@ -63,3 +61,47 @@ func (c *TCPClient) Close() {
 		_ = c.connection.Close()
 	}
 }
+
+func (c *TCPClient) connect() error {
+	connection, err := net.Dial("tcp", c.address)
+	if err != nil {
+		return fmt.Errorf("failed to dial: %w", err)
+	}
+
+	if c.idleTimeout != 0 {
+		if err = connection.SetDeadline(time.Now().Add(c.idleTimeout)); err != nil {
+			_ = connection.Close()
+			return fmt.Errorf("failed to set deadline for connection: %w", err)
+		}
+	}
+
+	c.connection = connection
+	return nil
+}
+
+func (c *TCPClient) send(request []byte) ([]byte, error) {
+	if c.requestTimeout != 0 {
+		if err := c.connection.SetDeadline(time.Now().Add(c.requestTimeout)); err != nil {
+			return nil, fmt.Errorf("failed to set deadline for request: %w", err)
+		}
+	}
+
+	if err := protocol.WriteFrame(c.connection, request, c.bufferSize); err != nil {
+		return nil, sizeError(err)
+	}
+
+	response, err := protocol.ReadFrame(c.connection, c.bufferSize)
+	if err != nil {
+		return nil, sizeError(err)
+	}
+
+	return response, nil
+}
+
+func sizeError(err error) error {
+	if errors.Is(err, protocol.ErrFrameTooLarge) {
+		return errors.New("small buffer size")
+	}
+
+	return err
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"database-simon/internal/network/protocol"
 )
 
 func TestTCPClient(t *testing.T) {
@@ -26,11 +28,21 @@ func TestTCPClient(t *testing.T) {
 				return
 			}
 
-			_, err = connection.Read(make([]byte, 2048))
-			require.NoError(t, err)
+			go func(connection net.Conn) {
+				defer func() {
+					_ = connection.Close()
+				}()
+
+				for {
+					if _, err := protocol.ReadFrame(connection, 2048); err != nil {
+						return
+					}
 
-			_, err = connection.Write([]byte(serverResponse))
-			require.NoError(t, err)
+					if err := protocol.WriteFrame(connection, []byte(serverResponse), 0); err != nil {
+						return
+					}
+				}
+			}(connection)
 		}
 	}()
 
@@ -0,0 +1,56 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// HeaderSize is a size of the frame header with payload length
+const HeaderSize = 4
+
+var (
+	// ErrFrameTooLarge ...
+	ErrFrameTooLarge = errors.New("frame is too large")
+)
+
+// WriteFrame writes a payload prefixed with its length (big-endian uint32)
+func WriteFrame(writer io.Writer, payload []byte, maxSize int) error {
+	if maxSize > 0 && len(payload) > maxSize {
+		return ErrFrameTooLarge
+	}
+
+	frame := make([]byte, HeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload))) // nolint : G115: integer overflow conversion int -> uint32
+	copy(frame[HeaderSize:], payload)
+
+	if _, err := writer.Write(frame); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReadFrame reads a whole frame regardless of how it was split by the transport
+func ReadFrame(reader io.Reader, maxSize int) ([]byte, error) {
+	var header [HeaderSize]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if maxSize > 0 && uint64(size) > uint64(maxSize) { // nolint : G115: integer overflow conversion int -> uint64
+		return nil, fmt.Errorf("%w: %d bytes, max %d bytes", ErrFrameTooLarge, size, maxSize)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,137 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFrame(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		payload []byte
+		maxSize int
+
+		expectedData []byte
+		expectedErr  error
+	}{
+		"write empty frame": {
+			payload:      nil,
+			expectedData: []byte{0, 0, 0, 0},
+		},
+		"write frame": {
+			payload:      []byte("GET key"),
+			maxSize:      7,
+			expectedData: append([]byte{0, 0, 0, 7}, []byte("GET key")...),
+		},
+		"write too large frame": {
+			payload:     []byte("GET key"),
+			maxSize:     6,
+			expectedErr: ErrFrameTooLarge,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buffer bytes.Buffer
+			err := WriteFrame(&buffer, test.payload, test.maxSize)
+			assert.ErrorIs(t, err, test.expectedErr)
+			if test.expectedErr == nil {
+				assert.Equal(t, test.expectedData, buffer.Bytes())
+			}
+		})
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		data    []byte
+		maxSize int
+
+		expectedPayload []byte
+		expectedErr     error
+	}{
+		"read frame": {
+			data:            append([]byte{0, 0, 0, 3}, []byte("abc")...),
+			expectedPayload: []byte("abc"),
+		},
+		"read empty frame": {
+			data:            []byte{0, 0, 0, 0},
+			expectedPayload: []byte{},
+		},
+		"read frame without data": {
+			data:        nil,
+			expectedErr: io.EOF,
+		},
+		"read frame with truncated header": {
+			data:        []byte{0, 0},
+			expectedErr: io.ErrUnexpectedEOF,
+		},
+		"read frame with truncated payload": {
+			data:        append([]byte{0, 0, 0, 5}, []byte("abc")...),
+			expectedErr: io.ErrUnexpectedEOF,
+		},
+		"read too large frame": {
+			data:        append([]byte{0, 0, 0, 5}, []byte("abcde")...),
+			maxSize:     4,
+			expectedErr: ErrFrameTooLarge,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			payload, err := ReadFrame(bytes.NewReader(test.data), test.maxSize)
+			assert.True(t, errors.Is(err, test.expectedErr))
+			assert.Equal(t, test.expectedPayload, payload)
+		})
+	}
+}
+
+func TestReadFrameFromSplitStream(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteFrame(&buffer, []byte("SET key1 value1"), 0))
+	require.NoError(t, WriteFrame(&buffer, []byte("GET key1"), 0))
+
+	reader := &oneByteReader{data: buffer.Bytes()}
+
+	payload, err := ReadFrame(reader, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "SET key1 value1", string(payload))
+
+	payload, err = ReadFrame(reader, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "GET key1", string(payload))
+
+	_, err = ReadFrame(reader, 0)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+type oneByteReader struct {
+	data []byte
+}
+
+func (r *oneByteReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	p[0] = r.data[0]
+	r.data = r.data[1:]
+	return 1, nil
+}
@@ -7,11 +7,14 @@ import (
 	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 
+	"database-simon/internal/common"
 	"database-simon/internal/concurrency"
+	"database-simon/internal/network/protocol"
 )
 
 // TCPHandler ...
@@ -22,6 +25,7 @@ type TCPServer struct {
 	listener net.Listener
 
 	semaphore concurrency.Semaphore
+	sessions  atomic.Int64
 
 	idleTimeout    time.Duration
 	bufferSize     int
@@ -112,7 +116,11 @@ func (s *TCPServer) handleConnection(ctx context.Context, connection net.Conn, h
 		}
 	}()
 
-	request := make([]byte, s.bufferSize)
+	// every connection is a separate session, its context is canceled
+	// when the connection is closed
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx = common.ContextWithSessionID(ctx, s.sessions.Add(1))
 
 	for {
 		if s.idleTimeout != 0 {
@@ -122,16 +130,24 @@ func (s *TCPServer) handleConnection(ctx context.Context, connection net.Conn, h
 			}
 		}
 
-		count, err := connection.Read(request)
-		if err != nil && err != io.EOF {
+		request, err := protocol.ReadFrame(connection, s.bufferSize)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if errors.Is(err, protocol.ErrFrameTooLarge) {
+			s.logger.Warn("too large message", zap.Int("buffer_size", s.bufferSize), zap.Error(err))
+			break
+		} else if err != nil {
 			s.logger.Warn(
 				"failed to read data",
 				zap.String("address", connection.RemoteAddr().String()),
 				zap.Error(err),
 			)
 			break
-		} else if count == s.bufferSize {
-			s.logger.Warn("small buffer size", zap.Int("buffer_size", s.bufferSize))
+		}
+
+		// connections are dropped when the server is stopped,
+		// so clients don't wait for responses of the stopped handler
+		if ctx.Err() != nil {
 			break
 		}
 
@@ -142,9 +158,9 @@ func (s *TCPServer) handleConnection(ctx context.Context, connection net.Conn, h
 			}
 		}
 
-		response := handler(ctx, request[:count])
+		response := handler(ctx, request)
 
-		if _, err = connection.Write(response); err != nil {
+		if err = protocol.WriteFrame(connection, response, 0); err != nil {
 			s.logger.Warn(
 				"failed to write data",
 				zap.String("address", connection.RemoteAddr().String()),
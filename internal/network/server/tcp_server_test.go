@@ -1,8 +1,10 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"net"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
@@ -10,6 +12,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+
+	"database-simon/internal/common"
+	"database-simon/internal/network/protocol"
 )
 
 func TestTCPServer(t *testing.T) {
@@ -39,17 +44,16 @@ func TestTCPServer(t *testing.T) {
 		connection, clientErr := net.Dial("tcp", serverAddress)
 		require.NoError(t, clientErr)
 
-		_, clientErr = connection.Write([]byte("client-1"))
+		clientErr = protocol.WriteFrame(connection, []byte("client-1"), 0)
 		require.NoError(t, clientErr)
 
-		buffer := make([]byte, 1024)
-		size, clientErr := connection.Read(buffer)
+		response, clientErr := protocol.ReadFrame(connection, 1024)
 		require.NoError(t, clientErr)
 
 		clientErr = connection.Close()
 		require.NoError(t, clientErr)
 
-		assert.Equal(t, "hello-client-1", string(buffer[:size]))
+		assert.Equal(t, "hello-client-1", string(response))
 	}()
 
 	go func() {
@@ -58,19 +62,121 @@ func TestTCPServer(t *testing.T) {
 		connection, clientErr := net.Dial("tcp", serverAddress)
 		require.NoError(t, clientErr)
 
-		_, clientErr = connection.Write([]byte("client-2"))
+		clientErr = protocol.WriteFrame(connection, []byte("client-2"), 0)
 		require.NoError(t, clientErr)
 
-		buffer := make([]byte, 1024)
-		size, clientErr := connection.Read(buffer)
+		response, clientErr := protocol.ReadFrame(connection, 1024)
 		require.NoError(t, clientErr)
 
 		clientErr = connection.Close()
 		require.NoError(t, clientErr)
 
-		assert.Equal(t, "hello-client-2", string(buffer[:size]))
+		assert.Equal(t, "hello-client-2", string(response))
 	}()
 
 	wg.Wait()
 	cancel()
 }
+
+func TestTCPServerWithSegmentedMessages(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverAddress := "localhost:55557"
+	server, err := NewTCPServer(serverAddress, zap.NewNop(), WithServerBufferSize(16))
+	require.NoError(t, err)
+
+	go func() {
+		server.HandleQueries(ctx, func(_ context.Context, data []byte) []byte {
+			return []byte("hello-" + string(data))
+		})
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	connection, err := net.Dial("tcp", serverAddress)
+	require.NoError(t, err)
+	defer func() {
+		_ = connection.Close()
+	}()
+
+	var data bytes.Buffer
+	require.NoError(t, protocol.WriteFrame(&data, []byte("client-1"), 0))
+	require.NoError(t, protocol.WriteFrame(&data, []byte("client-2"), 0))
+
+	// split the first message and glue its tail with the second one
+	_, err = connection.Write(data.Bytes()[:6])
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	_, err = connection.Write(data.Bytes()[6:])
+	require.NoError(t, err)
+
+	response, err := protocol.ReadFrame(connection, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "hello-client-1", string(response))
+
+	response, err = protocol.ReadFrame(connection, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "hello-client-2", string(response))
+
+	// message larger than the buffer size drops the connection
+	require.NoError(t, protocol.WriteFrame(connection, []byte("very-long-client-message"), 0))
+	_, err = protocol.ReadFrame(connection, 1024)
+	assert.Error(t, err)
+}
+
+func TestTCPServerSessions(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverAddress := "localhost:55558"
+	server, err := NewTCPServer(serverAddress, zap.NewNop())
+	require.NoError(t, err)
+
+	closedSessions := make(chan int64, 3)
+	go func() {
+		server.HandleQueries(ctx, func(ctx context.Context, _ []byte) []byte {
+			sessionID, found := common.GetSessionIDFromContext(ctx)
+			require.True(t, found)
+
+			context.AfterFunc(ctx, func() {
+				closedSessions <- sessionID
+			})
+			return []byte(strconv.FormatInt(sessionID, 10))
+		})
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	send := func(connection net.Conn) string {
+		require.NoError(t, protocol.WriteFrame(connection, []byte("request"), 0))
+		response, errRead := protocol.ReadFrame(connection, 1024)
+		require.NoError(t, errRead)
+		return string(response)
+	}
+
+	connection1, err := net.Dial("tcp", serverAddress)
+	require.NoError(t, err)
+	connection2, err := net.Dial("tcp", serverAddress)
+	require.NoError(t, err)
+
+	session1 := send(connection1)
+	assert.Equal(t, session1, send(connection1))
+
+	session2 := send(connection2)
+	assert.NotEqual(t, session1, session2)
+
+	require.NoError(t, connection1.Close())
+	select {
+	case sessionID := <-closedSessions:
+		assert.Equal(t, session1, strconv.FormatInt(sessionID, 10))
+	case <-time.After(time.Second):
+		assert.Fail(t, "session context is not canceled")
+	}
+
+	require.NoError(t, connection2.Close())
+}
@@ -1,7 +1,6 @@
 package e2e
 
 import (
-	"net"
 	"os/exec"
 	"syscall"
 	"testing"
@@ -9,10 +8,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"database-simon/internal/network/client"
 )
 
 func TestE2ENetwork(t *testing.T) {
-	buffer := make([]byte, 1024)
 	const serverAddress = "localhost:8081"
 
 	cmd := exec.Command("../../storage_server", "-config", "./config_network.yml")
@@ -20,45 +20,32 @@ func TestE2ENetwork(t *testing.T) {
 
 	time.Sleep(time.Second)
 
-	connection, clientErr := net.Dial("tcp", serverAddress)
-	require.NoError(t, clientErr)
-
-	_, clientErr = connection.Write([]byte("GET key1"))
-	require.NoError(t, clientErr)
-
-	size, clientErr := connection.Read(buffer)
+	connection, clientErr := client.NewTCPClient(serverAddress)
 	require.NoError(t, clientErr)
-	assert.Equal(t, "[not found]", string(buffer[:size]))
 
-	_, clientErr = connection.Write([]byte("SET key1 value1"))
-	require.NoError(t, clientErr)
-
-	size, clientErr = connection.Read(buffer)
-	require.NoError(t, clientErr)
-	assert.Equal(t, "[ok]", string(buffer[:size]))
-
-	_, clientErr = connection.Write([]byte("GET key1"))
-	require.NoError(t, clientErr)
+	var response []byte
 
-	size, clientErr = connection.Read(buffer)
+	response, clientErr = connection.Send([]byte("GET key1"))
 	require.NoError(t, clientErr)
-	assert.Equal(t, "value1", string(buffer[:size]))
+	assert.Equal(t, "[not found]", string(response))
 
-	_, clientErr = connection.Write([]byte("DEL key1"))
+	response, clientErr = connection.Send([]byte("SET key1 value1"))
 	require.NoError(t, clientErr)
+	assert.Equal(t, "[ok]", string(response))
 
-	size, clientErr = connection.Read(buffer)
+	response, clientErr = connection.Send([]byte("GET key1"))
 	require.NoError(t, clientErr)
-	assert.Equal(t, "[ok]", string(buffer[:size]))
+	assert.Equal(t, "value1", string(response))
 
-	_, clientErr = connection.Write([]byte("GET key1"))
+	response, clientErr = connection.Send([]byte("DEL key1"))
 	require.NoError(t, clientErr)
+	assert.Equal(t, "[ok]", string(response))
 
-	size, clientErr = connection.Read(buffer)
+	response, clientErr = connection.Send([]byte("GET key1"))
 	require.NoError(t, clientErr)
-	assert.Equal(t, "[not found]", string(buffer[:size]))
+	assert.Equal(t, "[not found]", string(response))
 
-	require.NoError(t, connection.Close())
+	connection.Close()
 	require.NoError(t, cmd.Process.Signal(syscall.SIGTERM))
 
 	time.Sleep(time.Second)
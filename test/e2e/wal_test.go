@@ -1,7 +1,6 @@
 package e2e
 
 import (
-	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,10 +10,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"database-simon/internal/network/client"
 )
 
 func TestE2EWAL(t *testing.T) {
-	buffer := make([]byte, 1024)
 	const serverAddress = "localhost:3223"
 
 	cmd := exec.Command("../../storage_server", "-config", "./config_wal.yml")
@@ -22,29 +22,22 @@ func TestE2EWAL(t *testing.T) {
 
 	time.Sleep(time.Second)
 
-	connection, clientErr := net.Dial("tcp", serverAddress)
-	require.NoError(t, clientErr)
-
-	_, clientErr = connection.Write([]byte("GET key1"))
-	require.NoError(t, clientErr)
-
-	size, clientErr := connection.Read(buffer)
+	connection, clientErr := client.NewTCPClient(serverAddress)
 	require.NoError(t, clientErr)
-	assert.Equal(t, "[not found]", string(buffer[:size]))
 
-	_, clientErr = connection.Write([]byte("SET key1 value1"))
-	require.NoError(t, clientErr)
+	var response []byte
 
-	size, clientErr = connection.Read(buffer)
+	response, clientErr = connection.Send([]byte("GET key1"))
 	require.NoError(t, clientErr)
-	assert.Equal(t, "[ok]", string(buffer[:size]))
+	assert.Equal(t, "[not found]", string(response))
 
-	_, clientErr = connection.Write([]byte("GET key1"))
+	response, clientErr = connection.Send([]byte("SET key1 value1"))
 	require.NoError(t, clientErr)
+	assert.Equal(t, "[ok]", string(response))
 
-	size, clientErr = connection.Read(buffer)
+	response, clientErr = connection.Send([]byte("GET key1"))
 	require.NoError(t, clientErr)
-	assert.Equal(t, "value1", string(buffer[:size]))
+	assert.Equal(t, "value1", string(response))
 
 	time.Sleep(time.Second)
 
@@ -57,17 +50,14 @@ func TestE2EWAL(t *testing.T) {
 
 	time.Sleep(time.Second)
 
-	connection, clientErr = net.Dial("tcp", serverAddress)
-	require.NoError(t, clientErr)
-
-	_, clientErr = connection.Write([]byte("GET key1"))
+	connection, clientErr = client.NewTCPClient(serverAddress)
 	require.NoError(t, clientErr)
 
-	size, clientErr = connection.Read(buffer)
+	response, clientErr = connection.Send([]byte("GET key1"))
 	require.NoError(t, clientErr)
-	assert.Equal(t, "value1", string(buffer[:size]))
+	assert.Equal(t, "value1", string(response))
 
-	require.NoError(t, connection.Close())
+	connection.Close()
 	require.NoError(t, cmd.Process.Signal(syscall.SIGTERM))
 
 	files, errGlob := filepath.Glob("./data/wal/wal_*")